@@ -1,26 +1,40 @@
 package artifact
 
 import (
+	"fmt"
+
 	"github.com/ovh/cds/sdk"
 
 	"github.com/spf13/cobra"
 )
 
-var environment string
+var (
+	environment string
+	selector    sdk.ArtifactSelector
+)
 
 func cmdArtifactDownload() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:     "download",
-		Short:   "cds artifact download <projectName> <applicationName> <pipelineName> <tag> [artifactName]",
-		Long:    ``,
+		Short:   "cds artifact download <projectName> <applicationName> <pipelineName> [<tag> [artifactName]]",
+		Long:    `Download artifacts of a tag, or of the last build matching --version, --branch, --last-success and --promoted when no tag is given`,
 		Run:     downloadArtifacts,
 		Aliases: []string{"dl"},
 	}
 	cmd.Flags().StringVarP(&environment, "env", "", "", "environment name")
+	cmd.Flags().Int64VarP(&selector.Version, "version", "", 0, "download artifacts of this build version")
+	cmd.Flags().StringVarP(&selector.Branch, "branch", "", "", "download artifacts of the last build of this branch")
+	cmd.Flags().BoolVarP(&selector.LastSuccess, "last-success", "", false, "download artifacts of the last successful build")
+	cmd.Flags().BoolVarP(&selector.Promoted, "promoted", "", false, "download artifacts of the last build with promoted artifacts")
 	return cmd
 }
 
 func downloadArtifacts(cmd *cobra.Command, args []string) {
+	if len(args) == 3 && selector != (sdk.ArtifactSelector{}) {
+		downloadSelectedArtifacts(args)
+		return
+	}
+
 	if len(args) == 5 {
 		downloadArtifact(args)
 		return
@@ -55,3 +69,18 @@ func downloadArtifact(args []string) {
 		sdk.Exit("Error: Cannot download artifact %s (%s)\n", filename, err)
 	}
 }
+
+func downloadSelectedArtifacts(args []string) {
+	basedir := "."
+	project := args[0]
+	appName := args[1]
+	pipeline := args[2]
+
+	arts, err := sdk.DownloadArtifactsFrom(project, appName, pipeline, environment, selector, basedir)
+	if err != nil {
+		sdk.Exit("Error: Cannot download artifacts in %s-%s-%s (%s)\n", project, appName, pipeline, err)
+	}
+	for _, a := range arts {
+		fmt.Printf("%s (build #%d)\n", a.Name, a.BuildNumber)
+	}
+}
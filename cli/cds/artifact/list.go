@@ -2,6 +2,8 @@ package artifact
 
 import (
 	"fmt"
+	"os"
+	"text/tabwriter"
 
 	"github.com/ovh/cds/sdk"
 
@@ -36,7 +38,21 @@ func listArtifacts(cmd *cobra.Command, args []string) {
 		sdk.Exit("Error: Cannot list artifacts in %s-%s-%s/%s (%s)\n", project, appName, pipeline, tag, err)
 	}
 
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSIZE\tCHECKSUM\tPROMOTED")
 	for _, a := range arts {
-		fmt.Printf("- %s\n", a.Name)
+		checksum := "-"
+		switch {
+		case a.SHA256 != "":
+			checksum = "sha256:" + a.SHA256
+		case a.MD5sum != "":
+			checksum = "md5:" + a.MD5sum
+		}
+		promoted := "-"
+		if a.Promoted {
+			promoted = a.PromotedBy
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", a.Name, a.Size, checksum, promoted)
 	}
+	w.Flush()
 }
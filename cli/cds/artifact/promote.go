@@ -0,0 +1,55 @@
+package artifact
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/ovh/cds/sdk"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	promoteEnv    string
+	promoteRemove bool
+)
+
+func cmdArtifactPromote() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "promote",
+		Short: "cds artifact promote <projectName> <applicationName> <pipelineName> <buildNumber>",
+		Long:  `Mark artifacts of a build as released, they are kept by artifact retention`,
+		Run:   promoteArtifacts,
+	}
+	cmd.Flags().StringVarP(&promoteEnv, "env", "", "", "environment name")
+	cmd.Flags().BoolVarP(&promoteRemove, "remove", "", false, "remove the promotion")
+	return cmd
+}
+
+func promoteArtifacts(cmd *cobra.Command, args []string) {
+	if len(args) != 4 {
+		sdk.Exit("Wrong usage: %s\n", cmd.Short)
+	}
+	project := args[0]
+	appName := args[1]
+	pipeline := args[2]
+	buildNumber, err := strconv.Atoi(args[3])
+	if err != nil {
+		sdk.Exit("Error: buildNumber must be an integer (%s)\n", err)
+	}
+
+	if promoteRemove {
+		err = sdk.UnpromoteArtifacts(project, appName, pipeline, promoteEnv, buildNumber)
+	} else {
+		err = sdk.PromoteArtifacts(project, appName, pipeline, promoteEnv, buildNumber)
+	}
+	if err != nil {
+		sdk.Exit("Error: Cannot update artifacts of %s-%s-%s build %d (%s)\n", project, appName, pipeline, buildNumber, err)
+	}
+
+	if promoteRemove {
+		fmt.Printf("Artifacts of %s-%s-%s build %d are no longer promoted\n", project, appName, pipeline, buildNumber)
+		return
+	}
+	fmt.Printf("Artifacts of %s-%s-%s build %d promoted\n", project, appName, pipeline, buildNumber)
+}
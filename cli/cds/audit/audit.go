@@ -0,0 +1,86 @@
+package audit
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ovh/cds/sdk"
+)
+
+var (
+	auditProject string
+	auditUser    string
+	auditSince   string
+	auditUntil   string
+	auditLimit   int
+	auditDiff    bool
+)
+
+// Cmd audit
+func Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Audit log of changes made through CDS API",
+		Long: `Audit log of changes made through CDS API
+
+Without --project, listing audit log requires to be CDS administrator.
+Dates are either RFC3339 (2016-10-18T10:00:00Z) or durations before now (24h).`,
+		Run: listAudit,
+	}
+
+	cmd.Flags().StringVarP(&auditProject, "project", "", "", "Project key")
+	cmd.Flags().StringVarP(&auditUser, "user", "", "", "Username or worker name")
+	cmd.Flags().StringVarP(&auditSince, "since", "", "", "Events since date or duration")
+	cmd.Flags().StringVarP(&auditUntil, "until", "", "", "Events until date or duration")
+	cmd.Flags().IntVarP(&auditLimit, "limit", "", 100, "Maximum number of events")
+	cmd.Flags().BoolVarP(&auditDiff, "diff", "", false, "Display changed fields")
+	return cmd
+}
+
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func listAudit(cmd *cobra.Command, args []string) {
+	since, err := parseDate(auditSince)
+	if err != nil {
+		sdk.Exit("Invalid --since: %s\n", err)
+	}
+	until, err := parseDate(auditUntil)
+	if err != nil {
+		sdk.Exit("Invalid --until: %s\n", err)
+	}
+
+	events, err := sdk.ListAuditEvents(sdk.AuditFilter{
+		ProjectKey: auditProject,
+		Username:   auditUser,
+		Since:      since,
+		Until:      until,
+		Limit:      auditLimit,
+	})
+	if err != nil {
+		sdk.Exit("Error: cannot list audit log (%s)\n", err)
+	}
+
+	for _, e := range events {
+		who := e.Username
+		if e.Worker != "" {
+			who = fmt.Sprintf("worker %s", e.Worker)
+		}
+		fmt.Printf("%s %-15s %-6s %d %s %s\n", e.Date.Format(time.RFC3339), e.IP, e.Method, e.Status, who, e.Path)
+		if !auditDiff {
+			continue
+		}
+		for _, d := range e.Diff {
+			fmt.Printf("\t%s: %s -> %s\n", d.Field, d.Before, d.After)
+		}
+	}
+}
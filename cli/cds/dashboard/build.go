@@ -0,0 +1,429 @@
+package dashboard
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gizak/termui"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func (ui *Termui) initBuildKeys() {
+	ui.bind(BuildView, "<down>", func() {
+		ui.selectedRow++
+		ui.drawBuild()
+	})
+	ui.bind(BuildView, "j", func() {
+		ui.selectedRow++
+		ui.drawBuild()
+	})
+	ui.bind(BuildView, "<up>", func() {
+		if ui.selectedRow > 0 {
+			ui.selectedRow--
+			ui.drawBuild()
+		}
+	})
+	ui.bind(BuildView, "k", func() {
+		if ui.selectedRow > 0 {
+			ui.selectedRow--
+			ui.drawBuild()
+		}
+	})
+	ui.bind(BuildView, "<enter>", func() {
+		if ab, ok := ui.selectedActionBuild(); ok {
+			ui.showBuildLogs(ab)
+			return
+		}
+		ui.approveSelectedTrigger()
+	})
+	ui.bind(BuildView, "a", ui.approveSelectedTrigger)
+	ui.bind(BuildView, "r", func() {
+		pb := ui.build
+		key := ui.buildProject
+		ui.confirm(fmt.Sprintf("Restart %s #%d?", pb.Pipeline.Name, pb.BuildNumber), func() {
+			go func() {
+				if err := sdk.RestartPipelineBuild(key, pb.Application.Name, pb.Pipeline.Name, pb.Environment.Name, pb.BuildNumber); err != nil {
+					ui.msg.Text = fmt.Sprintf("Cannot restart build: %s", err)
+					return
+				}
+				ui.msg.Text = fmt.Sprintf("%s #%d restarted", pb.Pipeline.Name, pb.BuildNumber)
+				ui.loadBuild()
+			}()
+		})
+	})
+	ui.bind(BuildView, "x", func() {
+		pb := ui.build
+		key := ui.buildProject
+		ui.confirm(fmt.Sprintf("Stop %s #%d?", pb.Pipeline.Name, pb.BuildNumber), func() {
+			go func() {
+				if err := sdk.StopPipelineBuild(key, pb.Application.Name, pb.Pipeline.Name, pb.Environment.Name, pb.BuildNumber); err != nil {
+					ui.msg.Text = fmt.Sprintf("Cannot stop build: %s", err)
+					return
+				}
+				ui.msg.Text = fmt.Sprintf("%s #%d stopped", pb.Pipeline.Name, pb.BuildNumber)
+				ui.loadBuild()
+			}()
+		})
+	})
+	// History is sorted from newest to oldest build
+	ui.bind(BuildView, "p", func() {
+		ui.switchBuild(1)
+	})
+	ui.bind(BuildView, "n", func() {
+		ui.switchBuild(-1)
+	})
+	back := func() {
+		if ui.buildFrom == QueueView {
+			ui.showQueue()
+			return
+		}
+		ui.showDashboard()
+		ui.selected = PipelineSelected
+		ui.drawProjects()
+	}
+	ui.bind(BuildView, "<left>", back)
+	ui.bind(BuildView, "<escape>", back)
+}
+
+// showSelectedBuild drills down to the pipeline build selected in dashboard
+func (ui *Termui) showSelectedBuild() {
+	ui.Lock()
+	if ui.selectedProject <= 0 || ui.selectedProject > len(ui.proj) {
+		ui.Unlock()
+		return
+	}
+	p := ui.proj[ui.selectedProject-1]
+	if ui.selectedApp <= 0 || ui.selectedApp > len(p.Applications) {
+		ui.Unlock()
+		return
+	}
+	app := p.Applications[ui.selectedApp-1]
+	if ui.selectedPipeline <= 0 || ui.selectedPipeline > len(app.PipelinesBuild) {
+		ui.Unlock()
+		return
+	}
+	pb := app.PipelinesBuild[ui.selectedPipeline-1]
+	pb.Application = sdk.Application{ID: app.ID, Name: app.Name}
+	ui.buildFrom = DashboardView
+	ui.Unlock()
+
+	ui.showBuild(p.Key, pb)
+}
+
+func (ui *Termui) showBuild(projectKey string, pb sdk.PipelineBuild) {
+	ui.Lock()
+	ui.current = BuildView
+	ui.buildProject = projectKey
+	ui.build = pb
+	ui.buildHistory = nil
+	ui.buildTriggers = nil
+	ui.buildActions = nil
+	ui.selectedRow = 0
+	termui.Body.Rows = nil
+
+	ui.buildInfo = termui.NewPar("")
+	ui.buildInfo.Height = 6
+	ui.buildInfo.TextFgColor = termui.ColorWhite
+	ui.buildInfo.BorderLabel = "Build"
+	ui.buildInfo.BorderFg = termui.ColorCyan
+
+	ui.buildList = termui.NewList()
+	ui.buildList.Height = termui.TermHeight() - 9
+	ui.buildList.ItemFgColor = termui.ColorWhite
+	ui.buildList.BorderLabel = "Actions | <enter> logs | (r)estart | (x) stop | (a)pprove"
+	ui.buildList.BorderFg = termui.ColorCyan
+
+	ui.historyList = termui.NewList()
+	ui.historyList.Height = termui.TermHeight() - 3
+	ui.historyList.ItemFgColor = termui.ColorWhite
+	ui.historyList.BorderLabel = "History | (p)revious | (n)ext"
+	ui.historyList.BorderFg = termui.ColorCyan
+
+	termui.Body.AddRows(
+		termui.NewRow(
+			termui.NewCol(6, 0, ui.header),
+			termui.NewCol(6, 0, ui.msg),
+		),
+		termui.NewRow(
+			termui.NewCol(9, 0, ui.buildInfo, ui.buildList),
+			termui.NewCol(3, 0, ui.historyList),
+		),
+	)
+	ui.Unlock()
+
+	termui.Clear()
+	ui.draw(0)
+	ui.updateBuild()
+}
+
+func (ui *Termui) updateBuild() {
+	ui.loadBuild()
+	ui.loadBuildHistory()
+
+	go func() {
+		for {
+			time.Sleep(2 * time.Second)
+			if ui.current != BuildView {
+				return
+			}
+			ui.loadBuild()
+		}
+	}()
+}
+
+// loadBuild loads state of drilled down pipeline build
+func (ui *Termui) loadBuild() {
+	ui.Lock()
+	pb := ui.build
+	key := ui.buildProject
+	ui.Unlock()
+
+	begin := time.Now()
+	state, err := sdk.GetBuildState(key, pb.Application.Name, pb.Pipeline.Name, pb.Environment.Name, fmt.Sprintf("%d", pb.BuildNumber))
+	if err != nil {
+		ui.msg.Text = fmt.Sprintf("Cannot load build %s #%d: %s", pb.Pipeline.Name, pb.BuildNumber, err)
+		return
+	}
+	ui.msg.Text = fmt.Sprintf("Delay: %s", time.Since(begin).String())
+
+	ui.Lock()
+	if ui.build.BuildNumber != pb.BuildNumber {
+		// User switched to another build meanwhile
+		ui.Unlock()
+		return
+	}
+	// Keep what identifies the build, state may not carry it
+	state.BuildNumber = pb.BuildNumber
+	state.Application = pb.Application
+	state.Pipeline = pb.Pipeline
+	state.Environment = pb.Environment
+	if state.Version == 0 {
+		state.Version = pb.Version
+	}
+	if state.Trigger.VCSChangesBranch == "" {
+		state.Trigger = pb.Trigger
+	}
+	ui.build = state
+	ui.Unlock()
+
+	ui.drawBuild()
+}
+
+// loadBuildHistory loads recent builds and manual triggers of drilled down pipeline
+func (ui *Termui) loadBuildHistory() {
+	ui.Lock()
+	pb := ui.build
+	key := ui.buildProject
+	ui.Unlock()
+
+	history, err := sdk.GetPipelineBuildHistory(key, pb.Application.Name, pb.Pipeline.Name, pb.Environment.Name)
+	if err != nil {
+		ui.msg.Text = fmt.Sprintf("Cannot load history of %s: %s", pb.Pipeline.Name, err)
+	}
+
+	triggers, err := sdk.GetTriggers(key, pb.Application.Name, pb.Pipeline.Name, pb.Environment.Name)
+	if err != nil {
+		ui.msg.Text = fmt.Sprintf("Cannot load triggers of %s: %s", pb.Pipeline.Name, err)
+	}
+	var manual []sdk.PipelineTrigger
+	for _, t := range triggers {
+		if t.Manual {
+			manual = append(manual, t)
+		}
+	}
+
+	ui.Lock()
+	ui.buildHistory = history
+	ui.buildTriggers = manual
+	ui.Unlock()
+
+	ui.drawBuild()
+}
+
+// switchBuild drills down to the build at given distance in history
+func (ui *Termui) switchBuild(delta int) {
+	ui.Lock()
+	var next *sdk.PipelineBuild
+	for i := range ui.buildHistory {
+		if ui.buildHistory[i].BuildNumber == ui.build.BuildNumber {
+			if j := i + delta; j >= 0 && j < len(ui.buildHistory) {
+				next = &ui.buildHistory[j]
+			}
+			break
+		}
+	}
+	if next == nil {
+		ui.Unlock()
+		ui.msg.Text = "No other build in history"
+		return
+	}
+	pb := *next
+	pb.Application = ui.build.Application
+	pb.Pipeline = ui.build.Pipeline
+	pb.Environment = ui.build.Environment
+	ui.build = pb
+	ui.selectedRow = 0
+	ui.Unlock()
+
+	ui.loadBuild()
+}
+
+func (ui *Termui) selectedActionBuild() (sdk.ActionBuild, bool) {
+	ui.Lock()
+	defer ui.Unlock()
+	if ui.selectedRow < len(ui.buildActions) {
+		return ui.buildActions[ui.selectedRow], true
+	}
+	return sdk.ActionBuild{}, false
+}
+
+// approveSelectedTrigger runs the pipeline of selected manual trigger with drilled down build as parent
+func (ui *Termui) approveSelectedTrigger() {
+	ui.Lock()
+	i := ui.selectedRow - len(ui.buildActions)
+	if ui.build.Status != sdk.StatusSuccess || i < 0 || i >= len(ui.buildTriggers) {
+		ui.Unlock()
+		ui.msg.Text = "Select a manual trigger of a successful build to approve"
+		return
+	}
+	t := ui.buildTriggers[i]
+	pb := ui.build
+	key := ui.buildProject
+	ui.Unlock()
+
+	env := t.DestEnvironment.Name
+	if env == "" {
+		env = sdk.DefaultEnv.Name
+	}
+	parentEnvID := t.SrcEnvironment.ID
+	if parentEnvID == 0 {
+		parentEnvID = sdk.DefaultEnv.ID
+	}
+
+	ui.confirm(fmt.Sprintf("Run %s/%s [%s] from %s #%d?", t.DestApplication.Name, t.DestPipeline.Name, env, pb.Pipeline.Name, pb.BuildNumber), func() {
+		request := sdk.RunRequest{
+			Params:              t.Parameters,
+			ParentBuildNumber:   pb.BuildNumber,
+			ParentPipelineID:    t.SrcPipeline.ID,
+			ParentApplicationID: t.SrcApplication.ID,
+			ParentEnvironmentID: parentEnvID,
+		}
+		go func() {
+			if _, err := sdk.RunPipeline(key, t.DestApplication.Name, t.DestPipeline.Name, env, false, request, false); err != nil {
+				ui.msg.Text = fmt.Sprintf("Cannot run %s: %s", t.DestPipeline.Name, err)
+				return
+			}
+			ui.msg.Text = fmt.Sprintf("%s/%s [%s] started", t.DestApplication.Name, t.DestPipeline.Name, env)
+		}()
+	})
+}
+
+func (ui *Termui) drawBuild() {
+	ui.Lock()
+	defer ui.Unlock()
+
+	if ui.current != BuildView {
+		return
+	}
+	pb := ui.build
+
+	info := fmt.Sprintf("%s %s[➤](fg-cyan)%s[➤](fg-cyan)%s[#%d](fg-cyan) v%d", statusText(pb.Status), ui.buildProject, pb.Application.Name, pb.Pipeline.Name, pb.BuildNumber, pb.Version)
+	if pb.Environment.Name != "" && pb.Environment.Name != sdk.DefaultEnv.Name {
+		info += fmt.Sprintf(" [%s](fg-magenta)", pb.Environment.Name)
+	}
+	if pb.Trigger.VCSChangesBranch != "" {
+		info += fmt.Sprintf("\nBranch: [%s](fg-magenta) %s %s", pb.Trigger.VCSChangesBranch, pb.Trigger.VCSChangesHash, pb.Trigger.VCSChangesAuthor)
+	}
+	if pb.Trigger.TriggeredBy != nil {
+		info += fmt.Sprintf("\nTriggered by %s", pb.Trigger.TriggeredBy.Username)
+	}
+	if !pb.Start.IsZero() {
+		info += fmt.Sprintf("\nStarted %s ago (%s)", since(pb.Start), duration(pb.Start, pb.Done))
+	}
+	ui.buildInfo.Text = info
+
+	ui.buildActions = nil
+	var items []string
+	for _, s := range pb.Stages {
+		for _, ab := range s.ActionBuilds {
+			ui.buildActions = append(ui.buildActions, ab)
+			items = append(items, fmt.Sprintf("%s %s [➤](fg-cyan) %s %s", statusText(ab.Status), s.Name, ab.ActionName, duration(ab.Start, ab.Done)))
+		}
+	}
+	if pb.Status == sdk.StatusSuccess {
+		for _, t := range ui.buildTriggers {
+			items = append(items, fmt.Sprintf("[⇢](fg-magenta) %s[➤](fg-cyan)%s [%s](fg-magenta) (manual trigger)", t.DestApplication.Name, t.DestPipeline.Name, t.DestEnvironment.Name))
+		}
+	}
+
+	if ui.selectedRow >= len(items) {
+		ui.selectedRow = len(items) - 1
+	}
+	if ui.selectedRow < 0 {
+		ui.selectedRow = 0
+	}
+	for i := range items {
+		if i == ui.selectedRow {
+			items[i] = "[▶](fg-cyan) " + items[i]
+		} else {
+			items[i] = "  " + items[i]
+		}
+	}
+	ui.buildList.Items = items
+
+	var history []string
+	for _, h := range ui.buildHistory {
+		item := fmt.Sprintf("%s #%d v%d %s", statusText(h.Status), h.BuildNumber, h.Version, h.Trigger.VCSChangesBranch)
+		if h.BuildNumber == pb.BuildNumber {
+			item = "[▶](fg-cyan) " + item
+		} else {
+			item = "  " + item
+		}
+		history = append(history, item)
+	}
+	ui.historyList.Items = history
+}
+
+func statusText(s sdk.Status) string {
+	switch s {
+	case sdk.StatusBuilding:
+		return "[↻](fg-blue)"
+	case sdk.StatusSuccess:
+		return "[✓](fg-green)"
+	case sdk.StatusFail:
+		return "[✗](fg-red)"
+	case sdk.StatusWaiting:
+		return "[…](fg-yellow)"
+	default:
+		return "[·](fg-white)"
+	}
+}
+
+func statusColor(s sdk.Status) termui.Attribute {
+	switch s {
+	case sdk.StatusSuccess:
+		return termui.ColorGreen
+	case sdk.StatusFail:
+		return termui.ColorRed
+	case sdk.StatusBuilding:
+		return termui.ColorBlue
+	default:
+		return termui.ColorYellow
+	}
+}
+
+func since(t time.Time) string {
+	return (time.Since(t) / time.Second * time.Second).String()
+}
+
+// duration returns time between start and done, or since start if not done yet
+func duration(start, done time.Time) string {
+	if start.IsZero() {
+		return ""
+	}
+	if done.Before(start) {
+		return since(start)
+	}
+	return (done.Sub(start) / time.Second * time.Second).String()
+}
@@ -33,9 +33,11 @@ func (ui *Termui) showHome() {
 
 	version %s
 
-	type 'd' to view your CDS dashboard
+	type 'd' to view your CDS dashboard, then <enter> on a pipeline to drill down to its builds
 	type 'm' to monitor your building pipelines
 	type 's' to check CDS status
+	type 'u' to browse the queue
+	type 'w' to browse worker models and workers
 	`, sdk.VERSION)
 
 	termui.Clear()
@@ -0,0 +1,125 @@
+package dashboard
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gizak/termui"
+)
+
+// prompt reads text typed by user in message box
+type prompt struct {
+	label string
+	text  string
+	// single prompts complete on first key
+	single bool
+	done   func(string)
+}
+
+func (p *prompt) String() string {
+	if p.single {
+		return fmt.Sprintf("%s (y/n)", p.label)
+	}
+	return fmt.Sprintf("%s: %s_", p.label, p.text)
+}
+
+// bind binds key to f in given view, or in all views if view is empty
+func (ui *Termui) bind(view, key string, f func()) {
+	if ui.bindings == nil {
+		ui.bindings = map[string]map[string]func(){}
+	}
+	if ui.bindings[view] == nil {
+		ui.bindings[view] = map[string]func(){}
+	}
+	ui.bindings[view][key] = f
+}
+
+// onKey is called in order for each event, typed text goes to current prompt
+// and other keys run the function bound in current view, or in all views.
+// Bound functions run synchronously, in the order of keys.
+func (ui *Termui) onKey(e termui.Event) {
+	kbd, ok := e.Data.(termui.EvtKbd)
+	if !ok || !strings.HasPrefix(e.Path, "/sys/kbd/") {
+		return
+	}
+	key := kbd.KeyStr
+
+	if ui.prompt != nil {
+		ui.typeKey(key)
+		ui.draw(0)
+		return
+	}
+
+	f := ui.bindings[ui.current][key]
+	if f == nil {
+		f = ui.bindings[""][key]
+	}
+	if f == nil {
+		ui.msg.Text = fmt.Sprintf("No command for %s", key)
+		return
+	}
+	f()
+}
+
+func (ui *Termui) typeKey(key string) {
+	p := ui.prompt
+	if p.single {
+		ui.prompt = nil
+		ui.msg.Text = ""
+		if key != "<escape>" {
+			p.done(key)
+		}
+		return
+	}
+
+	switch key {
+	case "<enter>":
+		ui.prompt = nil
+		ui.msg.Text = ""
+		p.done(p.text)
+		return
+	case "<escape>":
+		ui.prompt = nil
+		ui.msg.Text = ""
+		return
+	case "<backspace>", "C-8":
+		if len(p.text) > 0 {
+			r := []rune(p.text)
+			p.text = string(r[:len(r)-1])
+		}
+	case "<space>":
+		p.text += " "
+	default:
+		if !strings.HasPrefix(key, "<") && !strings.HasPrefix(key, "C-") && !strings.HasPrefix(key, "M-") {
+			p.text += key
+		}
+	}
+	ui.msg.Text = p.String()
+}
+
+// ask prompts user for a line of text, done is called with text once <enter> is hit
+func (ui *Termui) ask(label, text string, done func(string)) {
+	ui.prompt = &prompt{label: label, text: text, done: done}
+	ui.msg.Text = ui.prompt.String()
+}
+
+// confirm asks user a yes/no question, yes is called if user hits 'y'
+func (ui *Termui) confirm(question string, yes func()) {
+	ui.prompt = &prompt{label: question, single: true, done: func(key string) {
+		if key == "y" || key == "Y" {
+			yes()
+		}
+	}}
+	ui.msg.Text = ui.prompt.String()
+}
+
+// matchFilter returns true if all words of filter are in s, case insensitive
+func matchFilter(s, filter string) bool {
+	s = strings.ToLower(s)
+	for _, w := range strings.Fields(strings.ToLower(filter)) {
+		if !strings.Contains(s, w) {
+			return false
+		}
+	}
+	return true
+}
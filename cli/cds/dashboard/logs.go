@@ -0,0 +1,216 @@
+package dashboard
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gizak/termui"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func (ui *Termui) initBuildLogsKeys() {
+	ui.bind(BuildLogsView, "<down>", func() { ui.scrollLogs(1) })
+	ui.bind(BuildLogsView, "j", func() { ui.scrollLogs(1) })
+	ui.bind(BuildLogsView, "<up>", func() { ui.scrollLogs(-1) })
+	ui.bind(BuildLogsView, "k", func() { ui.scrollLogs(-1) })
+	ui.bind(BuildLogsView, "<next>", func() { ui.scrollLogs(ui.logsHeight()) })
+	ui.bind(BuildLogsView, "<space>", func() { ui.scrollLogs(ui.logsHeight()) })
+	ui.bind(BuildLogsView, "<previous>", func() { ui.scrollLogs(-ui.logsHeight()) })
+	ui.bind(BuildLogsView, "g", func() {
+		ui.logsFollow = false
+		ui.logsOffset = 0
+		ui.drawBuildLogs()
+	})
+	ui.bind(BuildLogsView, "G", func() {
+		ui.logsFollow = true
+		ui.drawBuildLogs()
+	})
+	ui.bind(BuildLogsView, "/", func() {
+		ui.ask("Search", ui.logsSearch, func(s string) {
+			ui.logsSearch = s
+			ui.searchLogs(0)
+		})
+	})
+	ui.bind(BuildLogsView, "n", func() { ui.searchLogs(1) })
+	ui.bind(BuildLogsView, "N", func() { ui.searchLogs(-1) })
+
+	back := func() {
+		row := ui.selectedRow
+		ui.showBuild(ui.buildProject, ui.build)
+		ui.selectedRow = row
+		ui.drawBuild()
+	}
+	ui.bind(BuildLogsView, "<left>", back)
+	ui.bind(BuildLogsView, "<escape>", back)
+}
+
+func (ui *Termui) showBuildLogs(ab sdk.ActionBuild) {
+	ui.Lock()
+	ui.current = BuildLogsView
+	ui.logsBuild = ab
+	ui.logsOffset = 0
+	ui.logsFollow = true
+	termui.Body.Rows = nil
+
+	ui.logsPar = termui.NewPar("")
+	ui.logsPar.Height = termui.TermHeight() - 3
+	ui.logsPar.TextFgColor = termui.ColorWhite
+
+	termui.Body.AddRows(
+		termui.NewRow(
+			termui.NewCol(6, 0, ui.header),
+			termui.NewCol(6, 0, ui.msg),
+		),
+		termui.NewRow(
+			termui.NewCol(12, 0, ui.logsPar),
+		),
+	)
+	ui.Unlock()
+
+	ui.msg.Text = "<j><k> scroll | <g><G> top/follow | </> search, <n><N> next/previous"
+	termui.Clear()
+	ui.drawBuildLogs()
+	ui.draw(0)
+	ui.updateBuildLogs()
+}
+
+// updateBuildLogs reloads logs of action build until it is done
+func (ui *Termui) updateBuildLogs() {
+	go func() {
+		id := ui.logsBuild.ID
+		for {
+			if ui.current != BuildLogsView || ui.logsBuild.ID != id {
+				return
+			}
+			if s := ui.logsBuild.Status; s != sdk.StatusBuilding && s != sdk.StatusWaiting && s != "" {
+				return
+			}
+			time.Sleep(2 * time.Second)
+
+			pb := ui.build
+			state, err := sdk.GetBuildState(ui.buildProject, pb.Application.Name, pb.Pipeline.Name, pb.Environment.Name, fmt.Sprintf("%d", pb.BuildNumber))
+			if err != nil {
+				ui.msg.Text = fmt.Sprintf("Cannot load logs: %s", err)
+				continue
+			}
+			for _, s := range state.Stages {
+				for _, ab := range s.ActionBuilds {
+					if ab.ID == id {
+						ui.Lock()
+						ui.logsBuild = ab
+						ui.Unlock()
+					}
+				}
+			}
+			ui.drawBuildLogs()
+		}
+	}()
+}
+
+func (ui *Termui) logsHeight() int {
+	if ui.logsPar == nil {
+		return 0
+	}
+	return ui.logsPar.Height - 2
+}
+
+func (ui *Termui) scrollLogs(n int) {
+	ui.logsFollow = false
+	ui.logsOffset += n
+	ui.drawBuildLogs()
+}
+
+// searchLogs moves to next (1) or previous (-1) line matching search, or to first match from current line (0)
+func (ui *Termui) searchLogs(direction int) {
+	if ui.logsSearch == "" {
+		return
+	}
+	search := strings.ToLower(ui.logsSearch)
+	lines := strings.Split(ui.logsBuild.Logs, "\n")
+
+	step := direction
+	if step == 0 {
+		step = 1
+	}
+	for i := 0; i < len(lines); i++ {
+		l := (ui.logsOffset + direction + i*step + 2*len(lines)) % len(lines)
+		if strings.Contains(strings.ToLower(lines[l]), search) {
+			ui.logsFollow = false
+			ui.logsOffset = l
+			ui.msg.Text = fmt.Sprintf("%q found line %d", ui.logsSearch, l+1)
+			ui.drawBuildLogs()
+			return
+		}
+	}
+	ui.msg.Text = fmt.Sprintf("%q not found", ui.logsSearch)
+}
+
+func (ui *Termui) drawBuildLogs() {
+	ui.Lock()
+	defer ui.Unlock()
+
+	if ui.current != BuildLogsView || ui.logsPar == nil {
+		return
+	}
+	ab := ui.logsBuild
+	lines := strings.Split(strings.TrimRight(ab.Logs, "\n"), "\n")
+	height := ui.logsPar.Height - 2
+
+	max := len(lines) - height
+	if max < 0 {
+		max = 0
+	}
+	if ui.logsFollow || ui.logsOffset > max {
+		ui.logsOffset = max
+	}
+	if ui.logsOffset < 0 {
+		ui.logsOffset = 0
+	}
+	end := ui.logsOffset + height
+	if end > len(lines) {
+		end = len(lines)
+	}
+
+	visible := make([]string, 0, height)
+	for _, l := range lines[ui.logsOffset:end] {
+		visible = append(visible, highlight(l, ui.logsSearch))
+	}
+	ui.logsPar.Text = strings.Join(visible, "\n")
+
+	label := fmt.Sprintf("%s [%s] lines %d-%d/%d", ab.ActionName, ab.Status, ui.logsOffset+1, end, len(lines))
+	if ui.logsFollow {
+		label += " (following)"
+	}
+	if ui.logsSearch != "" {
+		label += fmt.Sprintf(" search: %s", ui.logsSearch)
+	}
+	ui.logsPar.BorderLabel = label
+	ui.logsPar.BorderFg = statusColor(ab.Status)
+	ui.logsPar.BorderLabelFg = statusColor(ab.Status)
+}
+
+// highlight marks all occurrences of search in line, case insensitive
+func highlight(line, search string) string {
+	if search == "" {
+		return line
+	}
+	lower := strings.ToLower(line)
+	search = strings.ToLower(search)
+	if len(lower) != len(line) {
+		// Lower case would not keep offsets of line
+		lower = line
+	}
+
+	var res string
+	for {
+		i := strings.Index(lower, search)
+		if i < 0 {
+			return res + line
+		}
+		res += line[:i] + fmt.Sprintf("[%s](fg-black,bg-yellow)", line[i:i+len(search)])
+		line = line[i+len(search):]
+		lower = lower[i+len(search):]
+	}
+}
@@ -0,0 +1,118 @@
+package dashboard
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gizak/termui"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func (ui *Termui) initWorkerModelsKeys() {
+	ui.bind(WorkerModelsView, "/", func() {
+		ui.ask("Filter", ui.modelsFilter, func(f string) {
+			ui.modelsFilter = f
+			ui.updateWorkerModels()
+		})
+	})
+	ui.bind(WorkerModelsView, "c", func() {
+		ui.modelsFilter = ""
+		ui.updateWorkerModels()
+	})
+}
+
+func (ui *Termui) showWorkerModels() {
+	ui.Lock()
+	ui.current = WorkerModelsView
+	termui.Body.Rows = nil
+
+	ui.modelsList = termui.NewList()
+	ui.modelsList.Height = (termui.TermHeight() - 3) / 2
+	ui.modelsList.ItemFgColor = termui.ColorWhite
+	ui.modelsList.BorderFg = termui.ColorCyan
+
+	ui.workersList = termui.NewList()
+	ui.workersList.Height = termui.TermHeight() - 3 - ui.modelsList.Height
+	ui.workersList.ItemFgColor = termui.ColorWhite
+	ui.workersList.BorderFg = termui.ColorCyan
+
+	termui.Body.AddRows(
+		termui.NewRow(
+			termui.NewCol(6, 0, ui.header),
+			termui.NewCol(6, 0, ui.msg),
+		),
+		termui.NewRow(
+			termui.NewCol(12, 0, ui.modelsList, ui.workersList),
+		),
+	)
+	ui.Unlock()
+
+	termui.Clear()
+	ui.draw(0)
+	ui.updateWorkerModels()
+
+	go func() {
+		for {
+			time.Sleep(2 * time.Second)
+			if ui.current != WorkerModelsView {
+				return
+			}
+			ui.updateWorkerModels()
+		}
+	}()
+}
+
+func (ui *Termui) updateWorkerModels() {
+	begin := time.Now()
+	status, err := sdk.GetWorkerModelStatus()
+	if err != nil {
+		ui.msg.Text = fmt.Sprintf("Cannot load worker models: %s", err)
+		return
+	}
+	workers, err := sdk.GetWorkers()
+	if err != nil {
+		ui.msg.Text = fmt.Sprintf("Cannot load workers: %s", err)
+		return
+	}
+	ui.msg.Text = fmt.Sprintf("Delay: %s", time.Since(begin).String())
+
+	ui.Lock()
+	defer ui.Unlock()
+
+	if ui.current != WorkerModelsView {
+		return
+	}
+
+	modelNames := map[int64]string{}
+	var models []string
+	for _, s := range status {
+		modelNames[s.ModelID] = s.ModelName
+		if !matchFilter(s.ModelName, ui.modelsFilter) {
+			continue
+		}
+		item := fmt.Sprintf("%-30s [%d](fg-green) up [%d](fg-blue) building [%d](fg-red) wanted", s.ModelName, s.CurrentCount, s.BuildingCount, s.WantedCount)
+		if s.QueueWait > 0 {
+			item += fmt.Sprintf(" [queue wait %s](fg-yellow)", time.Duration(s.QueueWait)*time.Second)
+		}
+		models = append(models, item)
+	}
+
+	var list []string
+	for _, w := range workers {
+		model := modelNames[w.Model]
+		if !matchFilter(fmt.Sprintf("%s %s %s", w.Name, model, w.Status), ui.modelsFilter) {
+			continue
+		}
+		list = append(list, fmt.Sprintf("%s %-40s %-30s %s", statusText(w.Status), w.Name, model, w.Status))
+	}
+
+	label := " | </> filter | (c)lear filter"
+	if ui.modelsFilter != "" {
+		label += fmt.Sprintf(" | filter: %s", ui.modelsFilter)
+	}
+	ui.modelsList.Items = models
+	ui.modelsList.BorderLabel = fmt.Sprintf("Worker models (%d)%s", len(models), label)
+	ui.workersList.Items = list
+	ui.workersList.BorderLabel = fmt.Sprintf("Workers (%d)", len(list))
+}
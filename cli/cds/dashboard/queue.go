@@ -0,0 +1,176 @@
+package dashboard
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gizak/termui"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func (ui *Termui) initQueueKeys() {
+	ui.bind(QueueView, "<down>", func() {
+		ui.selectedQueue++
+		ui.drawQueue()
+	})
+	ui.bind(QueueView, "<up>", func() {
+		if ui.selectedQueue > 0 {
+			ui.selectedQueue--
+			ui.drawQueue()
+		}
+	})
+	ui.bind(QueueView, "/", func() {
+		ui.ask("Filter", ui.queueFilter, func(f string) {
+			ui.queueFilter = f
+			ui.drawQueue()
+		})
+	})
+	ui.bind(QueueView, "c", func() {
+		ui.queueFilter = ""
+		ui.drawQueue()
+	})
+	ui.bind(QueueView, "<enter>", func() {
+		ui.Lock()
+		if ui.selectedQueue >= len(ui.queueShown) {
+			ui.Unlock()
+			return
+		}
+		ab := ui.queueShown[ui.selectedQueue]
+		ui.Unlock()
+
+		pb := sdk.PipelineBuild{
+			BuildNumber: int64(ab.BuildNumber),
+			Application: sdk.Application{Name: sdk.ParameterValue(ab.Args, "cds.application")},
+			Pipeline:    sdk.Pipeline{Name: sdk.ParameterValue(ab.Args, "cds.pipeline")},
+			Environment: sdk.Environment{Name: sdk.ParameterValue(ab.Args, "cds.environment")},
+		}
+		ui.buildFrom = QueueView
+		ui.showBuild(sdk.ParameterValue(ab.Args, "cds.project"), pb)
+	})
+}
+
+func (ui *Termui) showQueue() {
+	ui.Lock()
+	ui.current = QueueView
+	ui.selectedQueue = 0
+	termui.Body.Rows = nil
+
+	ui.queueList = termui.NewList()
+	ui.queueList.Height = termui.TermHeight() - 3
+	ui.queueList.ItemFgColor = termui.ColorWhite
+	ui.queueList.BorderFg = termui.ColorCyan
+
+	termui.Body.AddRows(
+		termui.NewRow(
+			termui.NewCol(6, 0, ui.header),
+			termui.NewCol(6, 0, ui.msg),
+		),
+		termui.NewRow(
+			termui.NewCol(12, 0, ui.queueList),
+		),
+	)
+	ui.Unlock()
+
+	termui.Clear()
+	ui.draw(0)
+	ui.updateQueueList()
+}
+
+func (ui *Termui) updateQueueList() {
+	go func() {
+		for {
+			if ui.current != QueueView {
+				return
+			}
+
+			begin := time.Now()
+			queue, err := sdk.GetBuildQueue()
+			if err != nil {
+				ui.msg.Text = fmt.Sprintf("Cannot load queue: %s", err)
+			} else {
+				ui.msg.Text = fmt.Sprintf("Delay: %s", time.Since(begin).String())
+				ui.Lock()
+				ui.queueBuilds = queue
+				ui.Unlock()
+				ui.drawQueue()
+			}
+			time.Sleep(2 * time.Second)
+		}
+	}()
+}
+
+// queueFilterText returns what filters are matched against
+func queueFilterText(ab sdk.ActionBuild) string {
+	text := []string{ab.ActionName, string(ab.Status)}
+	for _, name := range []string{"cds.project", "cds.application", "cds.pipeline", "cds.environment"} {
+		text = append(text, sdk.ParameterValue(ab.Args, name))
+	}
+	for _, r := range ab.Requirements {
+		text = append(text, r.Name, r.Value)
+	}
+	return strings.Join(text, " ")
+}
+
+func queueItem(ab sdk.ActionBuild) string {
+	var reqs []string
+	for _, r := range ab.Requirements {
+		reqs = append(reqs, fmt.Sprintf("%s:%s", r.Type, r.Value))
+	}
+	item := fmt.Sprintf("%s[➤](fg-cyan)%s[➤](fg-cyan)%s[#%d](fg-cyan) %s",
+		sdk.ParameterValue(ab.Args, "cds.project"),
+		sdk.ParameterValue(ab.Args, "cds.application"),
+		sdk.ParameterValue(ab.Args, "cds.pipeline"),
+		ab.BuildNumber,
+		ab.ActionName)
+	if env := sdk.ParameterValue(ab.Args, "cds.environment"); env != "" && env != sdk.DefaultEnv.Name {
+		item += fmt.Sprintf(" [%s](fg-magenta)", env)
+	}
+	if !ab.Queued.IsZero() {
+		item += fmt.Sprintf(" waiting for %s", since(ab.Queued))
+	}
+	if len(reqs) > 0 {
+		item += fmt.Sprintf(" [%s](fg-yellow)", strings.Join(reqs, " "))
+	}
+	return item
+}
+
+func (ui *Termui) drawQueue() {
+	ui.Lock()
+	defer ui.Unlock()
+
+	if ui.current != QueueView {
+		return
+	}
+
+	ui.queueShown = nil
+	var items []string
+	for _, ab := range ui.queueBuilds {
+		if !matchFilter(queueFilterText(ab), ui.queueFilter) {
+			continue
+		}
+		ui.queueShown = append(ui.queueShown, ab)
+		items = append(items, queueItem(ab))
+	}
+
+	if ui.selectedQueue >= len(items) {
+		ui.selectedQueue = len(items) - 1
+	}
+	if ui.selectedQueue < 0 {
+		ui.selectedQueue = 0
+	}
+	for i := range items {
+		if i == ui.selectedQueue {
+			items[i] = "[▶](fg-cyan) " + items[i]
+		} else {
+			items[i] = "  " + items[i]
+		}
+	}
+
+	ui.queueList.Items = items
+	ui.queueList.BorderLabel = fmt.Sprintf("Queue (%d) | <enter> build | </> filter | (c)lear filter", len(items))
+	if ui.queueFilter != "" {
+		ui.queueList.BorderLabel += fmt.Sprintf(" | filter: %s", ui.queueFilter)
+	}
+}
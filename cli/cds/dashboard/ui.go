@@ -46,6 +46,37 @@ type Termui struct {
 
 	buildingPipelines []*termui.Row
 
+	// build drill-down, from dashboard or queue view
+	buildFrom     string
+	build         sdk.PipelineBuild
+	buildProject  string
+	buildHistory  []sdk.PipelineBuild
+	buildTriggers []sdk.PipelineTrigger
+	buildActions  []sdk.ActionBuild
+	selectedRow   int
+	buildInfo     *termui.Par
+	buildList     *termui.List
+	historyList   *termui.List
+
+	// build logs
+	logsBuild  sdk.ActionBuild
+	logsOffset int
+	logsFollow bool
+	logsSearch string
+	logsPar    *termui.Par
+
+	// queue
+	queueList     *termui.List
+	queueFilter   string
+	queueBuilds   []sdk.ActionBuild
+	queueShown    []sdk.ActionBuild
+	selectedQueue int
+
+	// worker models
+	modelsList   *termui.List
+	workersList  *termui.List
+	modelsFilter string
+
 	// status
 	workers              *termui.MBarChart
 	totalBuildingWorkers int64
@@ -53,6 +84,10 @@ type Termui struct {
 	queue                *termui.Par
 	status               *termui.Par
 
+	// keys bound by view, keys of all views are bound to ""
+	bindings map[string]map[string]func()
+	prompt   *prompt
+
 	// mutex
 	sync.Mutex
 }
@@ -63,12 +98,18 @@ const (
 	DashboardView    = "dashboard"
 	MonitoringView   = "monitoring"
 	StatusView       = "status"
+	BuildView        = "build"
+	BuildLogsView    = "buildlogs"
+	QueueView        = "queue"
+	WorkerModelsView = "workermodels"
 	ProjectSelected  = "project"
 	AppSelected      = "app"
 	PipelineSelected = "pipeline"
 	LogsSelected     = "logs"
 )
 
+const menu = "(h)ome | (d)ashboard | (m)onitoring | (s)tatus | q(u)eue | (w)orkers | (q)uit"
+
 func (ui *Termui) init() {
 	// Initialize termui
 	err := termui.Init()
@@ -82,47 +123,55 @@ func (ui *Termui) init() {
 		ui.draw(int(t.Count))
 	})
 
-	termui.Handle("/sys/kbd/q", func(termui.Event) {
-		termui.StopLoop()
-	})
+	// Keys are dispatched in order by a hook, so that typed text is not mixed up
+	termui.Handle("/sys/kbd", func(termui.Event) {})
+	termui.DefaultEvtStream.Hook(ui.onKey)
 
-	termui.Handle("/sys/kbd", func(e termui.Event) {
-		ui.msg.Text = fmt.Sprintf("No command for %v", e)
+	ui.bind("", "q", func() {
+		termui.StopLoop()
 	})
 
-	termui.Handle("/sys/kbd/h", func(termui.Event) {
+	ui.bind("", "h", func() {
 		ui.showHome()
 	})
 
-	termui.Handle("/sys/kbd/d", func(e termui.Event) {
+	ui.bind("", "d", func() {
 		ui.current = DashboardView
 		ui.selectedApp = 1
 		ui.showDashboard()
 	})
 
-	termui.Handle("/sys/kbd/m", func(e termui.Event) {
+	ui.bind("", "m", func() {
 		ui.current = "monitoring"
 		ui.showMonitoring()
 	})
 
-	termui.Handle("/sys/kbd/s", func(e termui.Event) {
+	ui.bind("", "s", func() {
 		ui.current = "status"
 		ui.showStatus()
 	})
 
-	termui.Handle("/sys/kbd/k", func(e termui.Event) {
+	ui.bind("", "u", func() {
+		ui.showQueue()
+	})
+
+	ui.bind("", "w", func() {
+		ui.showWorkerModels()
+	})
+
+	ui.bind(DashboardView, "k", func() {
 		if ui.selected == LogsSelected && ui.offset > 0 {
 			ui.offset -= 5
 			ui.drawApplications()
 		}
 	})
-	termui.Handle("/sys/kbd/j", func(e termui.Event) {
+	ui.bind(DashboardView, "j", func() {
 		if ui.selected == LogsSelected {
 			ui.offset += 5
 			ui.drawApplications()
 		}
 	})
-	termui.Handle("/sys/kbd/<tab>", func(e termui.Event) {
+	ui.bind(DashboardView, "<tab>", func() {
 		if ui.selected == PipelineSelected && ui.current == DashboardView {
 			ui.selected = LogsSelected
 			ui.selectedLogs = 1
@@ -130,124 +179,127 @@ func (ui *Termui) init() {
 		}
 	})
 
-	termui.Handle("/sys/kbd/<down>", func(e termui.Event) {
-		if ui.current == DashboardView {
-			switch ui.selected {
-			case ProjectSelected:
-				ui.selectedProject++
-				ui.selectedApp = 0
+	ui.bind(DashboardView, "<down>", func() {
+		switch ui.selected {
+		case ProjectSelected:
+			ui.selectedProject++
+			ui.selectedApp = 0
+			ui.drawProjects()
+			break
+		case AppSelected:
+			if ui.selectedApp < ui.appCount {
+				ui.selectedApp++
 				ui.drawProjects()
-				break
-			case AppSelected:
-				if ui.selectedApp < ui.appCount {
-					ui.selectedApp++
-					ui.drawProjects()
-				}
-				break
-			case LogsSelected:
-				ui.selectedLogs++
-				ui.offset = 0
+			}
+			break
+		case LogsSelected:
+			ui.selectedLogs++
+			ui.offset = 0
+			ui.drawProjects()
+			break
+		case PipelineSelected:
+			if ui.selectedApp < ui.appCount {
+				ui.selectedApp++
 				ui.drawProjects()
-				break
-			case PipelineSelected:
-				if ui.selectedApp < ui.appCount {
-					ui.selectedApp++
-					ui.drawProjects()
-					if ui.selectedPipeline > ui.pipCount {
-						ui.selectedPipeline = ui.pipCount
-					}
+				if ui.selectedPipeline > ui.pipCount {
+					ui.selectedPipeline = ui.pipCount
 				}
-				break
 			}
+			break
 		}
 	})
-	termui.Handle("/sys/kbd/<up>", func(e termui.Event) {
-		if ui.current == DashboardView {
-			switch ui.selected {
-			case ProjectSelected:
-				ui.selectedProject--
-				ui.selectedApp = 0
+	ui.bind(DashboardView, "<up>", func() {
+		switch ui.selected {
+		case ProjectSelected:
+			ui.selectedProject--
+			ui.selectedApp = 0
+			ui.drawProjects()
+			break
+		case AppSelected:
+			if ui.selectedApp > 1 {
+				ui.selectedApp--
 				ui.drawProjects()
-				break
-			case AppSelected:
-				if ui.selectedApp > 1 {
-					ui.selectedApp--
-					ui.drawProjects()
-				}
-				break
-			case LogsSelected:
-				if ui.selectedLogs > 1 {
-					ui.selectedLogs--
-					ui.offset = 0
-					ui.drawProjects()
-				}
-				break
-			case PipelineSelected:
-				if ui.selectedApp > 1 {
-					ui.selectedApp--
-					ui.drawProjects()
-					if ui.selectedPipeline > ui.pipCount {
-						ui.selectedPipeline = ui.pipCount
-					}
+			}
+			break
+		case LogsSelected:
+			if ui.selectedLogs > 1 {
+				ui.selectedLogs--
+				ui.offset = 0
+				ui.drawProjects()
+			}
+			break
+		case PipelineSelected:
+			if ui.selectedApp > 1 {
+				ui.selectedApp--
+				ui.drawProjects()
+				if ui.selectedPipeline > ui.pipCount {
+					ui.selectedPipeline = ui.pipCount
 				}
-				break
 			}
+			break
 		}
 	})
-	termui.Handle("/sys/kbd/<left>", func(e termui.Event) {
-		if ui.current == DashboardView {
-			switch ui.selected {
-			case AppSelected:
-				ui.selected = ProjectSelected
-				ui.selectedApp = 0
+	ui.bind(DashboardView, "<left>", func() {
+		switch ui.selected {
+		case AppSelected:
+			ui.selected = ProjectSelected
+			ui.selectedApp = 0
+			ui.drawProjects()
+			break
+		case PipelineSelected:
+			if ui.selectedPipeline == 1 {
+				ui.selected = AppSelected
+				ui.selectedPipeline = 0
 				ui.drawProjects()
-				break
-			case PipelineSelected:
-				if ui.selectedPipeline == 1 {
-					ui.selected = AppSelected
-					ui.selectedPipeline = 0
-					ui.drawProjects()
-				} else {
-					ui.selectedPipeline--
-					ui.drawProjects()
-				}
-				break
-			case LogsSelected:
-				ui.selected = PipelineSelected
-				ui.selectedLogs = 0
+			} else {
+				ui.selectedPipeline--
 				ui.drawProjects()
-				break
 			}
+			break
+		case LogsSelected:
+			ui.selected = PipelineSelected
+			ui.selectedLogs = 0
+			ui.drawProjects()
+			break
 		}
 	})
-	termui.Handle("/sys/kbd/<right>", func(e termui.Event) {
-		if ui.current == DashboardView {
-			switch ui.selected {
-			case ProjectSelected:
-				ui.selected = AppSelected
-				ui.selectedApp = 1
+	ui.bind(DashboardView, "<right>", func() {
+		switch ui.selected {
+		case ProjectSelected:
+			ui.selected = AppSelected
+			ui.selectedApp = 1
+			ui.drawProjects()
+			break
+		case AppSelected:
+			ui.selected = PipelineSelected
+			ui.selectedPipeline = 1
+			ui.drawProjects()
+			break
+		case PipelineSelected:
+			if ui.selectedPipeline == ui.pipCount {
+
+				ui.selected = LogsSelected
+				ui.selectedLogs = 1
 				ui.drawProjects()
-				break
-			case AppSelected:
-				ui.selected = PipelineSelected
-				ui.selectedPipeline = 1
+			} else {
+				ui.selectedPipeline++
 				ui.drawProjects()
-				break
-			case PipelineSelected:
-				if ui.selectedPipeline == ui.pipCount {
-
-					ui.selected = LogsSelected
-					ui.selectedLogs = 1
-					ui.drawProjects()
-				} else {
-					ui.selectedPipeline++
-					ui.drawProjects()
-				}
-				break
 			}
+			break
 		}
 	})
 
+	ui.bind(DashboardView, "<enter>", func() {
+		if ui.selected == PipelineSelected || ui.selected == LogsSelected {
+			ui.showSelectedBuild()
+		}
+	})
+
+	ui.initBuildKeys()
+	ui.initBuildLogsKeys()
+	ui.initQueueKeys()
+	ui.initWorkerModelsKeys()
+
 	ui.initHeader()
 	ui.initProjects()
 	ui.initMsg()
@@ -265,8 +317,13 @@ func (ui *Termui) draw(i int) {
 		break
 	}
 
+	// Keep prompt visible over messages of polling goroutines
+	if ui.prompt != nil {
+		ui.msg.Text = ui.prompt.String()
+	}
+
 	// Add a moving part to check that ui is not frozen
-	ui.header.Text = fmt.Sprintf("(h)ome | (d)ashboard | (m)onitoring | (s)tatus | (q)uit | %s", time.Now().String()[11:19])
+	ui.header.Text = fmt.Sprintf("%s | %s", menu, time.Now().String()[11:19])
 
 	// calculate layout
 	termui.Body.Align()
@@ -274,7 +331,7 @@ func (ui *Termui) draw(i int) {
 }
 
 func (ui *Termui) initHeader() {
-	p := termui.NewPar("(h)ome | (d)ashboard | (m)onitoring | (s)tatus | (q)uit")
+	p := termui.NewPar(menu)
 	p.Height = 3
 	p.TextFgColor = termui.ColorWhite
 	p.BorderLabel = "Menu"
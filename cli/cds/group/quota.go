@@ -0,0 +1,66 @@
+package group
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ovh/cds/sdk"
+)
+
+var (
+	maxBuildingP int64
+	weightP      int64
+)
+
+func cmdGroupQuota() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "quota",
+		Short: "cds group quota <groupName> [--max-building <n>] [--weight <n>]",
+		Long: `
+		Show or set (CDS administrators only) the quota of a group:
+		- max building: maximum number of concurrent action builds of the group, 0 means unlimited
+		- weight: share of the group when several groups have builds waiting in queue
+
+		Builds are accounted to the group with the highest permission on their project.
+		`,
+		Run: groupQuota,
+	}
+
+	cmd.Flags().Int64Var(&maxBuildingP, "max-building", 0, "Maximum number of concurrent action builds, 0 for unlimited")
+	cmd.Flags().Int64Var(&weightP, "weight", 1, "Share of the group in queue")
+
+	return cmd
+}
+
+func groupQuota(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		sdk.Exit("Wrong usage: %s\n", cmd.Short)
+	}
+	name := args[0]
+
+	q, err := sdk.GetGroupQuota(name)
+	if err != nil {
+		sdk.Exit("Error: cannot get quota of group %s (%s)\n", name, err)
+	}
+
+	if cmd.Flags().Changed("max-building") || cmd.Flags().Changed("weight") {
+		if cmd.Flags().Changed("max-building") {
+			q.MaxBuilding = maxBuildingP
+		}
+		if cmd.Flags().Changed("weight") {
+			q.Weight = weightP
+		}
+		if err := sdk.UpdateGroupQuota(name, q); err != nil {
+			sdk.Exit("Error: cannot update quota of group %s (%s)\n", name, err)
+		}
+	}
+
+	max := "unlimited"
+	if q.MaxBuilding > 0 {
+		max = fmt.Sprintf("%d", q.MaxBuilding)
+	}
+	fmt.Printf("Max building: %s\n", max)
+	fmt.Printf("Weight: %d\n", q.Weight)
+	fmt.Printf("Building: %d\n", q.Building)
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/ovh/cds/cli/cds/action"
 	"github.com/ovh/cds/cli/cds/application"
 	"github.com/ovh/cds/cli/cds/artifact"
+	"github.com/ovh/cds/cli/cds/audit"
 	"github.com/ovh/cds/cli/cds/dashboard"
 	"github.com/ovh/cds/cli/cds/environment"
 	"github.com/ovh/cds/cli/cds/generate"
@@ -91,6 +92,7 @@ func main() {
 	rootCmd.AddCommand(action.Cmd)
 	rootCmd.AddCommand(application.Cmd())
 	rootCmd.AddCommand(artifact.Cmd)
+	rootCmd.AddCommand(audit.Cmd())
 	rootCmd.AddCommand(environment.Cmd())
 	rootCmd.AddCommand(statusCmd())
 	rootCmd.AddCommand(pipeline.Cmd())
@@ -57,4 +57,7 @@ func buildPipeline(cmd *cobra.Command, args []string) {
 	for _, s := range t.TestSuites {
 		fmt.Printf("%s: %d Total, %d Failures\n", s.Name, s.Total, s.Failures)
 	}
+	if t.Coverage != nil {
+		fmt.Printf("Coverage: %.2f%% (%d/%d lines)\n", t.Coverage.Percent, t.Coverage.LinesCovered, t.Coverage.LinesValid)
+	}
 }
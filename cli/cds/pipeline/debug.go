@@ -0,0 +1,84 @@
+package pipeline
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/gorilla/websocket"
+	"github.com/spf13/cobra"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func pipelineDebugCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "debug",
+		Short: "cds pipeline debug <projectKey> <appName> <pipelineName> [envName] <buildNumber> <actionBuildID>",
+		Long: `Open a shell on the worker of a failed action build
+
+Workers of models with keep-on-failure set (cds worker model keep-on-failure) wait for a debug session after a failed step, in the working directory of the build.
+The command to run is given in the logs of the build. Exit the shell to end the session and release the worker.`,
+		Run: debugPipeline,
+	}
+
+	return cmd
+}
+
+func debugPipeline(cmd *cobra.Command, args []string) {
+	if len(args) < 5 {
+		sdk.Exit("Wrong usage: see %s\n", cmd.Short)
+	}
+
+	pk := args[0]
+	app := args[1]
+	name := args[2]
+	var env, bnS, idS string
+	if len(args) > 5 {
+		env = args[3]
+		bnS = args[4]
+		idS = args[5]
+	} else {
+		bnS = args[3]
+		idS = args[4]
+	}
+
+	bn, err := strconv.ParseInt(bnS, 10, 64)
+	if err != nil {
+		sdk.Exit("%s is not a valid build number (%s)\n", bnS, err)
+	}
+	id, err := strconv.ParseInt(idS, 10, 64)
+	if err != nil {
+		sdk.Exit("%s is not a valid action build ID (%s)\n", idS, err)
+	}
+
+	conn, err := sdk.DebugActionBuild(pk, app, name, env, bn, id)
+	if err != nil {
+		sdk.Exit("Cannot open debug session (%s)\n", err)
+	}
+	defer conn.Close()
+
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				conn.WriteMessage(websocket.BinaryMessage, []byte("exit\n"))
+				return
+			}
+			if err := conn.WriteMessage(websocket.BinaryMessage, buf[:n]); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+			return
+		}
+		if err != nil {
+			sdk.Exit("Debug session lost (%s)\n", err)
+		}
+		os.Stdout.Write(data)
+	}
+}
@@ -23,12 +23,15 @@ func Cmd() *cobra.Command {
 	cmd.AddCommand(pipelineRestartCmd())
 	cmd.AddCommand(pipelineShowBuildCmd())
 	cmd.AddCommand(pipelineCommitsCmd())
+	cmd.AddCommand(pipelineDebugCmd())
 	cmd.AddCommand(pipelineShowCmd())
 	cmd.AddCommand(pipelineStageCmd)
 	cmd.AddCommand(pipelineHookCmd)
 	cmd.AddCommand(pipelineParameterCmd)
 	cmd.AddCommand(pipelineJoinedCmd())
 	cmd.AddCommand(pipelineBuildCmd())
+	cmd.AddCommand(pipelinePriorityCmd())
+	cmd.AddCommand(pipelineTestsCmd)
 
 	return cmd
 }
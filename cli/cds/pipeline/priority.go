@@ -0,0 +1,42 @@
+package pipeline
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func pipelinePriorityCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "priority",
+		Short: "cds pipeline priority <projectKey> <pipelineName> <priority>",
+		Long: `
+		Set priority of pipeline builds in queue. Highest priorities are taken first.
+		Without priority, deployment pipelines have priority 20, testing pipelines 10 and build pipelines 0.
+		`,
+		Run: setPipelinePriority,
+	}
+	return cmd
+}
+
+func setPipelinePriority(cmd *cobra.Command, args []string) {
+	if len(args) != 3 {
+		sdk.Exit("Wrong usage: see %s\n", cmd.Short)
+	}
+
+	projectKey := args[0]
+	name := args[1]
+	priority, err := strconv.Atoi(args[2])
+	if err != nil {
+		sdk.Exit("Error: priority must be an integer (%s)\n", err)
+	}
+
+	if err := sdk.UpdatePipelinePriority(projectKey, name, priority); err != nil {
+		sdk.Exit("Error: cannot set priority of pipeline %s (%s)\n", name, err)
+	}
+
+	fmt.Printf("Pipeline %s priority set to %d.\n", name, priority)
+}
@@ -0,0 +1,161 @@
+package pipeline
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ovh/cds/sdk"
+)
+
+var (
+	cmdPipelineTestsBranch string
+	cmdPipelineTestsBuilds int
+	cmdPipelineTestsLimit  int
+)
+
+var pipelineTestsCmd = &cobra.Command{
+	Use:   "tests",
+	Short: "Analyse test results of last builds of a pipeline",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+func init() {
+	pipelineTestsCmd.PersistentFlags().StringVarP(&cmdPipelineTestsBranch, "branch", "", "", "Only analyse builds of this branch")
+	pipelineTestsCmd.PersistentFlags().IntVarP(&cmdPipelineTestsBuilds, "builds", "", 0, "Number of last builds to analyse")
+
+	slowestCmd := pipelineTestsSlowestCmd()
+	slowestCmd.Flags().IntVarP(&cmdPipelineTestsLimit, "limit", "", 10, "Number of tests displayed")
+
+	pipelineTestsCmd.AddCommand(pipelineTestsFlakyCmd())
+	pipelineTestsCmd.AddCommand(slowestCmd)
+	pipelineTestsCmd.AddCommand(pipelineTestsHistoryCmd())
+	pipelineTestsCmd.AddCommand(pipelineTestsCoverageCmd())
+}
+
+// testsArgs reads <projectKey> <applicationName> <pipelineName> [envName] then extra arguments
+func testsArgs(cmd *cobra.Command, args []string, extra int) (string, string, string, sdk.TestHistoryQuery, []string) {
+	if len(args) < 3+extra || len(args) > 4+extra {
+		sdk.Exit("Wrong usage: see %s\n", cmd.Short)
+	}
+
+	q := sdk.TestHistoryQuery{Branch: cmdPipelineTestsBranch, Builds: cmdPipelineTestsBuilds}
+	if len(args) == 4+extra {
+		q.Env = args[3]
+		return args[0], args[1], args[2], q, args[4:]
+	}
+	return args[0], args[1], args[2], q, args[3:]
+}
+
+func pipelineTestsFlakyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "flaky",
+		Short: "cds pipeline tests flaky <projectKey> <applicationName> <pipelineName> [envName]",
+		Long:  `List tests whose status changed when a commit was built again, flakiest first.`,
+		Run:   flakyTests,
+	}
+	return cmd
+}
+
+func flakyTests(cmd *cobra.Command, args []string) {
+	projectKey, appName, pipelineName, q, _ := testsArgs(cmd, args, 0)
+
+	stats, err := sdk.GetFlakyTests(projectKey, appName, pipelineName, q)
+	if err != nil {
+		sdk.Exit("Error: cannot retrieve flaky tests (%s)\n", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 10, 1, 2, ' ', 0)
+	titles := []string{"SCORE", "FLIPS", "RUNS", "FAILURES", "LAST", "SUITE", "TEST"}
+	fmt.Fprintln(w, strings.Join(titles, "\t"))
+	for _, s := range stats {
+		fmt.Fprintf(w, "%.2f\t%d\t%d\t%d\t%s\t%s\t%s\n", s.FlakyScore, s.Flips, s.Runs, s.Failures, s.LastStatus, s.Suite, s.Name)
+	}
+	w.Flush()
+}
+
+func pipelineTestsSlowestCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "slowest",
+		Short: "cds pipeline tests slowest <projectKey> <applicationName> <pipelineName> [envName]",
+		Long:  `List tests with the highest average duration.`,
+		Run:   slowestTests,
+	}
+	return cmd
+}
+
+func slowestTests(cmd *cobra.Command, args []string) {
+	projectKey, appName, pipelineName, q, _ := testsArgs(cmd, args, 0)
+
+	stats, err := sdk.GetSlowestTests(projectKey, appName, pipelineName, q, cmdPipelineTestsLimit)
+	if err != nil {
+		sdk.Exit("Error: cannot retrieve slowest tests (%s)\n", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 10, 1, 2, ' ', 0)
+	titles := []string{"AVG (s)", "MAX (s)", "RUNS", "SUITE", "TEST"}
+	fmt.Fprintln(w, strings.Join(titles, "\t"))
+	for _, s := range stats {
+		fmt.Fprintf(w, "%.3f\t%.3f\t%d\t%s\t%s\n", s.AvgDuration, s.MaxDuration, s.Runs, s.Suite, s.Name)
+	}
+	w.Flush()
+}
+
+func pipelineTestsHistoryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "cds pipeline tests history <projectKey> <applicationName> <pipelineName> [envName] <suite> <test>",
+		Long:  `Show results of a test in last builds.`,
+		Run:   testHistory,
+	}
+	return cmd
+}
+
+func testHistory(cmd *cobra.Command, args []string) {
+	projectKey, appName, pipelineName, q, extra := testsArgs(cmd, args, 2)
+
+	results, err := sdk.GetTestCaseHistory(projectKey, appName, pipelineName, q, extra[0], extra[1])
+	if err != nil {
+		sdk.Exit("Error: cannot retrieve test history (%s)\n", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 10, 1, 2, ' ', 0)
+	titles := []string{"BUILD", "STATUS", "DURATION (s)", "BRANCH", "COMMIT"}
+	fmt.Fprintln(w, strings.Join(titles, "\t"))
+	for _, r := range results {
+		fmt.Fprintf(w, "#%d\t%s\t%.3f\t%s\t%s\n", r.BuildNumber, r.Status, r.Duration, r.Branch, r.Hash)
+	}
+	w.Flush()
+}
+
+func pipelineTestsCoverageCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "coverage",
+		Short: "cds pipeline tests coverage <projectKey> <applicationName> <pipelineName> [envName]",
+		Long:  `Show code coverage of last builds.`,
+		Run:   coverageTrend,
+	}
+	return cmd
+}
+
+func coverageTrend(cmd *cobra.Command, args []string) {
+	projectKey, appName, pipelineName, q, _ := testsArgs(cmd, args, 0)
+
+	trend, err := sdk.GetCoverageTrend(projectKey, appName, pipelineName, q.Env, q.Builds)
+	if err != nil {
+		sdk.Exit("Error: cannot retrieve coverage (%s)\n", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 10, 1, 2, ' ', 0)
+	titles := []string{"BUILD", "VERSION", "COVERAGE", "LINES", "BRANCH"}
+	fmt.Fprintln(w, strings.Join(titles, "\t"))
+	for _, c := range trend {
+		fmt.Fprintf(w, "#%d\t%d\t%.2f%%\t%d/%d\t%s\n", c.BuildNumber, c.Version, c.Percent, c.LinesCovered, c.LinesValid, c.Branch)
+	}
+	w.Flush()
+}
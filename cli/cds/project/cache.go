@@ -0,0 +1,100 @@
+package project
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ovh/cds/sdk"
+)
+
+// CmdCache Command to manage build caches of a project
+var CmdCache = &cobra.Command{
+	Use:   "cache",
+	Short: "Build caches management",
+	Long:  ``,
+}
+
+func init() {
+	CmdCache.AddCommand(cmdProjectListCache())
+	CmdCache.AddCommand(cmdProjectRemoveCache())
+	CmdCache.AddCommand(cmdProjectCacheLimit())
+}
+
+func cmdProjectListCache() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "cds project cache list <projectKey>",
+		Long:  ``,
+		Run:   listCacheInProject,
+	}
+	return cmd
+}
+
+func listCacheInProject(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		sdk.Exit("Wrong usage: %s\n", cmd.Short)
+	}
+	projectKey := args[0]
+
+	pc, err := sdk.ListCaches(projectKey)
+	if err != nil {
+		sdk.Exit("Error: cannot list caches of project %s (%s)\n", projectKey, err)
+	}
+
+	for _, c := range pc.Caches {
+		fmt.Printf("- %s: %d MB (last used %s)\n", c.Key, c.Size>>20, c.LastUsed.Format("2006-01-02 15:04:05"))
+	}
+	fmt.Printf("Total: %d MB / %d MB\n", pc.Size>>20, pc.MaxSize>>20)
+}
+
+func cmdProjectRemoveCache() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "remove",
+		Short: "cds project cache remove <projectKey> <cacheKey>",
+		Long:  ``,
+		Run:   removeCacheFromProject,
+	}
+	return cmd
+}
+
+func removeCacheFromProject(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		sdk.Exit("Wrong usage: %s\n", cmd.Short)
+	}
+	projectKey := args[0]
+	cacheKey := args[1]
+
+	if err := sdk.DeleteCache(projectKey, cacheKey); err != nil {
+		sdk.Exit("Error: cannot remove cache %s from project %s (%s)\n", cacheKey, projectKey, err)
+	}
+	fmt.Printf("OK\n")
+}
+
+func cmdProjectCacheLimit() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "limit",
+		Short: "cds project cache limit <projectKey> <sizeInMB>",
+		Long:  `Set the size limit of build caches of a project, least recently used caches are evicted above it. 0 resets the limit to the default one.`,
+		Run:   setCacheLimitOfProject,
+	}
+	return cmd
+}
+
+func setCacheLimitOfProject(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		sdk.Exit("Wrong usage: %s\n", cmd.Short)
+	}
+	projectKey := args[0]
+
+	size, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		sdk.Exit("Error: size must be a number of MB (%s)\n", err)
+	}
+
+	if err := sdk.UpdateCacheLimit(projectKey, size<<20); err != nil {
+		sdk.Exit("Error: cannot set cache limit of project %s (%s)\n", projectKey, err)
+	}
+	fmt.Printf("OK\n")
+}
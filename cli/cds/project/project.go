@@ -15,6 +15,7 @@ func init() {
 	Cmd.AddCommand(cmdProjectList)
 	Cmd.AddCommand(group.CmdGroup)
 	Cmd.AddCommand(CmdVariable)
+	Cmd.AddCommand(CmdCache)
 	Cmd.AddCommand(repositoriesmanager.Cmd)
 }
 
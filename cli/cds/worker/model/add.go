@@ -14,6 +14,7 @@ var (
 	imageP                 string
 	openstackFlavorP       string
 	openstackUserDataFileP string
+	singleUseP             bool
 )
 
 func cmdWorkerModelAdd() *cobra.Command {
@@ -31,6 +32,7 @@ func cmdWorkerModelAdd() *cobra.Command {
 	cmd.Flags().StringVar(&imageP, "image", "", "Image value (docker or openstack)")
 	cmd.Flags().StringVar(&openstackFlavorP, "flavor", "", "Flavor value (openstack)")
 	cmd.Flags().StringVar(&openstackUserDataFileP, "userdata", "", "Path to UserData file (openstack)")
+	cmd.Flags().BoolVar(&singleUseP, "single-use", false, "Workers run only one action, then are destroyed")
 
 	return cmd
 }
@@ -82,10 +84,16 @@ func addWorkerModel(cmd *cobra.Command, args []string) {
 		sdk.Exit("Unknown worker type: %s\n", modelType)
 	}
 
-	_, err := sdk.AddWorkerModel(name, t, image)
+	m, err := sdk.AddWorkerModel(name, t, image)
 	if err != nil {
 		sdk.Exit("Error: cannot add worker model (%s)\n", err)
 	}
+
+	if singleUseP {
+		if err := sdk.SetWorkerModelSingleUse(m.ID, true); err != nil {
+			sdk.Exit("Error: cannot set worker model single use (%s)\n", err)
+		}
+	}
 }
 
 func cmdWorkerModelCapabilityAdd() *cobra.Command {
@@ -0,0 +1,87 @@
+package model
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/ovh/cds/sdk"
+)
+
+var (
+	minP                int
+	maxP                int
+	warmPoolP           int
+	warmPoolScheduleP   []string
+	scaleUpCooldownP    int
+	scaleDownCooldownP  int
+	queueWaitThresholdP int
+	scaleUpStepP        int
+	predictiveP         bool
+)
+
+func cmdWorkerModelAutoscaling() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "autoscaling",
+		Short: "cds worker model autoscaling <name>",
+		Long: `
+		Set how hatcheries scale workers of a model:
+		- min and max number of workers, idle or building (max 0 means unlimited)
+		- warm pool: number of idle workers kept ready
+		- warm pool schedule: "<days> <from>-<to> <size>", days being a range (1-5) or a list (0,6), 0 is sunday
+		- cooldowns, in seconds, between two spawns and before killing workers
+		- queue wait threshold, in seconds, after which scale up step more workers are spawned
+		- predictive: smooth demand over time to keep workers when demand oscillates
+
+		$ cds worker model autoscaling ubuntu --min 1 --max 20 --warm-pool 1 --warm-pool-schedule "1-5 08:00-19:00 5" --scale-down-cooldown 300
+		`,
+		Run: autoscalingWorkerModel,
+	}
+
+	cmd.Flags().IntVar(&minP, "min", 0, "Minimum number of workers")
+	cmd.Flags().IntVar(&maxP, "max", 0, "Maximum number of workers, 0 for unlimited")
+	cmd.Flags().IntVar(&warmPoolP, "warm-pool", 0, "Number of idle workers kept ready")
+	cmd.Flags().StringArrayVar(&warmPoolScheduleP, "warm-pool-schedule", nil, "Warm pool size on some days and hours, i.e. \"1-5 08:00-19:00 3\"")
+	cmd.Flags().IntVar(&scaleUpCooldownP, "scale-up-cooldown", 0, "Minimum delay between two spawns, in seconds")
+	cmd.Flags().IntVar(&scaleDownCooldownP, "scale-down-cooldown", 0, "Minimum delay after a spawn or a kill before killing workers, in seconds")
+	cmd.Flags().IntVar(&queueWaitThresholdP, "queue-wait-threshold", 0, "Queue wait, in seconds, after which more workers are spawned. 0 to disable")
+	cmd.Flags().IntVar(&scaleUpStepP, "scale-up-step", 1, "Number of workers spawned when queue wait threshold is exceeded")
+	cmd.Flags().BoolVar(&predictiveP, "predictive", false, "Smooth demand over time")
+
+	return cmd
+}
+
+func autoscalingWorkerModel(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		sdk.Exit("Wrong usage: %s\n", cmd.Short)
+	}
+	name := args[0]
+
+	m, err := sdk.GetWorkerModel(name)
+	if err != nil {
+		sdk.Exit("Error: cannot retrieve worker model %s (%s)\n", name, err)
+	}
+
+	a := sdk.ModelAutoscaling{
+		Min:                minP,
+		Max:                maxP,
+		WarmPool:           warmPoolP,
+		ScaleUpCooldown:    scaleUpCooldownP,
+		ScaleDownCooldown:  scaleDownCooldownP,
+		QueueWaitThreshold: queueWaitThresholdP,
+		ScaleUpStep:        scaleUpStepP,
+		Predictive:         predictiveP,
+	}
+	for _, s := range warmPoolScheduleP {
+		schedule, err := sdk.ParseWarmPoolSchedule(s)
+		if err != nil {
+			sdk.Exit("Error: %s\n", err)
+		}
+		a.WarmPoolSchedule = append(a.WarmPoolSchedule, schedule)
+	}
+	if err := a.IsValid(); err != nil {
+		sdk.Exit("Error: %s\n", err)
+	}
+
+	if err := sdk.UpdateWorkerModelAutoscaling(m.ID, a); err != nil {
+		sdk.Exit("Error: cannot update worker model autoscaling (%s)\n", err)
+	}
+}
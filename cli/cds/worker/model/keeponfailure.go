@@ -0,0 +1,43 @@
+package model
+
+import (
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func cmdWorkerModelKeepOnFailure() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "keep-on-failure",
+		Short: "cds worker model keep-on-failure <name> <minutes>",
+		Long: `
+		Workers of the model wait given minutes for a debug session after a failed step (cds pipeline debug),
+		before unregistering. Disabled if 0.
+		`,
+		Run: keepOnFailureWorkerModel,
+	}
+
+	return cmd
+}
+
+func keepOnFailureWorkerModel(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		sdk.Exit("Wrong usage: %s\n", cmd.Short)
+	}
+	name := args[0]
+	minutes, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil || minutes < 0 {
+		sdk.Exit("Wrong usage: %s\n", cmd.Short)
+	}
+
+	m, err := sdk.GetWorkerModel(name)
+	if err != nil {
+		sdk.Exit("Error: cannot retrieve worker model %s (%s)\n", name, err)
+	}
+
+	if err := sdk.SetWorkerModelKeepOnFailure(m.ID, minutes); err != nil {
+		sdk.Exit("Error: cannot update worker model %s (%s)\n", name, err)
+	}
+}
@@ -8,6 +8,9 @@ func init() {
 	Cmd.AddCommand(cmdWorkerModelUpdate())
 	Cmd.AddCommand(cmdWorkerModelList())
 	Cmd.AddCommand(cmdWorkerModelCapability())
+	Cmd.AddCommand(cmdWorkerModelAutoscaling())
+	Cmd.AddCommand(cmdWorkerModelSingleUse())
+	Cmd.AddCommand(cmdWorkerModelKeepOnFailure())
 }
 
 // Cmd model
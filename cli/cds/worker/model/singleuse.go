@@ -0,0 +1,43 @@
+package model
+
+import (
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func cmdWorkerModelSingleUse() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "single-use",
+		Short: "cds worker model single-use <name> <true|false>",
+		Long: `
+		Single use workers run only one action, then unregister and are destroyed by their hatchery,
+		so that every action runs in a clean environment.
+		`,
+		Run: singleUseWorkerModel,
+	}
+
+	return cmd
+}
+
+func singleUseWorkerModel(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		sdk.Exit("Wrong usage: %s\n", cmd.Short)
+	}
+	name := args[0]
+	singleUse, err := strconv.ParseBool(args[1])
+	if err != nil {
+		sdk.Exit("Wrong usage: %s\n", cmd.Short)
+	}
+
+	m, err := sdk.GetWorkerModel(name)
+	if err != nil {
+		sdk.Exit("Error: cannot retrieve worker model %s (%s)\n", name, err)
+	}
+
+	if err := sdk.SetWorkerModelSingleUse(m.ID, singleUse); err != nil {
+		sdk.Exit("Error: cannot update worker model %s (%s)\n", name, err)
+	}
+}
@@ -280,8 +280,11 @@ func UpdateActionDB(db database.QueryExecuter, a *sdk.Action, userID int64) erro
 	}
 
 	query := `UPDATE action SET name=$1,description=$2, type=$3, enabled=$4 WHERE id=$5`
-	_, errdb := db.Exec(query, a.Name, a.Description, string(a.Type), a.Enabled, a.ID)
-	return errdb
+	if _, errdb := db.Exec(query, a.Name, a.Description, string(a.Type), a.Enabled, a.ID); errdb != nil {
+		return errdb
+	}
+	invalidateRequirements(a.ID)
+	return nil
 }
 
 // DeleteAction remove action from database
@@ -329,6 +332,7 @@ func DeleteAction(db database.QueryExecuter, actionID, userID int64) error {
 	if _, err := db.Exec(query, actionID); err != nil {
 		return err
 	}
+	invalidateRequirements(actionID)
 	return nil
 }
 
@@ -6,30 +6,28 @@ import (
 
 	"github.com/ovh/cds/engine/api/cache"
 	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/api/leader"
 	"github.com/ovh/cds/engine/log"
 	"github.com/ovh/cds/sdk"
 )
 
-//RequirementsCacheLoader set all action requirement in the cache
+// requirements of actions are cached by action ID
+var requirements = cache.NewKind(0, "action", "requirements")
+
+//RequirementsCacheLoader set all action requirement in the cache.
+//With a shared cache, only the leader loads them.
 func RequirementsCacheLoader(delay time.Duration) {
 	for {
 		time.Sleep(delay * time.Second)
 		db := database.DB()
-		if db != nil {
-			var mayIWork string
-			loaderKey := cache.Key("action", "requirements", "loading")
-			if cache.Get(loaderKey, &mayIWork) {
-				cache.SetWithTTL(loaderKey, "true", 60)
-				actions, err := LoadActions(db)
-				if err != nil {
-					log.Warning("RequirementsCacheLoader> Unable to load worker models: %s", err)
-					continue
-				}
-				for _, a := range actions {
-					k := cache.Key("action", "requirements", fmt.Sprintf("%d", a.ID))
-					cache.Set(k, a.Requirements)
-				}
-				cache.Delete(loaderKey)
+		if db != nil && (!cache.Shared() || leader.IsLeader()) {
+			actions, err := LoadActions(db)
+			if err != nil {
+				log.Warning("RequirementsCacheLoader> Unable to load actions: %s", err)
+				continue
+			}
+			for _, a := range actions {
+				requirements.Refresh(fmt.Sprintf("%d", a.ID), a.Requirements)
 			}
 		}
 	}
@@ -37,16 +35,20 @@ func RequirementsCacheLoader(delay time.Duration) {
 
 //GetRequirements load action capabilities from cache
 func GetRequirements(db database.Querier, id int64) ([]sdk.Requirement, error) {
-	k := cache.Key("action", "requirements", fmt.Sprintf("%d", id))
 	req := []sdk.Requirement{}
 	//if we didn't got any data, try to load from DB
-	if !cache.Get(k, &req) {
+	err := requirements.Load(fmt.Sprintf("%d", id), &req, func() error {
 		var err error
 		req, err = LoadActionRequirements(db, id)
-		if err != nil {
-			return nil, fmt.Errorf("GetRequirements> cannot LoadActionRequirements: %s\n", err)
-		}
-		cache.Set(k, req)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("GetRequirements> cannot LoadActionRequirements: %s\n", err)
 	}
 	return req, nil
 }
+
+// invalidateRequirements removes requirements of action from cache, on all instances
+func invalidateRequirements(id int64) {
+	requirements.Delete(fmt.Sprintf("%d", id))
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/ovh/cds/engine/api/keys"
 	"github.com/ovh/cds/engine/api/pipeline"
 	"github.com/ovh/cds/engine/api/repositoriesmanager"
+	"github.com/ovh/cds/engine/api/role"
 	"github.com/ovh/cds/engine/api/trigger"
 	"github.com/ovh/cds/engine/log"
 	"github.com/ovh/cds/sdk"
@@ -415,6 +416,14 @@ func DeleteApplication(db *sql.Tx, applicationID int64) error {
 		return err
 	}
 
+	// Delete test case history
+	query = `DELETE FROM test_case_result WHERE application_id = $1`
+	_, err = db.Exec(query, applicationID)
+	if err != nil {
+		log.Warning("DeleteApplication> Cannot delete test case history: %s\n", err)
+		return err
+	}
+
 	// Delete hook
 	query = `DELETE FROM hook WHERE application_id = $1`
 	_, err = db.Exec(query, applicationID)
@@ -509,10 +518,13 @@ func LoadApplicationByGroup(db database.Querier, group *sdk.Group) error {
 	                 application.name,
 	                 application.id,
 					 application_group.role,
-					 application.last_modified
+					 application.last_modified,
+					 role.name,
+					 role.capabilities
 	          FROM application
 	          JOIN application_group ON application_group.application_id = application.id
 	 	  JOIN project ON application.project_id = project.id
+	 	  LEFT JOIN role ON role.id = application_group.role_id
 	 	  WHERE application_group.group_id = $1
 	 	  ORDER BY application.name ASC`
 	rows, err := db.Query(query, group.ID)
@@ -525,7 +537,12 @@ func LoadApplicationByGroup(db database.Querier, group *sdk.Group) error {
 		var application sdk.Application
 		var perm int
 		var lastModified time.Time
-		err = rows.Scan(&application.ProjectKey, &application.Name, &application.ID, &perm, &lastModified)
+		var roleName, roleCapabilities sql.NullString
+		err = rows.Scan(&application.ProjectKey, &application.Name, &application.ID, &perm, &lastModified, &roleName, &roleCapabilities)
+		if err != nil {
+			return err
+		}
+		r, err := role.Granted(roleName, roleCapabilities, perm)
 		if err != nil {
 			return err
 		}
@@ -533,6 +550,7 @@ func LoadApplicationByGroup(db database.Querier, group *sdk.Group) error {
 		group.ApplicationGroups = append(group.ApplicationGroups, sdk.ApplicationGroup{
 			Application: application,
 			Permission:  perm,
+			Role:        r,
 		})
 	}
 	return nil
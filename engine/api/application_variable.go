@@ -151,7 +151,16 @@ func getVariablesInApplicationHandler(w http.ResponseWriter, r *http.Request, db
 	key := vars["key"]
 	appName := vars["permApplicationName"]
 
-	variables, err := application.GetAllVariable(db, key, appName)
+	args := []application.FuncArg{}
+	if r.FormValue("withSecrets") == "true" {
+		if !canReadSecrets(r, c) {
+			WriteError(w, r, sdk.ErrForbidden)
+			return
+		}
+		args = append(args, application.WithClearPassword())
+	}
+
+	variables, err := application.GetAllVariable(db, key, appName, args...)
 	if err != nil {
 		log.Warning("getVariablesInApplicationHandler: Cannot get variables for application %s: %s\n", appName, err)
 		WriteError(w, r, err)
@@ -10,6 +10,7 @@ import (
 
 	"github.com/ovh/cds/engine/api/build"
 	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/api/leader"
 	"github.com/ovh/cds/engine/api/pipeline"
 	"github.com/ovh/cds/engine/log"
 )
@@ -23,7 +24,7 @@ func Archive(interval, nHoursKeepsBuild int) {
 	for {
 		time.Sleep(time.Duration(interval) * time.Second)
 		db := database.DB()
-		if db != nil {
+		if db != nil && leader.IsLeader() {
 			buildIDs, err := pipeline.LoadBuildIDsToArchive(db, nHoursKeepsBuild)
 			if err != nil {
 				log.Warning("Archive> Cannot load buildIDs to archive: %s\n", err)
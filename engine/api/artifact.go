@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 
@@ -14,6 +15,7 @@ import (
 	"github.com/ovh/cds/engine/api/artifact"
 	"github.com/ovh/cds/engine/api/context"
 	"github.com/ovh/cds/engine/api/environment"
+	"github.com/ovh/cds/engine/api/objectstore"
 	"github.com/ovh/cds/engine/api/permission"
 	"github.com/ovh/cds/engine/api/pipeline"
 	"github.com/ovh/cds/engine/log"
@@ -140,7 +142,7 @@ func uploadArtifactHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c
 		err = artifact.SaveFile(db, p, a, art, file, env)
 		if err != nil {
 			log.Warning("uploadArtifactHandler> cannot save file: %s\n", err)
-			w.WriteHeader(http.StatusInternalServerError)
+			WriteError(w, r, err)
 			file.Close()
 			return
 		}
@@ -187,14 +189,10 @@ func downloadArtifactHandler(w http.ResponseWriter, r *http.Request, db *sql.DB,
 	}
 
 	log.Info("downloadArtifactHandler: Serving %+v\n", art)
-	err = artifact.StreamFile(w, *art)
-	if err != nil {
+	if err := streamArtifact(w, r, art); err != nil {
 		log.Warning("downloadArtifactHandler: Cannot stream artifact %s-%s-%s-%s-%s file: %s\n", art.Project, art.Application, art.Environment, art.Pipeline, art.Tag, err)
-		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
-	w.Header().Add("Content-Type", "application/octet-stream")
-	w.Header().Add("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", art.Name))
 }
 
 func listArtifactsBuildHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
@@ -327,18 +325,65 @@ func downloadArtifactDirectHandler(w http.ResponseWriter, r *http.Request, db *s
 		return
 	}
 
-	w.Header().Add("Content-Type", "application/octet-stream")
-	w.Header().Add("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", art.Name))
-
 	log.Info("downloadArtifactDirectHandler: Serving %+v\n", art)
-	err = artifact.StreamFile(w, *art)
-	if err != nil {
+	if err := streamArtifact(w, r, art); err != nil {
 		log.Warning("downloadArtifactDirectHandler: Cannot stream artifact %s-%s-%s-%s-%s file: %s\n", art.Project, art.Application, art.Environment, art.Pipeline, art.Tag, err)
-		WriteError(w, r, err)
 		return
 	}
 }
 
+// streamArtifact writes headers then content of an artifact, or an error if it cannot be fetched.
+// A Range request with an open end, as sent to resume a download, gets the content after its start.
+func streamArtifact(w http.ResponseWriter, r *http.Request, art *sdk.Artifact) error {
+	offset, ok := rangeOffset(r.Header.Get("Range"))
+	// Size of artifacts uploaded before it was recorded is unknown, they are sent as a whole
+	if !ok || art.Size == 0 {
+		offset = 0
+	}
+	if offset > 0 && offset >= art.Size {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", art.Size))
+		w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+		return nil
+	}
+
+	// Headers are written only once the object is available
+	f, err := artifact.OpenFile(*art, offset)
+	if err != nil {
+		WriteError(w, r, err)
+		return err
+	}
+	defer f.Close()
+
+	w.Header().Add("Content-Type", "application/octet-stream")
+	w.Header().Add("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", art.Name))
+	if art.Size > 0 {
+		w.Header().Set("Accept-Ranges", "bytes")
+		w.Header().Set("Content-Length", strconv.FormatInt(art.Size-offset, 10))
+	}
+	if art.SHA256 != "" {
+		w.Header().Set(sdk.ArtifactSHA256Header, art.SHA256)
+	}
+	if offset > 0 {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, art.Size-1, art.Size))
+		w.WriteHeader(http.StatusPartialContent)
+	}
+
+	return objectstore.StreamFile(w, f)
+}
+
+// rangeOffset returns the start of a Range header "bytes=N-".
+// Other ranges are not supported and have to be ignored.
+func rangeOffset(header string) (int64, bool) {
+	if !strings.HasPrefix(header, "bytes=") || !strings.HasSuffix(header, "-") {
+		return 0, false
+	}
+	offset, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(header, "bytes="), "-"), 10, 64)
+	if err != nil || offset < 0 {
+		return 0, false
+	}
+	return offset, true
+}
+
 func generateHash() (string, error) {
 	size := 128
 	bs := make([]byte, size)
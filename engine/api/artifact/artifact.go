@@ -1,11 +1,17 @@
 package artifact
 
 import (
+	"crypto/md5"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"strings"
 
+	"github.com/lib/pq"
+
 	"github.com/ovh/cds/engine/api/database"
 	"github.com/ovh/cds/engine/api/objectstore"
 	"github.com/ovh/cds/engine/log"
@@ -17,7 +23,7 @@ func LoadArtifactByHash(db *sql.DB, hash string) (*sdk.Artifact, error) {
 	art := &sdk.Artifact{}
 	query := `SELECT artifact.id, artifact.name, artifact.tag, 
 		  pipeline.name, project.projectKey, application.name, environment.name,
-		  artifact.size, artifact.perm, artifact.md5sum, artifact.object_path
+		  artifact.size, artifact.perm, artifact.md5sum, artifact.sha256, artifact.object_path
 		  FROM artifact
 		  JOIN pipeline ON artifact.pipeline_id = pipeline.id
 		  JOIN project ON pipeline.project_id = project.id
@@ -25,15 +31,18 @@ func LoadArtifactByHash(db *sql.DB, hash string) (*sdk.Artifact, error) {
 		  JOIN environment ON environment.id = artifact.environment_id
 		  WHERE download_hash = $1`
 
-	var md5sum, objectpath sql.NullString
+	var md5sum, sha256sum, objectpath sql.NullString
 	var size, perm sql.NullInt64
-	err := db.QueryRow(query, hash).Scan(&art.ID, &art.Name, &art.Tag, &art.Pipeline, &art.Project, &art.Application, &art.Environment, &size, &perm, &md5sum, &objectpath)
+	err := db.QueryRow(query, hash).Scan(&art.ID, &art.Name, &art.Tag, &art.Pipeline, &art.Project, &art.Application, &art.Environment, &size, &perm, &md5sum, &sha256sum, &objectpath)
 	if err != nil {
 		return nil, err
 	}
 	if md5sum.Valid {
 		art.MD5sum = md5sum.String
 	}
+	if sha256sum.Valid {
+		art.SHA256 = sha256sum.String
+	}
 	if objectpath.Valid {
 		art.ObjectPath = objectpath.String
 	}
@@ -48,7 +57,7 @@ func LoadArtifactByHash(db *sql.DB, hash string) (*sdk.Artifact, error) {
 
 // LoadArtifactsByBuildNumber Load artifact by pipeline ID and buildNUmber
 func LoadArtifactsByBuildNumber(db *sql.DB, pipelineID int64, applicationID int64, buildNumber int, environmentID int64) ([]sdk.Artifact, error) {
-	query := `SELECT id, name, tag, download_hash, size, perm, md5sum, object_path
+	query := `SELECT id, name, tag, download_hash, size, perm, md5sum, sha256, object_path, build_number, promoted, promoted_at, promoted_by
 	          FROM "artifact"
 	          WHERE build_number = $1 AND pipeline_id = $2 AND application_id = $3 AND environment_id = $4
 	          ORDER BY name`
@@ -62,15 +71,20 @@ func LoadArtifactsByBuildNumber(db *sql.DB, pipelineID int64, applicationID int6
 	arts := []sdk.Artifact{}
 	for rows.Next() {
 		art := sdk.Artifact{}
-		var md5sum, objectpath sql.NullString
+		var md5sum, sha256sum, objectpath, promotedBy sql.NullString
 		var size, perm sql.NullInt64
-		err = rows.Scan(&art.ID, &art.Name, &art.Tag, &art.DownloadHash, &size, &perm, &md5sum, &objectpath)
+		var promotedAt pq.NullTime
+		err = rows.Scan(&art.ID, &art.Name, &art.Tag, &art.DownloadHash, &size, &perm, &md5sum, &sha256sum, &objectpath,
+			&art.BuildNumber, &art.Promoted, &promotedAt, &promotedBy)
 		if err != nil {
 			return nil, err
 		}
 		if md5sum.Valid {
 			art.MD5sum = md5sum.String
 		}
+		if sha256sum.Valid {
+			art.SHA256 = sha256sum.String
+		}
 		if objectpath.Valid {
 			art.ObjectPath = objectpath.String
 		}
@@ -80,6 +94,10 @@ func LoadArtifactsByBuildNumber(db *sql.DB, pipelineID int64, applicationID int6
 		if perm.Valid {
 			art.Perm = uint32(perm.Int64)
 		}
+		if promotedAt.Valid {
+			art.PromotedAt = &promotedAt.Time
+		}
+		art.PromotedBy = promotedBy.String
 		arts = append(arts, art)
 	}
 	return arts, nil
@@ -87,7 +105,7 @@ func LoadArtifactsByBuildNumber(db *sql.DB, pipelineID int64, applicationID int6
 
 // LoadArtifacts Load artifact by pipeline ID
 func LoadArtifacts(db *sql.DB, pipelineID int64, applicationID int64, environmentID int64, tag string) ([]sdk.Artifact, error) {
-	query := `SELECT id, name, download_hash, size, perm, md5sum, object_path
+	query := `SELECT id, name, download_hash, size, perm, md5sum, sha256, object_path, build_number, promoted, promoted_at, promoted_by
 		FROM "artifact" 
 		WHERE tag = $1 
 		AND pipeline_id = $2 
@@ -102,15 +120,20 @@ func LoadArtifacts(db *sql.DB, pipelineID int64, applicationID int64, environmen
 	var arts []sdk.Artifact
 	for rows.Next() {
 		art := sdk.Artifact{}
-		var md5sum, objectpath sql.NullString
+		var md5sum, sha256sum, objectpath, promotedBy sql.NullString
 		var size, perm sql.NullInt64
-		err = rows.Scan(&art.ID, &art.Name, &art.DownloadHash, &size, &perm, &md5sum, &objectpath)
+		var promotedAt pq.NullTime
+		err = rows.Scan(&art.ID, &art.Name, &art.DownloadHash, &size, &perm, &md5sum, &sha256sum, &objectpath,
+			&art.BuildNumber, &art.Promoted, &promotedAt, &promotedBy)
 		if err != nil {
 			return nil, err
 		}
 		if md5sum.Valid {
 			art.MD5sum = md5sum.String
 		}
+		if sha256sum.Valid {
+			art.SHA256 = sha256sum.String
+		}
 		if objectpath.Valid {
 			art.ObjectPath = objectpath.String
 		}
@@ -120,6 +143,10 @@ func LoadArtifacts(db *sql.DB, pipelineID int64, applicationID int64, environmen
 		if perm.Valid {
 			art.Perm = uint32(perm.Int64)
 		}
+		if promotedAt.Valid {
+			art.PromotedAt = &promotedAt.Time
+		}
+		art.PromotedBy = promotedBy.String
 		arts = append(arts, art)
 	}
 
@@ -129,7 +156,7 @@ func LoadArtifacts(db *sql.DB, pipelineID int64, applicationID int64, environmen
 // LoadArtifact Load artifact by ID
 func LoadArtifact(db *sql.DB, id int64) (*sdk.Artifact, error) {
 	query := `SELECT 
-			artifact.name, artifact.tag, artifact.download_hash, artifact.size, artifact.perm, artifact.md5sum, artifact.object_path, 
+			artifact.name, artifact.tag, artifact.download_hash, artifact.size, artifact.perm, artifact.md5sum, artifact.sha256, artifact.object_path, 
 			pipeline.name, project.projectKey, application.name, environment.name FROM artifact
 			JOIN pipeline ON artifact.pipeline_id = pipeline.id
 			JOIN project ON pipeline.project_id = project.id
@@ -138,13 +165,16 @@ func LoadArtifact(db *sql.DB, id int64) (*sdk.Artifact, error) {
 			WHERE artifact.id = $1`
 
 	s := &sdk.Artifact{}
-	var md5sum, objectpath sql.NullString
+	var md5sum, sha256sum, objectpath sql.NullString
 	var size, perm sql.NullInt64
-	err := db.QueryRow(query, id).Scan(&s.Name, &s.Tag, &s.DownloadHash, &size, &perm, &md5sum, &objectpath,
+	err := db.QueryRow(query, id).Scan(&s.Name, &s.Tag, &s.DownloadHash, &size, &perm, &md5sum, &sha256sum, &objectpath,
 		&s.Pipeline, &s.Project, &s.Application, &s.Environment)
 	if md5sum.Valid {
 		s.MD5sum = md5sum.String
 	}
+	if sha256sum.Valid {
+		s.SHA256 = sha256sum.String
+	}
 	if objectpath.Valid {
 		s.ObjectPath = objectpath.String
 	}
@@ -190,18 +220,36 @@ func DeleteArtifact(db database.QueryExecuter, id int64) error {
 	return nil
 }
 
+// checkNotPromoted returns ErrArtifactPromoted if the artifact an upload would replace is promoted,
+// it locks the artifact until the end of the transaction
+func checkNotPromoted(db database.Querier, pipelineID, applicationID int64, environmentID int64, art sdk.Artifact) error {
+	query := `SELECT promoted FROM "artifact" WHERE name = $1 AND tag = $2 AND pipeline_id = $3 AND application_id = $4 AND environment_id = $5 FOR UPDATE`
+	var promoted bool
+	if err := db.QueryRow(query, art.Name, art.Tag, pipelineID, applicationID, environmentID).Scan(&promoted); err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+	if promoted {
+		return sdk.ErrArtifactPromoted
+	}
+	return nil
+}
+
 func insertArtifact(db database.Executer, pipelineID, applicationID int64, environmentID int64, art sdk.Artifact) error {
-	query := `DELETE FROM "artifact" WHERE name = $1 AND tag = $2 AND pipeline_id = $3 AND application_id = $4 AND environment_id = $5`
+	// Promoted artifacts are never replaced, see checkNotPromoted
+	query := `DELETE FROM "artifact" WHERE name = $1 AND tag = $2 AND pipeline_id = $3 AND application_id = $4 AND environment_id = $5 AND promoted = false`
 	_, err := db.Exec(query, art.Name, art.Tag, pipelineID, applicationID, environmentID)
 	if err != nil {
 		return err
 	}
 
 	query = `INSERT INTO "artifact" 
-			(name, tag, pipeline_id, application_id, build_number, environment_id, download_hash, size, perm, md5sum, object_path) 
+			(name, tag, pipeline_id, application_id, build_number, environment_id, download_hash, size, perm, md5sum, sha256, object_path) 
 			VALUES 
-			($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
-	_, err = db.Exec(query, art.Name, art.Tag, pipelineID, applicationID, art.BuildNumber, environmentID, art.DownloadHash, art.Size, art.Perm, art.MD5sum, art.ObjectPath)
+			($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+	_, err = db.Exec(query, art.Name, art.Tag, pipelineID, applicationID, art.BuildNumber, environmentID, art.DownloadHash, art.Size, art.Perm, art.MD5sum, art.SHA256, art.ObjectPath)
 	if err != nil {
 		fmt.Println(err)
 		return err
@@ -209,7 +257,8 @@ func insertArtifact(db database.Executer, pipelineID, applicationID int64, envir
 	return nil
 }
 
-// SaveFile Insert file in db and write it in data directory
+// SaveFile Insert file in db and write it in data directory.
+// Size and checksums of the artifact, if set, are verified against the stored content.
 func SaveFile(db *sql.DB, p *sdk.Pipeline, a *sdk.Application, art sdk.Artifact, content io.ReadCloser, e *sdk.Environment) error {
 	tx, err := db.Begin()
 	if err != nil {
@@ -217,12 +266,36 @@ func SaveFile(db *sql.DB, p *sdk.Pipeline, a *sdk.Application, art sdk.Artifact,
 	}
 	defer tx.Rollback()
 
-	objectPath, err := objectstore.StoreArtifact(art, content)
+	// Stored object of the artifact is overwritten, it must not be promoted
+	if err := checkNotPromoted(tx, p.ID, a.ID, e.ID, art); err != nil {
+		return err
+	}
+
+	sha := sha256.New()
+	md := md5.New()
+	counter := &byteCounter{}
+	hashed := &readCloser{Reader: io.TeeReader(content, io.MultiWriter(sha, md, counter)), Closer: content}
+
+	objectPath, err := objectstore.StoreArtifact(art, hashed)
 	if err != nil {
 		return err
 	}
 	log.Debug("objectpath=%s\n", objectPath)
 	art.ObjectPath = objectPath
+
+	sha256sum := hex.EncodeToString(sha.Sum(nil))
+	md5sum := hex.EncodeToString(md.Sum(nil))
+	if (art.SHA256 != "" && art.SHA256 != sha256sum) || (art.MD5sum != "" && art.MD5sum != md5sum) || (art.Size > 0 && art.Size != counter.n) {
+		log.Warning("SaveFile> %s: received %d bytes with sha256 %s and md5 %s, expected %d bytes with sha256 %s and md5 %s\n",
+			art.Name, counter.n, sha256sum, md5sum, art.Size, art.SHA256, art.MD5sum)
+		if err := objectstore.DeleteArtifact(art); err != nil {
+			log.Warning("SaveFile> cannot delete corrupted artifact %s: %s\n", art.Name, err)
+		}
+		return sdk.ErrArtifactChecksum
+	}
+	art.SHA256 = sha256sum
+	art.MD5sum = md5sum
+	art.Size = counter.n
 	if err = insertArtifact(tx, p.ID, a.ID, e.ID, art); err != nil {
 		return err
 	}
@@ -230,15 +303,38 @@ func SaveFile(db *sql.DB, p *sdk.Pipeline, a *sdk.Application, art sdk.Artifact,
 	return tx.Commit()
 }
 
-// StreamFile Stream artifact
-func StreamFile(w io.Writer, art sdk.Artifact) error {
+// OpenFile fetches artifact content from given offset
+func OpenFile(art sdk.Artifact, offset int64) (io.ReadCloser, error) {
 	f, err := objectstore.FetchArtifact(art)
 	if err != nil {
-		return fmt.Errorf("cannot fetch artifact: %s", err)
+		return nil, fmt.Errorf("cannot fetch artifact: %s", err)
 	}
 
-	if err := objectstore.StreamFile(w, f); err != nil {
-		return err
+	if offset > 0 {
+		if s, ok := f.(io.Seeker); ok {
+			_, err = s.Seek(offset, 0)
+		} else {
+			_, err = io.CopyN(ioutil.Discard, f, offset)
+		}
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("cannot seek artifact to %d: %s", offset, err)
+		}
 	}
-	return f.Close()
+	return f, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// byteCounter counts bytes written to it
+type byteCounter struct {
+	n int64
+}
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
 }
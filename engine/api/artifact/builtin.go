@@ -31,11 +31,40 @@ func CreateBuiltinArtifactActions(db *sql.DB) error {
 			log.Warning("CreateBuiltinArtifactActions> createBuiltinArtifactDownloadAction err:%s", err.Error())
 			return err
 		}
+	} else if err == nil {
+		// Parameters selecting the build were added later
+		if err := addMissingParameters(db, artifactDownloadAction()); err != nil {
+			log.Warning("CreateBuiltinArtifactActions> addMissingParameters err:%s", err.Error())
+			return err
+		}
 	}
 
 	return nil
 }
 
+// addMissingParameters adds to a builtin action in database the parameters it does not have yet
+func addMissingParameters(db *sql.DB, a *sdk.Action) error {
+	current, err := action.LoadPublicAction(db, a.Name)
+	if err != nil {
+		return err
+	}
+
+	existing := map[string]bool{}
+	for _, p := range current.Parameters {
+		existing[p.Name] = true
+	}
+
+	for _, p := range a.Parameters {
+		if existing[p.Name] {
+			continue
+		}
+		if err := action.InsertActionParameter(db, current.ID, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func createBuiltinArtifactUploadAction(db *sql.DB) error {
 	upload := sdk.NewAction(sdk.ArtifactUpload)
 	upload.Type = sdk.BuiltinAction
@@ -69,7 +98,7 @@ func createBuiltinArtifactUploadAction(db *sql.DB) error {
 	return tx.Commit()
 }
 
-func createBuiltinArtifactDownloadAction(db *sql.DB) error {
+func artifactDownloadAction() *sdk.Action {
 	dl := sdk.NewAction(sdk.ArtifactDownload)
 	dl.Type = sdk.BuiltinAction
 	dl.Parameter(sdk.Parameter{
@@ -78,7 +107,7 @@ func createBuiltinArtifactDownloadAction(db *sql.DB) error {
 		Type:        sdk.StringParameter})
 	dl.Parameter(sdk.Parameter{
 		Name:        "tag",
-		Description: "Artifact are uploaded with a tag, generally {{.cds.version}}",
+		Description: "Artifact are uploaded with a tag, generally {{.cds.version}}. Ignored if version, branch, lastSuccess or promoted is set",
 		Type:        sdk.StringParameter})
 	dl.Parameter(sdk.Parameter{
 		Name:        "pipeline",
@@ -93,6 +122,33 @@ func createBuiltinArtifactDownloadAction(db *sql.DB) error {
 		Type:        sdk.BooleanParameter,
 		Description: "Enable artifact download",
 		Value:       "true"})
+	dl.Parameter(sdk.Parameter{
+		Name:        "environment",
+		Description: "Environment from where artifacts will be downloaded, current environment if empty",
+		Type:        sdk.StringParameter})
+	dl.Parameter(sdk.Parameter{
+		Name:        "version",
+		Description: "Download artifacts of the build with this version, example: {{.cds.parent.version}}",
+		Type:        sdk.StringParameter})
+	dl.Parameter(sdk.Parameter{
+		Name:        "branch",
+		Description: "Download artifacts of the last build of this branch",
+		Type:        sdk.StringParameter})
+	dl.Parameter(sdk.Parameter{
+		Name:        "lastSuccess",
+		Type:        sdk.BooleanParameter,
+		Description: "Download artifacts of the last successful build",
+		Value:       "false"})
+	dl.Parameter(sdk.Parameter{
+		Name:        "promoted",
+		Type:        sdk.BooleanParameter,
+		Description: "Download artifacts of the last build with promoted artifacts",
+		Value:       "false"})
+	return dl
+}
+
+func createBuiltinArtifactDownloadAction(db *sql.DB) error {
+	dl := artifactDownloadAction()
 
 	tx, err := db.Begin()
 	if err != nil {
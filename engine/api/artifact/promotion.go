@@ -0,0 +1,125 @@
+package artifact
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/api/leader"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+// FindBuildNumber returns the number of the last build of a pipeline with artifacts matching selector.
+// Both running and archived builds are looked up.
+func FindBuildNumber(db database.Querier, pipelineID, applicationID, environmentID int64, s sdk.ArtifactSelector) (int, error) {
+	var status string
+	if s.LastSuccess {
+		status = string(sdk.StatusSuccess)
+	}
+
+	query := `SELECT builds.build_number FROM (
+			SELECT build_number, version, status, vcs_changes_branch FROM pipeline_build
+			WHERE pipeline_id = $1 AND application_id = $2 AND environment_id = $3
+			UNION
+			SELECT build_number, version, status, vcs_changes_branch FROM pipeline_history
+			WHERE pipeline_id = $1 AND application_id = $2 AND environment_id = $3
+		) AS builds
+		WHERE ($4 = 0 OR builds.version = $4)
+		AND ($5 = '' OR builds.vcs_changes_branch = $5)
+		AND ($6 = '' OR builds.status = $6)
+		AND builds.build_number IN (
+			SELECT build_number FROM artifact
+			WHERE pipeline_id = $1 AND application_id = $2 AND environment_id = $3
+			AND ($7 = false OR promoted = true)
+		)
+		ORDER BY builds.build_number DESC
+		LIMIT 1`
+
+	var buildNumber int
+	err := db.QueryRow(query, pipelineID, applicationID, environmentID, s.Version, s.Branch, status, s.Promoted).Scan(&buildNumber)
+	if err == sql.ErrNoRows {
+		return 0, sdk.ErrNotFound
+	}
+	return buildNumber, err
+}
+
+// Promote marks artifacts of a build as released, or removes the mark.
+// It returns the number of artifacts updated.
+func Promote(db database.Executer, pipelineID, applicationID, environmentID int64, buildNumber int, username string, promoted bool) (int64, error) {
+	query := `UPDATE artifact SET promoted = $1, promoted_at = $2, promoted_by = $3
+		WHERE pipeline_id = $4 AND application_id = $5 AND environment_id = $6 AND build_number = $7`
+
+	var at *time.Time
+	var by *string
+	if promoted {
+		now := time.Now()
+		at, by = &now, &username
+	}
+
+	res, err := db.Exec(query, promoted, at, by, pipelineID, applicationID, environmentID, buildNumber)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Clean deletes artifacts created before given time, except promoted ones.
+// It returns the number of artifacts deleted.
+func Clean(db *sql.DB, before time.Time) (int, error) {
+	rows, err := db.Query(`SELECT id FROM artifact WHERE created < $1 AND promoted = false`, before)
+	if err != nil {
+		return 0, err
+	}
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	var n int
+	for _, id := range ids {
+		if err := deleteArtifact(db, id); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// deleteArtifact deletes an artifact in its own transaction
+func deleteArtifact(db *sql.DB, id int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := DeleteArtifact(tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RetentionRoutine deletes artifacts older than retention days every hour.
+// Promoted artifacts are kept. Nothing is deleted if retention is 0.
+func RetentionRoutine(retention int) {
+	defer log.Critical("Artifact> RetentionRoutine exited")
+
+	for {
+		if db := database.DB(); db != nil && retention > 0 && leader.IsLeader() {
+			if n, err := Clean(db, time.Now().AddDate(0, 0, -retention)); err != nil {
+				log.Warning("Artifact> RetentionRoutine> %s\n", err)
+			} else if n > 0 {
+				log.Notice("Artifact> RetentionRoutine> %d artifacts removed\n", n)
+			}
+		}
+		time.Sleep(1 * time.Hour)
+	}
+}
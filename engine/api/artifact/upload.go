@@ -0,0 +1,262 @@
+package artifact
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/api/objectstore"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+// UploadTTL is how long an incomplete upload can be resumed
+const UploadTTL = 24 * time.Hour
+
+// Upload is a chunked upload of an artifact in a pipeline build
+type Upload struct {
+	sdk.ArtifactUploadSession
+	PipelineID    int64
+	ApplicationID int64
+	EnvironmentID int64
+}
+
+// StartUpload creates an upload session, or resumes the pending upload of the same file
+func StartUpload(db *sql.DB, u *Upload) error {
+	if err := PurgeUploads(db, time.Now().Add(-UploadTTL)); err != nil {
+		log.Warning("StartUpload> cannot purge expired uploads: %s\n", err)
+	}
+
+	query := `SELECT id, created FROM artifact_upload
+		WHERE pipeline_id = $1 AND application_id = $2 AND environment_id = $3 AND build_number = $4
+		AND tag = $5 AND name = $6 AND size = $7 AND sha256 = $8 AND chunk_size = $9`
+	err := db.QueryRow(query, u.PipelineID, u.ApplicationID, u.EnvironmentID, u.BuildNumber,
+		u.Tag, u.Name, u.Size, u.SHA256, u.ChunkSize).Scan(&u.ID, &u.Created)
+	if err == nil {
+		u.Received, err = loadReceivedChunks(db, u.ID)
+		return err
+	}
+	if err != sql.ErrNoRows {
+		return err
+	}
+
+	u.ID, err = newUploadID()
+	if err != nil {
+		return err
+	}
+	u.Received = []int{}
+	query = `INSERT INTO artifact_upload (id, pipeline_id, application_id, environment_id, build_number, tag, name, size, perm, sha256, chunk_size)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING created`
+	return db.QueryRow(query, u.ID, u.PipelineID, u.ApplicationID, u.EnvironmentID, u.BuildNumber,
+		u.Tag, u.Name, u.Size, u.Perm, u.SHA256, u.ChunkSize).Scan(&u.Created)
+}
+
+// LoadUpload loads an upload session with the chunks already received
+func LoadUpload(db *sql.DB, id string) (*Upload, error) {
+	u := &Upload{}
+	var perm sql.NullInt64
+	query := `SELECT artifact_upload.id, pipeline_id, application_id, environment_id, environment.name, build_number,
+		tag, artifact_upload.name, size, perm, sha256, chunk_size, artifact_upload.created
+		FROM artifact_upload
+		JOIN environment ON environment.id = artifact_upload.environment_id
+		WHERE artifact_upload.id = $1`
+	err := db.QueryRow(query, id).Scan(&u.ID, &u.PipelineID, &u.ApplicationID, &u.EnvironmentID, &u.Environment, &u.BuildNumber,
+		&u.Tag, &u.Name, &u.Size, &perm, &u.SHA256, &u.ChunkSize, &u.Created)
+	if err == sql.ErrNoRows {
+		return nil, sdk.ErrArtifactUploadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	u.Perm = uint32(perm.Int64)
+
+	u.Received, err = loadReceivedChunks(db, u.ID)
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func loadReceivedChunks(db database.Querier, id string) ([]int, error) {
+	rows, err := db.Query(`SELECT chunk FROM artifact_upload_chunk WHERE upload_id = $1 ORDER BY chunk`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	chunks := []int{}
+	for rows.Next() {
+		var i int
+		if err := rows.Scan(&i); err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, i)
+	}
+	return chunks, nil
+}
+
+// SaveChunk stores chunk i of an upload once its size and SHA-256 checksum are verified
+func SaveChunk(db *sql.DB, u *Upload, i int, sum string, content io.ReadCloser) error {
+	if i < 0 || i >= u.Chunks() {
+		return sdk.ErrWrongRequest
+	}
+	_, size := u.Chunk(i)
+
+	// Read one more byte than expected to detect oversized chunks
+	sha := sha256.New()
+	counter := &byteCounter{}
+	limited := &readCloser{Reader: io.TeeReader(io.LimitReader(content, size+1), io.MultiWriter(sha, counter)), Closer: content}
+
+	chunk := sdk.ArtifactChunk{UploadID: u.ID, Index: i}
+	if _, err := objectstore.StoreArtifactChunk(chunk, limited); err != nil {
+		return err
+	}
+
+	received := hex.EncodeToString(sha.Sum(nil))
+	if counter.n != size || received != strings.ToLower(sum) {
+		log.Warning("SaveChunk> upload %s chunk %d: received %d bytes with sha256 %s, expected %d bytes with sha256 %s\n",
+			u.ID, i, counter.n, received, size, sum)
+		if err := objectstore.DeleteArtifactChunk(chunk); err != nil {
+			log.Warning("SaveChunk> cannot delete corrupted chunk %d of upload %s: %s\n", i, u.ID, err)
+		}
+		return sdk.ErrArtifactChecksum
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM artifact_upload_chunk WHERE upload_id = $1 AND chunk = $2`, u.ID, i); err != nil {
+		return err
+	}
+	query := `INSERT INTO artifact_upload_chunk (upload_id, chunk, size, sha256) VALUES ($1, $2, $3, $4)`
+	if _, err := tx.Exec(query, u.ID, i, size, received); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CompleteUpload assembles chunks of an upload into the artifact, then deletes the upload.
+// The upload is deleted as well if the assembled artifact does not match its checksum, it has to start again.
+func CompleteUpload(db *sql.DB, u *Upload, p *sdk.Pipeline, a *sdk.Application, art sdk.Artifact, e *sdk.Environment) error {
+	if len(u.Missing()) > 0 {
+		return sdk.ErrArtifactUploadIncomplete
+	}
+
+	art.Name = u.Name
+	art.Tag = u.Tag
+	art.BuildNumber = u.BuildNumber
+	art.Size = u.Size
+	art.Perm = u.Perm
+	art.SHA256 = u.SHA256
+
+	err := SaveFile(db, p, a, art, &chunksReader{upload: u.ID, chunks: u.Chunks()}, e)
+	if err != nil && err != sdk.ErrArtifactChecksum {
+		return err
+	}
+
+	if errd := DeleteUpload(db, u.ID, u.Chunks()); errd != nil {
+		log.Warning("CompleteUpload> cannot delete upload %s: %s\n", u.ID, errd)
+	}
+	return err
+}
+
+// DeleteUpload removes stored chunks of an upload then the upload itself
+func DeleteUpload(db database.Executer, id string, chunks int) error {
+	for i := 0; i < chunks; i++ {
+		err := objectstore.DeleteArtifactChunk(sdk.ArtifactChunk{UploadID: id, Index: i})
+		// Chunk may not have been received
+		if err != nil && !strings.Contains(err.Error(), "404") {
+			log.Warning("DeleteUpload> cannot delete chunk %d of upload %s: %s\n", i, id, err)
+		}
+	}
+
+	if _, err := db.Exec(`DELETE FROM artifact_upload_chunk WHERE upload_id = $1`, id); err != nil {
+		return err
+	}
+	_, err := db.Exec(`DELETE FROM artifact_upload WHERE id = $1`, id)
+	return err
+}
+
+// PurgeUploads deletes uploads started before given time
+func PurgeUploads(db *sql.DB, before time.Time) error {
+	rows, err := db.Query(`SELECT id, size, chunk_size FROM artifact_upload WHERE created < $1`, before)
+	if err != nil {
+		return err
+	}
+
+	var uploads []sdk.ArtifactUploadSession
+	for rows.Next() {
+		var u sdk.ArtifactUploadSession
+		if err := rows.Scan(&u.ID, &u.Size, &u.ChunkSize); err != nil {
+			rows.Close()
+			return err
+		}
+		uploads = append(uploads, u)
+	}
+	rows.Close()
+
+	for _, u := range uploads {
+		if err := DeleteUpload(db, u.ID, u.Chunks()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newUploadID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// chunksReader reads stored chunks of an upload one after the other
+type chunksReader struct {
+	upload  string
+	chunks  int
+	next    int
+	current io.ReadCloser
+}
+
+func (r *chunksReader) Read(b []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if r.next >= r.chunks {
+				return 0, io.EOF
+			}
+			c, err := objectstore.FetchArtifactChunk(sdk.ArtifactChunk{UploadID: r.upload, Index: r.next})
+			if err != nil {
+				return 0, err
+			}
+			r.current = c
+			r.next++
+		}
+
+		n, err := r.current.Read(b)
+		if err == io.EOF {
+			r.current.Close()
+			r.current = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (r *chunksReader) Close() error {
+	if r.current == nil {
+		return nil
+	}
+	return r.current.Close()
+}
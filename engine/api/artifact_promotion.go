@@ -0,0 +1,143 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/ovh/cds/engine/api/application"
+	"github.com/ovh/cds/engine/api/artifact"
+	"github.com/ovh/cds/engine/api/context"
+	"github.com/ovh/cds/engine/api/environment"
+	"github.com/ovh/cds/engine/api/permission"
+	"github.com/ovh/cds/engine/api/pipeline"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+// loadArtifactsBuild loads pipeline, application and environment of route, checking access to environment
+func loadArtifactsBuild(r *http.Request, db *sql.DB, c *context.Context, perm int) (*sdk.Pipeline, *sdk.Application, *sdk.Environment, error) {
+	vars := mux.Vars(r)
+	project := vars["key"]
+	pipelineName := vars["permPipelineKey"]
+	appName := vars["permApplicationName"]
+	envName := r.FormValue("envName")
+
+	p, err := pipeline.LoadPipeline(db, project, pipelineName, false)
+	if err != nil {
+		log.Warning("loadArtifactsBuild> Cannot load pipeline %s: %s\n", pipelineName, err)
+		return nil, nil, nil, sdk.ErrPipelineNotFound
+	}
+
+	a, err := application.LoadApplicationByName(db, project, appName)
+	if err != nil {
+		log.Warning("loadArtifactsBuild> Cannot load application %s: %s\n", appName, err)
+		return nil, nil, nil, sdk.ErrApplicationNotFound
+	}
+
+	env := &sdk.DefaultEnv
+	if envName != "" && envName != sdk.DefaultEnv.Name && p.Type != sdk.BuildPipeline {
+		env, err = environment.LoadEnvironmentByName(db, project, envName)
+		if err != nil {
+			log.Warning("loadArtifactsBuild> Cannot load environment %s: %s\n", envName, err)
+			return nil, nil, nil, sdk.ErrNoEnvironment
+		}
+	}
+
+	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, perm) {
+		log.Warning("loadArtifactsBuild> No enought right on this environment %s: \n", envName)
+		return nil, nil, nil, sdk.ErrForbidden
+	}
+
+	return p, a, env, nil
+}
+
+func findArtifactsHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	p, a, env, err := loadArtifactsBuild(r, db, c, permission.PermissionRead)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	var s sdk.ArtifactSelector
+	if v := r.FormValue("version"); v != "" {
+		s.Version, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || s.Version <= 0 {
+			log.Warning("findArtifactsHandler> Invalid version %s\n", v)
+			WriteError(w, r, sdk.ErrWrongRequest)
+			return
+		}
+	}
+	s.Branch = r.FormValue("branch")
+	if status := r.FormValue("status"); status != "" {
+		if sdk.StatusFromString(status) != sdk.StatusSuccess {
+			log.Warning("findArtifactsHandler> Only %s status can be selected\n", sdk.StatusSuccess)
+			WriteError(w, r, sdk.ErrWrongRequest)
+			return
+		}
+		s.LastSuccess = true
+	}
+	s.Promoted = r.FormValue("promoted") == "true"
+
+	buildNumber, err := artifact.FindBuildNumber(db, p.ID, a.ID, env.ID, s)
+	if err != nil {
+		log.Warning("findArtifactsHandler> Cannot find build of %s-%s-%s matching %+v: %s\n", a.Name, p.Name, env.Name, s, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	arts, err := artifact.LoadArtifactsByBuildNumber(db, p.ID, a.ID, buildNumber, env.ID)
+	if err != nil {
+		log.Warning("findArtifactsHandler> Cannot load artifacts: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteJSON(w, r, arts, http.StatusOK)
+}
+
+func promoteArtifactsHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	setArtifactsPromotion(w, r, db, c, true)
+}
+
+func unpromoteArtifactsHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	setArtifactsPromotion(w, r, db, c, false)
+}
+
+func setArtifactsPromotion(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context, promoted bool) {
+	vars := mux.Vars(r)
+
+	buildNumber, err := strconv.Atoi(vars["build"])
+	if err != nil {
+		log.Warning("setArtifactsPromotion> BuildNumber must be an integer: %s\n", err)
+		WriteError(w, r, sdk.ErrWrongRequest)
+		return
+	}
+
+	p, a, env, err := loadArtifactsBuild(r, db, c, permission.PermissionReadWriteExecute)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	n, err := artifact.Promote(db, p.ID, a.ID, env.ID, buildNumber, c.User.Username, promoted)
+	if err != nil {
+		log.Warning("setArtifactsPromotion> Cannot update artifacts of %s-%s-%s build %d: %s\n", a.Name, p.Name, env.Name, buildNumber, err)
+		WriteError(w, r, err)
+		return
+	}
+	if n == 0 {
+		WriteError(w, r, sdk.ErrNotFound)
+		return
+	}
+
+	log.Notice("setArtifactsPromotion> %s set promoted=%t on %d artifacts of %s-%s-%s build %d\n", c.User.Username, promoted, n, a.Name, p.Name, env.Name, buildNumber)
+	arts, err := artifact.LoadArtifactsByBuildNumber(db, p.ID, a.ID, buildNumber, env.ID)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	WriteJSON(w, r, arts, http.StatusOK)
+}
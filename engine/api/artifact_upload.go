@@ -0,0 +1,218 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/ovh/cds/engine/api/application"
+	"github.com/ovh/cds/engine/api/artifact"
+	"github.com/ovh/cds/engine/api/context"
+	"github.com/ovh/cds/engine/api/environment"
+	"github.com/ovh/cds/engine/api/permission"
+	"github.com/ovh/cds/engine/api/pipeline"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+func startArtifactUploadHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	vars := mux.Vars(r)
+	project := vars["key"]
+	pipelineName := vars["permPipelineKey"]
+	appName := vars["permApplicationName"]
+
+	buildNumber, err := strconv.Atoi(vars["buildNumber"])
+	if err != nil {
+		log.Warning("startArtifactUploadHandler> BuildNumber must be an integer: %s\n", err)
+		WriteError(w, r, sdk.ErrWrongRequest)
+		return
+	}
+
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		WriteError(w, r, sdk.ErrWrongRequest)
+		return
+	}
+	var u artifact.Upload
+	if err := json.Unmarshal(data, &u.ArtifactUploadSession); err != nil {
+		WriteError(w, r, sdk.ErrWrongRequest)
+		return
+	}
+	u.Tag = vars["tag"]
+	u.BuildNumber = buildNumber
+
+	if !validArtifactUpload(u.ArtifactUploadSession) {
+		log.Warning("startArtifactUploadHandler> invalid upload of %s (%d bytes, chunks of %d bytes, sha256 %s)\n", u.Name, u.Size, u.ChunkSize, u.SHA256)
+		WriteError(w, r, sdk.ErrWrongRequest)
+		return
+	}
+
+	p, err := pipeline.LoadPipeline(db, project, pipelineName, false)
+	if err != nil {
+		log.Warning("startArtifactUploadHandler> cannot load pipeline %s-%s: %s\n", project, pipelineName, err)
+		WriteError(w, r, sdk.ErrPipelineNotFound)
+		return
+	}
+
+	a, err := application.LoadApplicationByName(db, project, appName)
+	if err != nil {
+		log.Warning("startArtifactUploadHandler> cannot load application %s-%s: %s\n", project, appName, err)
+		WriteError(w, r, sdk.ErrApplicationNotFound)
+		return
+	}
+
+	env := &sdk.DefaultEnv
+	if u.Environment != "" && u.Environment != sdk.DefaultEnv.Name {
+		env, err = environment.LoadEnvironmentByName(db, project, u.Environment)
+		if err != nil {
+			log.Warning("startArtifactUploadHandler> Cannot load environment %s: %s\n", u.Environment, err)
+			WriteError(w, r, sdk.ErrNoEnvironment)
+			return
+		}
+	}
+
+	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, permission.PermissionReadExecute) {
+		log.Warning("startArtifactUploadHandler> No enought right on this environment %s: \n", u.Environment)
+		WriteError(w, r, sdk.ErrForbidden)
+		return
+	}
+
+	u.PipelineID = p.ID
+	u.ApplicationID = a.ID
+	u.EnvironmentID = env.ID
+	u.Environment = env.Name
+	if err := artifact.StartUpload(db, &u); err != nil {
+		log.Warning("startArtifactUploadHandler> Cannot start upload of %s: %s\n", u.Name, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteJSON(w, r, u.ArtifactUploadSession, http.StatusOK)
+}
+
+// validArtifactUpload checks an upload can be stored as an artifact
+func validArtifactUpload(u sdk.ArtifactUploadSession) bool {
+	if u.Name == "" || strings.ContainsAny(u.Name, "/\\") || u.Name == "." || u.Name == ".." {
+		return false
+	}
+	if u.Size < 0 || u.ChunkSize <= 0 || u.ChunkSize > sdk.MaxArtifactChunkSize {
+		return false
+	}
+	if b, err := hex.DecodeString(u.SHA256); err != nil || len(b) != 32 {
+		return false
+	}
+	return true
+}
+
+// loadArtifactUpload loads the upload of route, checking it belongs to the pipeline and application of route
+func loadArtifactUpload(r *http.Request, db *sql.DB, c *context.Context) (*artifact.Upload, *sdk.Pipeline, *sdk.Application, error) {
+	vars := mux.Vars(r)
+	project := vars["key"]
+	pipelineName := vars["permPipelineKey"]
+	appName := vars["permApplicationName"]
+
+	p, err := pipeline.LoadPipeline(db, project, pipelineName, false)
+	if err != nil {
+		log.Warning("loadArtifactUpload> cannot load pipeline %s-%s: %s\n", project, pipelineName, err)
+		return nil, nil, nil, sdk.ErrPipelineNotFound
+	}
+
+	a, err := application.LoadApplicationByName(db, project, appName)
+	if err != nil {
+		log.Warning("loadArtifactUpload> cannot load application %s-%s: %s\n", project, appName, err)
+		return nil, nil, nil, sdk.ErrApplicationNotFound
+	}
+
+	u, err := artifact.LoadUpload(db, vars["uploadID"])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if u.PipelineID != p.ID || u.ApplicationID != a.ID {
+		return nil, nil, nil, sdk.ErrArtifactUploadNotFound
+	}
+
+	if u.EnvironmentID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(u.EnvironmentID, c.User, permission.PermissionReadExecute) {
+		log.Warning("loadArtifactUpload> No enought right on environment %s\n", u.Environment)
+		return nil, nil, nil, sdk.ErrForbidden
+	}
+
+	return u, p, a, nil
+}
+
+func getArtifactUploadHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	u, _, _, err := loadArtifactUpload(r, db, c)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteJSON(w, r, u.ArtifactUploadSession, http.StatusOK)
+}
+
+func uploadArtifactChunkHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	vars := mux.Vars(r)
+
+	chunk, err := strconv.Atoi(vars["chunk"])
+	if err != nil {
+		log.Warning("uploadArtifactChunkHandler> Chunk must be an integer: %s\n", err)
+		WriteError(w, r, sdk.ErrWrongRequest)
+		return
+	}
+
+	sum := r.Header.Get(sdk.ArtifactSHA256Header)
+	if sum == "" {
+		log.Warning("uploadArtifactChunkHandler> %s header is not set\n", sdk.ArtifactSHA256Header)
+		WriteError(w, r, sdk.ErrWrongRequest)
+		return
+	}
+
+	u, _, _, err := loadArtifactUpload(r, db, c)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	if err := artifact.SaveChunk(db, u, chunk, sum, r.Body); err != nil {
+		log.Warning("uploadArtifactChunkHandler> Cannot save chunk %d of upload %s: %s\n", chunk, u.ID, err)
+		WriteError(w, r, err)
+		return
+	}
+}
+
+func completeArtifactUploadHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	vars := mux.Vars(r)
+	project := vars["key"]
+
+	u, p, a, err := loadArtifactUpload(r, db, c)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	hash, err := generateHash()
+	if err != nil {
+		log.Warning("completeArtifactUploadHandler> Could not generate hash: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	art := sdk.Artifact{
+		Project:      project,
+		Pipeline:     p.Name,
+		Application:  a.Name,
+		Environment:  u.Environment,
+		DownloadHash: hash,
+	}
+	env := &sdk.Environment{ID: u.EnvironmentID, Name: u.Environment}
+	if err := artifact.CompleteUpload(db, u, p, a, art, env); err != nil {
+		log.Warning("completeArtifactUploadHandler> Cannot complete upload %s of %s: %s\n", u.ID, u.Name, err)
+		WriteError(w, r, err)
+		return
+	}
+}
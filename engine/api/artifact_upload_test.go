@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func TestRangeOffset(t *testing.T) {
+	offset, ok := rangeOffset("bytes=1024-")
+	assert.True(t, ok)
+	assert.Equal(t, int64(1024), offset)
+
+	for _, h := range []string{"", "bytes=0-99", "bytes=-500", "bytes=1-2,5-", "items=1-"} {
+		_, ok := rangeOffset(h)
+		assert.False(t, ok, h)
+	}
+}
+
+func TestValidArtifactUpload(t *testing.T) {
+	u := sdk.ArtifactUploadSession{
+		Name:      "app.tar.gz",
+		Size:      100,
+		ChunkSize: sdk.ArtifactChunkSize,
+		SHA256:    "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+	}
+	assert.True(t, validArtifactUpload(u))
+
+	invalid := u
+	invalid.Name = "../app.tar.gz"
+	assert.False(t, validArtifactUpload(invalid))
+
+	invalid = u
+	invalid.ChunkSize = sdk.MaxArtifactChunkSize + 1
+	assert.False(t, validArtifactUpload(invalid))
+
+	invalid = u
+	invalid.SHA256 = "2cf24dba"
+	assert.False(t, validArtifactUpload(invalid))
+}
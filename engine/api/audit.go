@@ -1,10 +1,30 @@
 package main
 
 import (
+	"bytes"
 	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/gorilla/mux"
+
+	"github.com/ovh/cds/engine/api/action"
+	"github.com/ovh/cds/engine/api/application"
+	"github.com/ovh/cds/engine/api/audit"
+	"github.com/ovh/cds/engine/api/context"
 	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/api/environment"
+	"github.com/ovh/cds/engine/api/group"
+	"github.com/ovh/cds/engine/api/leader"
+	"github.com/ovh/cds/engine/api/pipeline"
+	"github.com/ovh/cds/engine/api/project"
+	"github.com/ovh/cds/engine/api/worker"
 	"github.com/ovh/cds/engine/log"
 	"github.com/ovh/cds/sdk"
 )
@@ -18,7 +38,7 @@ func auditCleanerRoutine() {
 
 	for {
 		db := database.DB()
-		if db != nil {
+		if db != nil && leader.IsLeader() {
 			err := actionAuditCleaner(db)
 			if err != nil {
 				log.Warning("AuditCleanerRoutine> Action clean failed: %s\n", err)
@@ -78,3 +98,249 @@ func actionAuditCleaner(db *sql.DB) error {
 
 	return nil
 }
+
+// auditRecorder captures response status of a mutating request and records it in audit log
+type auditRecorder struct {
+	http.ResponseWriter
+	event sdk.AuditEvent
+}
+
+func (a *auditRecorder) WriteHeader(status int) {
+	a.event.Status = status
+	a.ResponseWriter.WriteHeader(status)
+}
+
+func (a *auditRecorder) Write(b []byte) (int, error) {
+	if a.event.Status == 0 {
+		a.event.Status = http.StatusOK
+	}
+	return a.ResponseWriter.Write(b)
+}
+
+// AuditLoader loads the current state of the resource of a route, recorded before PUT and DELETE requests
+type AuditLoader func(vars map[string]string, db *sql.DB, c *context.Context) (interface{}, error)
+
+func auditProject(vars map[string]string, db *sql.DB, c *context.Context) (interface{}, error) {
+	return project.LoadProject(db, vars["permProjectKey"], c.User)
+}
+
+func auditApplication(vars map[string]string, db *sql.DB, c *context.Context) (interface{}, error) {
+	return application.LoadApplicationByName(db, vars["key"], vars["permApplicationName"])
+}
+
+func auditPipeline(vars map[string]string, db *sql.DB, c *context.Context) (interface{}, error) {
+	return pipeline.LoadPipeline(db, vars["key"], vars["permPipelineKey"], true)
+}
+
+func auditEnvironment(vars map[string]string, db *sql.DB, c *context.Context) (interface{}, error) {
+	return environment.LoadEnvironmentByName(db, vars["key"], vars["permEnvironmentName"])
+}
+
+func auditAction(vars map[string]string, db *sql.DB, c *context.Context) (interface{}, error) {
+	return action.LoadPublicAction(db, vars["permActionName"])
+}
+
+func auditGroup(vars map[string]string, db *sql.DB, c *context.Context) (interface{}, error) {
+	return group.LoadGroup(db, vars["permGroupName"])
+}
+
+func auditWorkerModel(vars map[string]string, db *sql.DB, c *context.Context) (interface{}, error) {
+	id, err := strconv.ParseInt(vars["permModelID"], 10, 64)
+	if err != nil {
+		return nil, sdk.ErrInvalidID
+	}
+	return worker.LoadWorkerModelByID(database.DBMap(db), id)
+}
+
+// newAuditRecorder reads request body, which is restored for the actual handler
+func newAuditRecorder(w http.ResponseWriter, req *http.Request, uri string, c *context.Context) *auditRecorder {
+	vars := mux.Vars(req)
+	a := &auditRecorder{
+		ResponseWriter: w,
+		event: sdk.AuditEvent{
+			Date:   time.Now(),
+			Method: req.Method,
+			Path:   req.URL.Path,
+			Route:  uri,
+			IP:     remoteIP(req),
+		},
+	}
+
+	a.event.ProjectKey = vars["key"]
+	if a.event.ProjectKey == "" {
+		a.event.ProjectKey = vars["permProjectKey"]
+	}
+	// Workers authenticate with a user crafted from their name and group
+	if c.Worker.ID != "" {
+		a.event.Worker = c.Worker.Name
+	} else if c.User != nil {
+		a.event.Username = c.User.Username
+	}
+
+	// Streamed and large bodies, like artifacts, are not kept
+	if req.Body != nil && req.ContentLength > 0 && req.ContentLength <= audit.MaxBodySize {
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			log.Warning("newAuditRecorder> Cannot read body of %s %s: %s\n", req.Method, req.URL, err)
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		a.event.After = audit.Sanitize(body)
+	}
+
+	return a
+}
+
+// captureBefore records, on PUT and DELETE, the current state of the resource loaded by the audit loader
+// of the route, if any. It must only be called once permissions have been checked
+func (a *auditRecorder) captureBefore(req *http.Request, rc *routerConfig, db *sql.DB, c *context.Context) {
+	if (req.Method != "PUT" && req.Method != "DELETE") || rc.auditBefore == nil || db == nil {
+		return
+	}
+
+	v, err := rc.auditBefore(mux.Vars(req), db, c)
+	if err != nil {
+		log.Debug("captureBefore> Cannot load %s: %s\n", req.URL.Path, err)
+		return
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		log.Warning("captureBefore> Cannot marshal %s: %s\n", req.URL.Path, err)
+		return
+	}
+	if len(b) <= audit.MaxBodySize {
+		a.event.Before = audit.Sanitize(b)
+	}
+}
+
+// record queues the audit event once handler returned
+func (a *auditRecorder) record() {
+	if a.event.Status == 0 {
+		a.event.Status = http.StatusOK
+	}
+	a.event.Diff = audit.Diff(a.event.Before, a.event.After)
+	audit.Record(a.event)
+}
+
+// trustedProxies are the networks of reverse proxies allowed to set X-Forwarded-For
+var trustedProxies []*net.IPNet
+
+func initTrustedProxies(cidrs []string) error {
+	trustedProxies = nil
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			if strings.Contains(cidr, ":") {
+				cidr += "/128"
+			} else {
+				cidr += "/32"
+			}
+		}
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy %s: %s", cidr, err)
+		}
+		trustedProxies = append(trustedProxies, n)
+	}
+	return nil
+}
+
+func isTrustedProxy(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, n := range trustedProxies {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteIP returns the address of the client. X-Forwarded-For is only read when the request comes
+// from a trusted proxy, and the client is the last address not added by a trusted proxy
+func remoteIP(req *http.Request) string {
+	ip := req.RemoteAddr
+	if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		ip = host
+	}
+	if !isTrustedProxy(ip) {
+		return ip
+	}
+
+	fwd := strings.Split(req.Header.Get("X-Forwarded-For"), ",")
+	for i := len(fwd) - 1; i >= 0; i-- {
+		addr := strings.TrimSpace(fwd[i])
+		if addr == "" {
+			continue
+		}
+		ip = addr
+		if !isTrustedProxy(addr) {
+			break
+		}
+	}
+	return ip
+}
+
+func getAuditFilter(r *http.Request) (sdk.AuditFilter, error) {
+	f := sdk.AuditFilter{
+		ProjectKey: r.FormValue("project"),
+		Username:   r.FormValue("user"),
+	}
+
+	var err error
+	if since := r.FormValue("since"); since != "" {
+		if f.Since, err = time.Parse(time.RFC3339, since); err != nil {
+			return f, sdk.ErrWrongRequest
+		}
+	}
+	if until := r.FormValue("until"); until != "" {
+		if f.Until, err = time.Parse(time.RFC3339, until); err != nil {
+			return f, sdk.ErrWrongRequest
+		}
+	}
+	if limit := r.FormValue("limit"); limit != "" {
+		if f.Limit, err = strconv.Atoi(limit); err != nil {
+			return f, sdk.ErrWrongRequest
+		}
+	}
+	return f, nil
+}
+
+func getAuditHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	f, err := getAuditFilter(r)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	events, err := audit.Load(db, f)
+	if err != nil {
+		log.Warning("getAuditHandler> Cannot load audit events: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteJSON(w, r, events, http.StatusOK)
+}
+
+func getProjectAuditHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	vars := mux.Vars(r)
+	key := vars["permProjectKey"]
+
+	f, err := getAuditFilter(r)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	f.ProjectKey = key
+
+	events, err := audit.Load(db, f)
+	if err != nil {
+		log.Warning("getProjectAuditHandler> Cannot load audit events of project %s: %s\n", key, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteJSON(w, r, events, http.StatusOK)
+}
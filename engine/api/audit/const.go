@@ -2,7 +2,9 @@ package audit
 
 // Audit constants describing event
 const (
-	Added   = "added"
-	Deleted = "deleted"
-	Updated = "updated"
+	Added    = "added"
+	Deleted  = "deleted"
+	Updated  = "updated"
+	Promoted = "promoted"
+	Demoted  = "demoted"
 )
@@ -0,0 +1,247 @@
+package audit
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/api/leader"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+const (
+	// MaxBodySize is the maximum size of request and resource bodies stored in audit log
+	MaxBodySize = 64 * 1024
+	maskedValue = "******"
+)
+
+var (
+	events    = make(chan sdk.AuditEvent, 1000)
+	retention int
+	sink      *os.File
+)
+
+// Initialize sets audit log retention in days and opens file sink if path is set
+func Initialize(filePath string, retentionDays int) error {
+	retention = retentionDays
+	if filePath == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return fmt.Errorf("cannot open audit file %s: %s", filePath, err)
+	}
+	sink = f
+	return nil
+}
+
+// Record queues event for storage. Events are dropped if the queue is full
+func Record(e sdk.AuditEvent) {
+	select {
+	case events <- e:
+	default:
+		log.Warning("Audit> Queue is full, dropping event %s %s by %s%s\n", e.Method, e.Path, e.Username, e.Worker)
+	}
+}
+
+// LogRoutine stores queued events in database and file sink
+func LogRoutine() {
+	defer log.Critical("Audit> LogRoutine exited")
+
+	for e := range events {
+		if db := database.DB(); db != nil {
+			if err := Insert(db, &e); err != nil {
+				log.Warning("Audit> LogRoutine> Cannot insert event %s %s: %s\n", e.Method, e.Path, err)
+			}
+		}
+
+		if sink != nil {
+			b, err := json.Marshal(e)
+			if err != nil {
+				log.Warning("Audit> LogRoutine> Cannot marshal event: %s\n", err)
+				continue
+			}
+			if _, err := sink.Write(append(b, '\n')); err != nil {
+				log.Warning("Audit> LogRoutine> Cannot write event in file: %s\n", err)
+			}
+		}
+	}
+}
+
+// CleanerRoutine removes events older than retention every hour
+func CleanerRoutine() {
+	defer log.Critical("Audit> CleanerRoutine exited")
+
+	for {
+		if db := database.DB(); db != nil && retention > 0 && leader.IsLeader() {
+			if n, err := Clean(db, time.Now().AddDate(0, 0, -retention)); err != nil {
+				log.Warning("Audit> CleanerRoutine> %s\n", err)
+			} else if n > 0 {
+				log.Notice("Audit> CleanerRoutine> %d events removed\n", n)
+			}
+		}
+		time.Sleep(1 * time.Hour)
+	}
+}
+
+// Insert stores event in database
+func Insert(db database.QueryExecuter, e *sdk.AuditEvent) error {
+	diff, err := json.Marshal(e.Diff)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO audit_log (created, method, path, route, project_key, username, worker, ip, status, before, after, diff)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id`
+	return db.QueryRow(query, e.Date, e.Method, e.Path, e.Route, e.ProjectKey, e.Username, e.Worker, e.IP, e.Status, e.Before, e.After, diff).Scan(&e.ID)
+}
+
+// Load returns events matching filter, most recent first
+func Load(db database.Querier, f sdk.AuditFilter) ([]sdk.AuditEvent, error) {
+	query := `SELECT id, created, method, path, route, project_key, username, worker, ip, status, before, after, diff FROM audit_log WHERE 1 = 1`
+	args := []interface{}{}
+	where := func(clause string, arg interface{}) {
+		args = append(args, arg)
+		query += fmt.Sprintf(" AND "+clause, len(args))
+	}
+
+	if f.ProjectKey != "" {
+		where("project_key = $%d", f.ProjectKey)
+	}
+	if f.Username != "" {
+		where("(username = $%d OR worker = $%[1]d)", f.Username)
+	}
+	if !f.Since.IsZero() {
+		where("created >= $%d", f.Since)
+	}
+	if !f.Until.IsZero() {
+		where("created <= $%d", f.Until)
+	}
+	limit := f.Limit
+	if limit <= 0 || limit > 1000 {
+		limit = 1000
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY created DESC LIMIT $%d", len(args))
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []sdk.AuditEvent{}
+	for rows.Next() {
+		var e sdk.AuditEvent
+		var diff []byte
+		if err := rows.Scan(&e.ID, &e.Date, &e.Method, &e.Path, &e.Route, &e.ProjectKey, &e.Username, &e.Worker, &e.IP, &e.Status, &e.Before, &e.After, &diff); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(diff, &e.Diff); err != nil {
+			return nil, err
+		}
+		res = append(res, e)
+	}
+	return res, nil
+}
+
+// Clean removes events older than given date
+func Clean(db *sql.DB, before time.Time) (int64, error) {
+	res, err := db.Exec(`DELETE FROM audit_log WHERE created < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Sanitize masks passwords, tokens, secrets and password typed variable values in a JSON body.
+// Non JSON bodies are returned untouched
+func Sanitize(body []byte) string {
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return string(body)
+	}
+	b, err := json.Marshal(sanitize(v))
+	if err != nil {
+		return string(body)
+	}
+	return string(b)
+}
+
+func sanitize(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		secret := t["type"] == string(sdk.SecretVariable) || t["type"] == string(sdk.KeyVariable)
+		for k, val := range t {
+			key := strings.ToLower(k)
+			if strings.Contains(key, "password") || strings.Contains(key, "token") || strings.Contains(key, "secret") ||
+				(secret && key == "value") {
+				t[k] = maskedValue
+				continue
+			}
+			t[k] = sanitize(val)
+		}
+		return t
+	case []interface{}:
+		for i := range t {
+			t[i] = sanitize(t[i])
+		}
+		return t
+	}
+	return v
+}
+
+// Diff lists top level fields of after which differ from before.
+// Both states must be JSON objects, otherwise no diff is computed
+func Diff(before, after string) []sdk.AuditDiff {
+	var b, a map[string]json.RawMessage
+	if before == "" || after == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(before), &b); err != nil {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(after), &a); err != nil {
+		return nil
+	}
+
+	fields := []string{}
+	for k := range a {
+		fields = append(fields, k)
+	}
+	sort.Strings(fields)
+
+	diff := []sdk.AuditDiff{}
+	for _, k := range fields {
+		old, ok := b[k]
+		if ok && compact(old) == compact(a[k]) {
+			continue
+		}
+		d := sdk.AuditDiff{Field: k, After: compact(a[k])}
+		if ok {
+			d.Before = compact(old)
+		}
+		diff = append(diff, d)
+	}
+	return diff
+}
+
+// compact normalizes a JSON value so that equal values compare equal
+func compact(raw json.RawMessage) string {
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return string(raw)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return string(raw)
+	}
+	return string(b)
+}
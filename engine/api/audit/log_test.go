@@ -0,0 +1,30 @@
+package audit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func TestSanitize(t *testing.T) {
+	assert.Equal(t, `{"password":"******","username":"john"}`, Sanitize([]byte(`{"username":"john","password":"foo"}`)))
+	assert.Equal(t, `[{"name":"pwd","type":"password","value":"******"},{"name":"foo","type":"string","value":"bar"}]`,
+		Sanitize([]byte(`[{"name":"pwd","type":"password","value":"secret"},{"name":"foo","type":"string","value":"bar"}]`)))
+	assert.Equal(t, `{"config":{"api_token":"******"}}`, Sanitize([]byte(`{"config":{"api_token":"abc"}}`)))
+	assert.Equal(t, "not json", Sanitize([]byte("not json")))
+}
+
+func TestDiff(t *testing.T) {
+	before := `{"name":"app","description":"old","variables":[1, 2]}`
+	after := `{"name":"app","description":"new","variables":[1,2],"repo":"foo/bar"}`
+
+	assert.Equal(t, []sdk.AuditDiff{
+		{Field: "description", Before: `"old"`, After: `"new"`},
+		{Field: "repo", After: `"foo/bar"`},
+	}, Diff(before, after))
+
+	assert.Nil(t, Diff("", after))
+	assert.Nil(t, Diff(`[{"name":"app"}]`, after))
+}
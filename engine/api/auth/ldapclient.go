@@ -25,12 +25,15 @@ const errUserNotFound = "user not found"
 
 //LDAPConfig handles all config to connect to the LDAP
 type LDAPConfig struct {
-	Host         string
-	Port         int
-	Base         string
-	DN           string
-	SSL          bool
-	UserFullname string
+	Host                 string
+	Port                 int
+	Base                 string
+	DN                   string
+	SSL                  bool
+	UserFullname         string
+	GroupFilter          string
+	GroupMemberAttribute string
+	GroupMapping         []LDAPGroupMapping
 }
 
 //LDAPDriver is the LDAP client interface
@@ -0,0 +1,264 @@
+package auth
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ovh/cds/engine/api/audit"
+	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/api/group"
+	"github.com/ovh/cds/engine/api/leader"
+	"github.com/ovh/cds/engine/api/user"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+
+	"gopkg.in/ldap.v2"
+)
+
+const ldapGroupSyncAuthor = "ldap-sync"
+
+//LDAPGroupMapping binds a LDAP group to a CDS group
+type LDAPGroupMapping struct {
+	LDAPGroup string
+	Group     string
+	Admin     bool
+}
+
+//ParseLDAPGroupMapping parses mappings formatted as ldapGroup:cdsGroup or ldapGroup:cdsGroup:admin
+func ParseLDAPGroupMapping(mappings []string) ([]LDAPGroupMapping, error) {
+	res := []LDAPGroupMapping{}
+	for _, m := range mappings {
+		t := strings.Split(m, ":")
+		if len(t) < 2 || len(t) > 3 || t[0] == "" || t[1] == "" {
+			return nil, fmt.Errorf("invalid LDAP group mapping %s", m)
+		}
+		mapping := LDAPGroupMapping{LDAPGroup: t[0], Group: t[1]}
+		if len(t) == 3 {
+			if t[2] != "admin" {
+				return nil, fmt.Errorf("invalid LDAP group mapping %s", m)
+			}
+			mapping.Admin = true
+		}
+		res = append(res, mapping)
+	}
+	return res, nil
+}
+
+//GroupSyncEnabled returns true if at least one LDAP group is mapped on a CDS group
+func (c *LDAPClient) GroupSyncEnabled() bool {
+	return len(c.conf.GroupMapping) > 0
+}
+
+//SearchGroupMembers returns usernames of all members of the LDAP group
+func (c *LDAPClient) SearchGroupMembers(ldapGroup string) ([]string, error) {
+	filter := fmt.Sprintf(c.conf.GroupFilter, ldap.EscapeFilter(ldapGroup))
+	searchRequest := ldap.NewSearchRequest(
+		c.conf.Base,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		filter,
+		[]string{c.conf.GroupMemberAttribute},
+		nil,
+	)
+
+	sr, err := c.conn.Search(searchRequest)
+	if err != nil {
+		if !shoudRetry(err) {
+			return nil, err
+		}
+		if err := c.openLDAP(c.conf); err != nil {
+			return nil, err
+		}
+		sr, err = c.conn.Search(searchRequest)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	members := []string{}
+	for _, e := range sr.Entries {
+		for _, v := range e.GetAttributeValues(c.conf.GroupMemberAttribute) {
+			members = append(members, memberUsername(v))
+		}
+	}
+	return members, nil
+}
+
+//memberUsername extracts the username from a member attribute value,
+//which is either a plain uid (memberUid) or a DN (member, uniqueMember)
+func memberUsername(value string) string {
+	if !strings.Contains(value, "=") {
+		return value
+	}
+	dn, err := ldap.ParseDN(value)
+	if err != nil || len(dn.RDNs) == 0 || len(dn.RDNs[0].Attributes) == 0 {
+		return value
+	}
+	return dn.RDNs[0].Attributes[0].Value
+}
+
+//computeGroupSyncChanges compares current members of a CDS group with LDAP members.
+//If no LDAP group grants admin on the CDS group, current admins are left untouched
+func computeGroupSyncChanges(g *sdk.Group, wantedMembers, wantedAdmins map[string]bool) []sdk.LDAPGroupSyncChange {
+	changes := []sdk.LDAPGroupSyncChange{}
+	manageAdmins := len(wantedAdmins) > 0
+
+	current := map[string]bool{}
+	for _, u := range g.Users {
+		current[u.Username] = false
+	}
+	for _, u := range g.Admins {
+		current[u.Username] = true
+	}
+
+	usernames := []string{}
+	for u := range wantedMembers {
+		usernames = append(usernames, u)
+	}
+	for u := range wantedAdmins {
+		if !wantedMembers[u] {
+			usernames = append(usernames, u)
+		}
+	}
+	sort.Strings(usernames)
+
+	// Additions and promotions first, so that a group never lacks an admin
+	for _, u := range usernames {
+		isAdmin, isMember := current[u]
+		switch {
+		case !isMember:
+			changes = append(changes, sdk.LDAPGroupSyncChange{Group: g.Name, Username: u, Change: audit.Added})
+			if wantedAdmins[u] {
+				changes = append(changes, sdk.LDAPGroupSyncChange{Group: g.Name, Username: u, Change: audit.Promoted})
+			}
+		case !isAdmin && wantedAdmins[u]:
+			changes = append(changes, sdk.LDAPGroupSyncChange{Group: g.Name, Username: u, Change: audit.Promoted})
+		case isAdmin && manageAdmins && !wantedAdmins[u]:
+			changes = append(changes, sdk.LDAPGroupSyncChange{Group: g.Name, Username: u, Change: audit.Demoted})
+		}
+	}
+
+	removed := []string{}
+	for u, isAdmin := range current {
+		if wantedMembers[u] || wantedAdmins[u] {
+			continue
+		}
+		if isAdmin && !manageAdmins {
+			continue
+		}
+		removed = append(removed, u)
+	}
+	sort.Strings(removed)
+	for _, u := range removed {
+		changes = append(changes, sdk.LDAPGroupSyncChange{Group: g.Name, Username: u, Change: audit.Deleted})
+	}
+
+	return changes
+}
+
+//SyncGroups updates members and admins of mapped CDS groups from LDAP groups.
+//With dryRun, changes are only computed and returned
+func (c *LDAPClient) SyncGroups(db *sql.DB, dryRun bool) (*sdk.LDAPGroupSyncReport, error) {
+	report := &sdk.LDAPGroupSyncReport{
+		DryRun:  dryRun,
+		Date:    time.Now(),
+		Changes: []sdk.LDAPGroupSyncChange{},
+	}
+
+	wantedMembers := map[string]map[string]bool{}
+	wantedAdmins := map[string]map[string]bool{}
+	groupNames := []string{}
+	for _, m := range c.conf.GroupMapping {
+		if _, ok := wantedMembers[m.Group]; !ok {
+			wantedMembers[m.Group] = map[string]bool{}
+			wantedAdmins[m.Group] = map[string]bool{}
+			groupNames = append(groupNames, m.Group)
+		}
+
+		members, err := c.SearchGroupMembers(m.LDAPGroup)
+		if err != nil {
+			return nil, fmt.Errorf("cannot search members of LDAP group %s: %s", m.LDAPGroup, err)
+		}
+		for _, u := range members {
+			if m.Admin {
+				wantedAdmins[m.Group][u] = true
+			} else {
+				wantedMembers[m.Group][u] = true
+			}
+		}
+	}
+
+	for _, name := range groupNames {
+		g, err := group.LoadGroup(db, name)
+		if err != nil {
+			log.Warning("LDAP> SyncGroups> Cannot load group %s: %s", name, err)
+			continue
+		}
+		if err := group.LoadUserGroup(db, g); err != nil {
+			return nil, err
+		}
+
+		for _, change := range computeGroupSyncChanges(g, wantedMembers[name], wantedAdmins[name]) {
+			u, err := user.LoadUserWithoutAuth(db, change.Username)
+			if err == sql.ErrNoRows {
+				// Users are created on their first login
+				log.Debug("LDAP> SyncGroups> Skip unknown user %s", change.Username)
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			// Local users are managed by hand
+			if change.Change == audit.Deleted && u.Origin == "local" {
+				continue
+			}
+
+			report.Changes = append(report.Changes, change)
+			if dryRun {
+				continue
+			}
+
+			if err := applyGroupSyncChange(db, g, u, change); err != nil {
+				log.Warning("LDAP> SyncGroups> Cannot apply %s on user %s in group %s: %s", change.Change, u.Username, g.Name, err)
+			}
+		}
+	}
+
+	return report, nil
+}
+
+func applyGroupSyncChange(db *sql.DB, g *sdk.Group, u *sdk.User, change sdk.LDAPGroupSyncChange) error {
+	var err error
+	switch change.Change {
+	case audit.Added:
+		err = group.InsertUserInGroup(db, g.ID, u.ID, false)
+	case audit.Promoted:
+		err = group.SetUserGroupAdmin(db, g.ID, u.ID)
+	case audit.Demoted:
+		err = group.RemoveUserGroupAdmin(db, g.ID, u.ID)
+	case audit.Deleted:
+		err = group.DeleteUserFromGroup(db, g.ID, u.ID)
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Notice("LDAP> SyncGroups> User %s %s in group %s", u.Username, change.Change, g.Name)
+	return group.InsertAudit(db, g.ID, ldapGroupSyncAuthor, u.Username, change.Change)
+}
+
+//SyncGroupsRoutine synchronizes CDS groups with LDAP groups every interval seconds
+func (c *LDAPClient) SyncGroupsRoutine(interval int) {
+	defer log.Critical("LDAP> SyncGroupsRoutine exited")
+
+	for {
+		if db := database.DB(); db != nil && leader.IsLeader() {
+			if _, err := c.SyncGroups(db, false); err != nil {
+				log.Warning("LDAP> SyncGroupsRoutine> %s", err)
+			}
+		}
+		time.Sleep(time.Duration(interval) * time.Second)
+	}
+}
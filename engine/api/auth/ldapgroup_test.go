@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovh/cds/engine/api/audit"
+	"github.com/ovh/cds/sdk"
+)
+
+func TestParseLDAPGroupMapping(t *testing.T) {
+	m, err := ParseLDAPGroupMapping([]string{"developers:dev", "leads:dev:admin"})
+	assert.NoError(t, err)
+	assert.Equal(t, []LDAPGroupMapping{
+		{LDAPGroup: "developers", Group: "dev"},
+		{LDAPGroup: "leads", Group: "dev", Admin: true},
+	}, m)
+
+	for _, invalid := range []string{"developers", ":dev", "leads:dev:owner", "a:b:admin:c"} {
+		_, err := ParseLDAPGroupMapping([]string{invalid})
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestMemberUsername(t *testing.T) {
+	assert.Equal(t, "john", memberUsername("john"))
+	assert.Equal(t, "john", memberUsername("uid=john,ou=people,dc=example,dc=com"))
+}
+
+func TestComputeGroupSyncChanges(t *testing.T) {
+	g := &sdk.Group{
+		Name:   "dev",
+		Admins: []sdk.User{{Username: "alice"}, {Username: "bob"}},
+		Users:  []sdk.User{{Username: "carol"}, {Username: "dave"}},
+	}
+
+	changes := computeGroupSyncChanges(g,
+		map[string]bool{"bob": true, "carol": true, "erin": true},
+		map[string]bool{"alice": true, "dave": true},
+	)
+
+	assert.Equal(t, []sdk.LDAPGroupSyncChange{
+		{Group: "dev", Username: "bob", Change: audit.Demoted},
+		{Group: "dev", Username: "dave", Change: audit.Promoted},
+		{Group: "dev", Username: "erin", Change: audit.Added},
+	}, changes)
+}
+
+func TestComputeGroupSyncChangesWithoutAdminMapping(t *testing.T) {
+	g := &sdk.Group{
+		Name:   "dev",
+		Admins: []sdk.User{{Username: "alice"}},
+		Users:  []sdk.User{{Username: "carol"}},
+	}
+
+	changes := computeGroupSyncChanges(g, map[string]bool{"erin": true}, map[string]bool{})
+
+	assert.Equal(t, []sdk.LDAPGroupSyncChange{
+		{Group: "dev", Username: "erin", Change: audit.Added},
+		{Group: "dev", Username: "carol", Change: audit.Deleted},
+	}, changes)
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/ovh/cds/engine/api/artifact"
 	"github.com/ovh/cds/engine/api/group"
+	"github.com/ovh/cds/engine/api/role"
 	"github.com/ovh/cds/engine/api/worker"
 	"github.com/ovh/cds/engine/log"
 )
@@ -21,6 +22,11 @@ func InitiliazeDB(db *sql.DB) error {
 		return err
 	}
 
+	if err := role.CreateBuiltinRoles(db); err != nil {
+		log.Critical("Cannot setup builtin roles: %s\n", err)
+		return err
+	}
+
 	if err := worker.CreateBuiltinActions(db); err != nil {
 		log.Critical("Cannot setup builtin actions: %s\n", err)
 		return err
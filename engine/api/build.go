@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 
@@ -18,6 +19,7 @@ import (
 	"github.com/ovh/cds/engine/api/permission"
 	"github.com/ovh/cds/engine/api/pipeline"
 	"github.com/ovh/cds/engine/api/project"
+	"github.com/ovh/cds/engine/api/repositoriesmanager"
 	"github.com/ovh/cds/engine/api/stats"
 	"github.com/ovh/cds/engine/api/worker"
 	"github.com/ovh/cds/engine/log"
@@ -159,6 +161,13 @@ func deleteBuildHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *c
 			WriteError(w, r, err)
 			return
 		}
+
+		err = build.DeleteTestCaseResults(tx, result.ID)
+		if err != nil {
+			log.Warning("deleteBuildHandler> %s ! Cannot delete test case history [%d]: %s\n", c.User.Username, result.ID, err)
+			WriteError(w, r, err)
+			return
+		}
 	} else {
 		// Delete from pipeline_build
 		result, err := pipeline.LoadPipelineBuild(db, p.ID, a.ID, buildNumber, env.ID)
@@ -385,8 +394,14 @@ func addQueueResultHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c
 	}
 	defer tx.Rollback()
 
-	//Update worker status
-	err = worker.UpdateWorkerStatus(tx, c.Worker.ID, sdk.StatusWaiting)
+	//Update worker status, single use workers won't take any other action and wait to be destroyed by their hatchery
+	workerStatus := sdk.StatusWaiting
+	if singleUse, errS := worker.IsSingleUse(tx, c.Worker.ID); errS != nil {
+		log.Warning("addQueueResultHandler> Cannot check if worker %s is single use: %s\n", c.Worker.ID, errS)
+	} else if singleUse {
+		workerStatus = sdk.StatusDisabled
+	}
+	err = worker.UpdateWorkerStatus(tx, c.Worker.ID, workerStatus)
 	if err != nil {
 		log.Warning("addQueueResultHandler> Cannot update worker status (%s): %s\n", c.Worker.ID, err)
 		// We want to update ActionBuild status anyway
@@ -430,19 +445,30 @@ func takeActionBuildHandler(w http.ResponseWriter, r *http.Request, db *sql.DB,
 	}
 
 	// update database
-	ab, err := build.TakeActionBuild(db, id, caller)
+	abi, err := takeActionBuild(db, id, caller)
 	if err != nil {
-		if err != build.ErrAlreadyTaken {
+		if err != build.ErrAlreadyTaken && err != sdk.ErrGroupQuotaReached {
 			log.Warning("takeActionBuildHandler> Cannot give ActionBuild %s: %s\n", id, err)
 		}
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
+	WriteJSON(w, r, abi, http.StatusOK)
+}
+
+// takeActionBuild gives action build id to caller and loads what it needs to run it
+func takeActionBuild(db *sql.DB, id string, caller *sdk.Worker) (worker.ActionBuildInfo, error) {
+	abi := worker.ActionBuildInfo{}
+	ab, err := build.TakeActionBuild(db, id, caller)
+	if err != nil {
+		return abi, err
+	}
+
 	// Update worker status to "building"
-	err = worker.SetToBuilding(db, c.Worker.ID, ab.ID)
+	err = worker.SetToBuilding(db, caller.ID, ab.ID)
 	if err != nil {
-		log.Warning("takeActionBuildHandler> Cannot update worker status: %s\n", err)
+		log.Warning("takeActionBuild> Cannot update worker status: %s\n", err)
 		// We want the worker to run the task anyway now
 	}
 
@@ -451,23 +477,20 @@ func takeActionBuildHandler(w http.ResponseWriter, r *http.Request, db *sql.DB,
 	// load action and return it to worker
 	a, err := action.LoadActionByPipelineActionID(db, ab.PipelineActionID)
 	if err != nil {
-		log.Warning("takeActionBuildHandler> Cannot load action from  PipelineActionID %d: %s\n", ab.PipelineActionID, err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+		log.Warning("takeActionBuild> Cannot load action from  PipelineActionID %d: %s\n", ab.PipelineActionID, err)
+		return abi, err
 	}
 
 	secrets, err := loadActionBuildSecrets(db, ab.ID)
 	if err != nil {
-		log.Warning("takeActionBuildHandler> Cannot load action build secrets: %s\n", err)
-		WriteError(w, r, err)
-		return
+		log.Warning("takeActionBuild> Cannot load action build secrets: %s\n", err)
+		return abi, err
 	}
 
-	abi := worker.ActionBuildInfo{}
 	abi.ActionBuild = ab
 	abi.Action = *a
 	abi.Secrets = secrets
-	WriteJSON(w, r, abi, http.StatusOK)
+	return abi, nil
 }
 
 func loadActionBuildSecrets(db *sql.DB, abID int64) ([]sdk.Variable, error) {
@@ -538,6 +561,76 @@ func loadActionBuildSecrets(db *sql.DB, abID int64) ([]sdk.Variable, error) {
 	return secrets, nil
 }
 
+// getActionBuildCloneHandler gives GitClone builtin action of the calling worker the clone urls
+// and credentials of the application repository. They are resolved when cloning, and not given
+// to other actions as action build secrets
+func getActionBuildCloneHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	if c.Agent != sdk.WorkerAgent || c.Worker.ID == "" {
+		WriteError(w, r, sdk.ErrForbidden)
+		return
+	}
+
+	idS := mux.Vars(r)["id"]
+	id, err := strconv.ParseInt(idS, 10, 64)
+	if err != nil {
+		WriteError(w, r, sdk.ErrInvalidID)
+		return
+	}
+	if workerID, err := worker.FindBuildingWorker(db, idS); err != nil || workerID != c.Worker.ID {
+		log.Warning("getActionBuildCloneHandler> worker %s is not building %s: %v\n", c.Worker.ID, idS, err)
+		WriteError(w, r, sdk.ErrForbidden)
+		return
+	}
+
+	query := `SELECT project.projectkey, pipeline_build.application_id
+	FROM pipeline_build JOIN action_build ON action_build.pipeline_build_id = pipeline_build.id
+	JOIN pipeline ON pipeline.id = pipeline_build.pipeline_id
+	JOIN project ON project.id = pipeline.project_id
+	WHERE action_build.id = $1`
+	var projectKey string
+	var appID int64
+	if err := db.QueryRow(query, id).Scan(&projectKey, &appID); err != nil {
+		log.Warning("getActionBuildCloneHandler> Cannot load pipeline build of action build %d: %s\n", id, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	app, err := application.LoadApplicationByID(db, appID)
+	if err != nil {
+		log.Warning("getActionBuildCloneHandler> Cannot load application %d: %s\n", appID, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	var clone sdk.VCSClone
+	if app.RepositoriesManager == nil || app.RepositoryFullname == "" {
+		WriteJSON(w, r, clone, http.StatusOK)
+		return
+	}
+
+	client, err := repositoriesmanager.AuthorizedClient(db, projectKey, app.RepositoriesManager.Name)
+	if err != nil {
+		log.Warning("getActionBuildCloneHandler> Cannot get client of %s: %s\n", app.RepositoriesManager.Name, err)
+		WriteError(w, r, err)
+		return
+	}
+	repo, err := client.RepoByFullname(app.RepositoryFullname)
+	if err != nil {
+		log.Warning("getActionBuildCloneHandler> Cannot get repository %s: %s\n", app.RepositoryFullname, err)
+		WriteError(w, r, err)
+		return
+	}
+	clone.HTTPCloneURL = repo.HTTPCloneURL
+	clone.SSHCloneURL = repo.SSHCloneURL
+
+	clone.User, clone.Password, err = repositoriesmanager.CloneCredentials(db, projectKey, app.RepositoriesManager.Name)
+	if err != nil {
+		log.Warning("getActionBuildCloneHandler> Cannot load clone credentials of %s: %s\n", app.RepositoriesManager.Name, err)
+	}
+
+	WriteJSON(w, r, clone, http.StatusOK)
+}
+
 func getQueueHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
 	if c.Worker.ID != "" {
 		// Load calling worker
@@ -578,6 +671,96 @@ func getQueueHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *cont
 	WriteJSON(w, r, queue, http.StatusOK)
 }
 
+// maxQueueWait is the longest time a worker can wait for an action build pushed in queue
+const maxQueueWait = 60 * time.Second
+
+// takeQueueHandler gives the calling worker an action build matching its declared capabilities, waiting for one
+// to be pushed in queue until ?timeout= seconds. It answers as takeActionBuildHandler, or with no content on timeout.
+func takeQueueHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	if c.Agent != sdk.WorkerAgent || c.Worker.ID == "" {
+		WriteError(w, r, sdk.ErrForbidden)
+		return
+	}
+
+	caller, errW := worker.LoadWorker(db, c.Worker.ID)
+	if errW != nil {
+		log.Warning("takeQueueHandler> cannot load calling worker: %s\n", errW)
+		WriteError(w, r, errW)
+		return
+	}
+	if caller.Status != sdk.StatusWaiting {
+		log.Debug("takeQueueHandler> worker %s is not available to build (status = %s)\n", caller.ID, caller.Status)
+		WriteError(w, r, sdk.ErrInvalidID)
+		return
+	}
+
+	capas, ok := worker.GetCapabilities(caller.ID)
+	if !ok {
+		WriteError(w, r, sdk.ErrWorkerCapabilitiesMissing)
+		return
+	}
+	match := func(b sdk.ActionBuild) bool {
+		return capas.Meet(b.Requirements)
+	}
+
+	timeout := maxQueueWait
+	if t, err := strconv.Atoi(r.FormValue("timeout")); err == nil && t > 0 && time.Duration(t)*time.Second < maxQueueWait {
+		timeout = time.Duration(t) * time.Second
+	}
+	deadline := time.Now().Add(timeout)
+
+	// take returns false if action build is already taken or cannot be taken yet
+	take := func(b sdk.ActionBuild) bool {
+		abi, err := takeActionBuild(db, strconv.FormatInt(b.ID, 10), caller)
+		if err == build.ErrAlreadyTaken || err == sdk.ErrGroupQuotaReached || err == sql.ErrNoRows {
+			return false
+		}
+		if err != nil {
+			log.Warning("takeQueueHandler> Cannot give ActionBuild %d: %s\n", b.ID, err)
+			WriteError(w, r, err)
+			return true
+		}
+		WriteJSON(w, r, abi, http.StatusOK)
+		return true
+	}
+
+	// Action builds already waiting, missed by push or not taken yet
+	queue, errQ := build.LoadGroupWaitingQueue(db, caller.GroupID)
+	if errQ != nil {
+		log.Warning("takeQueueHandler> Cannot load queue from db: %s\n", errQ)
+		WriteError(w, r, errQ)
+		return
+	}
+	unknown := false
+	for i := range queue {
+		if match(queue[i]) && take(queue[i]) {
+			return
+		}
+		unknown = unknown || !capas.Knows(queue[i].Requirements)
+	}
+	// Worker checks requirements added since it declared its capabilities before waiting
+	if unknown {
+		WriteError(w, r, sdk.ErrWorkerCapabilitiesMissing)
+		return
+	}
+
+	for {
+		wait := deadline.Sub(time.Now())
+		if wait <= 0 {
+			break
+		}
+		b, ok := build.WaitPushed(caller.GroupID, match, wait)
+		if !ok {
+			break
+		}
+		if take(b) {
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func requirementsErrorHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
@@ -808,6 +991,10 @@ func addBuildTestResultsHandler(w http.ResponseWriter, r *http.Request, db *sql.
 		}
 	}
 
+	if new.Coverage != nil {
+		tests.AddCoverageReports(new.Coverage.Reports...)
+	}
+
 	// update total values
 	tests.Total = 0
 	tests.TotalOK = 0
@@ -826,6 +1013,11 @@ func addBuildTestResultsHandler(w http.ResponseWriter, r *http.Request, db *sql.
 		WriteError(w, r, err)
 	}
 
+	// Test case history is only used by analytics, do not fail the build
+	if err := build.InsertTestCaseResults(db, pb, tests); err != nil {
+		log.Warning("addBuildTestResultsHandler> Cannot insert test case results: %s\n", err)
+	}
+
 	stats.TestEvent(db, p.ProjectID, a.ID, tests)
 }
 
@@ -920,3 +1112,72 @@ func getBuildTestResultsHandler(w http.ResponseWriter, r *http.Request, db *sql.
 
 	WriteJSON(w, r, tests, http.StatusOK)
 }
+
+// loadTestedPipeline loads pipeline and application of test reports requests, with environment ?envName=
+// on which caller needs read permission
+func loadTestedPipeline(r *http.Request, db *sql.DB, c *context.Context) (*sdk.Pipeline, *sdk.Application, *sdk.Environment, error) {
+	vars := mux.Vars(r)
+	projectKey := vars["key"]
+	pipelineName := vars["permPipelineKey"]
+	appName := vars["permApplicationName"]
+
+	p, err := pipeline.LoadPipeline(db, projectKey, pipelineName, false)
+	if err != nil {
+		if err != sdk.ErrPipelineNotFound {
+			log.Warning("loadTestedPipeline> Cannot load pipeline %s: %s\n", pipelineName, err)
+		}
+		return nil, nil, nil, err
+	}
+
+	a, err := application.LoadApplicationByName(db, projectKey, appName)
+	if err != nil {
+		if err != sdk.ErrApplicationNotFound {
+			log.Warning("loadTestedPipeline> Cannot load application %s: %s\n", appName, err)
+		}
+		return nil, nil, nil, err
+	}
+
+	envName := r.FormValue("envName")
+	env := &sdk.DefaultEnv
+	if envName != "" && envName != sdk.DefaultEnv.Name {
+		env, err = environment.LoadEnvironmentByName(db, projectKey, envName)
+		if err != nil {
+			log.Warning("loadTestedPipeline> Cannot load environment %s: %s\n", envName, err)
+			return nil, nil, nil, sdk.ErrUnknownEnv
+		}
+	}
+
+	if env.ID != sdk.DefaultEnv.ID && !permission.AccessToEnvironment(env.ID, c.User, permission.PermissionRead) {
+		log.Warning("loadTestedPipeline> No enought right on this environment %s: \n", envName)
+		return nil, nil, nil, sdk.ErrForbidden
+	}
+
+	return p, a, env, nil
+}
+
+func getCoverageTrendHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	limit := 20
+	if l := r.FormValue("limit"); l != "" {
+		var err error
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit <= 0 {
+			WriteError(w, r, sdk.ErrWrongRequest)
+			return
+		}
+	}
+
+	p, a, env, err := loadTestedPipeline(r, db, c)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	trend, err := build.LoadCoverageTrend(db, p.ID, a.ID, env.ID, limit)
+	if err != nil {
+		log.Warning("getCoverageTrendHandler> Cannot load coverage trend: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteJSON(w, r, trend, http.StatusOK)
+}
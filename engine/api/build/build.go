@@ -7,12 +7,16 @@ import (
 	"strings"
 	"time"
 
+	"github.com/lib/pq"
+
 	"github.com/ovh/cds/engine/api/action"
 	"github.com/ovh/cds/engine/api/database"
 	"github.com/ovh/cds/engine/api/group"
 	"github.com/ovh/cds/engine/api/notification"
 	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/engine/metrics"
 	"github.com/ovh/cds/sdk"
+	"github.com/ovh/cds/sdk/tracing"
 )
 
 var (
@@ -20,6 +24,13 @@ var (
 	ErrAlreadyTaken = fmt.Errorf("cds: action already taken")
 )
 
+var (
+	queueWait = metrics.NewHistogram("cds_queue_wait_seconds",
+		"Time action builds waited in queue before being taken by a worker", metrics.DurationBuckets)
+	actionBuildDuration = metrics.NewHistogram("cds_action_build_duration_seconds",
+		"Duration of action builds by final status", metrics.DurationBuckets, "status")
+)
+
 // LoadBuildByPipelineBuildID Load all actions_build by pipeline ID
 func LoadBuildByPipelineBuildID(db *sql.DB, pipelineBuildID int64) ([]sdk.ActionBuild, error) {
 
@@ -99,15 +110,29 @@ func LoadActionBuild(db *sql.DB, id string) (sdk.ActionBuild, error) {
 	return b, nil
 }
 
+// LoadTraceParent returns the trace context of the pipeline build of an action build, empty if it is not traced
+func LoadTraceParent(db *sql.DB, id int64) (string, error) {
+	var argsJSON string
+	if err := db.QueryRow(`SELECT args FROM action_build WHERE id = $1`, id).Scan(&argsJSON); err != nil {
+		return "", err
+	}
+	var args []sdk.Parameter
+	if err := json.Unmarshal([]byte(argsJSON), &args); err != nil {
+		return "", err
+	}
+	return sdk.ParameterValue(args, tracing.Parameter), nil
+}
+
 // UpdateActionBuildStatus Update status of an action_build
 func UpdateActionBuildStatus(db *sql.Tx, build *sdk.ActionBuild, status sdk.Status) error {
 	var query string
 	var err error
 	log.Debug("UpdateActionBuildStatus> Updating action_build %d to %s\n", build.ID, status)
 
-	query = `SELECT status FROM action_build WHERE id = $1 FOR UPDATE`
+	query = `SELECT status, start FROM action_build WHERE id = $1 FOR UPDATE`
 	var currentStatus string
-	err = db.QueryRow(query, build.ID).Scan(&currentStatus)
+	var start pq.NullTime
+	err = db.QueryRow(query, build.ID).Scan(&currentStatus, &start)
 	if err != nil {
 		return err
 	}
@@ -130,8 +155,12 @@ func UpdateActionBuildStatus(db *sql.Tx, build *sdk.ActionBuild, status sdk.Stat
 			return nil
 		}
 
+		done := time.Now()
 		query = `UPDATE action_build SET status = $1, done = $2 WHERE id = $3`
-		_, err = db.Exec(query, status.String(), time.Now(), build.ID)
+		_, err = db.Exec(query, status.String(), done, build.ID)
+		if err == nil && start.Valid && (status == sdk.StatusSuccess || status == sdk.StatusFail) {
+			actionBuildDuration.Observe(done.Sub(start.Time).Seconds(), status.String())
+		}
 	default:
 		err = fmt.Errorf("Cannot update ActionBuild %d to status %v", build.ID, status.String())
 	}
@@ -169,15 +198,19 @@ func LoadWaitingQueue(db *sql.DB) ([]sdk.ActionBuild, error) {
 			 action_build.args,
 			 action_build.status, action_build.pipeline_build_id,
 			 pipeline_build.pipeline_id,
-			 pipeline_build.build_number
+			 pipeline_build.build_number,
+			 pipeline.type,
+			 pipeline.priority,
+			 COALESCE(owner.group_id, 0)
 		  FROM action_build
 		  JOIN pipeline_build ON pipeline_build.id = action_build.pipeline_build_id
 		  JOIN pipeline_action ON pipeline_action.id = action_build.pipeline_action_id
 		  JOIN action ON action.id = pipeline_action.action_id
+		  JOIN pipeline ON pipeline.id = pipeline_build.pipeline_id
+		  ` + OwnerGroupJoin + `
 		  WHERE action_build.status = $1
 		  ORDER BY pipeline_build.id,action.name,action_build.pipeline_action_id
-			LIMIT 100`
-	var queue []sdk.ActionBuild
+			LIMIT 1000`
 
 	rows, err := db.Query(query, sdk.StatusWaiting.String())
 	if err != nil {
@@ -185,22 +218,12 @@ func LoadWaitingQueue(db *sql.DB) ([]sdk.ActionBuild, error) {
 	}
 	defer rows.Close()
 
-	for rows.Next() {
-		b, err := loadQueue(db, rows)
-		if err != nil {
-			return nil, err
-		}
-		queue = append(queue, b)
-	}
-
-	return queue, nil
+	return loadQueue(db, rows, true)
 }
 
 // LoadGroupWaitingQueue loads action build in queue accessbible to given group
 func LoadGroupWaitingQueue(db *sql.DB, groupID int64) ([]sdk.ActionBuild, error) {
 	//log.Notice("LoadGroupWaitingQueue for group %d\n", groupID)
-	var queue []sdk.ActionBuild
-
 	query := `
 			 SELECT action_build.id,
 			 action_build.pipeline_action_id,
@@ -209,12 +232,16 @@ func LoadGroupWaitingQueue(db *sql.DB, groupID int64) ([]sdk.ActionBuild, error)
 			 action_build.args,
 			 action_build.status, action_build.pipeline_build_id,
 			 pipeline_build.pipeline_id,
-			 pipeline_build.build_number
+			 pipeline_build.build_number,
+			 pipeline.type,
+			 pipeline.priority,
+			 COALESCE(owner.group_id, 0)
 		  FROM action_build
 		  JOIN pipeline_build ON pipeline_build.id = action_build.pipeline_build_id
 		  JOIN pipeline_action ON pipeline_action.id = action_build.pipeline_action_id
 		  JOIN action ON action.id = pipeline_action.action_id
 		  JOIN pipeline ON pipeline.id = pipeline_build.pipeline_id
+		  ` + OwnerGroupJoin + `
 			JOIN pipeline_group ON pipeline_group.pipeline_id = pipeline.id
 			WHERE action_build.status = $1
 			AND ( 
@@ -224,7 +251,7 @@ func LoadGroupWaitingQueue(db *sql.DB, groupID int64) ([]sdk.ActionBuild, error)
 			)
 			AND pipeline_group.role > 4
 			ORDER BY pipeline_build.id,action.name,action_build.pipeline_action_id
-			LIMIT 100
+			LIMIT 1000
 			`
 
 	rows, err := db.Query(query, sdk.StatusWaiting.String(), groupID, group.SharedInfraGroup)
@@ -233,16 +260,7 @@ func LoadGroupWaitingQueue(db *sql.DB, groupID int64) ([]sdk.ActionBuild, error)
 	}
 	defer rows.Close()
 
-	for rows.Next() {
-		b, err := loadQueue(db, rows)
-		if err != nil {
-			return nil, err
-		}
-		queue = append(queue, b)
-	}
-
-	return queue, nil
-
+	return loadQueue(db, rows, false)
 }
 
 // LoadUserWaitingQueue loads action build in queue where user has access
@@ -268,17 +286,21 @@ func LoadUserWaitingQueue(db *sql.DB, u *sdk.User) ([]sdk.ActionBuild, error) {
 			 action_build.args,
 			 action_build.status, action_build.pipeline_build_id,
 			 pipeline_build.pipeline_id,
-			 pipeline_build.build_number
+			 pipeline_build.build_number,
+			 pipeline.type,
+			 pipeline.priority,
+			 COALESCE(owner.group_id, 0)
 		  FROM action_build
 		  JOIN pipeline_build ON pipeline_build.id = action_build.pipeline_build_id
 		  JOIN pipeline_action ON pipeline_action.id = action_build.pipeline_action_id
 		  JOIN action ON action.id = pipeline_action.action_id
 		  JOIN pipeline ON pipeline.id = pipeline_build.pipeline_id
+		  ` + OwnerGroupJoin + `
 			JOIN pipeline_group ON pipeline_group.pipeline_id = pipeline.id
 			JOIN group_user ON group_user.group_id = pipeline_group.group_id
 			WHERE action_build.status = $1 AND group_user.user_id = $2
 		  ORDER BY pipeline_build.id,action.name,action_build.pipeline_action_id
-			LIMIT 100
+			LIMIT 1000
 			`
 
 	rows, err := db.Query(query, sdk.StatusWaiting.String(), u.ID)
@@ -287,33 +309,66 @@ func LoadUserWaitingQueue(db *sql.DB, u *sdk.User) ([]sdk.ActionBuild, error) {
 	}
 	defer rows.Close()
 
+	return loadQueue(db, rows, true)
+}
+
+// loadQueue reads waiting action builds, keeps the QueueLimit first ones in fair share order
+// and loads their requirements
+// Action builds of groups over quota are dropped, or kept at the end of queue if withBlocked is set
+func loadQueue(db *sql.DB, rows *sql.Rows, withBlocked bool) ([]sdk.ActionBuild, error) {
+	var queue []sdk.ActionBuild
+	actionIDs := map[int64]int64{}
 	for rows.Next() {
-		b, err := loadQueue(db, rows)
+		b, actionID, err := scanQueue(rows)
 		if err != nil {
 			return nil, err
 		}
+		actionIDs[b.ID] = actionID
 		queue = append(queue, b)
 	}
+	rows.Close()
 
+	usages, err := LoadGroupsUsage(db)
+	if err != nil {
+		return nil, err
+	}
+	queue, blocked := FairShare(queue, usages)
+	if withBlocked {
+		queue = append(queue, blocked...)
+	}
+	if len(queue) > QueueLimit {
+		queue = queue[:QueueLimit]
+	}
+
+	// load action requirements
+	for i := range queue {
+		a, err := action.LoadActionByID(db, actionIDs[queue[i].ID])
+		if err != nil {
+			return nil, err
+		}
+		queue[i].Requirements = a.Requirements
+	}
 	return queue, nil
 }
 
-func loadQueue(db *sql.DB, s database.Scanner) (sdk.ActionBuild, error) {
+func scanQueue(s database.Scanner) (sdk.ActionBuild, int64, error) {
 	var b sdk.ActionBuild
-	var argsJSON, actionName, sStatus string
+	var argsJSON, actionName, sStatus, pType string
+	var priority sql.NullInt64
 	var actionID int64
-	err := s.Scan(&b.ID, &b.PipelineActionID, &actionID, &actionName, &argsJSON, &sStatus, &b.PipelineBuildID, &b.PipelineID, &b.BuildNumber)
+	err := s.Scan(&b.ID, &b.PipelineActionID, &actionID, &actionName, &argsJSON, &sStatus, &b.PipelineBuildID, &b.PipelineID, &b.BuildNumber, &pType, &priority, &b.GroupID)
 	b.Status = sdk.StatusFromString(sStatus)
 	if err != nil {
-		return b, err
+		return b, actionID, err
 	}
+	b.Priority = sdk.PipelinePriority(sdk.PipelineTypeFromString(pType), NullInt(priority))
 
 	err = json.Unmarshal([]byte(argsJSON), &b.Args)
 	if err != nil {
 		var oa []string
 		err = json.Unmarshal([]byte(argsJSON), &oa)
 		if err != nil {
-			return b, err
+			return b, actionID, err
 		}
 		for _, op := range oa {
 			t := strings.SplitN(op, "=", 2)
@@ -326,13 +381,7 @@ func loadQueue(db *sql.DB, s database.Scanner) (sdk.ActionBuild, error) {
 		}
 	}
 
-	// load action requirements
-	a, err := action.LoadActionByID(db, actionID)
-	if err != nil {
-		return b, err
-	}
-	b.Requirements = a.Requirements
-	return b, nil
+	return b, actionID, nil
 }
 
 // TakeActionBuild Take an action build for update
@@ -351,13 +400,15 @@ func TakeActionBuild(db *sql.DB, buildID string, worker *sdk.Worker) (sdk.Action
 			 action_build.args,
 			 action_build.status,
 			 action_build.pipeline_build_id,
-			 pipeline_build.build_number
+			 pipeline_build.build_number,
+			 action_build.queued
 	     FROM action_build
 	     JOIN pipeline_build ON pipeline_build.id = action_build.pipeline_build_id
 			 WHERE action_build.id = $1 FOR UPDATE`
 
 	var sStatus string
-	err = tx.QueryRow(query, buildID).Scan(&b.ID, &b.PipelineActionID, &argsJSON, &sStatus, &b.PipelineBuildID, &b.BuildNumber)
+	var queued pq.NullTime
+	err = tx.QueryRow(query, buildID).Scan(&b.ID, &b.PipelineActionID, &argsJSON, &sStatus, &b.PipelineBuildID, &b.BuildNumber, &queued)
 	b.Status = sdk.StatusFromString(sStatus)
 	if err != nil {
 		return b, err
@@ -372,6 +423,10 @@ func TakeActionBuild(db *sql.DB, buildID string, worker *sdk.Worker) (sdk.Action
 		return b, ErrAlreadyTaken
 	}
 
+	if err := checkGroupQuota(tx, b.ID); err != nil {
+		return b, err
+	}
+
 	query = ` update action_build set worker_model_name = worker_model.name from worker_model where worker_model.id=$2 and action_build.id = $1`
 	if _, err := tx.Exec(query, b.ID, worker.Model); err != nil {
 		log.Warning("Cannot update model on action_build : %s", err)
@@ -382,7 +437,18 @@ func TakeActionBuild(db *sql.DB, buildID string, worker *sdk.Worker) (sdk.Action
 		return b, err
 	}
 
-	return b, tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return b, err
+	}
+	if queued.Valid {
+		queueWait.Since(queued.Time)
+
+		span := tracing.ContinueAt("queue", sdk.ParameterValue(b.Args, tracing.Parameter), queued.Time)
+		span.SetAttribute("cds.action_build", b.ID)
+		span.SetAttribute("cds.worker", worker.Name)
+		span.Finish()
+	}
+	return b, nil
 }
 
 // DeleteActionBuild Delete Action Build
@@ -0,0 +1,86 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ovh/cds/engine/api/cache"
+)
+
+// Debug sessions relay a shell between the worker keeping a failed action build and a user.
+// Both may be connected to different API instances, so shell input and output go through cache queues.
+
+// DebugSessionTTL is how long a debug session stays opened without being refreshed by its worker
+const DebugSessionTTL = 60
+
+// DebugMessage is shell output sent to user, End is set once worker ended the session
+type DebugMessage struct {
+	Data []byte `json:"data"`
+	End  bool   `json:"end"`
+}
+
+func debugKey(id int64, name string) string {
+	return cache.Key("debug", fmt.Sprintf("%d", id), name)
+}
+
+// OpenDebugSession marks a worker as waiting for debug sessions on action build, it must be refreshed until closed
+func OpenDebugSession(id int64) {
+	cache.SetWithTTL(debugKey(id, "session"), true, DebugSessionTTL)
+}
+
+// DebugSessionOpened returns true while a worker waits for debug sessions on action build
+func DebugSessionOpened(id int64) bool {
+	var opened bool
+	return cache.Get(debugKey(id, "session"), &opened) && opened
+}
+
+// AttachDebugSession records that a user is connected to the debug session of action build
+func AttachDebugSession(id int64) {
+	cache.SetWithTTL(debugKey(id, "attached"), true, DebugSessionTTL)
+}
+
+// CloseDebugSession ends the debug session of action build and tells connected user.
+// Messages nobody will read are dropped.
+func CloseDebugSession(id int64) {
+	cache.Delete(debugKey(id, "session"))
+	drainDebugQueue(debugKey(id, "input"))
+
+	var attached bool
+	if !cache.Get(debugKey(id, "attached"), &attached) {
+		drainDebugQueue(debugKey(id, "output"))
+		return
+	}
+	cache.Delete(debugKey(id, "attached"))
+	cache.Enqueue(debugKey(id, "output"), DebugMessage{End: true})
+}
+
+func drainDebugQueue(queue string) {
+	var m json.RawMessage
+	for cache.DequeueWithTimeout(queue, &m, 0) {
+	}
+}
+
+// SendDebugInput sends user input to the shell of action build
+func SendDebugInput(id int64, data []byte) {
+	cache.Enqueue(debugKey(id, "input"), data)
+}
+
+// ReadDebugInput returns user input of the shell of action build, or false on timeout
+func ReadDebugInput(id int64, timeout time.Duration) ([]byte, bool) {
+	var data []byte
+	ok := cache.DequeueWithTimeout(debugKey(id, "input"), &data, timeout)
+	return data, ok
+}
+
+// SendDebugOutput sends shell output to the user of the debug session of action build
+func SendDebugOutput(id int64, data []byte) {
+	cache.Enqueue(debugKey(id, "output"), DebugMessage{Data: data})
+}
+
+// ReadDebugOutput returns shell output of action build, or false on timeout
+func ReadDebugOutput(id int64, timeout time.Duration) (DebugMessage, bool) {
+	var m DebugMessage
+	ok := cache.DequeueWithTimeout(debugKey(id, "output"), &m, timeout)
+	return m, ok
+}
@@ -0,0 +1,46 @@
+package build
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovh/cds/engine/api/cache"
+)
+
+func TestDebugSession(t *testing.T) {
+	cache.Initialize("local", "", "", 60)
+
+	assert.False(t, DebugSessionOpened(1))
+	OpenDebugSession(1)
+	assert.True(t, DebugSessionOpened(1))
+
+	AttachDebugSession(1)
+	SendDebugInput(1, []byte("ls\n"))
+	data, ok := ReadDebugInput(1, time.Second)
+	assert.True(t, ok)
+	assert.Equal(t, "ls\n", string(data))
+
+	SendDebugOutput(1, []byte("file\n"))
+	CloseDebugSession(1)
+	assert.False(t, DebugSessionOpened(1))
+
+	m, ok := ReadDebugOutput(1, time.Second)
+	assert.True(t, ok)
+	assert.Equal(t, "file\n", string(m.Data))
+	m, ok = ReadDebugOutput(1, time.Second)
+	assert.True(t, ok)
+	assert.True(t, m.End)
+}
+
+func TestCloseDebugSessionWithoutUser(t *testing.T) {
+	cache.Initialize("local", "", "", 60)
+
+	OpenDebugSession(2)
+	SendDebugOutput(2, []byte("file\n"))
+	CloseDebugSession(2)
+
+	_, ok := ReadDebugOutput(2, 200*time.Millisecond)
+	assert.False(t, ok)
+}
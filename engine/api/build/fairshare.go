@@ -0,0 +1,167 @@
+package build
+
+import (
+	"database/sql"
+	"sort"
+
+	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/api/group"
+	"github.com/ovh/cds/sdk"
+)
+
+// QueueLimit is the maximum number of action builds returned in queue
+const QueueLimit = 100
+
+// OwnerGroupJoin joins the group accounted for builds of a pipeline: the group with the highest
+// permission on its project, the oldest one if several. It needs pipeline to be joined
+const OwnerGroupJoin = `LEFT JOIN LATERAL (
+				SELECT project_group.group_id FROM project_group
+				WHERE project_group.project_id = pipeline.project_id
+				ORDER BY project_group.role DESC, project_group.group_id ASC
+				LIMIT 1
+			) AS owner ON true`
+
+// quotaLockNamespace is the first key of advisory locks taken while checking group quotas
+const quotaLockNamespace = 1664
+
+// LoadGroupsUsage returns quotas and current number of action builds of groups
+func LoadGroupsUsage(db database.Querier) (map[int64]sdk.GroupQuota, error) {
+	usages, err := group.LoadQuotas(db)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT COALESCE(owner.group_id, 0), COUNT(action_build.id)
+		FROM action_build
+		JOIN pipeline_build ON pipeline_build.id = action_build.pipeline_build_id
+		JOIN pipeline ON pipeline.id = pipeline_build.pipeline_id
+		` + OwnerGroupJoin + `
+		WHERE action_build.status = $1
+		GROUP BY owner.group_id`
+	rows, err := db.Query(query, sdk.StatusBuilding.String())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var groupID, building int64
+		if err := rows.Scan(&groupID, &building); err != nil {
+			return nil, err
+		}
+		u, ok := usages[groupID]
+		if !ok {
+			u = sdk.DefaultGroupQuota
+			u.GroupID = groupID
+		}
+		u.Building = building
+		usages[groupID] = u
+	}
+	return usages, nil
+}
+
+type fairShareEntry struct {
+	build sdk.ActionBuild
+	share float64
+}
+
+type byFairShare []fairShareEntry
+
+func (s byFairShare) Len() int      { return len(s) }
+func (s byFairShare) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byFairShare) Less(i, j int) bool {
+	if s[i].build.Priority != s[j].build.Priority {
+		return s[i].build.Priority > s[j].build.Priority
+	}
+	return s[i].share < s[j].share
+}
+
+// FairShare orders a queue sorted by arrival: highest priorities first, then action builds of the
+// groups using the smallest part of their weight. Action builds which would exceed the quota of
+// their group are returned apart, in arrival order
+func FairShare(queue []sdk.ActionBuild, usages map[int64]sdk.GroupQuota) ([]sdk.ActionBuild, []sdk.ActionBuild) {
+	entries := make([]fairShareEntry, 0, len(queue))
+	blocked := []sdk.ActionBuild{}
+	taken := map[int64]int64{}
+
+	for _, b := range queue {
+		u, ok := usages[b.GroupID]
+		if !ok {
+			u = sdk.DefaultGroupQuota
+		}
+		if u.Weight < 1 {
+			u.Weight = 1
+		}
+
+		n := u.Building + taken[b.GroupID]
+		if u.MaxBuilding > 0 && n >= u.MaxBuilding {
+			blocked = append(blocked, b)
+			continue
+		}
+		taken[b.GroupID]++
+		entries = append(entries, fairShareEntry{build: b, share: float64(n) / float64(u.Weight)})
+	}
+
+	sort.Stable(byFairShare(entries))
+
+	res := make([]sdk.ActionBuild, len(entries))
+	for i := range entries {
+		res[i] = entries[i].build
+	}
+	return res, blocked
+}
+
+// checkGroupQuota returns sdk.ErrGroupQuotaReached if the group accounted for an action build
+// already has its maximum number of action builds running. It locks the group quota until
+// the end of the transaction so that concurrent takes can't exceed it
+func checkGroupQuota(tx *sql.Tx, actionBuildID int64) error {
+	query := `SELECT COALESCE(owner.group_id, 0)
+		FROM action_build
+		JOIN pipeline_build ON pipeline_build.id = action_build.pipeline_build_id
+		JOIN pipeline ON pipeline.id = pipeline_build.pipeline_id
+		` + OwnerGroupJoin + `
+		WHERE action_build.id = $1`
+	var groupID int64
+	if err := tx.QueryRow(query, actionBuildID).Scan(&groupID); err != nil {
+		return err
+	}
+	if groupID == 0 {
+		return nil
+	}
+
+	q, err := group.LoadQuota(tx, groupID)
+	if err != nil {
+		return err
+	}
+	if q.MaxBuilding == 0 {
+		return nil
+	}
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1, $2)`, quotaLockNamespace, groupID); err != nil {
+		return err
+	}
+
+	query = `SELECT COUNT(action_build.id)
+		FROM action_build
+		JOIN pipeline_build ON pipeline_build.id = action_build.pipeline_build_id
+		JOIN pipeline ON pipeline.id = pipeline_build.pipeline_id
+		` + OwnerGroupJoin + `
+		WHERE action_build.status = $1 AND owner.group_id = $2`
+	var building int64
+	if err := tx.QueryRow(query, sdk.StatusBuilding.String(), groupID).Scan(&building); err != nil {
+		return err
+	}
+	if building >= q.MaxBuilding {
+		return sdk.ErrGroupQuotaReached
+	}
+	return nil
+}
+
+// NullInt returns a pointer to the value of i, or nil if i is NULL
+func NullInt(i sql.NullInt64) *int {
+	if !i.Valid {
+		return nil
+	}
+	v := int(i.Int64)
+	return &v
+}
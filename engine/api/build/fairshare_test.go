@@ -0,0 +1,56 @@
+package build
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func ids(queue []sdk.ActionBuild) []int64 {
+	res := []int64{}
+	for _, b := range queue {
+		res = append(res, b.ID)
+	}
+	return res
+}
+
+func TestFairShare(t *testing.T) {
+	// Group 1 bursts, group 2 comes after
+	queue := []sdk.ActionBuild{
+		{ID: 1, GroupID: 1},
+		{ID: 2, GroupID: 1},
+		{ID: 3, GroupID: 1},
+		{ID: 4, GroupID: 1},
+		{ID: 5, GroupID: 2},
+		{ID: 6, GroupID: 2},
+	}
+
+	ordered, blocked := FairShare(queue, map[int64]sdk.GroupQuota{})
+	assert.Equal(t, []int64{1, 5, 2, 6, 3, 4}, ids(ordered))
+	assert.Empty(t, blocked)
+
+	// Group 1 already has 2 builds running and twice the weight of group 2
+	usages := map[int64]sdk.GroupQuota{1: {GroupID: 1, Building: 2, Weight: 2}}
+	ordered, _ = FairShare(queue, usages)
+	assert.Equal(t, []int64{5, 1, 6, 2, 3, 4}, ids(ordered))
+
+	// Group 1 can run 3 builds at most
+	usages = map[int64]sdk.GroupQuota{1: {GroupID: 1, Building: 2, Weight: 1, MaxBuilding: 3}}
+	ordered, blocked = FairShare(queue, usages)
+	assert.Equal(t, []int64{5, 6, 1}, ids(ordered))
+	assert.Equal(t, []int64{2, 3, 4}, ids(blocked))
+}
+
+func TestFairSharePriority(t *testing.T) {
+	queue := []sdk.ActionBuild{
+		{ID: 1, GroupID: 1, Priority: sdk.PipelinePriority(sdk.BuildPipeline, nil)},
+		{ID: 2, GroupID: 2, Priority: sdk.PipelinePriority(sdk.BuildPipeline, nil)},
+		{ID: 3, GroupID: 1, Priority: sdk.PipelinePriority(sdk.DeploymentPipeline, nil)},
+		{ID: 4, GroupID: 2, Priority: sdk.PipelinePriority(sdk.TestingPipeline, nil)},
+	}
+
+	ordered, _ := FairShare(queue, nil)
+	assert.Equal(t, []int64{3, 4, 1, 2}, ids(ordered))
+}
@@ -0,0 +1,227 @@
+package build
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/ovh/cds/engine/api/action"
+	"github.com/ovh/cds/engine/api/cache"
+	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/api/group"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+// pushQueue is the cache queue of action builds newly waiting for a worker, shared by API instances.
+// Database stays authoritative: a pushed action build is only handed over if still waiting,
+// and action builds missed by push are found by workers polling the queue.
+var pushQueue = cache.Key("queue", "actionbuild", "push")
+
+// pushWaiting is set in cache while workers wait on any API instance, action builds are only pushed meanwhile
+// so that the push queue does not grow while nobody dequeues it
+var pushWaiting = cache.Key("queue", "actionbuild", "waiting")
+
+// maxPushAge is how long a pushed action build stays in the push queue without any waiting worker able to run it
+const maxPushAge = time.Minute
+
+// pushRetryDelay is how long an API instance leaves workers waiting on other instances dequeue the push queue
+// after pushing back an action build none of its waiting workers can run
+const pushRetryDelay = time.Second
+
+type pushMessage struct {
+	ID     int64     `json:"id"`
+	Pushed time.Time `json:"pushed"`
+}
+
+// waiter is a worker waiting for an action build on this API instance
+type waiter struct {
+	groupID int64
+	match   func(sdk.ActionBuild) bool
+	build   chan sdk.ActionBuild
+}
+
+var waiters = struct {
+	sync.Mutex
+	list []*waiter
+	wake chan struct{}
+}{wake: make(chan struct{}, 1)}
+
+// Push notifies waiting workers of new action builds waiting in queue. It must be called once action builds are committed.
+func Push(ids ...int64) {
+	var waiting bool
+	if !cache.Get(pushWaiting, &waiting) {
+		return
+	}
+	now := time.Now()
+	for _, id := range ids {
+		cache.Enqueue(pushQueue, pushMessage{ID: id, Pushed: now})
+	}
+}
+
+// WaitPushed blocks until an action build pushed in queue can be run by a worker of given group and is matched by match,
+// or until timeout
+func WaitPushed(groupID int64, match func(sdk.ActionBuild) bool, timeout time.Duration) (sdk.ActionBuild, bool) {
+	cache.SetWithTTL(pushWaiting, true, int(timeout/time.Second)+1)
+
+	w := &waiter{groupID: groupID, match: match, build: make(chan sdk.ActionBuild, 1)}
+	waiters.Lock()
+	waiters.list = append(waiters.list, w)
+	waiters.Unlock()
+	select {
+	case waiters.wake <- struct{}{}:
+	default:
+	}
+
+	select {
+	case b := <-w.build:
+		return b, true
+	case <-time.After(timeout):
+	}
+
+	waiters.Lock()
+	removeWaiter(w)
+	waiters.Unlock()
+
+	// An action build may have been handed over before removal
+	select {
+	case b := <-w.build:
+		return b, true
+	default:
+		return sdk.ActionBuild{}, false
+	}
+}
+
+// removeWaiter removes w from waiters, waiters must be locked
+func removeWaiter(w *waiter) bool {
+	for i := range waiters.list {
+		if waiters.list[i] == w {
+			waiters.list = append(waiters.list[:i], waiters.list[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func hasWaiters() bool {
+	waiters.Lock()
+	defer waiters.Unlock()
+	return len(waiters.list) > 0
+}
+
+// PushRoutine hands action builds pushed in queue over to workers waiting on this API instance.
+// Pushed action builds are only dequeued while workers are waiting, to leave them to other instances otherwise.
+func PushRoutine() {
+	defer log.Critical("PushRoutine exited")
+
+	for {
+		if !hasWaiters() {
+			<-waiters.wake
+			continue
+		}
+
+		var m pushMessage
+		if !cache.DequeueWithTimeout(pushQueue, &m, 5*time.Second) {
+			continue
+		}
+		if dispatch(m) {
+			continue
+		}
+
+		// Leave the action build to other instances, unless a worker starts waiting here
+		select {
+		case <-waiters.wake:
+		case <-time.After(pushRetryDelay):
+		}
+	}
+}
+
+// dispatch hands a pushed action build over to the first waiting worker allowed and able to run it.
+// It returns false if it pushed it back for workers waiting on other instances.
+func dispatch(m pushMessage) bool {
+	if time.Since(m.Pushed) > maxPushAge {
+		log.Debug("dispatch> No worker waiting for action build %d, left to polling\n", m.ID)
+		return true
+	}
+
+	db := database.DB()
+	if db == nil {
+		return true
+	}
+
+	b, groupIDs, err := loadPushed(db, m.ID)
+	if err == sql.ErrNoRows {
+		// Already taken
+		return true
+	}
+	if err != nil {
+		log.Warning("dispatch> Cannot load pushed action build %d: %s\n", m.ID, err)
+		return true
+	}
+
+	waiters.Lock()
+	for _, w := range waiters.list {
+		if groupIDs[w.groupID] && w.match(b) {
+			removeWaiter(w)
+			waiters.Unlock()
+			w.build <- b
+			return true
+		}
+	}
+	waiters.Unlock()
+
+	cache.Enqueue(pushQueue, m)
+	return false
+}
+
+// loadPushed loads an action build still waiting in queue, with IDs of groups allowed to run it
+func loadPushed(db *sql.DB, id int64) (sdk.ActionBuild, map[int64]bool, error) {
+	query := `
+			 SELECT action_build.id,
+			 action_build.pipeline_action_id,
+			 action.id,
+			 action.name,
+			 action_build.args,
+			 action_build.status, action_build.pipeline_build_id,
+			 pipeline_build.pipeline_id,
+			 pipeline_build.build_number,
+			 pipeline.type,
+			 pipeline.priority,
+			 COALESCE(owner.group_id, 0)
+		  FROM action_build
+		  JOIN pipeline_build ON pipeline_build.id = action_build.pipeline_build_id
+		  JOIN pipeline_action ON pipeline_action.id = action_build.pipeline_action_id
+		  JOIN action ON action.id = pipeline_action.action_id
+		  JOIN pipeline ON pipeline.id = pipeline_build.pipeline_id
+		  ` + OwnerGroupJoin + `
+			WHERE action_build.id = $1 AND action_build.status = $2`
+
+	b, actionID, err := scanQueue(db.QueryRow(query, id, sdk.StatusWaiting.String()))
+	if err != nil {
+		return b, nil, err
+	}
+
+	a, err := action.LoadActionByID(db, actionID)
+	if err != nil {
+		return b, nil, err
+	}
+	b.Requirements = a.Requirements
+
+	query = `SELECT pipeline_group.group_id FROM pipeline_group WHERE pipeline_group.pipeline_id = $1 AND pipeline_group.role > 4
+		UNION SELECT id FROM "group" WHERE name = $2`
+	rows, err := db.Query(query, b.PipelineID, group.SharedInfraGroup)
+	if err != nil {
+		return b, nil, err
+	}
+	defer rows.Close()
+
+	groupIDs := map[int64]bool{}
+	for rows.Next() {
+		var groupID int64
+		if err := rows.Scan(&groupID); err != nil {
+			return b, nil, err
+		}
+		groupIDs[groupID] = true
+	}
+	return b, groupIDs, rows.Err()
+}
@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"encoding/json"
 
+	"github.com/lib/pq"
+
 	"github.com/ovh/cds/engine/api/database"
 	"github.com/ovh/cds/sdk"
 )
@@ -32,14 +34,20 @@ func LoadTestResults(db *sql.DB, pbID int64) (sdk.Tests, error) {
 
 // InsertTestResults inserts test results of a specific pipeline build in database
 func InsertTestResults(db database.Executer, pbID int64, tests sdk.Tests) error {
-	query := `INSERT INTO pipeline_build_test (pipeline_build_id, tests) VALUES ($1, $2)`
+	query := `INSERT INTO pipeline_build_test (pipeline_build_id, tests, coverage) VALUES ($1, $2, $3)`
 
 	data, err := json.Marshal(tests)
 	if err != nil {
 		return err
 	}
 
-	_, err = db.Exec(query, pbID, string(data))
+	// Coverage is duplicated out of tests to be trended without unmarshalling every build
+	var coverage sql.NullFloat64
+	if tests.Coverage != nil {
+		coverage = sql.NullFloat64{Float64: tests.Coverage.Percent, Valid: true}
+	}
+
+	_, err = db.Exec(query, pbID, string(data), coverage)
 	if err != nil {
 		return err
 	}
@@ -47,6 +55,47 @@ func InsertTestResults(db database.Executer, pbID int64, tests sdk.Tests) error
 	return nil
 }
 
+// LoadCoverageTrend returns coverage of last builds in history of a pipeline, most recent first
+func LoadCoverageTrend(db database.Querier, pipelineID, applicationID, environmentID int64, limit int) ([]sdk.CoverageTrend, error) {
+	query := `SELECT ph.build_number, ph.version, ph.vcs_changes_branch, ph.done, pbt.coverage, pbt.tests
+		FROM pipeline_history ph
+		JOIN pipeline_build_test pbt ON pbt.pipeline_build_id = ph.pipeline_build_id
+		WHERE ph.pipeline_id = $1 AND ph.application_id = $2 AND ph.environment_id = $3
+		AND pbt.coverage IS NOT NULL
+		ORDER BY ph.build_number DESC
+		LIMIT $4`
+
+	rows, err := db.Query(query, pipelineID, applicationID, environmentID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trend := []sdk.CoverageTrend{}
+	for rows.Next() {
+		var c sdk.CoverageTrend
+		var branch sql.NullString
+		var done pq.NullTime
+		var data string
+		if err := rows.Scan(&c.BuildNumber, &c.Version, &branch, &done, &c.Percent, &data); err != nil {
+			return nil, err
+		}
+		c.Branch = branch.String
+		c.Done = done.Time
+
+		var t sdk.Tests
+		if err := json.Unmarshal([]byte(data), &t); err != nil {
+			return nil, err
+		}
+		if t.Coverage != nil {
+			c.LinesValid = t.Coverage.LinesValid
+			c.LinesCovered = t.Coverage.LinesCovered
+		}
+		trend = append(trend, c)
+	}
+	return trend, nil
+}
+
 // UpdateTestResults update test results of a specific pipeline build in database
 func UpdateTestResults(db *sql.DB, pbID int64, tests sdk.Tests) error {
 	tx, err := db.Begin()
@@ -83,7 +132,20 @@ func DeleteTestResults(db database.Executer, pbID int64) error {
 		return err
 	}
 
-	return nil
+	return DeleteTestCaseResults(db, pbID)
+}
+
+// RestartTestResults removes test results of a pipeline build being restarted,
+// its test case results are kept as a previous attempt
+func RestartTestResults(db database.Executer, pbID int64) error {
+	query := `DELETE FROM pipeline_build_test WHERE pipeline_build_id = $1`
+
+	_, err := db.Exec(query, pbID)
+	if err != nil {
+		return err
+	}
+
+	return keepTestCaseResults(db, pbID)
 }
 
 // DeletePipelineTestResults removes from database test results for a specific pipeline
@@ -96,6 +158,12 @@ func DeletePipelineTestResults(db database.Executer, pipID int64) error {
 		return err
 	}
 
+	query = `DELETE FROM test_case_result WHERE pipeline_id = $1`
+	_, err = db.Exec(query, pipID)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -0,0 +1,249 @@
+package build
+
+import (
+	"database/sql"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/sdk"
+)
+
+// TestHistoryFilter selects builds of a pipeline whose test case results are loaded
+type TestHistoryFilter struct {
+	PipelineID    int64
+	ApplicationID int64
+	EnvironmentID int64
+	// Branch restricts builds to a branch if not empty
+	Branch string
+	// Builds is the number of last builds loaded
+	Builds int
+	// Suite and Name restrict results to a test case if not empty
+	Suite string
+	Name  string
+}
+
+// InsertTestCaseResults replaces results of each test case of the last run of a pipeline build
+func InsertTestCaseResults(db *sql.DB, pb sdk.PipelineBuild, tests sdk.Tests) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM test_case_result WHERE pipeline_build_id = $1 AND attempt = 0`, pb.ID); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO test_case_result (pipeline_build_id, pipeline_id, application_id, environment_id, build_number, branch, hash, suite, name, status, duration)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, s := range tests.TestSuites {
+		for _, t := range s.Tests {
+			_, err := stmt.Exec(pb.ID, pb.Pipeline.ID, pb.Application.ID, pb.Environment.ID, pb.BuildNumber,
+				pb.Trigger.VCSChangesBranch, pb.Trigger.VCSChangesHash, s.Name, t.Name, string(testCaseStatus(t)), testCaseDuration(t))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// LoadTestCaseHistory returns results of test cases in last builds matching filter,
+// ordered by test case then build number, then run of build
+func LoadTestCaseHistory(db database.Querier, f TestHistoryFilter) ([]sdk.TestCaseResult, error) {
+	query := `SELECT build_number, branch, hash, suite, name, status, duration, created, attempt
+		FROM test_case_result
+		WHERE application_id = $1 AND pipeline_id = $2 AND environment_id = $3
+		AND ($4 = '' OR branch = $4)
+		AND ($5 = '' OR suite = $5)
+		AND ($6 = '' OR name = $6)
+		AND build_number IN (
+			SELECT DISTINCT build_number FROM test_case_result
+			WHERE application_id = $1 AND pipeline_id = $2 AND environment_id = $3
+			AND ($4 = '' OR branch = $4)
+			ORDER BY build_number DESC
+			LIMIT $7
+		)
+		ORDER BY suite, name, build_number, attempt DESC`
+
+	rows, err := db.Query(query, f.ApplicationID, f.PipelineID, f.EnvironmentID, f.Branch, f.Suite, f.Name, f.Builds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []sdk.TestCaseResult{}
+	for rows.Next() {
+		var r sdk.TestCaseResult
+		var branch, hash sql.NullString
+		var duration sql.NullFloat64
+		var created pq.NullTime
+		var status string
+		if err := rows.Scan(&r.BuildNumber, &branch, &hash, &r.Suite, &r.Name, &status, &duration, &created, &r.Attempt); err != nil {
+			return nil, err
+		}
+		r.Branch = branch.String
+		r.Hash = hash.String
+		r.Status = sdk.StatusFromString(status)
+		r.Duration = duration.Float64
+		r.Created = created.Time
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+// DeleteTestCaseResults removes test case results of all runs of a pipeline build
+func DeleteTestCaseResults(db database.Executer, pbID int64) error {
+	_, err := db.Exec(`DELETE FROM test_case_result WHERE pipeline_build_id = $1`, pbID)
+	return err
+}
+
+// keepTestCaseResults keeps test case results of the last run of a pipeline build being restarted,
+// as a previous attempt: reruns on the same commit tell flaky tests
+func keepTestCaseResults(db database.Executer, pbID int64) error {
+	_, err := db.Exec(`UPDATE test_case_result SET attempt = attempt + 1 WHERE pipeline_build_id = $1`, pbID)
+	return err
+}
+
+// FlakyTests returns stats of test cases with at least one flip, flakiest first
+func FlakyTests(results []sdk.TestCaseResult) []sdk.TestCaseStats {
+	flaky := []sdk.TestCaseStats{}
+	for _, s := range TestCaseStats(results) {
+		if s.Flips > 0 {
+			flaky = append(flaky, s)
+		}
+	}
+
+	sort.Stable(byFlakiness(flaky))
+	return flaky
+}
+
+// SlowestTests returns stats of the limit test cases with the highest average duration
+func SlowestTests(results []sdk.TestCaseResult, limit int) []sdk.TestCaseStats {
+	stats := TestCaseStats(results)
+	sort.Stable(byAvgDuration(stats))
+	if limit > 0 && len(stats) > limit {
+		stats = stats[:limit]
+	}
+	return stats
+}
+
+// TestCaseStats aggregates results by test case. Results must be ordered by test case then build number.
+func TestCaseStats(results []sdk.TestCaseResult) []sdk.TestCaseStats {
+	stats := []sdk.TestCaseStats{}
+
+	for start := 0; start < len(results); {
+		end := start
+		for end < len(results) && results[end].Suite == results[start].Suite && results[end].Name == results[start].Name {
+			end++
+		}
+		stats = append(stats, testCaseStats(results[start:end]))
+		start = end
+	}
+	return stats
+}
+
+// testCaseStats computes stats of results of a single test case
+func testCaseStats(results []sdk.TestCaseResult) sdk.TestCaseStats {
+	s := sdk.TestCaseStats{
+		Suite:      results[0].Suite,
+		Name:       results[0].Name,
+		LastStatus: results[len(results)-1].Status,
+	}
+
+	var total float64
+	var timed int
+	// Last status of the test case on each commit
+	lastOnCommit := map[string]sdk.Status{}
+	var reruns int
+	for _, r := range results {
+		s.Runs++
+		switch r.Status {
+		case sdk.StatusFail:
+			s.Failures++
+		case sdk.StatusSkipped:
+			s.Skipped++
+			// A skipped run neither passes nor fails
+			continue
+		}
+
+		total += r.Duration
+		timed++
+		if r.Duration > s.MaxDuration {
+			s.MaxDuration = r.Duration
+		}
+
+		if r.Hash == "" {
+			continue
+		}
+		if last, ok := lastOnCommit[r.Hash]; ok {
+			reruns++
+			if last != r.Status {
+				s.Flips++
+			}
+		}
+		lastOnCommit[r.Hash] = r.Status
+	}
+
+	if timed > 0 {
+		s.AvgDuration = total / float64(timed)
+	}
+	if reruns > 0 {
+		s.FlakyScore = float64(s.Flips) / float64(reruns)
+	}
+	return s
+}
+
+type byFlakiness []sdk.TestCaseStats
+
+func (s byFlakiness) Len() int      { return len(s) }
+func (s byFlakiness) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byFlakiness) Less(i, j int) bool {
+	if s[i].FlakyScore != s[j].FlakyScore {
+		return s[i].FlakyScore > s[j].FlakyScore
+	}
+	return s[i].Flips > s[j].Flips
+}
+
+type byAvgDuration []sdk.TestCaseStats
+
+func (s byAvgDuration) Len() int           { return len(s) }
+func (s byAvgDuration) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byAvgDuration) Less(i, j int) bool { return s[i].AvgDuration > s[j].AvgDuration }
+
+func testCaseStatus(t sdk.Test) sdk.Status {
+	switch {
+	case t.Failure != "" || t.Error != "":
+		return sdk.StatusFail
+	case t.Skip != nil:
+		return sdk.StatusSkipped
+	}
+	return sdk.StatusSuccess
+}
+
+// testCaseDuration parses time of a test in seconds, as written by JUnit reports:
+// a comma is a thousands separator along with a decimal point, a decimal comma otherwise
+func testCaseDuration(t sdk.Test) float64 {
+	s := strings.TrimSpace(t.Time)
+	if strings.Contains(s, ".") {
+		s = strings.Replace(s, ",", "", -1)
+	} else {
+		s = strings.Replace(s, ",", ".", -1)
+	}
+	d, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return d
+}
@@ -0,0 +1,56 @@
+package build
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func TestFlakyTests(t *testing.T) {
+	results := []sdk.TestCaseResult{
+		// Fails then passes when rebuilt on the same commit
+		{Suite: "s", Name: "flaky", BuildNumber: 1, Hash: "a", Status: sdk.StatusFail, Duration: 1},
+		{Suite: "s", Name: "flaky", BuildNumber: 2, Hash: "a", Status: sdk.StatusSuccess, Duration: 3},
+		{Suite: "s", Name: "flaky", BuildNumber: 3, Hash: "b", Status: sdk.StatusSuccess, Duration: 2},
+		{Suite: "s", Name: "flaky", BuildNumber: 4, Hash: "b", Status: sdk.StatusSuccess, Duration: 2},
+		// Broken by commit b then fixed by commit c, not flaky
+		{Suite: "s", Name: "fixed", BuildNumber: 1, Hash: "a", Status: sdk.StatusSuccess, Duration: 10},
+		{Suite: "s", Name: "fixed", BuildNumber: 3, Hash: "b", Status: sdk.StatusFail, Duration: 10},
+		{Suite: "s", Name: "fixed", BuildNumber: 5, Hash: "c", Status: sdk.StatusSuccess, Duration: 10},
+		{Suite: "s", Name: "skipped", BuildNumber: 1, Hash: "a", Status: sdk.StatusSkipped},
+		{Suite: "s", Name: "skipped", BuildNumber: 2, Hash: "a", Status: sdk.StatusFail},
+	}
+
+	stats := TestCaseStats(results)
+	assert.Len(t, stats, 3)
+	assert.Equal(t, 4, stats[0].Runs)
+	assert.Equal(t, 1, stats[0].Flips)
+	assert.Equal(t, 0.5, stats[0].FlakyScore)
+	assert.Equal(t, 2.0, stats[0].AvgDuration)
+	assert.Equal(t, 3.0, stats[0].MaxDuration)
+	assert.Equal(t, 0, stats[1].Flips)
+	assert.Equal(t, sdk.StatusSuccess, stats[1].LastStatus)
+	// Skipped runs are not compared
+	assert.Equal(t, 0, stats[2].Flips)
+	assert.Equal(t, 1, stats[2].Skipped)
+
+	flaky := FlakyTests(results)
+	assert.Len(t, flaky, 1)
+	assert.Equal(t, "flaky", flaky[0].Name)
+
+	slowest := SlowestTests(results, 2)
+	assert.Len(t, slowest, 2)
+	assert.Equal(t, "fixed", slowest[0].Name)
+	assert.Equal(t, "flaky", slowest[1].Name)
+}
+
+func TestTestCaseDuration(t *testing.T) {
+	assert.Equal(t, 1234.5, testCaseDuration(sdk.Test{Time: "1,234.5"}))
+	assert.Equal(t, 0.5, testCaseDuration(sdk.Test{Time: "0,5"}))
+	assert.Equal(t, 0.0, testCaseDuration(sdk.Test{}))
+	skip := ""
+	assert.Equal(t, sdk.StatusSkipped, testCaseStatus(sdk.Test{Skip: &skip}))
+	assert.Equal(t, sdk.StatusFail, testCaseStatus(sdk.Test{Error: "panic"}))
+}
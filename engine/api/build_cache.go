@@ -0,0 +1,209 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/ovh/cds/engine/api/buildcache"
+	"github.com/ovh/cds/engine/api/context"
+	"github.com/ovh/cds/engine/api/objectstore"
+	"github.com/ovh/cds/engine/api/project"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+func getCachesHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	vars := mux.Vars(r)
+	key := vars["permProjectKey"]
+
+	p, err := project.LoadProject(db, key, c.User)
+	if err != nil {
+		log.Warning("getCachesHandler> Cannot load project %s: %s\n", key, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	caches, err := buildcache.LoadAll(db, p.Key)
+	if err != nil {
+		log.Warning("getCachesHandler> Cannot load caches of project %s: %s\n", key, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	maxSize, err := buildcache.LoadLimit(db, p.ID)
+	if err != nil {
+		log.Warning("getCachesHandler> Cannot load cache limit of project %s: %s\n", key, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	pc := sdk.ProjectCaches{MaxSize: maxSize, Caches: caches}
+	for _, cache := range caches {
+		pc.Size += cache.Size
+	}
+
+	WriteJSON(w, r, pc, http.StatusOK)
+}
+
+func updateCacheLimitHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	vars := mux.Vars(r)
+	key := vars["permProjectKey"]
+
+	// Cache storage is shared by all projects
+	if !c.User.Admin {
+		WriteError(w, r, sdk.ErrForbidden)
+		return
+	}
+
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		WriteError(w, r, sdk.ErrWrongRequest)
+		return
+	}
+
+	var limit sdk.CacheLimit
+	if err := json.Unmarshal(data, &limit); err != nil {
+		WriteError(w, r, sdk.ErrWrongRequest)
+		return
+	}
+
+	if limit.MaxSize < 0 {
+		WriteError(w, r, sdk.ErrInvalidCacheLimit)
+		return
+	}
+
+	p, err := project.LoadProject(db, key, c.User)
+	if err != nil {
+		log.Warning("updateCacheLimitHandler> Cannot load project %s: %s\n", key, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	if err := buildcache.UpdateLimit(db, p.ID, limit.MaxSize); err != nil {
+		log.Warning("updateCacheLimitHandler> Cannot update cache limit of project %s: %s\n", key, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteJSON(w, r, limit, http.StatusOK)
+}
+
+func getCacheHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	vars := mux.Vars(r)
+	key := vars["permProjectKey"]
+	cacheKey := vars["cacheKey"]
+
+	cache, err := buildcache.Load(db, key, cacheKey)
+	if err != nil {
+		if err != sdk.ErrCacheNotFound {
+			log.Warning("getCacheHandler> Cannot load cache %s of project %s: %s\n", cacheKey, key, err)
+		}
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteJSON(w, r, cache, http.StatusOK)
+}
+
+func downloadCacheHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	vars := mux.Vars(r)
+	key := vars["permProjectKey"]
+	cacheKey := vars["cacheKey"]
+
+	cache, err := buildcache.Load(db, key, cacheKey)
+	if err != nil {
+		if err != sdk.ErrCacheNotFound {
+			log.Warning("downloadCacheHandler> Cannot load cache %s of project %s: %s\n", cacheKey, key, err)
+		}
+		WriteError(w, r, err)
+		return
+	}
+
+	if err := buildcache.Touch(db, cache); err != nil {
+		log.Warning("downloadCacheHandler> Cannot touch cache %s of project %s: %s\n", cacheKey, key, err)
+	}
+
+	f, err := objectstore.FetchCache(*cache)
+	if err != nil {
+		log.Warning("downloadCacheHandler> Cannot fetch cache %s of project %s: %s\n", cacheKey, key, err)
+		WriteError(w, r, err)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Add("Content-Type", "application/octet-stream")
+	w.Header().Add("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", cache.GetName()))
+	if err := objectstore.StreamFile(w, f); err != nil {
+		log.Warning("downloadCacheHandler> Cannot stream cache %s of project %s: %s\n", cacheKey, key, err)
+	}
+}
+
+func uploadCacheHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	vars := mux.Vars(r)
+	key := vars["permProjectKey"]
+	cacheKey := vars["cacheKey"]
+
+	if cacheKey == "" || sdk.CacheKey(cacheKey) != cacheKey {
+		WriteError(w, r, sdk.ErrWrongRequest)
+		return
+	}
+
+	p, err := project.LoadProject(db, key, c.User)
+	if err != nil {
+		log.Warning("uploadCacheHandler> Cannot load project %s: %s\n", key, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	maxSize, err := buildcache.LoadLimit(db, p.ID)
+	if err != nil {
+		log.Warning("uploadCacheHandler> Cannot load cache limit of project %s: %s\n", key, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	// Archive would evict every other cache then itself
+	if r.ContentLength > maxSize {
+		WriteError(w, r, sdk.ErrCacheTooLarge)
+		return
+	}
+
+	cache := sdk.Cache{
+		Project: p.Key,
+		Key:     cacheKey,
+		MD5sum:  r.Header.Get(sdk.CacheMD5Header),
+	}
+
+	if err := buildcache.Save(db, p.ID, cache, r.Body); err != nil {
+		log.Warning("uploadCacheHandler> Cannot save cache %s of project %s: %s\n", cacheKey, key, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func deleteCacheHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	vars := mux.Vars(r)
+	key := vars["permProjectKey"]
+	cacheKey := vars["cacheKey"]
+
+	cache, err := buildcache.Load(db, key, cacheKey)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	if err := buildcache.Delete(db, cache); err != nil {
+		log.Warning("deleteCacheHandler> Cannot delete cache %s of project %s: %s\n", cacheKey, key, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
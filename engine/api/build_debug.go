@@ -0,0 +1,196 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+
+	"github.com/ovh/cds/engine/api/build"
+	"github.com/ovh/cds/engine/api/context"
+	"github.com/ovh/cds/engine/api/environment"
+	"github.com/ovh/cds/engine/api/permission"
+	"github.com/ovh/cds/engine/api/worker"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+var debugUpgrader = websocket.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: 4096,
+	// Clients are authenticated by headers, not by cookies
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// debugRefresh is how often debug sessions and attached users are refreshed in cache
+const debugRefresh = 10 * time.Second
+
+// workerDebugHandler is the websocket of a worker keeping its action build after a failed step:
+// it receives shell output and sends user input until the worker closes it
+func workerDebugHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	if c.Agent != sdk.WorkerAgent || c.Worker.ID == "" {
+		WriteError(w, r, sdk.ErrForbidden)
+		return
+	}
+
+	idS := mux.Vars(r)["id"]
+	id, err := strconv.ParseInt(idS, 10, 64)
+	if err != nil {
+		WriteError(w, r, sdk.ErrInvalidID)
+		return
+	}
+	if workerID, err := worker.FindBuildingWorker(db, idS); err != nil || workerID != c.Worker.ID {
+		log.Warning("workerDebugHandler> worker %s is not building %s: %v\n", c.Worker.ID, idS, err)
+		WriteError(w, r, sdk.ErrForbidden)
+		return
+	}
+
+	conn, err := debugUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Warning("workerDebugHandler> Cannot upgrade connection: %s\n", err)
+		return
+	}
+	defer conn.Close()
+
+	build.OpenDebugSession(id)
+	defer build.CloseDebugSession(id)
+	log.WithFields(c.Fields()).Notice("workerDebugHandler> Debug session opened on action build %d\n", id)
+
+	done := make(chan bool)
+	defer close(done)
+	go func() {
+		refreshed := time.Now()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if time.Since(refreshed) > debugRefresh {
+				build.OpenDebugSession(id)
+				refreshed = time.Now()
+			}
+			data, ok := build.ReadDebugInput(id, time.Second)
+			if !ok {
+				continue
+			}
+			if err := conn.WriteMessage(websocket.BinaryMessage, data); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			log.WithFields(c.Fields()).Notice("workerDebugHandler> Debug session closed on action build %d\n", id)
+			return
+		}
+		build.SendDebugOutput(id, data)
+	}
+}
+
+// debugActionBuildHandler is the websocket of a user debugging an action build:
+// it sends user input to the worker shell and receives its output
+func debugActionBuildHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	vars := mux.Vars(r)
+	ab, err := build.LoadActionBuild(db, vars["actionBuildID"])
+	if err != nil {
+		log.Warning("debugActionBuildHandler> Cannot load action build %s: %s\n", vars["actionBuildID"], err)
+		WriteError(w, r, sdk.ErrNotFound)
+		return
+	}
+
+	envName := r.FormValue("envName")
+	if envName == "" {
+		envName = sdk.DefaultEnv.Name
+	}
+	// Permissions are checked on route, action build must be one of its builds
+	if sdk.ParameterValue(ab.Args, "cds.project") != vars["key"] ||
+		sdk.ParameterValue(ab.Args, "cds.application") != vars["permApplicationName"] ||
+		sdk.ParameterValue(ab.Args, "cds.pipeline") != vars["permPipelineKey"] ||
+		sdk.ParameterValue(ab.Args, "cds.environment") != envName ||
+		sdk.ParameterValue(ab.Args, "cds.buildNumber") != vars["build"] {
+		WriteError(w, r, sdk.ErrNotFound)
+		return
+	}
+
+	// A debug shell gives access to secrets of the build
+	if !canReadSecrets(r, c) {
+		WriteError(w, r, sdk.ErrForbidden)
+		return
+	}
+
+	if envName != sdk.DefaultEnv.Name {
+		env, err := environment.LoadEnvironmentByName(db, vars["key"], envName)
+		if err != nil {
+			log.Warning("debugActionBuildHandler> Cannot load environment %s: %s\n", envName, err)
+			WriteError(w, r, err)
+			return
+		}
+		if !permission.AccessToEnvironment(env.ID, c.User, permission.PermissionReadWriteExecute) {
+			WriteError(w, r, sdk.ErrForbidden)
+			return
+		}
+	}
+
+	if !build.DebugSessionOpened(ab.ID) {
+		WriteError(w, r, sdk.ErrNoDebugSession)
+		return
+	}
+
+	conn, err := debugUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Warning("debugActionBuildHandler> Cannot upgrade connection: %s\n", err)
+		return
+	}
+	defer conn.Close()
+
+	build.AttachDebugSession(ab.ID)
+	log.WithFields(c.Fields()).Notice("debugActionBuildHandler> %s attached to debug session of action build %d\n", c.User.Username, ab.ID)
+
+	done := make(chan bool)
+	go func() {
+		defer close(done)
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			build.SendDebugInput(ab.ID, data)
+		}
+	}()
+
+	refreshed := time.Now()
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+
+		if time.Since(refreshed) > debugRefresh {
+			if !build.DebugSessionOpened(ab.ID) {
+				break
+			}
+			build.AttachDebugSession(ab.ID)
+			refreshed = time.Now()
+		}
+
+		m, ok := build.ReadDebugOutput(ab.ID, time.Second)
+		if !ok {
+			continue
+		}
+		if m.End {
+			break
+		}
+		if err := conn.WriteMessage(websocket.BinaryMessage, m.Data); err != nil {
+			return
+		}
+	}
+
+	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "debug session ended"))
+}
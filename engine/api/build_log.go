@@ -304,33 +304,45 @@ func addBuildLogHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *c
 	}
 }
 
+// engineLogLevels is the log configuration of the API returned by /mon/log
+type engineLogLevels struct {
+	Level    string            `json:"level"`
+	Format   string            `json:"format"`
+	Packages map[string]string `json:"packages"`
+}
+
+func getEngineLogLevels(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	levels := engineLogLevels{
+		Level:    log.GetLevel().String(),
+		Format:   string(log.GetFormat()),
+		Packages: map[string]string{},
+	}
+	for pkg, l := range log.PackageLevels() {
+		levels.Packages[pkg] = l.String()
+	}
+	WriteJSON(w, r, levels, http.StatusOK)
+}
+
+// setEngineLogLevel sets log level of the API, or of a package of the API with ?package=engine/api/scheduler
 func setEngineLogLevel(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
 
 	// Get log level in URL
 	vars := mux.Vars(r)
-	lvl := vars["level"]
-
-	switch lvl {
-	case "debug":
-		log.SetLevel(log.DebugLevel)
-		break
-	case "info":
-		log.SetLevel(log.InfoLevel)
-		break
-	case "notice":
-		log.SetLevel(log.NoticeLevel)
-		break
-	case "warning":
-		log.SetLevel(log.WarningLevel)
-		break
-	case "critical":
-		log.SetLevel(log.CriticalLevel)
-		break
-
-	default:
-		log.Warning("setEngineLogLevel> Unknown log level %s\n", lvl)
+	lvl, err := log.ParseLevel(vars["level"])
+	if err != nil {
+		log.Warning("setEngineLogLevel> Unknown log level %s\n", vars["level"])
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
+	if pkg := r.FormValue("package"); pkg != "" {
+		log.SetPackageLevel(pkg, lvl)
+		return
+	}
+	log.SetLevel(lvl)
+}
+
+// deleteEnginePackageLogLevel removes log level of a package given with ?package=, of all packages otherwise
+func deleteEnginePackageLogLevel(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	log.DeletePackageLevel(r.FormValue("package"))
 }
@@ -0,0 +1,262 @@
+package buildcache
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/api/objectstore"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+// DefaultMaxSize is the size limit of caches of projects without one, in bytes
+var DefaultMaxSize int64 = 2 << 30
+
+// lockNamespace is the first key of advisory locks taken while evicting caches of a project
+const lockNamespace = 1665
+
+// LoadLimit returns the size limit of caches of a project
+func LoadLimit(db database.Querier, projectID int64) (int64, error) {
+	var maxSize int64
+	query := `SELECT max_size FROM build_cache_limit WHERE project_id = $1`
+	if err := db.QueryRow(query, projectID).Scan(&maxSize); err != nil {
+		if err == sql.ErrNoRows {
+			return DefaultMaxSize, nil
+		}
+		return 0, err
+	}
+	return maxSize, nil
+}
+
+// UpdateLimit sets the size limit of caches of a project, 0 resets it to DefaultMaxSize
+func UpdateLimit(db database.Executer, projectID, maxSize int64) error {
+	if _, err := db.Exec(`DELETE FROM build_cache_limit WHERE project_id = $1`, projectID); err != nil {
+		return err
+	}
+	if maxSize == 0 {
+		return nil
+	}
+
+	_, err := db.Exec(`INSERT INTO build_cache_limit (project_id, max_size) VALUES ($1, $2)`, projectID, maxSize)
+	return err
+}
+
+// LoadAll returns caches of a project, most recently used first
+func LoadAll(db database.Querier, projectKey string) ([]sdk.Cache, error) {
+	query := `SELECT build_cache.id, build_cache.cache_key, build_cache.size, build_cache.md5sum, build_cache.object_path, build_cache.created, build_cache.last_used
+		FROM build_cache
+		JOIN project ON project.id = build_cache.project_id
+		WHERE project.projectkey = $1
+		ORDER BY build_cache.last_used DESC, build_cache.id DESC`
+
+	rows, err := db.Query(query, projectKey)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	caches := []sdk.Cache{}
+	for rows.Next() {
+		c := sdk.Cache{Project: projectKey}
+		var md5sum, objectPath sql.NullString
+		if err := rows.Scan(&c.ID, &c.Key, &c.Size, &md5sum, &objectPath, &c.Created, &c.LastUsed); err != nil {
+			return nil, err
+		}
+		c.MD5sum = md5sum.String
+		c.ObjectPath = objectPath.String
+		caches = append(caches, c)
+	}
+	return caches, nil
+}
+
+// Load returns cache of a project stored with given key
+func Load(db database.Querier, projectKey, key string) (*sdk.Cache, error) {
+	query := `SELECT build_cache.id, build_cache.size, build_cache.md5sum, build_cache.object_path, build_cache.created, build_cache.last_used
+		FROM build_cache
+		JOIN project ON project.id = build_cache.project_id
+		WHERE project.projectkey = $1 AND build_cache.cache_key = $2`
+
+	c := &sdk.Cache{Project: projectKey, Key: key}
+	var md5sum, objectPath sql.NullString
+	if err := db.QueryRow(query, projectKey, key).Scan(&c.ID, &c.Size, &md5sum, &objectPath, &c.Created, &c.LastUsed); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, sdk.ErrCacheNotFound
+		}
+		return nil, err
+	}
+	c.MD5sum = md5sum.String
+	c.ObjectPath = objectPath.String
+	return c, nil
+}
+
+// Touch marks a cache as used, so it is evicted last
+func Touch(db database.Executer, c *sdk.Cache) error {
+	_, err := db.Exec(`UPDATE build_cache SET last_used = $2 WHERE id = $1`, c.ID, time.Now())
+	return err
+}
+
+// Save stores a cache archive of a project, then evicts least recently used
+// caches of the project until their total size fits in its limit.
+// Each upload is stored under a new name, the previous archive of the key is deleted once replaced.
+func Save(db *sql.DB, projectID int64, c sdk.Cache, content io.ReadCloser) error {
+	c.Upload = fmt.Sprintf("%d", time.Now().UnixNano())
+	c.ObjectPath = ""
+	counter := &countingReader{ReadCloser: content}
+	objectPath, err := objectstore.StoreCache(c, counter)
+	if err != nil {
+		deleteObject(c)
+		return err
+	}
+	c.ObjectPath = objectPath
+	c.Size = counter.n
+
+	replaced, evicted, err := save(db, projectID, c)
+	if err != nil {
+		deleteObject(c)
+		return err
+	}
+
+	if replaced != nil {
+		deleteObject(*replaced)
+	}
+	for i := range evicted {
+		log.Info("buildcache.Save> Evicting cache %s of project %s (%d bytes)\n", evicted[i].Key, c.Project, evicted[i].Size)
+		deleteObject(evicted[i])
+	}
+	return nil
+}
+
+// save replaces the row of the cache key by the uploaded cache, and returns the replaced and evicted caches
+// whose archives must be deleted once committed
+func save(db *sql.DB, projectID int64, c sdk.Cache) (*sdk.Cache, []sdk.Cache, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1, $2)`, lockNamespace, projectID); err != nil {
+		return nil, nil, err
+	}
+
+	maxSize, err := LoadLimit(tx, projectID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if c.Size > maxSize {
+		return nil, nil, sdk.ErrCacheTooLarge
+	}
+
+	replaced, err := Load(tx, c.Project, c.Key)
+	if err != nil && err != sdk.ErrCacheNotFound {
+		return nil, nil, err
+	}
+	if replaced != nil {
+		if _, err := tx.Exec(`DELETE FROM build_cache WHERE id = $1`, replaced.ID); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	now := time.Now()
+	query := `INSERT INTO build_cache (project_id, cache_key, size, md5sum, object_path, created, last_used)
+		VALUES ($1, $2, $3, $4, $5, $6, $6)`
+	if _, err := tx.Exec(query, projectID, c.Key, c.Size, c.MD5sum, c.ObjectPath, now); err != nil {
+		return nil, nil, err
+	}
+
+	caches, err := LoadAll(tx, c.Project)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	evicted := evict(caches, maxSize, c.Key)
+	for _, e := range evicted {
+		if _, err := tx.Exec(`DELETE FROM build_cache WHERE id = $1`, e.ID); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	return replaced, evicted, nil
+}
+
+// Delete removes cache of a project stored with given key
+func Delete(db database.Executer, c *sdk.Cache) error {
+	if _, err := db.Exec(`DELETE FROM build_cache WHERE id = $1`, c.ID); err != nil {
+		return err
+	}
+	deleteObject(*c)
+	return nil
+}
+
+// DeleteAll removes all caches of a project and its size limit
+func DeleteAll(db database.QueryExecuter, projectKey string, projectID int64) error {
+	caches, err := LoadAll(db, projectKey)
+	if err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(`DELETE FROM build_cache WHERE project_id = $1`, projectID); err != nil {
+		return err
+	}
+	if _, err := db.Exec(`DELETE FROM build_cache_limit WHERE project_id = $1`, projectID); err != nil {
+		return err
+	}
+
+	for i := range caches {
+		deleteObject(caches[i])
+	}
+	return nil
+}
+
+// evict returns caches to remove so the most recently used ones fit in maxSize.
+// Caches must be sorted by last use, most recent first. Cache with key keep is never evicted.
+func evict(caches []sdk.Cache, maxSize int64, keep string) []sdk.Cache {
+	var evicted []sdk.Cache
+	var total int64
+	for _, c := range caches {
+		if c.Key == keep {
+			total += c.Size
+		}
+	}
+
+	full := false
+	for _, c := range caches {
+		if c.Key == keep {
+			continue
+		}
+		if full || total+c.Size > maxSize {
+			// Older caches are evicted as well, even if they would fit
+			full = true
+			evicted = append(evicted, c)
+			continue
+		}
+		total += c.Size
+	}
+	return evicted
+}
+
+func deleteObject(c sdk.Cache) {
+	// If it's 404, it's lost anyway...
+	if err := objectstore.DeleteCache(c); err != nil && !strings.Contains(err.Error(), "404") {
+		log.Warning("buildcache> Cannot delete cache %s of project %s: %s\n", c.Key, c.Project, err)
+	}
+}
+
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
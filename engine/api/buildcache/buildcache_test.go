@@ -0,0 +1,42 @@
+package buildcache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func TestEvict(t *testing.T) {
+	caches := []sdk.Cache{
+		{Key: "new", Size: 40},
+		{Key: "recent", Size: 30},
+		{Key: "old", Size: 20},
+		{Key: "oldest", Size: 5},
+	}
+
+	assert.Empty(t, evict(caches, 100, "new"))
+
+	evicted := evict(caches, 80, "new")
+	assert.Equal(t, 2, len(evicted))
+	assert.Equal(t, "old", evicted[0].Key)
+	assert.Equal(t, "oldest", evicted[1].Key)
+
+	// Kept cache fits first, whatever its last use
+	evicted = evict(caches, 60, "old")
+	assert.Equal(t, 2, len(evicted))
+	assert.Equal(t, "recent", evicted[0].Key)
+	assert.Equal(t, "oldest", evicted[1].Key)
+}
+
+func TestCacheName(t *testing.T) {
+	c := sdk.Cache{Project: "FOO", Key: "deps", Upload: "42"}
+	assert.Equal(t, "deps.42.tar.gz", c.GetName())
+
+	// Stored caches keep the name of their archive, even after a later upload
+	c = sdk.Cache{Project: "FOO", Key: "deps", ObjectPath: "/var/lib/cds/cache/FOO/deps.41.tar.gz"}
+	assert.Equal(t, "deps.41.tar.gz", c.GetName())
+	c = sdk.Cache{Project: "FOO", Key: "deps", ObjectPath: "cache/FOO/deps.tar.gz"}
+	assert.Equal(t, "deps.tar.gz", c.GetName())
+}
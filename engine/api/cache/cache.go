@@ -4,13 +4,29 @@ import (
 	"container/list"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/engine/metrics"
 )
 
 //Status : local ok redis
 var Status string
 
+// shared is set when instances share the cache
+var shared bool
+
+//LocalLevelSize is the maximum number of values kept in memory by the twolevel cache
+var LocalLevelSize = 10000
+
+//LocalLevelTTL is how long, in seconds, values are kept in memory by the twolevel cache
+var LocalLevelTTL = 60
+
+// defaultTTL is the TTL of the cache, in seconds
+var defaultTTL int
+
+var requests = metrics.NewCounter("cds_cache_requests_total", "Cache lookups by kind of key and result (hit or miss)", "kind", "result")
+
 //Key make a key as expected
 func Key(args ...string) string {
 	return strings.Join(args, ":")
@@ -21,15 +37,19 @@ type Store interface {
 	Get(key string, value interface{}) bool
 	Set(key string, value interface{})
 	SetWithTTL(key string, value interface{}, ttl int)
+	Expire(key string, ttl int)
 	Delete(key string)
 	DeleteAll(key string)
 	Enqueue(queueName string, value interface{})
 	Dequeue(queueName string, value interface{})
+	DequeueWithTimeout(queueName string, value interface{}, timeout time.Duration) bool
 }
 
-//Initialize the global cache in memory, or redis
+//Initialize the global cache in memory, in redis, or in both with twolevel
 func Initialize(mode, redisHost, redisPassword string, TTL int) {
 	Status = mode
+	shared = mode != "local"
+	defaultTTL = TTL
 	switch mode {
 	case "local":
 		log.Notice("Cache> Initialize local cache (TTL=%d seconds)", TTL)
@@ -41,12 +61,23 @@ func Initialize(mode, redisHost, redisPassword string, TTL int) {
 		}
 	case "redis":
 		log.Notice("Cache> Initialize redis cache (Host=%s, TTL=%d seconds)", redisHost, TTL)
-		var err error
-		s, err = NewRedisStore(redisHost, redisPassword, TTL)
+		rs, err := NewRedisStore(redisHost, redisPassword, TTL)
 		if err != nil {
 			Status += " KO"
 			log.Critical("cache> Cannot init redis cache (Host=%s, TTL=%d seconds): %s", redisHost, TTL, err)
+			return
+		}
+		s = rs
+		Status += " OK"
+	case "twolevel":
+		log.Notice("Cache> Initialize twolevel cache (Host=%s, TTL=%d seconds, local size=%d, local TTL=%d seconds)", redisHost, TTL, LocalLevelSize, LocalLevelTTL)
+		ts, err := NewTwoLevelStore(redisHost, redisPassword, TTL, LocalLevelSize, LocalLevelTTL)
+		if err != nil {
+			Status += " KO"
+			log.Critical("cache> Cannot init twolevel cache (Host=%s, TTL=%d seconds): %s", redisHost, TTL, err)
+			return
 		}
+		s = ts
 		Status += " OK"
 	default:
 		log.Critical("Cache> Unsupported cache mode : %s", mode)
@@ -59,7 +90,23 @@ func Get(key string, value interface{}) bool {
 	if s == nil {
 		return false
 	}
-	return s.Get(key, value)
+	found := s.Get(key, value)
+	if found {
+		requests.Inc(kindOf(key), "hit")
+	} else {
+		requests.Inc(kindOf(key), "miss")
+	}
+	return found
+}
+
+// kindOf returns the kind of key labelling metrics, the first part of the key, see Key
+func kindOf(key string) string {
+	return strings.SplitN(key, ":", 2)[0]
+}
+
+//Shared returns true if API instances share the cache, false if each instance has its own
+func Shared() bool {
+	return shared
 }
 
 //Set something from the cache.
@@ -78,6 +125,14 @@ func SetWithTTL(key string, value interface{}, ttl int) {
 	s.SetWithTTL(key, value, ttl)
 }
 
+//Expire sets the ttl (in seconds) of something in the cache: (0 for eternity)
+func Expire(key string, ttl int) {
+	if s == nil {
+		return
+	}
+	s.Expire(key, ttl)
+}
+
 //Delete something from the cache.
 func Delete(key string) {
 	if s == nil {
@@ -109,3 +164,13 @@ func Dequeue(queueName string, value interface{}) {
 	}
 	s.Dequeue(queueName, value)
 }
+
+//DequeueWithTimeout gets from queue, blocking until timeout while there is nothing in the queue.
+//It returns false on timeout, or after timeout if there is no cache.
+func DequeueWithTimeout(queueName string, value interface{}, timeout time.Duration) bool {
+	if s == nil {
+		time.Sleep(timeout)
+		return false
+	}
+	return s.DequeueWithTimeout(queueName, value, timeout)
+}
@@ -94,6 +94,7 @@ type redisClient interface {
 	SetXX(key string, value interface{}, expiration time.Duration) *redis.BoolCmd
 	Sort(key string, sort redis.Sort) *redis.StringSliceCmd
 	StrLen(key string) *redis.IntCmd
+	Subscribe(channels ...string) (*redis.PubSub, error)
 	TTL(key string) *redis.DurationCmd
 	Type(key string) *redis.StatusCmd
 	ZAdd(key string, members ...redis.Z) *redis.IntCmd
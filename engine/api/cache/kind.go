@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/engine/metrics"
+)
+
+var loads = metrics.NewCounter("cds_cache_loads_total", "Values loaded on cache miss by kind and result (ok or error)", "kind", "result")
+
+//Forever is the ttl of kinds whose values never expire
+const Forever = -1
+
+//Kind is a kind of cached values, like the requirements of actions, identified by an ID.
+//Its name is the prefix of its keys and labels its metrics.
+type Kind struct {
+	name string
+	ttl  int
+}
+
+//NewKind returns the kind of values whose keys start with prefix, cached for ttl seconds, cache TTL if 0, or Forever
+func NewKind(ttl int, prefix ...string) *Kind {
+	return &Kind{name: Key(prefix...), ttl: ttl}
+}
+
+//Name returns the name of the kind
+func (k *Kind) Name() string {
+	return k.name
+}
+
+//Key returns the key of value identified by id
+func (k *Kind) Key(id string) string {
+	return Key(k.name, id)
+}
+
+//Get value identified by id from the cache
+func (k *Kind) Get(id string, value interface{}) bool {
+	if s == nil {
+		return false
+	}
+	found := s.Get(k.Key(id), value)
+	if found {
+		requests.Inc(k.name, "hit")
+	} else {
+		requests.Inc(k.name, "miss")
+	}
+	return found
+}
+
+//Load gets value identified by id from the cache, or loads it with load on cache miss and caches it
+func (k *Kind) Load(id string, value interface{}, load func() error) error {
+	if k.Get(id, value) {
+		return nil
+	}
+	if err := load(); err != nil {
+		loads.Inc(k.name, "error")
+		return err
+	}
+	loads.Inc(k.name, "ok")
+	k.Set(id, value)
+	return nil
+}
+
+//Set value identified by id in the cache
+func (k *Kind) Set(id string, value interface{}) {
+	SetWithTTL(k.Key(id), value, k.expiration())
+}
+
+// expiration returns the ttl of values in seconds, 0 for eternity
+func (k *Kind) expiration() int {
+	switch {
+	case k.ttl == Forever:
+		return 0
+	case k.ttl > 0:
+		return k.ttl
+	}
+	return defaultTTL
+}
+
+//Refresh sets value identified by id in the cache if it changed, and returns true if so.
+//Unlike Set, it does not invalidate unchanged values cached by other instances, it only renews their ttl.
+func (k *Kind) Refresh(id string, value interface{}) bool {
+	b, err := json.Marshal(value)
+	if err != nil {
+		log.Warning("Cache> Cannot marshal %s: %s", k.Key(id), err)
+		return false
+	}
+	var cached json.RawMessage
+	if s != nil && s.Get(k.Key(id), &cached) && bytes.Equal(cached, b) {
+		Expire(k.Key(id), k.expiration())
+		return false
+	}
+	k.Set(id, value)
+	return true
+}
+
+//Delete value identified by id from the cache, on all instances
+func (k *Kind) Delete(id string) {
+	Delete(k.Key(id))
+}
@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKind(t *testing.T) {
+	Initialize("local", "", "", 60)
+	k := NewKind(0, "test", "kind")
+	assert.Equal(t, "test:kind:1", k.Key("1"))
+
+	var loaded int
+	load := func(v *[]string) func() error {
+		return func() error {
+			loaded++
+			*v = []string{"a"}
+			return nil
+		}
+	}
+
+	var v []string
+	assert.NoError(t, k.Load("1", &v, load(&v)))
+	assert.Equal(t, []string{"a"}, v)
+	var cached []string
+	assert.NoError(t, k.Load("1", &cached, load(&cached)))
+	assert.Equal(t, []string{"a"}, cached)
+	assert.Equal(t, 1, loaded)
+
+	assert.False(t, k.Refresh("1", []string{"a"}))
+	assert.True(t, k.Refresh("1", []string{"b"}))
+	assert.True(t, k.Get("1", &cached))
+	assert.Equal(t, []string{"b"}, cached)
+
+	k.Delete("1")
+	assert.False(t, k.Get("1", &cached))
+	assert.Error(t, k.Load("1", &cached, func() error { return errors.New("down") }))
+	assert.False(t, k.Get("1", &cached))
+}
+
+func TestKindExpiration(t *testing.T) {
+	Initialize("local", "", "", 60)
+	assert.Equal(t, 60, NewKind(0, "test").expiration())
+	assert.Equal(t, 10, NewKind(10, "test").expiration())
+	assert.Equal(t, 0, NewKind(Forever, "test").expiration())
+}
@@ -17,6 +17,9 @@ type LocalStore struct {
 	Data   map[string][]byte
 	Queues map[string]*list.List
 	TTL    int
+
+	// expires is when keys with a ttl expire
+	expires map[string]time.Time
 }
 
 //Get a key from local store
@@ -42,17 +45,42 @@ func (s *LocalStore) SetWithTTL(key string, value interface{}, ttl int) {
 	}
 	s.Mutex.Lock()
 	s.Data[key] = b
+	s.expire(key, ttl)
 	s.Mutex.Unlock()
+}
 
-	if ttl > 0 {
-		go func(key string) {
-			time.Sleep(time.Duration(ttl) * time.Second)
-			log.Debug("Cache> Delete %s from cache after %d seconds", key, s.TTL)
-			s.Mutex.Lock()
-			delete(s.Data, key)
-			s.Mutex.Unlock()
-		}(key)
+//Expire sets the ttl (in seconds) of a key in local store: (0 for eternity)
+func (s *LocalStore) Expire(key string, ttl int) {
+	s.Mutex.Lock()
+	if _, ok := s.Data[key]; ok {
+		s.expire(key, ttl)
+	}
+	s.Mutex.Unlock()
+}
+
+// expire deletes key after ttl seconds unless its ttl is set again meanwhile, Mutex must be locked
+func (s *LocalStore) expire(key string, ttl int) {
+	if s.expires == nil {
+		s.expires = map[string]time.Time{}
+	}
+	if ttl <= 0 {
+		delete(s.expires, key)
+		return
 	}
+	expires := time.Now().Add(time.Duration(ttl) * time.Second)
+	s.expires[key] = expires
+
+	go func() {
+		time.Sleep(time.Duration(ttl) * time.Second)
+		s.Mutex.Lock()
+		defer s.Mutex.Unlock()
+		if s.expires[key] != expires {
+			return
+		}
+		log.Debug("Cache> Delete %s from cache after %d seconds", key, ttl)
+		delete(s.Data, key)
+		delete(s.expires, key)
+	}()
 }
 
 //Set a value in local store
@@ -62,53 +90,69 @@ func (s *LocalStore) Set(key string, value interface{}) {
 
 //Delete a key from local store
 func (s *LocalStore) Delete(key string) {
+	s.Mutex.Lock()
 	delete(s.Data, key)
+	s.Mutex.Unlock()
 }
 
 //DeleteAll on locastore delete all the things
 func (s *LocalStore) DeleteAll(key string) {
+	s.Mutex.Lock()
 	s.Data = map[string][]byte{}
+	s.Mutex.Unlock()
 }
 
 //Enqueue pushes to queue
 func (s *LocalStore) Enqueue(queueName string, value interface{}) {
-	l := s.Queues[queueName]
-	if l == nil {
-		s.Queues[queueName] = &list.List{}
-		l = s.Queues[queueName]
-	}
 	b, err := json.Marshal(value)
 	if err != nil {
 		return
 	}
-	l.PushFront(b)
+	s.Mutex.Lock()
+	s.queue(queueName).PushFront(b)
+	s.Mutex.Unlock()
 }
 
 //Dequeue gets from queue This is blocking while there is nothing in the queue
 func (s *LocalStore) Dequeue(queueName string, value interface{}) {
-	l := s.Queues[queueName]
-	if l == nil {
-		s.Queues[queueName] = &list.List{}
-		l = s.Queues[queueName]
+	for !s.DequeueWithTimeout(queueName, value, time.Minute) {
 	}
+}
 
-	elemChan := make(chan *list.Element)
-	go func() {
-		for {
-			time.Sleep(500 * time.Millisecond)
-			e := l.Back()
-			if e != nil {
-				elemChan <- e
-				return
+//DequeueWithTimeout gets from queue, blocking until timeout while there is nothing in the queue.
+//It returns false on timeout.
+func (s *LocalStore) DequeueWithTimeout(queueName string, value interface{}, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		s.Mutex.Lock()
+		l := s.queue(queueName)
+		e := l.Back()
+		if e != nil {
+			l.Remove(e)
+		}
+		s.Mutex.Unlock()
+
+		if e != nil {
+			b, ok := e.Value.([]byte)
+			if !ok {
+				return false
 			}
+			json.Unmarshal(b, value)
+			return true
 		}
-	}()
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
 
-	e := <-elemChan
-	b, ok := e.Value.([]byte)
-	if !ok {
-		return
+// queue returns the queue named queueName, Mutex must be locked
+func (s *LocalStore) queue(queueName string) *list.List {
+	l := s.Queues[queueName]
+	if l == nil {
+		l = &list.List{}
+		s.Queues[queueName] = l
 	}
-	json.Unmarshal(b, value)
-	return
+	return l
 }
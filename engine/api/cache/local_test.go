@@ -0,0 +1,22 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStoreQueue(t *testing.T) {
+	Initialize("local", "", "", 60)
+
+	Enqueue("test-queue", 1)
+	Enqueue("test-queue", 2)
+
+	var i int
+	assert.True(t, DequeueWithTimeout("test-queue", &i, time.Second))
+	assert.Equal(t, 1, i)
+	assert.True(t, DequeueWithTimeout("test-queue", &i, time.Second))
+	assert.Equal(t, 2, i)
+	assert.False(t, DequeueWithTimeout("test-queue", &i, 200*time.Millisecond))
+}
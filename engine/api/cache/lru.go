@@ -0,0 +1,151 @@
+package cache
+
+import (
+	"container/list"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// lruStore keeps marshalled values in memory, up to size entries, for ttl seconds at most.
+// The least recently used entry is evicted when it is full.
+type lruStore struct {
+	mutex   sync.Mutex
+	size    int
+	ttl     int
+	list    *list.List
+	entries map[string]*list.Element
+
+	// loads are the generations of keys being loaded, see loading
+	gen   uint64
+	loads map[string]uint64
+}
+
+type lruEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+func newLRUStore(size, ttl int) *lruStore {
+	return &lruStore{
+		size:    size,
+		ttl:     ttl,
+		list:    list.New(),
+		entries: map[string]*list.Element{},
+		loads:   map[string]uint64{},
+	}
+}
+
+// get returns the marshalled value of key, if it has not expired
+func (s *lruStore) get(key string) ([]byte, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	e, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := e.Value.(*lruEntry)
+	if time.Now().After(entry.expires) {
+		s.remove(e)
+		return nil, false
+	}
+	s.list.MoveToFront(e)
+	return entry.value, true
+}
+
+// set stores the marshalled value of key for ttl seconds, or for the ttl of the store if lower or 0
+func (s *lruStore) set(key string, value []byte, ttl int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.put(key, value, ttl)
+}
+
+// loading records that key is being loaded and returns the generation of the load, to be passed to loaded
+func (s *lruStore) loading(key string) uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.gen++
+	s.loads[key] = s.gen
+	return s.gen
+}
+
+// loaded ends the load of key of generation gen, and stores its value if any, unless key was invalidated
+// or loaded again since loading returned gen
+func (s *lruStore) loaded(key string, gen uint64, value []byte, ttl int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.loads[key] != gen {
+		return
+	}
+	delete(s.loads, key)
+	if value != nil {
+		s.put(key, value, ttl)
+	}
+}
+
+// put stores value of key for ttl seconds, or for the ttl of the store if lower or 0, mutex must be locked
+func (s *lruStore) put(key string, value []byte, ttl int) {
+	if ttl <= 0 || ttl > s.ttl {
+		ttl = s.ttl
+	}
+	expires := time.Now().Add(time.Duration(ttl) * time.Second)
+	if e, ok := s.entries[key]; ok {
+		entry := e.Value.(*lruEntry)
+		entry.value = value
+		entry.expires = expires
+		s.list.MoveToFront(e)
+		return
+	}
+	s.entries[key] = s.list.PushFront(&lruEntry{key: key, value: value, expires: expires})
+	for s.list.Len() > s.size {
+		s.remove(s.list.Back())
+	}
+}
+
+// delete removes key
+func (s *lruStore) delete(key string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.loads, key)
+	if e, ok := s.entries[key]; ok {
+		s.remove(e)
+	}
+}
+
+// deleteAll removes keys matching pattern, with the wildcards of redis KEYS: * and ?
+func (s *lruStore) deleteAll(pattern string) {
+	r := regexp.QuoteMeta(pattern)
+	r = strings.Replace(r, `\*`, ".*", -1)
+	r = strings.Replace(r, `\?`, ".", -1)
+	re := regexp.MustCompile("^" + r + "$")
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for key, e := range s.entries {
+		if re.MatchString(key) {
+			s.remove(e)
+		}
+	}
+	for key := range s.loads {
+		if re.MatchString(key) {
+			delete(s.loads, key)
+		}
+	}
+}
+
+// purge removes all keys
+func (s *lruStore) purge() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.list.Init()
+	s.entries = map[string]*list.Element{}
+	s.loads = map[string]uint64{}
+}
+
+// remove removes an element, mutex must be locked
+func (s *lruStore) remove(e *list.Element) {
+	s.list.Remove(e)
+	delete(s.entries, e.Value.(*lruEntry).key)
+}
@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUStoreEviction(t *testing.T) {
+	s := newLRUStore(2, 60)
+	s.set("a", []byte("1"), 0)
+	s.set("b", []byte("2"), 0)
+
+	_, ok := s.get("a")
+	assert.True(t, ok)
+
+	// b is the least recently used
+	s.set("c", []byte("3"), 0)
+	_, ok = s.get("b")
+	assert.False(t, ok)
+	b, ok := s.get("a")
+	assert.True(t, ok)
+	assert.Equal(t, "1", string(b))
+	_, ok = s.get("c")
+	assert.True(t, ok)
+}
+
+func TestLRUStoreDeleteAll(t *testing.T) {
+	s := newLRUStore(10, 60)
+	s.set("application:FOO:app1:pipelines", []byte("1"), 0)
+	s.set("application:FOO:app2:pipelines", []byte("2"), 0)
+	s.set("application:BAR:app1:pipelines", []byte("3"), 0)
+	s.set("pipeline:FOO:build", []byte("4"), 0)
+
+	s.deleteAll(Key("application", "FOO", "*app1*"))
+	_, ok := s.get("application:FOO:app1:pipelines")
+	assert.False(t, ok)
+	_, ok = s.get("application:FOO:app2:pipelines")
+	assert.True(t, ok)
+
+	s.deleteAll(Key("application", "*"))
+	_, ok = s.get("application:BAR:app1:pipelines")
+	assert.False(t, ok)
+	_, ok = s.get("pipeline:FOO:build")
+	assert.True(t, ok)
+}
+
+func TestLRUStoreLoadInvalidated(t *testing.T) {
+	s := newLRUStore(10, 60)
+	gen := s.loading("a")
+	s.loaded("a", gen, []byte("1"), 0)
+	_, ok := s.get("a")
+	assert.True(t, ok)
+
+	// Invalidated while loading
+	gen = s.loading("b")
+	s.delete("b")
+	s.loaded("b", gen, []byte("stale"), 0)
+	_, ok = s.get("b")
+	assert.False(t, ok)
+
+	gen = s.loading("application:FOO:app1")
+	s.deleteAll("application:*")
+	s.loaded("application:FOO:app1", gen, []byte("stale"), 0)
+	_, ok = s.get("application:FOO:app1")
+	assert.False(t, ok)
+	assert.Empty(t, s.loads)
+}
@@ -54,23 +54,32 @@ func NewRedisStore(host, password string, ttl int) (*RedisStore, error) {
 
 //Get a key from redis
 func (s *RedisStore) Get(key string, value interface{}) bool {
+	b, ok := s.get(key)
+	if !ok {
+		return false
+	}
+	if err := json.Unmarshal(b, value); err != nil {
+		log.Warning("redis> Cannot unmarshal %s :%s", key, err)
+		return false
+	}
+	return true
+}
+
+// get returns the marshalled value of key
+func (s *RedisStore) get(key string) ([]byte, bool) {
 	if s.Client == nil {
 		log.Critical("redis> cannot get redis client")
-		return false
+		return nil, false
 	}
 	val, err := s.Client.Get(key).Result()
 	if err != nil && err != redis.Nil {
 		log.Warning("redis> Get error %s : %s", key, err)
-		return false
+		return nil, false
 	}
-	if val != "" && err != redis.Nil {
-		if err := json.Unmarshal([]byte(val), value); err != nil {
-			log.Warning("redis> Cannot unmarshal %s :%s", key, err)
-			return false
-		}
-		return true
+	if val == "" || err == redis.Nil {
+		return nil, false
 	}
-	return false
+	return []byte(val), true
 }
 
 //SetWithTTL a value in local store (0 for eternity)
@@ -93,6 +102,23 @@ func (s *RedisStore) Set(key string, value interface{}) {
 	s.SetWithTTL(key, value, s.ttl)
 }
 
+//Expire sets the ttl (in seconds) of a key in redis: (0 for eternity)
+func (s *RedisStore) Expire(key string, ttl int) {
+	if s.Client == nil {
+		log.Critical("redis> cannot get redis client")
+		return
+	}
+	var err error
+	if ttl > 0 {
+		err = s.Client.Expire(key, time.Duration(ttl)*time.Second).Err()
+	} else {
+		err = s.Client.Persist(key).Err()
+	}
+	if err != nil {
+		log.Warning("redis> Error setting ttl of %s : %s", key, err)
+	}
+}
+
 //Delete a key in redis
 func (s *RedisStore) Delete(key string) {
 	if s.Client == nil {
@@ -147,3 +173,27 @@ func (s *RedisStore) Dequeue(queueName string, value interface{}) {
 		log.Warning("redis> Cannot unmarshal %s :%s", queueName, err)
 	}
 }
+
+//DequeueWithTimeout gets from queue, blocking until timeout while there is nothing in the queue.
+//It returns false on timeout. Timeout is rounded to the second, at least one.
+func (s *RedisStore) DequeueWithTimeout(queueName string, value interface{}, timeout time.Duration) bool {
+	if timeout < time.Second {
+		timeout = time.Second
+	}
+	res, err := s.Client.BRPop(timeout, queueName).Result()
+	if err == redis.Nil {
+		return false
+	}
+	if err != nil {
+		log.Warning("redis> Error dequeueing %s:%s", queueName, err)
+		return false
+	}
+	if len(res) != 2 {
+		return false
+	}
+	if err := json.Unmarshal([]byte(res[1]), value); err != nil {
+		log.Warning("redis> Cannot unmarshal %s :%s", queueName, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,164 @@
+package cache
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/engine/metrics"
+)
+
+// invalidationChannel is the redis channel where instances publish the keys they change
+const invalidationChannel = "cds:cache:invalidation"
+
+var (
+	localRequests = metrics.NewCounter("cds_cache_local_requests_total", "Lookups in the local level of the twolevel cache by kind of key and result (hit or miss)", "kind", "result")
+	invalidations = metrics.NewCounter("cds_cache_invalidations_total", "Keys invalidated in the local level of the twolevel cache by other instances, by kind of key", "kind")
+)
+
+// invalidation is published by an instance changing a key, or keys matching a pattern if All is set
+type invalidation struct {
+	Key string `json:"key"`
+	All bool   `json:"all"`
+}
+
+//TwoLevelStore keeps recently used values in memory in front of redis.
+//Each instance publishes the keys it changes, all instances remove them from memory.
+//Values stay in memory for LocalLevelTTL seconds at most, in case an invalidation is lost.
+type TwoLevelStore struct {
+	local  *lruStore
+	remote *RedisStore
+}
+
+//NewTwoLevelStore initiates a new TwoLevelStore and subscribes to invalidations
+func NewTwoLevelStore(host, password string, ttl, localSize, localTTL int) (*TwoLevelStore, error) {
+	remote, err := NewRedisStore(host, password, ttl)
+	if err != nil {
+		return nil, err
+	}
+	s := &TwoLevelStore{
+		local:  newLRUStore(localSize, localTTL),
+		remote: remote,
+	}
+	go s.subscribe()
+	return s, nil
+}
+
+// subscribe removes from memory keys invalidated by all instances, it never returns
+func (s *TwoLevelStore) subscribe() {
+	for {
+		pubsub, err := s.remote.Client.Subscribe(invalidationChannel)
+		if err != nil {
+			log.Warning("twolevel> Cannot subscribe to %s: %s", invalidationChannel, err)
+			time.Sleep(time.Second)
+			continue
+		}
+		// Invalidations may have been missed while not subscribed
+		s.local.purge()
+
+		for {
+			msg, err := pubsub.ReceiveMessage()
+			if err != nil {
+				log.Warning("twolevel> Cannot receive from %s: %s", invalidationChannel, err)
+				break
+			}
+			var i invalidation
+			if err := json.Unmarshal([]byte(msg.Payload), &i); err != nil {
+				log.Warning("twolevel> Cannot unmarshal invalidation %s: %s", msg.Payload, err)
+				continue
+			}
+			if i.All {
+				s.local.deleteAll(i.Key)
+			} else {
+				s.local.delete(i.Key)
+			}
+			invalidations.Inc(kindOf(i.Key))
+		}
+		pubsub.Close()
+		time.Sleep(time.Second)
+	}
+}
+
+// invalidate removes key, or keys matching it if all is set, from memory of all instances
+func (s *TwoLevelStore) invalidate(key string, all bool) {
+	if all {
+		s.local.deleteAll(key)
+	} else {
+		s.local.delete(key)
+	}
+	b, _ := json.Marshal(invalidation{Key: key, All: all})
+	if err := s.remote.Client.Publish(invalidationChannel, string(b)).Err(); err != nil {
+		log.Warning("twolevel> Cannot publish invalidation of %s: %s", key, err)
+	}
+}
+
+//Get a key from memory, or from redis
+func (s *TwoLevelStore) Get(key string, value interface{}) bool {
+	b, ok := s.local.get(key)
+	if ok {
+		localRequests.Inc(kindOf(key), "hit")
+	} else {
+		localRequests.Inc(kindOf(key), "miss")
+		// An invalidation received while reading redis discards the value read
+		gen := s.local.loading(key)
+		if b, ok = s.remote.get(key); !ok {
+			s.local.loaded(key, gen, nil, 0)
+			return false
+		}
+		ttl := 0
+		if d, err := s.remote.Client.TTL(key).Result(); err == nil && d > 0 {
+			ttl = int(d / time.Second)
+		}
+		s.local.loaded(key, gen, b, ttl)
+	}
+	if err := json.Unmarshal(b, value); err != nil {
+		log.Warning("twolevel> Cannot unmarshal %s :%s", key, err)
+		return false
+	}
+	return true
+}
+
+//SetWithTTL a value in redis with a specific ttl (in seconds): (0 for eternity), and invalidates it in memory
+func (s *TwoLevelStore) SetWithTTL(key string, value interface{}, ttl int) {
+	s.remote.SetWithTTL(key, value, ttl)
+	s.invalidate(key, false)
+}
+
+//Expire sets the ttl (in seconds) of a key in redis: (0 for eternity), it stays in memory for LocalLevelTTL seconds at most
+func (s *TwoLevelStore) Expire(key string, ttl int) {
+	s.remote.Expire(key, ttl)
+}
+
+//Set a value in redis, and invalidates it in memory
+func (s *TwoLevelStore) Set(key string, value interface{}) {
+	s.remote.Set(key, value)
+	s.invalidate(key, false)
+}
+
+//Delete a key in redis and in memory
+func (s *TwoLevelStore) Delete(key string) {
+	s.remote.Delete(key)
+	s.invalidate(key, false)
+}
+
+//DeleteAll delete all matching keys in redis and in memory
+func (s *TwoLevelStore) DeleteAll(pattern string) {
+	s.remote.DeleteAll(pattern)
+	s.invalidate(pattern, true)
+}
+
+//Enqueue pushes to redis queue
+func (s *TwoLevelStore) Enqueue(queueName string, value interface{}) {
+	s.remote.Enqueue(queueName, value)
+}
+
+//Dequeue gets from redis queue This is blocking while there is nothing in the queue
+func (s *TwoLevelStore) Dequeue(queueName string, value interface{}) {
+	s.remote.Dequeue(queueName, value)
+}
+
+//DequeueWithTimeout gets from redis queue, blocking until timeout while there is nothing in the queue.
+//It returns false on timeout.
+func (s *TwoLevelStore) DequeueWithTimeout(queueName string, value interface{}, timeout time.Duration) bool {
+	return s.remote.DequeueWithTimeout(queueName, value, timeout)
+}
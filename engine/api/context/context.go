@@ -1,12 +1,26 @@
 package context
 
 import (
+	"github.com/ovh/cds/engine/log"
 	"github.com/ovh/cds/sdk"
 )
 
 // Context gather information about http call origin
 type Context struct {
-	Agent  sdk.Agent
-	User   *sdk.User
-	Worker sdk.Worker
+	Agent     sdk.Agent
+	User      *sdk.User
+	Worker    sdk.Worker
+	RequestID string
+}
+
+// Fields returns log fields correlating log lines of the call
+func (c *Context) Fields() log.Fields {
+	f := log.Fields{log.FieldRequestID: c.RequestID}
+	if c.User != nil && c.User.Username != "" {
+		f[log.FieldUser] = c.User.Username
+	}
+	if c.Worker.ID != "" {
+		f[log.FieldWorkerID] = c.Worker.ID
+	}
+	return f
 }
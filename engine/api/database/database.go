@@ -131,3 +131,8 @@ func Status() string {
 
 	return fmt.Sprintf("Database: %s OK (%d conns)", dbDriver, db.Stats().OpenConnections)
 }
+
+// Driver returns the name of the database driver, postgres or ramsql
+func Driver() string {
+	return dbDriver
+}
@@ -20,6 +20,19 @@ func (m *WorkerModel) PostInsert(s gorp.SqlExecutor) error {
 		return err
 	}
 
+	var autoscaling interface{}
+	if m.Autoscaling != nil {
+		btes, err := json.Marshal(m.Autoscaling)
+		if err != nil {
+			return err
+		}
+		autoscaling = string(btes)
+	}
+	query = "update worker_model set autoscaling = $2 where id = $1"
+	if _, err := s.Exec(query, m.ID, autoscaling); err != nil {
+		return err
+	}
+
 	for _, a := range m.Capabilities {
 		query := `insert into worker_capability (worker_model_id, type, name, argument) values ($1, $2, $3, $4)`
 		if _, err := s.Exec(query, m.ID, a.Type, a.Name, a.Value); err != nil {
@@ -70,6 +83,19 @@ func (m *WorkerModel) PostSelect(s gorp.SqlExecutor) error {
 		})
 	}
 
+	//Load autoscaling
+	autoscaling, errSelect := s.SelectNullStr("select autoscaling from worker_model where id = $1", &m.ID)
+	if errSelect != nil {
+		return errSelect
+	}
+	m.Autoscaling = nil
+	if autoscaling.Valid && autoscaling.String != "" {
+		m.Autoscaling = &sdk.ModelAutoscaling{}
+		if err := json.Unmarshal([]byte(autoscaling.String), m.Autoscaling); err != nil {
+			return err
+		}
+	}
+
 	//Load created_by
 	m.CreatedBy = sdk.User{}
 	str, errSelect := s.SelectNullStr("select created_by from worker_model where id = $1", &m.ID)
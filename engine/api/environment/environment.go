@@ -10,6 +10,7 @@ import (
 	"github.com/ovh/cds/engine/api/artifact"
 	"github.com/ovh/cds/engine/api/database"
 	"github.com/ovh/cds/engine/api/keys"
+	"github.com/ovh/cds/engine/api/role"
 	"github.com/ovh/cds/engine/log"
 	"github.com/ovh/cds/sdk"
 )
@@ -191,6 +192,13 @@ func DeleteEnvironment(db *sql.Tx, environmentID int64) error {
 		return err
 	}
 
+	query = `DELETE FROM test_case_result where environment_id = $1`
+	_, err = db.Exec(query, environmentID)
+	if err != nil {
+		log.Warning("DeleteEnvironment> Cannot delete environment related test case history: %s\n", err)
+		return err
+	}
+
 	// Delete builds
 	query = `DELETE FROM build_log where action_build_id IN (
 			SELECT id FROM action_build WHERE pipeline_build_id IN (
@@ -325,10 +333,13 @@ func LoadEnvironmentByGroup(db database.Querier, group *sdk.Group) error {
 	query := `SELECT project.projectKey,
 			 environment.id,
 	                 environment.name,
-	                 environment_group.role
+	                 environment_group.role,
+	                 role.name,
+	                 role.capabilities
 	          FROM environment
 	          JOIN environment_group ON environment_group.environment_id = environment.id
 	 	  JOIN project ON environment.project_id = project.id
+	 	  LEFT JOIN role ON role.id = environment_group.role_id
 	 	  WHERE environment_group.group_id = $1
 	 	  ORDER BY environment.name ASC`
 	rows, err := db.Query(query, group.ID)
@@ -340,13 +351,19 @@ func LoadEnvironmentByGroup(db database.Querier, group *sdk.Group) error {
 	for rows.Next() {
 		var environment sdk.Environment
 		var perm int
-		err = rows.Scan(&environment.ProjectKey, &environment.ID, &environment.Name, &perm)
+		var roleName, roleCapabilities sql.NullString
+		err = rows.Scan(&environment.ProjectKey, &environment.ID, &environment.Name, &perm, &roleName, &roleCapabilities)
+		if err != nil {
+			return err
+		}
+		r, err := role.Granted(roleName, roleCapabilities, perm)
 		if err != nil {
 			return err
 		}
 		group.EnvironmentGroups = append(group.EnvironmentGroups, sdk.EnvironmentGroup{
 			Environment: environment,
 			Permission:  perm,
+			Role:        r,
 		})
 	}
 	return nil
@@ -130,7 +130,16 @@ func getVariablesInEnvironmentHandler(w http.ResponseWriter, r *http.Request, db
 	key := vars["key"]
 	envName := vars["permEnvironmentName"]
 
-	variables, err := environment.GetAllVariable(db, key, envName)
+	args := []environment.GetAllVariableFuncArg{}
+	if r.FormValue("withSecrets") == "true" {
+		if !canReadSecrets(r, c) {
+			WriteError(w, r, sdk.ErrForbidden)
+			return
+		}
+		args = append(args, environment.WithClearPassword())
+	}
+
+	variables, err := environment.GetAllVariable(db, key, envName, args...)
 	if err != nil {
 		log.Warning("getVariablesInEnvironmentHandler: Cannot get variables for environment %s: %s\n", envName, err)
 		WriteError(w, r, err)
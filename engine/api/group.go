@@ -9,6 +9,8 @@ import (
 
 	"github.com/gorilla/mux"
 
+	"github.com/ovh/cds/engine/api/auth"
+	"github.com/ovh/cds/engine/api/build"
 	"github.com/ovh/cds/engine/api/context"
 	"github.com/ovh/cds/engine/api/group"
 	"github.com/ovh/cds/engine/api/user"
@@ -429,3 +431,116 @@ func removeUserGroupAdminHandler(w http.ResponseWriter, r *http.Request, db *sql
 		return
 	}
 }
+
+func ldapGroupSyncClient() (*auth.LDAPClient, error) {
+	ldapClient, ok := router.authDriver.(*auth.LDAPClient)
+	if !ok || !ldapClient.GroupSyncEnabled() {
+		return nil, sdk.ErrLDAPGroupSyncDisabled
+	}
+	return ldapClient, nil
+}
+
+func getLDAPGroupSyncReportHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	ldapClient, err := ldapGroupSyncClient()
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	report, err := ldapClient.SyncGroups(db, true)
+	if err != nil {
+		log.Warning("getLDAPGroupSyncReportHandler: Cannot compute LDAP group synchronization: %s\n", err)
+		WriteError(w, r, sdk.NewError(sdk.ErrLDAPConn, err))
+		return
+	}
+
+	WriteJSON(w, r, report, http.StatusOK)
+}
+
+func syncLDAPGroupsHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	ldapClient, err := ldapGroupSyncClient()
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	report, err := ldapClient.SyncGroups(db, false)
+	if err != nil {
+		log.Warning("syncLDAPGroupsHandler: Cannot synchronize LDAP groups: %s\n", err)
+		WriteError(w, r, sdk.NewError(sdk.ErrLDAPConn, err))
+		return
+	}
+
+	WriteJSON(w, r, report, http.StatusOK)
+}
+
+func getGroupQuotaHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	vars := mux.Vars(r)
+	name := vars["permGroupName"]
+
+	g, err := group.LoadGroup(db, name)
+	if err != nil {
+		log.Warning("getGroupQuotaHandler: Cannot load %s: %s\n", name, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	usages, err := build.LoadGroupsUsage(db)
+	if err != nil {
+		log.Warning("getGroupQuotaHandler: Cannot load groups usage: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	q, ok := usages[g.ID]
+	if !ok {
+		q = sdk.DefaultGroupQuota
+		q.GroupID = g.ID
+	}
+
+	WriteJSON(w, r, q, http.StatusOK)
+}
+
+func updateGroupQuotaHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	vars := mux.Vars(r)
+	name := vars["permGroupName"]
+
+	// Group admins must not raise their own quota
+	if !c.User.Admin {
+		WriteError(w, r, sdk.ErrForbidden)
+		return
+	}
+
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		WriteError(w, r, sdk.ErrWrongRequest)
+		return
+	}
+
+	var q sdk.GroupQuota
+	if err := json.Unmarshal(data, &q); err != nil {
+		WriteError(w, r, sdk.ErrWrongRequest)
+		return
+	}
+
+	if err := q.IsValid(); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	g, err := group.LoadGroup(db, name)
+	if err != nil {
+		log.Warning("updateGroupQuotaHandler: Cannot load %s: %s\n", name, err)
+		WriteError(w, r, err)
+		return
+	}
+	q.GroupID = g.ID
+
+	if err := group.UpdateQuota(db, q); err != nil {
+		log.Warning("updateGroupQuotaHandler: Cannot update quota of %s: %s\n", name, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteJSON(w, r, q, http.StatusOK)
+}
@@ -60,7 +60,7 @@ func InsertGroupInApplication(db database.Executer, applicationID, groupID int64
 // UpdateGroupRoleInApplication update permission on application
 func UpdateGroupRoleInApplication(db database.Executer, key, appName, groupName string, role int) error {
 	query := `UPDATE application_group
-	          SET role=$1
+	          SET role=$1, role_id=NULL
 	          FROM application, project, "group"
 	          WHERE application.id = application_id AND application.project_id = project.id AND "group".id = group_id
 	          AND application.name = $2 AND  project.projectKey = $3 AND "group".name = $4 `
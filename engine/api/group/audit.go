@@ -0,0 +1,15 @@
+package group
+
+import (
+	"github.com/ovh/cds/engine/api/database"
+)
+
+// InsertAudit stores a membership change on given group
+func InsertAudit(db database.Executer, groupID int64, author, username, change string) error {
+	query := `
+		INSERT INTO group_audit (versionned, group_id, author, username, change)
+		VALUES (NOW(), $1, $2, $3, $4)
+	`
+	_, err := db.Exec(query, groupID, author, username, change)
+	return err
+}
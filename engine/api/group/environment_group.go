@@ -62,7 +62,7 @@ func InsertGroupsInEnvironment(db database.Executer, groupPermission []sdk.Group
 // UpdateGroupRoleInEnvironment update permission on environment
 func UpdateGroupRoleInEnvironment(db database.Executer, key, envName, groupName string, role int) error {
 	query := `UPDATE environment_group
-	          SET role=$1
+	          SET role=$1, role_id=NULL
 	          FROM environment, project, "group"
 	          WHERE environment.id = environment_id AND environment.project_id = project.id AND "group".id = group_id
 	          AND environment.name = $2 AND  project.projectKey = $3 AND "group".name = $4 `
@@ -43,6 +43,12 @@ func DeleteGroupAndDependencies(db database.Executer, group *sdk.Group) error {
 		return err
 	}
 
+	err = deleteGroupQuota(db, group)
+	if err != nil {
+		log.Warning("deleteGroupAndDependencies: Cannot delete group quota %s: %s\n", group.Name, err)
+		return err
+	}
+
 	err = deleteGroup(db, group)
 	if err != nil {
 		log.Warning("deleteGroupAndDependencies: Cannot delete group %s: %s\n", group.Name, err)
@@ -47,7 +47,7 @@ func InsertGroupInPipeline(db database.Executer, pipelineID, groupID int64, role
 
 // UpdateGroupRoleInPipeline update permission on pipeline
 func UpdateGroupRoleInPipeline(db database.Executer, pipelineID, groupID int64, role int) error {
-	query := `UPDATE pipeline_group SET role=$1 WHERE pipeline_id=$2 AND group_id=$3`
+	query := `UPDATE pipeline_group SET role=$1, role_id=NULL WHERE pipeline_id=$2 AND group_id=$3`
 	_, err := db.Exec(query, role, pipelineID, groupID)
 	return err
 }
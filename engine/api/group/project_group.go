@@ -36,7 +36,7 @@ func DeleteGroupFromProject(db database.Executer, projectID, groupID int64) erro
 
 // UpdateGroupRoleInProject Update group role for the given project
 func UpdateGroupRoleInProject(db database.Executer, projectID, groupID int64, role int) error {
-	query := `UPDATE project_group SET role=$1 WHERE project_id=$2 AND group_id=$3`
+	query := `UPDATE project_group SET role=$1, role_id=NULL WHERE project_id=$2 AND group_id=$3`
 	_, err := db.Exec(query, role, projectID, groupID)
 	return err
 }
@@ -0,0 +1,60 @@
+package group
+
+import (
+	"database/sql"
+
+	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/sdk"
+)
+
+// LoadQuota loads quota of a group, or default quota if not set
+func LoadQuota(db database.Querier, groupID int64) (sdk.GroupQuota, error) {
+	q := sdk.DefaultGroupQuota
+	q.GroupID = groupID
+
+	query := `SELECT max_building, weight FROM group_quota WHERE group_id = $1`
+	if err := db.QueryRow(query, groupID).Scan(&q.MaxBuilding, &q.Weight); err != nil && err != sql.ErrNoRows {
+		return q, err
+	}
+	return q, nil
+}
+
+// LoadQuotas loads quotas of all groups having one
+func LoadQuotas(db database.Querier) (map[int64]sdk.GroupQuota, error) {
+	rows, err := db.Query(`SELECT group_id, max_building, weight FROM group_quota`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	quotas := map[int64]sdk.GroupQuota{}
+	for rows.Next() {
+		var q sdk.GroupQuota
+		if err := rows.Scan(&q.GroupID, &q.MaxBuilding, &q.Weight); err != nil {
+			return nil, err
+		}
+		quotas[q.GroupID] = q
+	}
+	return quotas, nil
+}
+
+// UpdateQuota sets quota of a group
+func UpdateQuota(db database.QueryExecuter, q sdk.GroupQuota) error {
+	query := `UPDATE group_quota SET max_building = $2, weight = $3 WHERE group_id = $1`
+	res, err := db.Exec(query, q.GroupID, q.MaxBuilding, q.Weight)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return nil
+	}
+
+	query = `INSERT INTO group_quota (group_id, max_building, weight) VALUES ($1, $2, $3)`
+	_, err = db.Exec(query, q.GroupID, q.MaxBuilding, q.Weight)
+	return err
+}
+
+func deleteGroupQuota(db database.Executer, g *sdk.Group) error {
+	_, err := db.Exec(`DELETE FROM group_quota WHERE group_id = $1`, g.ID)
+	return err
+}
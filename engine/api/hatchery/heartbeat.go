@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/api/leader"
 	"github.com/ovh/cds/engine/log"
 )
 
@@ -18,7 +19,7 @@ func Heartbeat() {
 
 	for {
 		db := database.DB()
-		if db != nil {
+		if db != nil && leader.IsLeader() {
 			w, err := LoadDeadHatcheries(db, HatcheryHeartbeatTimeout)
 			if err != nil {
 				log.Warning("HatcheryHeartbeat> Cannot load hatcherys: %s\n", err)
@@ -265,12 +265,13 @@ func deleteBranchBuilds(db *sql.DB, appID int64, branch string) error {
 	}
 	rows.Close()
 
-	// For each pipeline build in history, load and delete related artifacts
+	// For each pipeline build in history, load and delete related artifacts, promoted ones are kept
 	query = `SELECT id FROM artifact
 	WHERE build_number = $1
 	AND application_id = $2
 	AND pipeline_id = $3
-	AND environment_id = $4`
+	AND environment_id = $4
+	AND promoted = false`
 	for _, pb := range pbs {
 		rows, err := db.Query(query, pb.BuildNumber, appID, pb.Pipeline.ID, pb.Environment.ID)
 		if err != nil {
@@ -303,6 +304,12 @@ func deleteBranchBuilds(db *sql.DB, appID int64, branch string) error {
 		return err
 	}
 
+	query = `DELETE FROM test_case_result WHERE branch = $1 AND application_id = $2`
+	_, err = db.Exec(query, branch, appID)
+	if err != nil {
+		return err
+	}
+
 	// Now select all related build in pipeline build
 	query = `SELECT id	FROM pipeline_build WHERE vcs_changes_branch = $1 AND application_id = $2`
 	rows, err = db.Query(query, branch, appID)
@@ -0,0 +1,191 @@
+// Package leader elects the API instance running singleton routines, like the scheduler or the archivist,
+// when several instances share the same database.
+//
+// The leader holds a PostgreSQL session-level advisory lock on a dedicated connection. Other instances
+// try to take the lock every few seconds, so they take over as soon as the leader releases it on shutdown,
+// or as soon as PostgreSQL closes the session of a dead leader.
+package leader
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/engine/metrics"
+)
+
+// lockID is the key of the advisory lock held by the leader, "cdsl" in ASCII
+const lockID = 0x6364736c
+
+// electionInterval is the time between two attempts to take, or to check, the leadership
+const electionInterval = 2 * time.Second
+
+var (
+	// mutex guards the election, state guards its result
+	mutex    sync.Mutex
+	resigned bool
+	conn     *sql.Conn
+	state    sync.RWMutex
+	leader   bool
+	since    time.Time
+	instance string
+
+	leaderGauge = metrics.NewGauge("cds_api_leader", "1 if this API instance runs singleton routines, 0 otherwise")
+)
+
+func init() {
+	hostname, _ := os.Hostname()
+	instance = fmt.Sprintf("cds-api %s/%d", hostname, os.Getpid())
+	leaderGauge.Set(0)
+}
+
+// IsLeader returns true if this instance must run singleton routines
+func IsLeader() bool {
+	state.RLock()
+	defer state.RUnlock()
+	return leader
+}
+
+// Routine takes part in the election until Resign is called
+func Routine() {
+	// If this goroutine exits, then it's a crash
+	defer log.Fatalf("Goroutine of leader.Routine exited - Exit CDS Engine")
+
+	for {
+		mutex.Lock()
+		if !resigned {
+			elect()
+		}
+		mutex.Unlock()
+		time.Sleep(electionInterval)
+	}
+}
+
+// elect takes the leadership if available, or checks the leadership is still held. mutex must be locked.
+func elect() {
+	db := database.DB()
+	if db == nil {
+		stepDown("database is unavailable")
+		return
+	}
+
+	// RamSQL is only used by a single instance, without advisory locks
+	if database.Driver() == "ramsql" {
+		becomeLeader()
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), electionInterval)
+	defer cancel()
+
+	if conn == nil {
+		c, err := db.Conn(ctx)
+		if err != nil {
+			log.Warning("leader.elect> Cannot get connection: %s\n", err)
+			return
+		}
+		// Instances read name of the leader in pg_stat_activity
+		if _, err := c.ExecContext(ctx, "SELECT set_config('application_name', $1, false)", instance); err != nil {
+			log.Warning("leader.elect> Cannot set application name: %s\n", err)
+		}
+		conn = c
+	}
+
+	if IsLeader() {
+		// The lock lives as long as the session
+		if _, err := conn.ExecContext(ctx, "SELECT 1"); err != nil {
+			stepDown(err.Error())
+		}
+		return
+	}
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", lockID).Scan(&locked); err != nil {
+		log.Warning("leader.elect> Cannot take lock: %s\n", err)
+		closeConn()
+		return
+	}
+	if locked {
+		becomeLeader()
+	}
+}
+
+func becomeLeader() {
+	state.Lock()
+	defer state.Unlock()
+	if leader {
+		return
+	}
+	log.Notice("leader> %s is now the leader\n", instance)
+	leader = true
+	since = time.Now()
+	leaderGauge.Set(1)
+}
+
+// stepDown stops singleton routines and releases the session holding the lock, if any
+func stepDown(reason string) {
+	state.Lock()
+	if leader {
+		log.Warning("leader> %s is not the leader anymore: %s\n", instance, reason)
+		leader = false
+		leaderGauge.Set(0)
+	}
+	state.Unlock()
+	closeConn()
+}
+
+func closeConn() {
+	if conn != nil {
+		conn.Close()
+		conn = nil
+	}
+}
+
+// Resign releases the leadership for another instance to take over at once, and stops taking part in the election.
+// It is called on shutdown.
+func Resign() {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	resigned = true
+	if IsLeader() && conn != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), electionInterval)
+		defer cancel()
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", lockID); err != nil {
+			log.Warning("leader.Resign> Cannot release lock: %s\n", err)
+		}
+	}
+	stepDown("shutting down")
+}
+
+// Status returns the leader in a printable string
+func Status() string {
+	state.RLock()
+	isLeader, leaderSince := leader, since
+	state.RUnlock()
+
+	if isLeader {
+		return fmt.Sprintf("Leader: %s (this instance, since %s)", instance, leaderSince.Format(time.RFC3339))
+	}
+
+	db := database.DB()
+	if db == nil || database.Driver() == "ramsql" {
+		return "Leader: none"
+	}
+	query := `SELECT pg_stat_activity.application_name
+		FROM pg_locks JOIN pg_stat_activity ON pg_stat_activity.pid = pg_locks.pid
+		WHERE pg_locks.locktype = 'advisory' AND pg_locks.granted AND pg_locks.classid = 0 AND pg_locks.objid = $1 AND pg_locks.objsubid = 1`
+	var name string
+	if err := db.QueryRow(query, lockID).Scan(&name); err != nil {
+		if err == sql.ErrNoRows {
+			return "Leader: none"
+		}
+		return fmt.Sprintf("Leader: KO (%s)", err)
+	}
+	return fmt.Sprintf("Leader: %s", name)
+}
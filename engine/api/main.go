@@ -17,11 +17,16 @@ import (
 
 	"github.com/ovh/cds/engine/api/action"
 	"github.com/ovh/cds/engine/api/archivist"
+	"github.com/ovh/cds/engine/api/artifact"
+	"github.com/ovh/cds/engine/api/audit"
 	"github.com/ovh/cds/engine/api/auth"
 	"github.com/ovh/cds/engine/api/bootstrap"
+	"github.com/ovh/cds/engine/api/build"
+	"github.com/ovh/cds/engine/api/buildcache"
 	"github.com/ovh/cds/engine/api/cache"
 	"github.com/ovh/cds/engine/api/database"
 	"github.com/ovh/cds/engine/api/hatchery"
+	"github.com/ovh/cds/engine/api/leader"
 	"github.com/ovh/cds/engine/api/mail"
 	"github.com/ovh/cds/engine/api/notification"
 	"github.com/ovh/cds/engine/api/objectstore"
@@ -34,6 +39,8 @@ import (
 	"github.com/ovh/cds/engine/api/stats"
 	"github.com/ovh/cds/engine/api/worker"
 	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+	"github.com/ovh/cds/sdk/tracing"
 )
 
 var startupTime time.Time
@@ -65,6 +72,19 @@ var mainCmd = &cobra.Command{
 			viper.GetString("artifact_basedir")); err != nil {
 			log.Fatalf("Cannot initialize storage: %s\n", err)
 		}
+		buildcache.DefaultMaxSize = viper.GetInt64("build_cache_max_size") << 20
+
+		if err := tracing.Initialize("cds-api", viper.GetString("tracing_exporter")); err != nil {
+			log.Fatalf("Cannot initialize tracing: %s\n", err)
+		}
+
+		if err := audit.Initialize(viper.GetString("audit_file"), viper.GetInt("audit_retention")); err != nil {
+			log.Fatalf("Cannot initialize audit log: %s\n", err)
+		}
+
+		if err := initTrustedProxies(viper.GetStringSlice("trusted_proxies")); err != nil {
+			log.Fatalf("Cannot initialize trusted proxies: %s\n", err)
+		}
 
 		db, err := database.Init()
 		if err != nil {
@@ -79,18 +99,6 @@ var mainCmd = &cobra.Command{
 			if err = bootstrap.InitiliazeDB(db); err != nil {
 				log.Critical("Cannot setup databases: %s\n", err)
 			}
-
-			// Gracefully shutdown sql connections
-			c := make(chan os.Signal, 1)
-			signal.Notify(c, os.Interrupt)
-			signal.Notify(c, syscall.SIGTERM)
-			signal.Notify(c, syscall.SIGKILL)
-			go func() {
-				<-c
-				log.Warning("Cleanup SQL connections\n")
-				db.Close()
-				os.Exit(0)
-			}()
 		}
 
 		// Make a new Broker instance
@@ -113,28 +121,25 @@ var mainCmd = &cobra.Command{
 
 		//Initialize secret driver
 		secretBackend := viper.GetString("secret_backend")
-		secretBackendOptions := viper.GetStringSlice("secret_backend_option")
-		secretBackendOptionsMap := map[string]string{}
-		for _, o := range secretBackendOptions {
-			if !strings.Contains(o, "=") {
-				log.Warning("Malformated options : %s", o)
-				continue
-			}
-			t := strings.Split(o, "=")
-			secretBackendOptionsMap[t[0]] = t[1]
-		}
+		secretBackendOptionsMap := parseSecretBackendOptions(viper.GetStringSlice("secret_backend_option"))
 		if err := secret.Init(secretBackend, secretBackendOptionsMap); err != nil {
 			log.Critical("Cannot initialize secret manager: %s\n", err)
 		}
 
 		//Intialize repositories manager
-		if err := repositoriesmanager.Initialize(
-			secret.Client,
-			viper.GetString("keys_directory"),
-			baseURL,
-			viper.GetString("api_url"),
-		); err != nil {
-			log.Warning("Error initializing repositories manager connections: %s\n", err)
+		initRepositoriesManager := func() {
+			if err := repositoriesmanager.Initialize(
+				secret.Client,
+				viper.GetString("keys_directory"),
+				baseURL,
+				viper.GetString("api_url"),
+			); err != nil {
+				log.Warning("Error initializing repositories manager connections: %s\n", err)
+			}
+		}
+		initRepositoriesManager()
+		if interval := viper.GetInt("secret_watch_interval"); interval > 0 {
+			go secret.WatchRoutine(interval, initRepositoriesManager)
 		}
 
 		// Initialize the auth driver
@@ -143,13 +148,20 @@ var mainCmd = &cobra.Command{
 		switch viper.GetBool("ldap_enable") {
 		case true:
 			authMode = "ldap"
+			groupMapping, err := auth.ParseLDAPGroupMapping(viper.GetStringSlice("ldap_group_mapping"))
+			if err != nil {
+				log.Fatalf("Cannot parse LDAP group mapping: %s\n", err)
+			}
 			authOptions = auth.LDAPConfig{
-				Host:         viper.GetString("ldap_host"),
-				Port:         viper.GetInt("ldap_port"),
-				Base:         viper.GetString("ldap_base"),
-				DN:           viper.GetString("ldap_dn"),
-				SSL:          viper.GetBool("ldap_ssl"),
-				UserFullname: viper.GetString("ldap_user_fullname"),
+				Host:                 viper.GetString("ldap_host"),
+				Port:                 viper.GetInt("ldap_port"),
+				Base:                 viper.GetString("ldap_base"),
+				DN:                   viper.GetString("ldap_dn"),
+				SSL:                  viper.GetBool("ldap_ssl"),
+				UserFullname:         viper.GetString("ldap_user_fullname"),
+				GroupFilter:          viper.GetString("ldap_group_filter"),
+				GroupMemberAttribute: viper.GetString("ldap_group_member_attribute"),
+				GroupMapping:         groupMapping,
 			}
 		default:
 			authMode = "local"
@@ -164,8 +176,12 @@ var mainCmd = &cobra.Command{
 
 		router.authDriver, _ = auth.GetDriver(authMode, authOptions, storeOptions)
 
+		cache.LocalLevelSize = viper.GetInt("cache_local_size")
+		cache.LocalLevelTTL = viper.GetInt("cache_local_ttl")
 		cache.Initialize(viper.GetString("cache"), viper.GetString("redis_host"), viper.GetString("redis_password"), viper.GetInt("cache_ttl"))
 
+		// Singleton routines only work on the instance elected as leader
+		go leader.Routine()
 		go archivist.Archive(viper.GetInt("interval_archive_seconds"), viper.GetInt("archived_build_hours"))
 		go scheduler.Schedule()
 		go pipeline.AWOLPipelineKiller()
@@ -174,14 +190,25 @@ var mainCmd = &cobra.Command{
 		go hatchery.Heartbeat()
 		go log.RemovalRoutine()
 		go auditCleanerRoutine()
+		go audit.LogRoutine()
+		go audit.CleanerRoutine()
+		go artifact.RetentionRoutine(viper.GetInt("artifact_retention"))
 		go repositoriesmanager.RepositoriesCacheLoader(30)
 		go stats.StartRoutine()
+		go stats.MetricsRoutine()
 		go action.RequirementsCacheLoader(5)
 		go worker.ModelCapabilititiesCacheLoader(5)
 		go hookRecoverer()
+		go build.PushRoutine()
 		go polling.Initialize()
 		go polling.ExecutionCleaner()
 
+		if ldapClient, ok := router.authDriver.(*auth.LDAPClient); ok && ldapClient.GroupSyncEnabled() {
+			if interval := viper.GetInt("ldap_group_sync_interval"); interval > 0 {
+				go ldapClient.SyncGroupsRoutine(interval)
+			}
+		}
+
 		s := &http.Server{
 			Addr:           ":" + viper.GetString("listen_port"),
 			Handler:        router.mux,
@@ -190,21 +217,35 @@ var mainCmd = &cobra.Command{
 			MaxHeaderBytes: 1 << 20,
 		}
 
+		// Gracefully shutdown on SIGTERM
+		shutdownDone := make(chan struct{})
+		go func() {
+			c := make(chan os.Signal, 1)
+			signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+			<-c
+			shutdown(s, time.Duration(viper.GetInt("shutdown_delay"))*time.Second, time.Duration(viper.GetInt("shutdown_timeout"))*time.Second)
+			close(shutdownDone)
+		}()
+
 		log.Notice("Listening on :%s\n", viper.GetString("listen_port"))
-		if err := s.ListenAndServe(); err != nil {
+		if err := s.ListenAndServe(); err != http.ErrServerClosed {
 			log.Fatalf("Cannot start cds-server: %s\n", err)
 		}
+		<-shutdownDone
 	},
 }
 
 func (router *Router) init() {
 	router.Handle("/login", Auth(false), POST(LoginUser))
 
+	// Audit
+	router.Handle("/audit", NeedAdmin(true), GET(getAuditHandler))
+
 	// Action
 	router.Handle("/action", GET(getActionsHandler))
 	router.Handle("/action/import", NeedAdmin(true), POST(importActionHandler))
 	router.Handle("/action/requirement", Auth(false), GET(getActionsRequirements))
-	router.Handle("/action/{permActionName}", GET(getActionHandler), POST(addActionHandler), PUT(updateActionHandler), DELETE(deleteActionHandler))
+	router.Handle("/action/{permActionName}", AuditBefore(auditAction), GET(getActionHandler), POST(addActionHandler), PUT(updateActionHandler), DELETE(deleteActionHandler))
 	router.Handle("/action/{actionName}/using", NeedAdmin(true), GET(getPipelinesUsingActionHandler))
 	router.Handle("/action/{actionID}/audit", NeedAdmin(true), GET(getActionAuditHandler))
 
@@ -222,15 +263,17 @@ func (router *Router) init() {
 	// Group
 	router.Handle("/group", GET(getGroups), POST(addGroupHandler))
 	router.Handle("/group/public", GET(getPublicGroups))
-	router.Handle("/group/{permGroupName}", GET(getGroupHandler), PUT(updateGroupHandler), DELETE(deleteGroupHandler))
+	router.Handle("/group/ldap/sync", NeedAdmin(true), GET(getLDAPGroupSyncReportHandler), POST(syncLDAPGroupsHandler))
+	router.Handle("/group/{permGroupName}", AuditBefore(auditGroup), GET(getGroupHandler), PUT(updateGroupHandler), DELETE(deleteGroupHandler))
 	router.Handle("/group/{permGroupName}/user", POST(addUserInGroup))
 	router.Handle("/group/{permGroupName}/user/{user}", DELETE(removeUserFromGroupHandler))
 	router.Handle("/group/{permGroupName}/user/{user}/admin", POST(setUserGroupAdminHandler), DELETE(removeUserGroupAdminHandler))
+	router.Handle("/group/{permGroupName}/quota", GET(getGroupQuotaHandler), PUT(updateGroupQuotaHandler))
 	router.Handle("/group/{permGroupName}/token/{expiration}", POST(generateTokenHandler))
 
 	// Hatchery
 	router.Handle("/hatchery", Auth(false), POST(registerHatchery))
-	router.Handle("/hatchery/{id}", PUT(refreshHatcheryHandler))
+	router.Handle("/hatchery/{id}", Audit(false), PUT(refreshHatcheryHandler))
 
 	// Hooks
 	router.Handle("/hook", Auth(false) /* Public handler called by third parties */, POST(receiveHook))
@@ -239,9 +282,11 @@ func (router *Router) init() {
 	router.Handle("/mon/status", Auth(false), GET(statusHandler))
 	router.Handle("/mon/status/polling", Auth(false), GET(pollinStatusHandler))
 	router.Handle("/mon/smtp/ping", Auth(true), GET(smtpPingHandler))
-	router.Handle("/mon/log/{level}", Auth(false), POST(setEngineLogLevel))
+	router.Handle("/mon/log", NeedAdmin(true), GET(getEngineLogLevels), DELETE(deleteEnginePackageLogLevel))
+	router.Handle("/mon/log/{level}", NeedAdmin(true), POST(setEngineLogLevel))
 	router.Handle("/mon/sla/{date}", POST(slaHandler))
 	router.Handle("/mon/version", Auth(false), GET(getVersionHandler))
+	router.Handle("/metrics", Auth(false), GET(metricsHandler))
 	router.Handle("/mon/error", Auth(false), GET(getError))
 	router.Handle("/mon/stats", Auth(false), GET(getStats))
 	router.Handle("/mon/models", Auth(false), GET(getWorkerModelsStatsHandler))
@@ -255,22 +300,29 @@ func (router *Router) init() {
 
 	// Project
 	router.Handle("/project", GET(getProjects), POST(addProject))
-	router.Handle("/project/{permProjectKey}", GET(getProject), PUT(updateProject), DELETE(deleteProject))
+	router.Handle("/project/{permProjectKey}", AuditBefore(auditProject), GET(getProject), PUT(updateProject), DELETE(deleteProject))
 	router.Handle("/project/{permProjectKey}/group", POST(addGroupInProject), PUT(updateGroupsInProject))
 	router.Handle("/project/{permProjectKey}/group/{group}", PUT(updateGroupRoleOnProjectHandler), DELETE(deleteGroupFromProjectHandler))
-	router.Handle("/project/{permProjectKey}/variable", GET(getVariablesInProjectHandler), PUT(updateVariablesInProjectHandler))
-	router.Handle("/project/{key}/variable/audit", GET(getVariablesAuditInProjectnHandler))
-	router.Handle("/project/{key}/variable/audit/{auditID}", PUT(restoreProjectVariableAuditHandler))
-	router.Handle("/project/{permProjectKey}/variable/{name}", GET(getVariableInProjectHandler), POST(addVariableInProjectHandler), PUT(updateVariableInProjectHandler), DELETE(deleteVariableFromProjectHandler))
+	router.Handle("/project/{permProjectKey}/group/{group}/role/{role}", PUT(assignRoleOnProjectHandler))
+	router.Handle("/project/{permProjectKey}/access", GET(explainAccessHandler))
+	router.Handle("/project/{permProjectKey}/audit", GET(getProjectAuditHandler))
+	router.Handle("/project/{permProjectKey}/variable", WriteCapability(sdk.CapabilityEditVariables), GET(getVariablesInProjectHandler), PUT(updateVariablesInProjectHandler))
+	router.Handle("/project/{permProjectKey}/variable/audit", GET(getVariablesAuditInProjectnHandler))
+	router.Handle("/project/{permProjectKey}/variable/audit/{auditID}", WriteCapability(sdk.CapabilityEditVariables), PUT(restoreProjectVariableAuditHandler))
+	router.Handle("/project/{permProjectKey}/variable/{name}", WriteCapability(sdk.CapabilityEditVariables), GET(getVariableInProjectHandler), POST(addVariableInProjectHandler), PUT(updateVariableInProjectHandler), DELETE(deleteVariableFromProjectHandler))
+	router.Handle("/project/{permProjectKey}/cache", GET(getCachesHandler), PUT(updateCacheLimitHandler))
+	router.Handle("/project/{permProjectKey}/cache/{cacheKey}", GET(getCacheHandler), POSTEXECUTE(uploadCacheHandler), DELETE(deleteCacheHandler))
+	router.Handle("/project/{permProjectKey}/cache/{cacheKey}/download", GET(downloadCacheHandler))
 	router.Handle("/project/{permProjectKey}/applications", GET(getApplicationsHandler), POST(addApplicationHandler))
 
 	// Application
-	router.Handle("/project/{key}/application/{permApplicationName}", GET(getApplicationHandler), PUT(updateApplicationHandler), DELETE(deleteApplicationHandler))
+	router.Handle("/project/{key}/application/{permApplicationName}", AuditBefore(auditApplication), GET(getApplicationHandler), PUT(updateApplicationHandler), DELETE(deleteApplicationHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/branches", GET(getApplicationBranchHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/version", GET(getApplicationBranchVersionHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/clone", POST(cloneApplicationHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/group", POST(addGroupInApplicationHandler), PUT(updateGroupsInApplicationHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/group/{group}", PUT(updateGroupRoleOnApplicationHandler), DELETE(deleteGroupFromApplicationHandler))
+	router.Handle("/project/{key}/application/{permApplicationName}/group/{group}/role/{role}", PUT(assignRoleOnApplicationHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/history", GET(getApplicationHistoryHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/history/branch", GET(getPipelineBuildBranchHistoryHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/history/env/deploy", GET(getApplicationDeployHistoryHandler))
@@ -278,22 +330,28 @@ func (router *Router) init() {
 	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}", POST(attachPipelineToApplicationHandler), PUT(updatePipelineToApplicationHandler), DELETE(removePipelineFromApplicationHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/notification", GET(getUserNotificationApplicationPipelineHandler), PUT(updateUserNotificationApplicationPipelineHandler), DELETE(deleteUserNotificationApplicationPipelineHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/tree", GET(getApplicationTreeHandler))
-	router.Handle("/project/{key}/application/{permApplicationName}/variable", GET(getVariablesInApplicationHandler), PUT(updateVariablesInApplicationHandler))
+	router.Handle("/project/{key}/application/{permApplicationName}/variable", WriteCapability(sdk.CapabilityEditVariables), GET(getVariablesInApplicationHandler), PUT(updateVariablesInApplicationHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/variable/audit", GET(getVariablesAuditInApplicationHandler))
-	router.Handle("/project/{key}/application/{permApplicationName}/variable/audit/{auditID}", PUT(restoreAuditHandler))
-	router.Handle("/project/{key}/application/{permApplicationName}/variable/{name}", GET(getVariableInApplicationHandler), POST(addVariableInApplicationHandler), PUT(updateVariableInApplicationHandler), DELETE(deleteVariableFromApplicationHandler))
+	router.Handle("/project/{key}/application/{permApplicationName}/variable/audit/{auditID}", WriteCapability(sdk.CapabilityEditVariables), PUT(restoreAuditHandler))
+	router.Handle("/project/{key}/application/{permApplicationName}/variable/{name}", WriteCapability(sdk.CapabilityEditVariables), GET(getVariableInApplicationHandler), POST(addVariableInApplicationHandler), PUT(updateVariableInApplicationHandler), DELETE(deleteVariableFromApplicationHandler))
 
 	// Pipeline
 	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/history", GET(getPipelineHistoryHandler))
+	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/test/coverage", GET(getCoverageTrendHandler))
+	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/test/history", GET(getTestCaseHistoryHandler))
+	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/test/flaky", GET(getFlakyTestsHandler))
+	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/test/slowest", GET(getSlowestTestsHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/build/{build}/log", GET(getBuildLogsHandler))
 	router.Handle("/project/{key}/application/{app}/pipeline/{permPipelineKey}/build/{build}/test", POSTEXECUTE(addBuildTestResultsHandler), GET(getBuildTestResultsHandler))
 	router.Handle("/project/{key}/application/{app}/pipeline/{permPipelineKey}/build/{build}/variable", POSTEXECUTE(addBuildVariableHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/build/{build}/action/{actionID}/log", GET(getActionBuildLogsHandler))
+	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/build/{build}/action/{actionBuildID}/debug", ReadCapability(sdk.CapabilityExecute), GET(debugActionBuildHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/build/{build}", GET(getBuildStateHandler), DELETE(deleteBuildHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/build/{build}/triggered", GET(getPipelineBuildTriggeredHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/build/{build}/stop", POSTEXECUTE(stopPipelineBuildHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/build/{build}/restart", POSTEXECUTE(restartPipelineBuildHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/build/{build}/commits", GET(getPipelineBuildCommitsHandler))
+	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/build/{build}/promote", POST(promoteArtifactsHandler), DELETE(unpromoteArtifactsHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/commits", GET(getPipelineCommitsHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/run", POSTEXECUTE(runPipelineHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/runwithlastparent", POSTEXECUTE(runPipelineWithLastParentHandler))
@@ -302,9 +360,10 @@ func (router *Router) init() {
 	router.Handle("/project/{key}/pipeline/{permPipelineKey}/application", GET(getApplicationUsingPipelineHandler))
 	router.Handle("/project/{key}/pipeline/{permPipelineKey}/group", POST(addGroupInPipelineHandler), PUT(updateGroupsOnPipelineHandler))
 	router.Handle("/project/{key}/pipeline/{permPipelineKey}/group/{group}", PUT(updateGroupRoleOnPipelineHandler), DELETE(deleteGroupFromPipelineHandler))
+	router.Handle("/project/{key}/pipeline/{permPipelineKey}/group/{group}/role/{role}", PUT(assignRoleOnPipelineHandler))
 	router.Handle("/project/{key}/pipeline/{permPipelineKey}/parameter", GET(getParametersInPipelineHandler), PUT(updateParametersInPipelineHandler))
 	router.Handle("/project/{key}/pipeline/{permPipelineKey}/parameter/{name}", POST(addParameterInPipelineHandler), PUT(updateParameterInPipelineHandler), DELETE(deleteParameterFromPipelineHandler))
-	router.Handle("/project/{key}/pipeline/{permPipelineKey}", GET(getPipelineHandler), PUT(updatePipelineHandler), DELETE(deletePipeline))
+	router.Handle("/project/{key}/pipeline/{permPipelineKey}", AuditBefore(auditPipeline), GET(getPipelineHandler), PUT(updatePipelineHandler), DELETE(deletePipeline))
 	router.Handle("/project/{key}/pipeline/{permPipelineKey}/stage", POST(addStageHandler))
 	router.Handle("/project/{key}/pipeline/{permPipelineKey}/stage/move", POST(moveStageHandler))
 	router.Handle("/project/{key}/pipeline/{permPipelineKey}/stage/{stageID}", GET(getStageHandler), PUT(updateStageHandler), DELETE(deleteStageHandler))
@@ -324,19 +383,24 @@ func (router *Router) init() {
 
 	// Environment
 	router.Handle("/project/{permProjectKey}/environment", GET(getEnvironmentsHandler), POST(addEnvironmentHandler), PUT(updateEnvironmentsHandler))
-	router.Handle("/project/{key}/environment/{permEnvironmentName}", GET(getEnvironmentHandler), PUT(updateEnvironmentHandler), DELETE(deleteEnvironmentHandler))
+	router.Handle("/project/{key}/environment/{permEnvironmentName}", AuditBefore(auditEnvironment), GET(getEnvironmentHandler), PUT(updateEnvironmentHandler), DELETE(deleteEnvironmentHandler))
 	router.Handle("/project/{key}/environment/{permEnvironmentName}/audit", GET(getEnvironmentsAuditHandler))
-	router.Handle("/project/{key}/environment/{permEnvironmentName}/audit/{auditID}", PUT(restoreEnvironmentAuditHandler))
+	router.Handle("/project/{key}/environment/{permEnvironmentName}/audit/{auditID}", WriteCapability(sdk.CapabilityEditVariables), PUT(restoreEnvironmentAuditHandler))
 	router.Handle("/project/{key}/environment/{permEnvironmentName}/group", POST(addGroupInEnvironmentHandler))
 	router.Handle("/project/{key}/environment/{permEnvironmentName}/group/{group}", PUT(updateGroupRoleOnEnvironmentHandler), DELETE(deleteGroupFromEnvironmentHandler))
+	router.Handle("/project/{key}/environment/{permEnvironmentName}/group/{group}/role/{role}", PUT(assignRoleOnEnvironmentHandler))
 	router.Handle("/project/{key}/environment/{permEnvironmentName}/variable", GET(getVariablesInEnvironmentHandler))
-	router.Handle("/project/{key}/environment/{permEnvironmentName}/variable/{name}", POST(addVariableInEnvironmentHandler), PUT(updateVariableInEnvironmentHandler), DELETE(deleteVariableFromEnvironmentHandler))
+	router.Handle("/project/{key}/environment/{permEnvironmentName}/variable/{name}", WriteCapability(sdk.CapabilityEditVariables), POST(addVariableInEnvironmentHandler), PUT(updateVariableInEnvironmentHandler), DELETE(deleteVariableFromEnvironmentHandler))
 
 	// Artifacts
+	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/artifact", GET(findArtifactsHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/artifact/{tag}", GET(listArtifactsHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/{buildNumber}/artifact", GET(listArtifactsBuildHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/{buildNumber}/artifact/{tag}", POSTEXECUTE(uploadArtifactHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/artifact/download/{id}", GET(downloadArtifactHandler))
+	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/{buildNumber}/artifact/{tag}/upload", POSTEXECUTE(startArtifactUploadHandler))
+	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/artifact/upload/{uploadID}", GET(getArtifactUploadHandler), POSTEXECUTE(completeArtifactUploadHandler))
+	router.Handle("/project/{key}/application/{permApplicationName}/pipeline/{permPipelineKey}/artifact/upload/{uploadID}/{chunk}", POSTEXECUTE(uploadArtifactChunkHandler))
 	router.Handle("/artifact/{hash}", Auth(false), GET(downloadArtifactDirectHandler))
 
 	// Hooks
@@ -350,10 +414,13 @@ func (router *Router) init() {
 
 	// Build queue
 	router.Handle("/queue", GET(getQueueHandler))
+	router.Handle("/queue/take", POST(takeQueueHandler))
 	router.Handle("/queue/requirements/errors", POST(requirementsErrorHandler))
 	router.Handle("/queue/{id}/take", POST(takeActionBuildHandler))
 	router.Handle("/queue/{id}/result", POST(addQueueResultHandler))
-	router.Handle("/build/{id}/log", POST(addBuildLogHandler))
+	router.Handle("/queue/{id}/debug", GET(workerDebugHandler))
+	router.Handle("/queue/{id}/clone", GET(getActionBuildCloneHandler))
+	router.Handle("/build/{id}/log", Audit(false), POST(addBuildLogHandler))
 
 	router.Handle("/variable/type", GET(getVariableTypeHandler))
 	router.Handle("/parameter/type", GET(getParameterTypeHandler))
@@ -386,6 +453,10 @@ func (router *Router) init() {
 	//Suggest
 	router.Handle("/suggest/variable/{permProjectKey}", GET(getVariablesHandler))
 
+	// Roles
+	router.Handle("/role", GET(getRolesHandler))
+	router.Handle("/role/{name}", NeedAdmin(true), POST(addRoleHandler), PUT(updateRoleHandler), DELETE(deleteRoleHandler))
+
 	// Templates
 	router.Handle("/template", Auth(false), GET(getTemplatesHandler))
 	router.Handle("/template/add", NeedAdmin(true), POST(addTemplateHandler))
@@ -407,12 +478,13 @@ func (router *Router) init() {
 	// Workers
 	router.Handle("/worker", Auth(false), GET(getWorkersHandler), POST(registerWorkerHandler))
 	router.Handle("/worker/status", GET(getWorkerModelStatus))
-	router.Handle("/worker/refresh", POST(refreshWorkerHandler))
+	router.Handle("/worker/refresh", Audit(false), POST(refreshWorkerHandler))
 	router.Handle("/worker/unregister", POST(unregisterWorkerHandler))
+	router.Handle("/worker/capabilities", PUT(declareWorkerCapabilitiesHandler))
 	router.Handle("/worker/{id}/disable", POST(disableWorkerHandler))
 	router.Handle("/worker/model", POST(addWorkerModel), GET(getWorkerModels))
 	router.Handle("/worker/model/type", GET(getWorkerModelTypes))
-	router.Handle("/worker/model/{permModelID}", PUT(updateWorkerModel), DELETE(deleteWorkerModel))
+	router.Handle("/worker/model/{permModelID}", AuditBefore(auditWorkerModel), PUT(updateWorkerModel), DELETE(deleteWorkerModel))
 	router.Handle("/worker/model/{permModelID}/capability", POST(addWorkerModelCapa))
 	router.Handle("/worker/model/{permModelID}/instances", GET(getWorkerModelInstances))
 	router.Handle("/worker/model/capability/type", GET(getWorkerModelCapaTypes))
@@ -446,6 +518,9 @@ func init() {
 	flags.String("log-level", "notice", "Log Level : debug, info, notice, warning, critical")
 	viper.BindPFlag("log_level", flags.Lookup("log-level"))
 
+	flags.String("log-format", "text", "Log Format : text or json")
+	viper.BindPFlag("log_format", flags.Lookup("log-format"))
+
 	flags.Bool("db-logging", false, "Logging in Database: true of false")
 	viper.BindPFlag("db_logging", flags.Lookup("db-logging"))
 
@@ -458,16 +533,30 @@ func init() {
 	flags.String("listen-port", "8081", "CDS Engine Listen Port")
 	viper.BindPFlag("listen_port", flags.Lookup("listen-port"))
 
+	flags.Int("shutdown-delay", 5, "Time to keep serving requests on SIGTERM, for load balancers to see API is shutting down, in seconds")
+	viper.BindPFlag("shutdown_delay", flags.Lookup("shutdown-delay"))
+
+	flags.Int("shutdown-timeout", 30, "Time to drain requests in progress on SIGTERM, in seconds")
+	viper.BindPFlag("shutdown_timeout", flags.Lookup("shutdown-timeout"))
+
 	flags.String("artifact-mode", "filesystem", "Artifact Mode: openstack or filesystem")
 	flags.String("artifact-address", "", "Artifact Adress: used with --artifact-mode=openstask")
 	flags.String("artifact-user", "", "Artifact User: used with --artifact-mode=openstask")
 	flags.String("artifact-password", "", "Artifact Password: used with --artifact-mode=openstask")
 	flags.String("artifact-basedir", "/tmp", "Artifact Basedir: used with --artifact-mode=filesystem")
+	flags.Int("artifact-retention", 0, "Artifact retention, in days. Promoted artifacts are kept (0 to keep forever)")
 	viper.BindPFlag("artifact_mode", flags.Lookup("artifact-mode"))
 	viper.BindPFlag("artifact_address", flags.Lookup("artifact-address"))
 	viper.BindPFlag("artifact_user", flags.Lookup("artifact-user"))
 	viper.BindPFlag("artifact_password", flags.Lookup("artifact-password"))
 	viper.BindPFlag("artifact_basedir", flags.Lookup("artifact-basedir"))
+	viper.BindPFlag("artifact_retention", flags.Lookup("artifact-retention"))
+
+	flags.String("tracing-exporter", "", "Export traces of pipeline builds to an OpenTelemetry collector (http://collector:4318) or a file (file:///var/log/cds/traces.json), disabled if empty")
+	viper.BindPFlag("tracing_exporter", flags.Lookup("tracing-exporter"))
+
+	flags.Int64("build-cache-max-size", 2048, "Default size limit of build caches of a project, in MB")
+	viper.BindPFlag("build_cache_max_size", flags.Lookup("build-cache-max-size"))
 
 	flags.Bool("no-smtp", true, "No SMTP mode: true or false")
 	flags.String("smtp-host", "", "SMTP Host")
@@ -523,31 +612,76 @@ func init() {
 	flags.String("ldap-user-fullname", "{{.givenName}} {{.sn}}", "LDAP User fullname")
 	viper.BindPFlag("ldap_user_fullname", flags.Lookup("ldap-user-fullname"))
 
-	flags.String("secret-backend", "", "Secret Backend plugin")
+	flags.String("ldap-group-filter", "(&(objectClass=posixGroup)(cn=%s))", "LDAP Group search filter")
+	viper.BindPFlag("ldap_group_filter", flags.Lookup("ldap-group-filter"))
+
+	flags.String("ldap-group-member-attribute", "memberUid", "LDAP Group attribute listing members")
+	viper.BindPFlag("ldap_group_member_attribute", flags.Lookup("ldap-group-member-attribute"))
+
+	flags.StringSlice("ldap-group-mapping", []string{}, "LDAP Group to CDS Group mapping: ldapgroup:cdsgroup or ldapgroup:cdsgroup:admin")
+	viper.BindPFlag("ldap_group_mapping", flags.Lookup("ldap-group-mapping"))
+
+	flags.Int("ldap-group-sync-interval", 3600, "Interval of LDAP Group synchronization, in seconds (0 to disable)")
+	viper.BindPFlag("ldap_group_sync_interval", flags.Lookup("ldap-group-sync-interval"))
+
+	flags.String("audit-file", "", "Audit log file sink, one JSON event per line (disabled if empty)")
+	viper.BindPFlag("audit_file", flags.Lookup("audit-file"))
+
+	flags.Int("audit-retention", 90, "Audit log retention in database, in days (0 to keep forever)")
+	viper.BindPFlag("audit_retention", flags.Lookup("audit-retention"))
+
+	flags.StringSlice("trusted-proxies", []string{}, "Addresses or networks of reverse proxies allowed to set X-Forwarded-For, recorded in audit log (10.0.0.0/8,127.0.0.1)")
+	viper.BindPFlag("trusted_proxies", flags.Lookup("trusted-proxies"))
+
+	flags.String("secret-backend", "", "Secret Backend plugin binary, or vault for embedded Vault Secret Backend")
 	viper.BindPFlag("secret_backend", flags.Lookup("secret-backend"))
 
 	flags.StringSlice("secret-backend-option", []string{}, "Secret Backend plugin options")
 	viper.BindPFlag("secret_backend_option", flags.Lookup("secret-backend-option"))
 
+	flags.Int("secret-watch-interval", 60, "Interval between two checks of secrets changes, in seconds (0 to disable)")
+	viper.BindPFlag("secret_watch_interval", flags.Lookup("secret-watch-interval"))
+
 	flags.String("redis-host", "localhost:6379", "Redis hostname")
 	viper.BindPFlag("redis_host", flags.Lookup("redis-host"))
 
 	flags.String("redis-password", "", "Redis password")
 	viper.BindPFlag("redis_password", flags.Lookup("redis-password"))
 
-	flags.String("cache", "local", "Cache : local|redis")
+	flags.String("cache", "local", "Cache : local|redis|twolevel")
 	viper.BindPFlag("cache", flags.Lookup("cache"))
 
+	flags.Int("cache-local-size", 10000, "Maximum number of values kept in memory by twolevel cache")
+	viper.BindPFlag("cache_local_size", flags.Lookup("cache-local-size"))
+
+	flags.Int("cache-local-ttl", 60, "Time values are kept in memory by twolevel cache (seconds)")
+	viper.BindPFlag("cache_local_ttl", flags.Lookup("cache-local-ttl"))
+
 	flags.Int("cache-ttl", 600, "Cache Time to Live (seconds)")
 	viper.BindPFlag("cache_ttl", flags.Lookup("cache-ttl"))
 
 	flags.Int("session-ttl", 60, "Session Time to Live (minutes)")
 	viper.BindPFlag("session_ttl", flags.Lookup("session-ttl"))
 
+	database.DBCmd.AddCommand(rotateSecretsCmd)
 	mainCmd.AddCommand(database.DBCmd)
 
 }
 
+// parseSecretBackendOptions parses options formatted as key=value
+func parseSecretBackendOptions(opts []string) map[string]string {
+	res := map[string]string{}
+	for _, o := range opts {
+		if !strings.Contains(o, "=") {
+			log.Warning("Malformated options : %s", o)
+			continue
+		}
+		t := strings.Split(o, "=")
+		res[t[0]] = t[1]
+	}
+	return res
+}
+
 func main() {
 	mainCmd.Execute()
 }
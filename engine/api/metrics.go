@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ovh/cds/engine/api/context"
+	"github.com/ovh/cds/engine/metrics"
+	"github.com/ovh/cds/sdk/tracing"
+)
+
+var requestDuration = metrics.NewHistogram("cds_api_http_request_duration_seconds",
+	"Latency of API requests by route, method and status code", metrics.DefaultBuckets, "route", "method", "code")
+
+func metricsHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	metrics.Handler().ServeHTTP(w, r)
+}
+
+// statusRecorder captures response status of a request for metrics
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return s.ResponseWriter.Write(b)
+}
+
+// Flush keeps server sent events working through the recorder
+func (s *statusRecorder) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// CloseNotify keeps server sent events working through the recorder
+func (s *statusRecorder) CloseNotify() <-chan bool {
+	if n, ok := s.ResponseWriter.(http.CloseNotifier); ok {
+		return n.CloseNotify()
+	}
+	return make(chan bool)
+}
+
+// Hijack keeps websockets working through the recorder
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := s.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer cannot be hijacked")
+	}
+	if s.status == 0 {
+		s.status = http.StatusSwitchingProtocols
+	}
+	return h.Hijack()
+}
+
+// observe records latency of a request on its route
+func (s *statusRecorder) observe(route string, req *http.Request, start time.Time) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	requestDuration.Since(start, route, req.Method, strconv.Itoa(s.status))
+}
+
+// trace ends span of a request with its status
+func (s *statusRecorder) trace(span *tracing.Span) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	span.SetAttribute("http.status_code", s.status)
+	if s.status >= 500 {
+		span.SetError(fmt.Errorf("HTTP %d", s.status))
+	}
+	span.Finish()
+}
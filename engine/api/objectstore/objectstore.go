@@ -3,12 +3,17 @@ package objectstore
 import (
 	"fmt"
 	"io"
+	"time"
 
+	"github.com/ovh/cds/engine/metrics"
 	"github.com/ovh/cds/sdk"
 )
 
 var storage Driver
 
+var requestDuration = metrics.NewHistogram("cds_objectstore_request_duration_seconds",
+	"Latency of objectstore operations", metrics.DefaultBuckets, "operation", "result")
+
 //Status is for status handler
 func Status() string {
 	if storage == nil {
@@ -90,6 +95,54 @@ func DeleteTemplateExtension(tmpl sdk.TemplateExtension) error {
 	return fmt.Errorf("store not initialized")
 }
 
+//StoreCache call Store on the common driver
+func StoreCache(c sdk.Cache, data io.ReadCloser) (string, error) {
+	if storage != nil {
+		return storage.Store(&c, data)
+	}
+	return "", fmt.Errorf("store not initialized")
+}
+
+//FetchCache call Fetch on the common driver
+func FetchCache(c sdk.Cache) (io.ReadCloser, error) {
+	if storage != nil {
+		return storage.Fetch(&c)
+	}
+	return nil, fmt.Errorf("store not initialized")
+}
+
+//DeleteCache call Delete on the common driver
+func DeleteCache(c sdk.Cache) error {
+	if storage != nil {
+		return storage.Delete(&c)
+	}
+	return fmt.Errorf("store not initialized")
+}
+
+//StoreArtifactChunk call Store on the common driver
+func StoreArtifactChunk(c sdk.ArtifactChunk, data io.ReadCloser) (string, error) {
+	if storage != nil {
+		return storage.Store(&c, data)
+	}
+	return "", fmt.Errorf("store not initialized")
+}
+
+//FetchArtifactChunk call Fetch on the common driver
+func FetchArtifactChunk(c sdk.ArtifactChunk) (io.ReadCloser, error) {
+	if storage != nil {
+		return storage.Fetch(&c)
+	}
+	return nil, fmt.Errorf("store not initialized")
+}
+
+//DeleteArtifactChunk call Delete on the common driver
+func DeleteArtifactChunk(c sdk.ArtifactChunk) error {
+	if storage != nil {
+		return storage.Delete(&c)
+	}
+	return fmt.Errorf("store not initialized")
+}
+
 // Driver allows artifact to be stored and retrieve the same way to any backend
 // - Openstack ObjectStore
 // - Filesystem
@@ -102,15 +155,49 @@ type Driver interface {
 
 // Initialize setup wanted ObjectStore driver
 func Initialize(mode, address, user, password, basedir string) error {
-	var err error
-	storage, err = New(mode, address, user, password, basedir)
+	d, err := New(mode, address, user, password, basedir)
 	if err != nil {
 		return err
 	}
 
+	storage = &instrumentedDriver{Driver: d}
 	return nil
 }
 
+// instrumentedDriver records latency of each operation of a driver
+type instrumentedDriver struct {
+	Driver
+}
+
+func (d *instrumentedDriver) Store(o Object, data io.ReadCloser) (string, error) {
+	start := time.Now()
+	path, err := d.Driver.Store(o, data)
+	requestDuration.Since(start, "store", result(err))
+	return path, err
+}
+
+// Fetch latency is the time to first byte, content is streamed afterwards
+func (d *instrumentedDriver) Fetch(o Object) (io.ReadCloser, error) {
+	start := time.Now()
+	rc, err := d.Driver.Fetch(o)
+	requestDuration.Since(start, "fetch", result(err))
+	return rc, err
+}
+
+func (d *instrumentedDriver) Delete(o Object) error {
+	start := time.Now()
+	err := d.Driver.Delete(o)
+	requestDuration.Since(start, "delete", result(err))
+	return err
+}
+
+func result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
+
 // New initialise a new ArtifactStorage
 func New(mode, address, user, password, basedir string) (Driver, error) {
 	switch mode {
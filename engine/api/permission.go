@@ -1,18 +1,20 @@
 package main
 
 import (
+	"net/http"
 	"strconv"
 
+	"github.com/gorilla/mux"
+
 	"github.com/ovh/cds/engine/api/context"
 	"github.com/ovh/cds/engine/api/database"
-	"github.com/ovh/cds/engine/api/permission"
 	"github.com/ovh/cds/engine/api/worker"
 	"github.com/ovh/cds/engine/log"
 	"github.com/ovh/cds/sdk"
 )
 
-// PermCheckFunc defines func call to check permission
-type PermCheckFunc func(key string, c *context.Context, permission int, routeVar map[string]string) bool
+// PermCheckFunc defines func call to check the capability on a resource
+type PermCheckFunc func(key string, c *context.Context, capability string, routeVar map[string]string) bool
 
 var permissionMapFunction = initPermissionFunc()
 
@@ -29,28 +31,43 @@ func initPermissionFunc() map[string]PermCheckFunc {
 	}
 }
 
-func getPermissionByMethod(method string, isExecution bool) int {
+func getCapabilityByMethod(method string, rc *routerConfig) string {
 	switch method {
 	case "POST":
-		if isExecution {
-			return permission.PermissionReadExecute
+		if rc.isExecution {
+			return sdk.CapabilityExecute
+		}
+		if rc.writeCapability != "" {
+			return rc.writeCapability
+		}
+		return sdk.CapabilityWrite
+	case "PUT", "DELETE":
+		if rc.writeCapability != "" {
+			return rc.writeCapability
 		}
-		return permission.PermissionReadWriteExecute
-	case "PUT":
-		return permission.PermissionReadWriteExecute
-	case "DELETE":
-		return permission.PermissionReadWriteExecute
+		return sdk.CapabilityWrite
 	default:
-		return permission.PermissionRead
+		if rc.readCapability != "" {
+			return rc.readCapability
+		}
+		return sdk.CapabilityRead
 	}
 }
 
-func checkPermission(routeVar map[string]string, c *context.Context, permission int) bool {
+// hasCapability checks the role granted to a group, links without role use their legacy permission level
+func hasCapability(r *sdk.Role, perm int, capability string) bool {
+	if r == nil {
+		r = sdk.RoleFromPermission(perm)
+	}
+	return r.Has(capability)
+}
+
+func checkPermission(routeVar map[string]string, c *context.Context, capability string) bool {
 	permissionOk := true
 	for key, value := range routeVar {
 		if permFunc, ok := permissionMapFunction[key]; ok {
 			log.Info("Check permission for %s", key)
-			permissionOk = permFunc(value, c, permission, routeVar)
+			permissionOk = permFunc(value, c, capability, routeVar)
 			if !permissionOk {
 				return permissionOk
 			}
@@ -59,11 +76,19 @@ func checkPermission(routeVar map[string]string, c *context.Context, permission
 	return permissionOk
 }
 
-func checkProjectPermissions(projectKey string, c *context.Context, permission int, routeVar map[string]string) bool {
+// canReadSecrets checks the read_secrets capability on resources of the route
+func canReadSecrets(r *http.Request, c *context.Context) bool {
+	if c.User.Admin {
+		return true
+	}
+	return checkPermission(mux.Vars(r), c, sdk.CapabilityReadSecrets)
+}
+
+func checkProjectPermissions(projectKey string, c *context.Context, capability string, routeVar map[string]string) bool {
 	if c.User.Groups != nil {
 		for _, g := range c.User.Groups {
 			for _, p := range g.ProjectGroups {
-				if projectKey == p.Project.Key && p.Permission >= permission {
+				if projectKey == p.Project.Key && hasCapability(p.Role, p.Permission, capability) {
 					return true
 				}
 			}
@@ -73,12 +98,12 @@ func checkProjectPermissions(projectKey string, c *context.Context, permission i
 	return false
 }
 
-func checkPipelinePermissions(pipelineName string, c *context.Context, permission int, routeVar map[string]string) bool {
+func checkPipelinePermissions(pipelineName string, c *context.Context, capability string, routeVar map[string]string) bool {
 	// Check if param key exist
 	if projectKey, ok := routeVar["key"]; ok {
 		for _, g := range c.User.Groups {
 			for _, p := range g.PipelineGroups {
-				if pipelineName == p.Pipeline.Name && p.Permission >= permission && projectKey == p.Pipeline.ProjectKey {
+				if pipelineName == p.Pipeline.Name && hasCapability(p.Role, p.Permission, capability) && projectKey == p.Pipeline.ProjectKey {
 					return true
 				}
 			}
@@ -90,13 +115,13 @@ func checkPipelinePermissions(pipelineName string, c *context.Context, permissio
 	return false
 }
 
-func checkEnvironmentPermissions(envName string, c *context.Context, permission int, routeVar map[string]string) bool {
+func checkEnvironmentPermissions(envName string, c *context.Context, capability string, routeVar map[string]string) bool {
 	// Check if param key exist
 	if projectKey, ok := routeVar["key"]; ok {
 		if c.User.Groups != nil {
 			for _, g := range c.User.Groups {
 				for _, p := range g.EnvironmentGroups {
-					if envName == p.Environment.Name && p.Permission >= permission && projectKey == p.Environment.ProjectKey {
+					if envName == p.Environment.Name && hasCapability(p.Role, p.Permission, capability) && projectKey == p.Environment.ProjectKey {
 						return true
 					}
 				}
@@ -109,13 +134,13 @@ func checkEnvironmentPermissions(envName string, c *context.Context, permission
 	return false
 }
 
-func checkApplicationPermissions(applicationName string, c *context.Context, permission int, routeVar map[string]string) bool {
+func checkApplicationPermissions(applicationName string, c *context.Context, capability string, routeVar map[string]string) bool {
 	// Check if param key exist
 	if projectKey, ok := routeVar["key"]; ok {
 		if c.User.Groups != nil {
 			for _, g := range c.User.Groups {
 				for _, a := range g.ApplicationGroups {
-					if applicationName == a.Application.Name && a.Permission >= permission && projectKey == a.Application.ProjectKey {
+					if applicationName == a.Application.Name && hasCapability(a.Role, a.Permission, capability) && projectKey == a.Application.ProjectKey {
 						return true
 					}
 				}
@@ -128,7 +153,7 @@ func checkApplicationPermissions(applicationName string, c *context.Context, per
 	return false
 }
 
-func checkApplicationIDPermissions(appIDS string, c *context.Context, permission int, routeVar map[string]string) bool {
+func checkApplicationIDPermissions(appIDS string, c *context.Context, capability string, routeVar map[string]string) bool {
 
 	appID, err := strconv.ParseInt(appIDS, 10, 64)
 	if err != nil {
@@ -139,7 +164,7 @@ func checkApplicationIDPermissions(appIDS string, c *context.Context, permission
 	if c.User.Groups != nil {
 		for _, g := range c.User.Groups {
 			for _, a := range g.ApplicationGroups {
-				if appID == a.Application.ID && a.Permission >= permission {
+				if appID == a.Application.ID && hasCapability(a.Role, a.Permission, capability) {
 					return true
 				}
 			}
@@ -150,11 +175,11 @@ func checkApplicationIDPermissions(appIDS string, c *context.Context, permission
 	return false
 }
 
-func checkGroupPermissions(groupName string, c *context.Context, permissionValue int, routeVar map[string]string) bool {
+func checkGroupPermissions(groupName string, c *context.Context, capability string, routeVar map[string]string) bool {
 	for _, g := range c.User.Groups {
 		if g.Name == groupName {
 
-			if permissionValue == permission.PermissionRead {
+			if capability == sdk.CapabilityRead {
 				return true
 			}
 
@@ -169,19 +194,19 @@ func checkGroupPermissions(groupName string, c *context.Context, permissionValue
 	return false
 }
 
-func checkActionPermissions(groupName string, c *context.Context, permissionValue int, routeVar map[string]string) bool {
-	if permissionValue == permission.PermissionRead {
+func checkActionPermissions(groupName string, c *context.Context, capability string, routeVar map[string]string) bool {
+	if capability == sdk.CapabilityRead {
 		return true
 	}
 
-	if permissionValue != permission.PermissionRead && c.User.Admin {
+	if c.User.Admin {
 		return true
 	}
 
 	return false
 }
 
-func checkWorkerModelPermissions(modelID string, c *context.Context, permissionValue int, routeVar map[string]string) bool {
+func checkWorkerModelPermissions(modelID string, c *context.Context, capability string, routeVar map[string]string) bool {
 	id, err := strconv.ParseInt(modelID, 10, 64)
 	if err != nil {
 		log.Warning("checkWorkerModelPermissions> modelID is not an integer: %s\n", err)
@@ -199,10 +224,10 @@ func checkWorkerModelPermissions(modelID string, c *context.Context, permissionV
 		return false
 	}
 
-	return checkWorkerModelPermissionsByUser(m, c.User, permissionValue)
+	return checkWorkerModelPermissionsByUser(m, c.User, capability)
 }
 
-func checkWorkerModelPermissionsByUser(m *sdk.Model, u *sdk.User, permissionValue int) bool {
+func checkWorkerModelPermissionsByUser(m *sdk.Model, u *sdk.User, capability string) bool {
 	if u.Admin {
 		return true
 	}
@@ -215,7 +240,7 @@ func checkWorkerModelPermissionsByUser(m *sdk.Model, u *sdk.User, permissionValu
 				}
 			}
 
-			if permissionValue == permission.PermissionRead {
+			if capability == sdk.CapabilityRead {
 				return true
 			}
 		}
@@ -196,3 +196,101 @@ func ApplicationPipelineEnvironmentUsers(db database.Querier, appID, pipID, envI
 	}
 	return users, nil
 }
+
+// Resource types explained by Explain
+const (
+	ResourceProject     = "project"
+	ResourceApplication = "application"
+	ResourcePipeline    = "pipeline"
+	ResourceEnvironment = "environment"
+)
+
+// Explain details which groups of the user grant, or fail to grant, the capability on
+// the project and on given application, pipeline and environment. Empty names are skipped
+func Explain(u *sdk.User, capability, projectKey, appName, pipName, envName string) *sdk.AccessExplanation {
+	e := &sdk.AccessExplanation{
+		Username:   u.Username,
+		Capability: capability,
+		Admin:      u.Admin,
+		Resources:  []sdk.AccessCheck{},
+	}
+
+	add := func(t, name string, grants []sdk.AccessGrant) {
+		check := sdk.AccessCheck{Type: t, Name: name, Grants: grants}
+		for _, g := range grants {
+			if g.Role.Has(capability) {
+				check.Granted = true
+			}
+		}
+		e.Resources = append(e.Resources, check)
+	}
+
+	grant := func(g sdk.Group, r *sdk.Role, perm int) sdk.AccessGrant {
+		if r == nil {
+			r = sdk.RoleFromPermission(perm)
+		}
+		return sdk.AccessGrant{Group: g.Name, Role: *r}
+	}
+
+	grants := []sdk.AccessGrant{}
+	for _, g := range u.Groups {
+		for _, pg := range g.ProjectGroups {
+			if pg.Project.Key == projectKey {
+				grants = append(grants, grant(g, pg.Role, pg.Permission))
+			}
+		}
+	}
+	add(ResourceProject, projectKey, grants)
+
+	if appName != "" {
+		grants := []sdk.AccessGrant{}
+		for _, g := range u.Groups {
+			for _, ag := range g.ApplicationGroups {
+				if ag.Application.ProjectKey == projectKey && ag.Application.Name == appName {
+					grants = append(grants, grant(g, ag.Role, ag.Permission))
+				}
+			}
+		}
+		add(ResourceApplication, appName, grants)
+	}
+
+	if pipName != "" {
+		grants := []sdk.AccessGrant{}
+		for _, g := range u.Groups {
+			for _, pg := range g.PipelineGroups {
+				if pg.Pipeline.ProjectKey == projectKey && pg.Pipeline.Name == pipName {
+					grants = append(grants, grant(g, pg.Role, pg.Permission))
+				}
+			}
+		}
+		add(ResourcePipeline, pipName, grants)
+	}
+
+	if envName != "" {
+		grants := []sdk.AccessGrant{}
+		for _, g := range u.Groups {
+			for _, eg := range g.EnvironmentGroups {
+				if eg.Environment.ProjectKey == projectKey && eg.Environment.Name == envName {
+					grants = append(grants, grant(g, eg.Role, eg.Permission))
+				}
+			}
+		}
+		add(ResourceEnvironment, envName, grants)
+	}
+
+	// As in the router, routes on project children don't check project permission
+	checks := e.Resources
+	if len(checks) > 1 {
+		checks = checks[1:]
+	}
+	e.Granted = true
+	for _, r := range checks {
+		if !r.Granted {
+			e.Granted = false
+		}
+	}
+	if u.Admin {
+		e.Granted = true
+	}
+	return e
+}
@@ -0,0 +1,54 @@
+package permission
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func TestExplain(t *testing.T) {
+	editor := &sdk.Role{Name: "variable-editor", Capabilities: []string{sdk.CapabilityRead, sdk.CapabilityEditVariables}}
+	u := &sdk.User{
+		Username: "john",
+		Groups: []sdk.Group{
+			{
+				Name: "dev",
+				ProjectGroups: []sdk.ProjectGroup{
+					{Project: sdk.Project{Key: "KEY"}, Permission: PermissionRead},
+				},
+				ApplicationGroups: []sdk.ApplicationGroup{
+					{Application: sdk.Application{ProjectKey: "KEY", Name: "app"}, Permission: PermissionRead, Role: editor},
+				},
+			},
+			{
+				Name: "ops",
+				ApplicationGroups: []sdk.ApplicationGroup{
+					{Application: sdk.Application{ProjectKey: "KEY", Name: "app"}, Permission: PermissionReadExecute},
+				},
+			},
+		},
+	}
+
+	e := Explain(u, sdk.CapabilityEditVariables, "KEY", "app", "", "")
+	assert.True(t, e.Granted)
+	assert.Len(t, e.Resources, 2)
+	assert.Equal(t, ResourceProject, e.Resources[0].Type)
+	assert.False(t, e.Resources[0].Granted)
+	assert.Equal(t, ResourceApplication, e.Resources[1].Type)
+	assert.True(t, e.Resources[1].Granted)
+	assert.Len(t, e.Resources[1].Grants, 2)
+	assert.Equal(t, "read-execute", e.Resources[1].Grants[1].Role.Name)
+
+	e = Explain(u, sdk.CapabilityWrite, "KEY", "app", "", "")
+	assert.False(t, e.Granted)
+
+	e = Explain(u, sdk.CapabilityRead, "KEY", "", "", "")
+	assert.True(t, e.Granted)
+	assert.Len(t, e.Resources, 1)
+
+	u.Admin = true
+	e = Explain(u, sdk.CapabilityWrite, "OTHER", "", "", "")
+	assert.True(t, e.Granted)
+}
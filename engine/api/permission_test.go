@@ -11,7 +11,7 @@ func Test_checkWorkerModelPermissionsByUser(t *testing.T) {
 	type args struct {
 		m *sdk.Model
 		u *sdk.User
-		p int
+		p string
 	}
 	tests := []struct {
 		name string
@@ -27,7 +27,7 @@ func Test_checkWorkerModelPermissionsByUser(t *testing.T) {
 				u: &sdk.User{
 					Admin: true,
 				},
-				p: 7,
+				p: sdk.CapabilityWrite,
 			},
 			want: true,
 		},
@@ -45,7 +45,7 @@ func Test_checkWorkerModelPermissionsByUser(t *testing.T) {
 						},
 					},
 				},
-				p: 4,
+				p: sdk.CapabilityRead,
 			},
 			want: true,
 		},
@@ -63,7 +63,7 @@ func Test_checkWorkerModelPermissionsByUser(t *testing.T) {
 						},
 					},
 				},
-				p: 7,
+				p: sdk.CapabilityWrite,
 			},
 			want: false,
 		},
@@ -81,7 +81,7 @@ func Test_checkWorkerModelPermissionsByUser(t *testing.T) {
 						},
 					},
 				},
-				p: 7,
+				p: sdk.CapabilityWrite,
 			},
 			want: false,
 		},
@@ -105,7 +105,7 @@ func Test_checkWorkerModelPermissionsByUser(t *testing.T) {
 						},
 					},
 				},
-				p: 7,
+				p: sdk.CapabilityWrite,
 			},
 			want: true,
 		},
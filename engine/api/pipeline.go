@@ -393,7 +393,15 @@ func runPipelineHandlerFunc(w http.ResponseWriter, r *http.Request, db *sql.DB,
 		trigger.VCSChangesBranch = parentPipelineBuild.Trigger.VCSChangesBranch
 	}
 
-	pb, err := scheduler.Run(tx, projectKey, app, pipelineName, envDest.Name, request.Params, version, trigger, c.User)
+	// Logs of the pipeline build, its workers and triggered pipeline builds are correlated with this request
+	params := []sdk.Parameter{{Name: sdk.RequestIDParameter, Type: sdk.StringParameter, Value: c.RequestID}}
+	for _, p := range request.Params {
+		if p.Name != sdk.RequestIDParameter {
+			params = append(params, p)
+		}
+	}
+
+	pb, err := scheduler.Run(tx, projectKey, app, pipelineName, envDest.Name, params, version, trigger, c.User)
 	if err != nil {
 		log.Warning("runPipelineHandler> Cannot run pipeline: %s\n", err)
 		WriteError(w, r, err)
@@ -652,6 +660,10 @@ func updatePipelineHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c
 
 	pipelineDB.Name = p.Name
 	pipelineDB.Type = p.Type
+	//If the pipeline priority has not been set, keep the old priority
+	if p.Priority != nil {
+		pipelineDB.Priority = p.Priority
+	}
 
 	err = pipeline.UpdatePipeline(db, pipelineDB)
 	if err != nil {
@@ -6,6 +6,7 @@ import (
 
 	"github.com/ovh/cds/engine/api/build"
 	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/api/leader"
 	"github.com/ovh/cds/engine/log"
 	"github.com/ovh/cds/sdk"
 )
@@ -21,7 +22,7 @@ func AWOLPipelineKiller() {
 		time.Sleep(1 * time.Minute)
 		db := database.DB()
 
-		if db != nil {
+		if db != nil && leader.IsLeader() {
 			ids, err := loadAWOLActionBuild(db)
 			if err != nil {
 				log.Warning("AWOLPipelineKiller> Cannot load awol building actions: %s\n", err)
@@ -8,6 +8,7 @@ import (
 	"github.com/ovh/cds/engine/api/build"
 	"github.com/ovh/cds/engine/api/cache"
 	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/api/role"
 	"github.com/ovh/cds/engine/api/trigger"
 	"github.com/ovh/cds/sdk"
 )
@@ -53,11 +54,12 @@ func LoadPipeline(db database.Querier, projectKey, name string, deep bool) (*sdk
 
 	var pType string
 	var lastModified time.Time
-	query := `SELECT pipeline.id, pipeline.name, pipeline.project_id, pipeline.type, pipeline.last_modified FROM pipeline
+	var priority sql.NullInt64
+	query := `SELECT pipeline.id, pipeline.name, pipeline.project_id, pipeline.type, pipeline.last_modified, pipeline.priority FROM pipeline
 	 		JOIN project on pipeline.project_id = project.id
 	 		WHERE pipeline.name = $1 AND project.projectKey = $2`
 
-	err := db.QueryRow(query, name, projectKey).Scan(&p.ID, &p.Name, &p.ProjectID, &pType, &lastModified)
+	err := db.QueryRow(query, name, projectKey).Scan(&p.ID, &p.Name, &p.ProjectID, &pType, &lastModified, &priority)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, sdk.ErrPipelineNotFound
@@ -67,6 +69,10 @@ func LoadPipeline(db database.Querier, projectKey, name string, deep bool) (*sdk
 	p.LastModified = lastModified.Unix()
 	p.Type = sdk.PipelineTypeFromString(pType)
 	p.ProjectKey = projectKey
+	if priority.Valid {
+		v := int(priority.Int64)
+		p.Priority = &v
+	}
 
 	if deep {
 		// load pipeline actions by stage
@@ -272,9 +278,10 @@ func LoadPipelines(db database.Querier, projectID int64, loadDependencies bool,
 
 // LoadPipelineByGroup loads all pipelines where group has access
 func LoadPipelineByGroup(db database.Querier, group *sdk.Group) error {
-	query := `SELECT project.projectKey, pipeline.id, pipeline.name,pipeline_group.role FROM pipeline
+	query := `SELECT project.projectKey, pipeline.id, pipeline.name,pipeline_group.role, role.name, role.capabilities FROM pipeline
 	 		  JOIN pipeline_group ON pipeline_group.pipeline_id = pipeline.id
 	 		  JOIN project ON pipeline.project_id = project.id
+	 		  LEFT JOIN role ON role.id = pipeline_group.role_id
 	 		  WHERE pipeline_group.group_id = $1 ORDER BY pipeline.name ASC`
 	rows, err := db.Query(query, group.ID)
 	if err != nil {
@@ -285,13 +292,19 @@ func LoadPipelineByGroup(db database.Querier, group *sdk.Group) error {
 	for rows.Next() {
 		var pipeline sdk.Pipeline
 		var perm int
-		err = rows.Scan(&pipeline.ProjectKey, &pipeline.ID, &pipeline.Name, &perm)
+		var roleName, roleCapabilities sql.NullString
+		err = rows.Scan(&pipeline.ProjectKey, &pipeline.ID, &pipeline.Name, &perm, &roleName, &roleCapabilities)
+		if err != nil {
+			return err
+		}
+		r, err := role.Granted(roleName, roleCapabilities, perm)
 		if err != nil {
 			return err
 		}
 		group.PipelineGroups = append(group.PipelineGroups, sdk.PipelineGroup{
 			Pipeline:   pipeline,
 			Permission: perm,
+			Role:       r,
 		})
 	}
 	return nil
@@ -348,8 +361,12 @@ func UpdatePipeline(db database.Executer, p *sdk.Pipeline) error {
 	}
 
 	//Update pipeline
-	query = `UPDATE pipeline SET name=$1, type=$2, last_modified = current_timestamp WHERE id=$3`
-	_, err = db.Exec(query, p.Name, string(p.Type), p.ID)
+	var priority interface{}
+	if p.Priority != nil {
+		priority = *p.Priority
+	}
+	query = `UPDATE pipeline SET name=$1, type=$2, priority=$3, last_modified = current_timestamp WHERE id=$4`
+	_, err = db.Exec(query, p.Name, string(p.Type), priority, p.ID)
 	return err
 }
 
@@ -507,6 +507,9 @@ func UpdatePipelineBuildStatus(db database.QueryExecuter, pb sdk.PipelineBuild,
 	}
 
 	pb.Status = status
+	if status == sdk.StatusSuccess || status == sdk.StatusFail {
+		traceBuildEnd(pb, status)
+	}
 
 	//Send notification
 	//Load previous pipeline (some app, pip, env and branch)
@@ -872,6 +875,9 @@ func InsertPipelineBuild(tx *sql.Tx, project *sdk.Project, p *sdk.Pipeline, appl
 		Value: strconv.FormatInt(pb.Version, 10),
 		Type:  sdk.StringParameter,
 	})
+	if p, ok := traceParameter(params); ok {
+		params = append(params, p)
+	}
 	if pb.Trigger.TriggeredBy != nil {
 		//Load user information to store them as args
 		params = append(params, sdk.Parameter{
@@ -1401,9 +1407,8 @@ func RestartPipelineBuild(db *sql.DB, pb sdk.PipelineBuild) error {
 			}
 		}
 
-		// Delete test results
-		err = build.DeletePipelineTestResults(db, pb.ID)
-		if err != nil {
+		// Delete test results, test case results are kept as a previous attempt
+		if err := build.RestartTestResults(tx, pb.ID); err != nil {
 			return err
 		}
 
@@ -1734,14 +1739,16 @@ func LoadBuildIDsToArchive(db *sql.DB, hours int) ([]int64, error) {
 	return buildIDs, nil
 }
 
-// DeletePipelineBuildArtifact Delete artifact for the current build
+// DeletePipelineBuildArtifact Delete artifact for the current build.
+// Promoted artifacts are kept, they are deleted with their pipeline, application or environment.
 func DeletePipelineBuildArtifact(db database.QueryExecuter, pipelineBuildID int64) error {
 	// Delete pipeline build artifacts
 	query := `SELECT artifact.id FROM artifact
 	WHERE artifact.application_id IN (SELECT application_id FROM pipeline_build WHERE id = $1)
 	AND artifact.environment_id IN (SELECT environment_id FROM pipeline_build WHERE id = $1)
 	AND artifact.pipeline_id IN (SELECT pipeline_id FROM pipeline_build WHERE id = $1)
-	AND artifact.build_number IN (SELECT build_number FROM pipeline_build WHERE id = $1);
+	AND artifact.build_number IN (SELECT build_number FROM pipeline_build WHERE id = $1)
+	AND artifact.promoted = false;
 	`
 	rows, err := db.Query(query, pipelineBuildID)
 	if err != nil {
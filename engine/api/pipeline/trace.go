@@ -0,0 +1,39 @@
+package pipeline
+
+import (
+	"fmt"
+
+	"github.com/ovh/cds/sdk"
+	"github.com/ovh/cds/sdk/tracing"
+)
+
+// traceParameter returns the parameter carrying trace context of a new pipeline build,
+// in the trace of its parent pipeline build if any
+func traceParameter(params []sdk.Parameter) (sdk.Parameter, bool) {
+	if !tracing.Enabled() {
+		return sdk.Parameter{}, false
+	}
+	sc := tracing.NewContext(sdk.ParameterValue(params, tracing.ParentParameter))
+	return sdk.Parameter{Name: tracing.Parameter, Value: sc.TraceParent(), Type: sdk.StringParameter}, true
+}
+
+// traceBuildEnd exports the span of a pipeline build, from its start to now
+func traceBuildEnd(pb sdk.PipelineBuild, status sdk.Status) {
+	sc, ok := tracing.ParseTraceParent(sdk.ParameterValue(pb.Parameters, tracing.Parameter))
+	if !ok || pb.Start.IsZero() {
+		return
+	}
+	parent, _ := tracing.ParseTraceParent(sdk.ParameterValue(pb.Parameters, tracing.ParentParameter))
+
+	span := tracing.Restore("pipeline build", sc, parent.SpanID, pb.Start)
+	span.SetAttribute("cds.project", pb.Pipeline.ProjectKey)
+	span.SetAttribute("cds.application", pb.Application.Name)
+	span.SetAttribute("cds.pipeline", pb.Pipeline.Name)
+	span.SetAttribute("cds.environment", pb.Environment.Name)
+	span.SetAttribute("cds.build_number", pb.BuildNumber)
+	span.SetAttribute("cds.status", status)
+	if status == sdk.StatusFail {
+		span.SetError(fmt.Errorf("pipeline build %s", status))
+	}
+	span.Finish()
+}
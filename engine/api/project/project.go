@@ -9,11 +9,13 @@ import (
 	"github.com/lib/pq"
 
 	"github.com/ovh/cds/engine/api/application"
+	"github.com/ovh/cds/engine/api/buildcache"
 	"github.com/ovh/cds/engine/api/database"
 	"github.com/ovh/cds/engine/api/environment"
 	"github.com/ovh/cds/engine/api/group"
 	"github.com/ovh/cds/engine/api/keys"
 	"github.com/ovh/cds/engine/api/pipeline"
+	"github.com/ovh/cds/engine/api/role"
 	"github.com/ovh/cds/engine/log"
 	"github.com/ovh/cds/sdk"
 )
@@ -50,9 +52,10 @@ func WithApplications(historylength int) Mod {
 // LoadProjectByGroup loads all projects where group has access
 func LoadProjectByGroup(db database.Querier, group *sdk.Group) error {
 	query := `
-		SELECT project.projectKey, project.name, project.last_modified, project_group.role
+		SELECT project.projectKey, project.name, project.last_modified, project_group.role, role.name, role.capabilities
 		FROM project
 	 	JOIN project_group ON project_group.project_id = project.id
+	 	LEFT JOIN role ON role.id = project_group.role_id
 	 	WHERE project_group.group_id = $1
 		ORDER BY project.name ASC`
 
@@ -66,7 +69,12 @@ func LoadProjectByGroup(db database.Querier, group *sdk.Group) error {
 		var projectKey, projectName string
 		var perm int
 		var lastModified time.Time
-		err = rows.Scan(&projectKey, &projectName, &lastModified, &perm)
+		var roleName, roleCapabilities sql.NullString
+		err = rows.Scan(&projectKey, &projectName, &lastModified, &perm, &roleName, &roleCapabilities)
+		if err != nil {
+			return err
+		}
+		r, err := role.Granted(roleName, roleCapabilities, perm)
 		if err != nil {
 			return err
 		}
@@ -77,6 +85,7 @@ func LoadProjectByGroup(db database.Querier, group *sdk.Group) error {
 				LastModified: lastModified.Unix(),
 			},
 			Permission: perm,
+			Role:       r,
 		})
 	}
 	return nil
@@ -631,6 +640,11 @@ func DeleteProject(db database.QueryExecuter, key string) error {
 		return err
 	}
 
+	err = buildcache.DeleteAll(db, key, projectID)
+	if err != nil {
+		return err
+	}
+
 	query = `DELETE FROM repositories_manager_project WHERE id_project = $1`
 	_, err = db.Exec(query, projectID)
 	if err != nil {
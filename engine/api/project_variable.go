@@ -19,7 +19,7 @@ import (
 
 func getVariablesAuditInProjectnHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
 	vars := mux.Vars(r)
-	key := vars["key"]
+	key := vars["permProjectKey"]
 
 	audits, err := project.GetVariableAudit(db, key)
 	if err != nil {
@@ -32,7 +32,7 @@ func getVariablesAuditInProjectnHandler(w http.ResponseWriter, r *http.Request,
 
 func restoreProjectVariableAuditHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
 	vars := mux.Vars(r)
-	key := vars["key"]
+	key := vars["permProjectKey"]
 	auditIDString := vars["auditID"]
 
 	auditID, err := strconv.ParseInt(auditIDString, 10, 64)
@@ -123,6 +123,19 @@ func getVariablesInProjectHandler(w http.ResponseWriter, r *http.Request, db *sq
 		return
 	}
 
+	if r.FormValue("withSecrets") == "true" {
+		if !canReadSecrets(r, c) {
+			WriteError(w, r, sdk.ErrForbidden)
+			return
+		}
+		p.Variable, err = project.GetAllVariableInProject(db, p.ID, project.WithClearPassword())
+		if err != nil {
+			log.Warning("getVariablesInProjectHandler: Cannot get variables for project %s: %s\n", key, err)
+			WriteError(w, r, err)
+			return
+		}
+	}
+
 	WriteJSON(w, r, p.Variable, http.StatusOK)
 }
 
@@ -368,12 +368,12 @@ func getReposFromRepositoriesManagerHandler(w http.ResponseWriter, r *http.Reque
 	}
 
 	var repos []sdk.VCSRepo
-	cacheKey := cache.Key("reposmanager", "repos", projectKey, rmName)
-	cache.Get(cacheKey, &repos)
-	if repos == nil || len(repos) == 0 {
-		log.Debug("getReposFromRepositoriesManagerHandler> loading from Stash")
+	err = repositoriesmanager.Repositories.Load(repositoriesmanager.RepositoriesCacheKey(projectKey, rmName), &repos, func() error {
+		log.Debug("getReposFromRepositoriesManagerHandler> loading from repositories manager")
+		var err error
 		repos, err = client.Repos()
-	}
+		return err
+	})
 	if err != nil {
 		log.Warning("getReposFromRepositoriesManagerHandler> Cannot get repos: %s", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -260,6 +260,45 @@ func AuthorizedClient(db database.Querier, projectKey, rmName string) (sdk.Repos
 		return nil, err
 	}
 
+	clientData, err := loadClientData(db, projectKey, rmName)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(clientData) > 0 && clientData["access_token"] != nil && clientData["access_token_secret"] != nil {
+		return rm.Consumer.GetAuthorized(clientData["access_token"].(string), clientData["access_token_secret"].(string))
+	}
+
+	return nil, sdk.ErrNoReposManagerClientAuth
+
+}
+
+//CloneCredentials returns user and password to clone repositories over https with the granted token.
+//Empty credentials are returned when the repositories manager does not support it.
+func CloneCredentials(db database.Querier, projectKey, rmName string) (string, string, error) {
+	rm, err := LoadForProject(db, projectKey, rmName)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Stash OAuth1 tokens can't be used by git
+	if rm.Type != sdk.Github {
+		return "", "", nil
+	}
+
+	clientData, err := loadClientData(db, projectKey, rmName)
+	if err != nil {
+		return "", "", err
+	}
+
+	token, _ := clientData["access_token"].(string)
+	if token == "" {
+		return "", "", sdk.ErrNoReposManagerClientAuth
+	}
+	return token, "x-oauth-basic", nil
+}
+
+func loadClientData(db database.Querier, projectKey, rmName string) (map[string]interface{}, error) {
 	var data string
 	query := `SELECT 	repositories_manager_project.data
 			FROM 	repositories_manager_project
@@ -276,13 +315,7 @@ func AuthorizedClient(db database.Querier, projectKey, rmName string) (sdk.Repos
 	if err := json.Unmarshal([]byte(data), &clientData); err != nil {
 		return nil, err
 	}
-
-	if len(clientData) > 0 && clientData["access_token"] != nil && clientData["access_token_secret"] != nil {
-		return rm.Consumer.GetAuthorized(clientData["access_token"].(string), clientData["access_token_secret"].(string))
-	}
-
-	return nil, sdk.ErrNoReposManagerClientAuth
-
+	return clientData, nil
 }
 
 //InsertForApplication associates a repositories manager with an application
@@ -14,6 +14,7 @@ import (
 	"github.com/ovh/cds/engine/api/application"
 	"github.com/ovh/cds/engine/api/cache"
 	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/api/leader"
 	"github.com/ovh/cds/engine/api/pipeline"
 	"github.com/ovh/cds/engine/api/poller"
 	"github.com/ovh/cds/engine/api/project"
@@ -55,7 +56,7 @@ type WorkerExecution struct {
 func Initialize() {
 	for {
 		db := database.DB()
-		if db == nil {
+		if db == nil || !leader.IsLeader() {
 			time.Sleep(30 * time.Second)
 			continue
 		}
@@ -154,7 +155,7 @@ func (w *Worker) poll(rm *sdk.RepositoriesManager, appID, pipID int64, quit chan
 	for RunningPollers.Workers[w.ProjectKey] != nil {
 		//Check database connection
 		db := database.DB()
-		if db == nil {
+		if db == nil || !leader.IsLeader() {
 			time.Sleep(60 * time.Second)
 			continue
 		}
@@ -385,7 +386,7 @@ func deleteExecution(db database.QueryExecuter, e *WorkerExecution) error {
 func ExecutionCleaner() {
 	for {
 		db := database.DB()
-		if db == nil {
+		if db == nil || !leader.IsLeader() {
 			time.Sleep(30 * time.Minute)
 			continue
 		}
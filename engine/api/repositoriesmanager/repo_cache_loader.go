@@ -7,79 +7,84 @@ import (
 
 	"github.com/ovh/cds/engine/api/cache"
 	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/api/leader"
 	"github.com/ovh/cds/engine/log"
 	"github.com/ovh/cds/sdk"
 )
 
+// Repositories are cached by project key and repositories manager name, see RepositoriesCacheKey
+var Repositories = cache.NewKind(cache.Forever, "reposmanager", "repos")
+
+//RepositoriesCacheKey returns the ID of repositories of a repositories manager of a project in Repositories
+func RepositoriesCacheKey(projectKey, rmName string) string {
+	return cache.Key(projectKey, rmName)
+}
+
 //RepositoriesCacheLoader has to be launched as a goroutine. It will scan all repositories manager
-//for all projects and start preloading repositories
+//for all projects and start preloading repositories. With a shared cache, only the leader loads them.
 func RepositoriesCacheLoader(delay int) {
 	for {
 		db := database.DB()
-		if db != nil {
-			var mayIWork string
-			loaderKey := cache.Key("reposmanager", "loading")
-			cache.Get(loaderKey, &mayIWork)
-			if mayIWork == "" {
-				cache.Set(loaderKey, "true")
-				projects := []*sdk.Project{}
+		if db != nil && (!cache.Shared() || leader.IsLeader()) {
+			projects := []*sdk.Project{}
 
-				var query string
-				var err error
-				var rows *sql.Rows
+			var query string
+			var err error
+			var rows *sql.Rows
 
-				query = `SELECT project.id, project.projectKey,project.name
+			query = `SELECT project.id, project.projectKey,project.name
 			  FROM project
 			  ORDER by project.name, project.projectkey ASC`
-				rows, err = db.Query(query)
+			rows, err = db.Query(query)
 
+			if err != nil {
+				log.Warning("RepositoriesCacheLoader> Cannot get projects: %s", err)
+				time.Sleep(time.Duration(delay) * time.Second)
+				continue
+			}
+			for rows.Next() {
+				var id int64
+				var key, name string
+				rows.Scan(&id, &key, &name)
+				p := sdk.NewProject(key)
+				p.Name = name
+				p.ID = id
+				projects = append(projects, p)
+			}
+			rows.Close()
+			wg := &sync.WaitGroup{}
+			for _, proj := range projects {
+				projectKey := proj.Key
+				rms, err := LoadAllForProject(db, projectKey)
 				if err != nil {
-					log.Warning("RepositoriesCacheLoader> Cannot get projects: %s", err)
+					log.Warning("RepositoriesCacheLoader> Cannot get repositories manager: %s", err)
 				}
-				defer rows.Close()
 
-				for rows.Next() {
-					var id int64
-					var key, name string
-					rows.Scan(&id, &key, &name)
-					p := sdk.NewProject(key)
-					p.Name = name
-					p.ID = id
-					projects = append(projects, p)
-				}
-				wg := &sync.WaitGroup{}
-				for _, proj := range projects {
-					projectKey := proj.Key
-					rms, err := LoadAllForProject(db, projectKey)
+				for _, rm := range rms {
+					rmName := rm.Name
+					client, err := AuthorizedClient(db, projectKey, rmName)
 					if err != nil {
-						log.Warning("RepositoriesCacheLoader> Cannot get repositories manager: %s", err)
+						log.Warning("RepositoriesCacheLoader> Cannot get client %s: %s", rmName, err)
+						continue
 					}
-
-					for _, rm := range rms {
-						rmName := rm.Name
-						client, err := AuthorizedClient(db, projectKey, rmName)
+					if client == nil {
+						continue
+					}
+					wg.Add(1)
+					go func(projectKey, rmName string) {
+						defer wg.Done()
+						log.Info("RepositoriesCacheLoader> Loading repos for %s on %s", projectKey, rmName)
+						repos, err := client.Repos()
 						if err != nil {
-							log.Warning("RepositoriesCacheLoader> Cannot get client %s: %s", rmName, err)
-							continue
+							log.Warning("RepositoriesCacheLoader> Cannot get repos for %s on %s: %s", projectKey, rmName, err)
+							return
 						}
-						if client == nil {
-							continue
-						}
-						go func(projectKey, rmName string) {
-							wg.Add(1)
-							var repos []sdk.VCSRepo
-							cacheKey := cache.Key("reposmanager", "repos", projectKey, rmName)
-							log.Info("RepositoriesCacheLoader> Loading repos for %s on %s", projectKey, rmName)
-							repos, err = client.Repos()
-							cache.SetWithTTL(cacheKey, &repos, 0)
-							wg.Done()
-						}(projectKey, rmName)
-						time.Sleep(120 * time.Second)
-					}
+						Repositories.Refresh(RepositoriesCacheKey(projectKey, rmName), repos)
+					}(projectKey, rmName)
+					time.Sleep(120 * time.Second)
 				}
-				wg.Wait()
-				cache.Delete(loaderKey)
 			}
+			wg.Wait()
 		}
 		time.Sleep(time.Duration(delay) * time.Second)
 	}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/ovh/cds/engine/api/application"
+	"github.com/ovh/cds/engine/api/context"
+	"github.com/ovh/cds/engine/api/environment"
+	"github.com/ovh/cds/engine/api/group"
+	"github.com/ovh/cds/engine/api/permission"
+	"github.com/ovh/cds/engine/api/pipeline"
+	"github.com/ovh/cds/engine/api/project"
+	"github.com/ovh/cds/engine/api/role"
+	"github.com/ovh/cds/engine/api/user"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+func getRolesHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	roles, err := role.LoadAll(db)
+	if err != nil {
+		log.Warning("getRolesHandler> Cannot load roles: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteJSON(w, r, roles, http.StatusOK)
+}
+
+func addRoleHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		WriteError(w, r, sdk.ErrWrongRequest)
+		return
+	}
+
+	var ro sdk.Role
+	if err := json.Unmarshal(data, &ro); err != nil {
+		WriteError(w, r, sdk.ErrWrongRequest)
+		return
+	}
+	ro.Name = name
+	ro.Builtin = false
+
+	if !ro.IsValid() {
+		WriteError(w, r, sdk.ErrInvalidRole)
+		return
+	}
+
+	if _, err := role.LoadByName(db, name); err == nil {
+		WriteError(w, r, sdk.ErrAlreadyExist)
+		return
+	} else if err != sdk.ErrRoleNotFound {
+		log.Warning("addRoleHandler> Cannot load role %s: %s\n", name, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	if err := role.Insert(db, &ro); err != nil {
+		log.Warning("addRoleHandler> Cannot insert role %s: %s\n", name, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteJSON(w, r, ro, http.StatusCreated)
+}
+
+func updateRoleHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		WriteError(w, r, sdk.ErrWrongRequest)
+		return
+	}
+
+	var ro sdk.Role
+	if err := json.Unmarshal(data, &ro); err != nil {
+		WriteError(w, r, sdk.ErrWrongRequest)
+		return
+	}
+
+	old, err := role.LoadByName(db, name)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	if old.Builtin {
+		WriteError(w, r, sdk.ErrRoleBuiltin)
+		return
+	}
+
+	ro.ID = old.ID
+	ro.Name = old.Name
+	if !ro.IsValid() {
+		WriteError(w, r, sdk.ErrInvalidRole)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Warning("updateRoleHandler> Cannot start transaction: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+	defer tx.Rollback()
+
+	if err := role.Update(tx, &ro); err != nil {
+		log.Warning("updateRoleHandler> Cannot update role %s: %s\n", name, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Warning("updateRoleHandler> Cannot commit transaction: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteJSON(w, r, ro, http.StatusOK)
+}
+
+func deleteRoleHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+
+	ro, err := role.LoadByName(db, name)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	if ro.Builtin {
+		WriteError(w, r, sdk.ErrRoleBuiltin)
+		return
+	}
+
+	if err := role.Delete(db, ro); err != nil {
+		log.Warning("deleteRoleHandler> Cannot delete role %s: %s\n", name, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// loadRoleAndGroup loads role and group from route variables
+func loadRoleAndGroup(db *sql.DB, vars map[string]string) (*sdk.Role, *sdk.Group, error) {
+	ro, err := role.LoadByName(db, vars["role"])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	g, err := group.LoadGroup(db, vars["group"])
+	if err != nil {
+		return nil, nil, sdk.ErrGroupNotFound
+	}
+	return ro, g, nil
+}
+
+// checkWriteGroupLeft ensures at least one group keeps write permission once the role is assigned to the group
+func checkWriteGroupLeft(writeGroups []sdk.GroupPermission, g *sdk.Group, ro *sdk.Role) error {
+	if ro.Permission() == permission.PermissionReadWriteExecute {
+		return nil
+	}
+	if len(writeGroups) == 1 && writeGroups[0].Group.ID == g.ID {
+		return sdk.ErrGroupNeedWrite
+	}
+	return nil
+}
+
+func assignRoleOnProjectHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	vars := mux.Vars(r)
+	key := vars["permProjectKey"]
+
+	p, err := project.LoadProject(db, key, c.User)
+	if err != nil {
+		log.Warning("assignRoleOnProjectHandler> Cannot load %s: %s\n", key, err)
+		WriteError(w, r, sdk.ErrNoProject)
+		return
+	}
+
+	ro, g, err := loadRoleAndGroup(db, vars)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	ok, err := group.CheckGroupInProject(db, p.ID, g.ID)
+	if err != nil {
+		log.Warning("assignRoleOnProjectHandler> Cannot check group %s in project %s: %s\n", g.Name, key, err)
+		WriteError(w, r, err)
+		return
+	}
+	if !ok {
+		WriteError(w, r, sdk.ErrGroupNotFound)
+		return
+	}
+
+	writeGroups, err := group.LoadAllProjectGroupByRole(db, p.ID, permission.PermissionReadWriteExecute)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	if err := checkWriteGroupLeft(writeGroups, g, ro); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	if err := role.AssignInProject(db, p.ID, g.ID, ro); err != nil {
+		log.Warning("assignRoleOnProjectHandler> Cannot assign role %s to group %s: %s\n", ro.Name, g.Name, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func assignRoleOnApplicationHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	vars := mux.Vars(r)
+	key := vars["key"]
+	appName := vars["permApplicationName"]
+
+	app, err := application.LoadApplicationByName(db, key, appName)
+	if err != nil {
+		log.Warning("assignRoleOnApplicationHandler> Cannot load application %s: %s\n", appName, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	ro, g, err := loadRoleAndGroup(db, vars)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	ok, err := group.CheckGroupInApplication(db, app.ID, g.ID)
+	if err != nil {
+		log.Warning("assignRoleOnApplicationHandler> Cannot check group %s in application %s: %s\n", g.Name, appName, err)
+		WriteError(w, r, err)
+		return
+	}
+	if !ok {
+		WriteError(w, r, sdk.ErrGroupNotFound)
+		return
+	}
+
+	writeGroups, err := group.LoadAllApplicationGroupByRole(db, app.ID, permission.PermissionReadWriteExecute)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	if err := checkWriteGroupLeft(writeGroups, g, ro); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	if err := role.AssignInApplication(db, app.ID, g.ID, ro); err != nil {
+		log.Warning("assignRoleOnApplicationHandler> Cannot assign role %s to group %s: %s\n", ro.Name, g.Name, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func assignRoleOnPipelineHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	vars := mux.Vars(r)
+	key := vars["key"]
+	pipName := vars["permPipelineKey"]
+
+	pip, err := pipeline.LoadPipeline(db, key, pipName, false)
+	if err != nil {
+		log.Warning("assignRoleOnPipelineHandler> Cannot load pipeline %s: %s\n", pipName, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	ro, g, err := loadRoleAndGroup(db, vars)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	ok, err := group.CheckGroupInPipeline(db, pip.ID, g.ID)
+	if err != nil {
+		log.Warning("assignRoleOnPipelineHandler> Cannot check group %s in pipeline %s: %s\n", g.Name, pipName, err)
+		WriteError(w, r, err)
+		return
+	}
+	if !ok {
+		WriteError(w, r, sdk.ErrGroupNotFound)
+		return
+	}
+
+	writeGroups, err := group.LoadAllPipelineGroupByRole(db, pip.ID, permission.PermissionReadWriteExecute)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	if err := checkWriteGroupLeft(writeGroups, g, ro); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	if err := role.AssignInPipeline(db, pip.ID, g.ID, ro); err != nil {
+		log.Warning("assignRoleOnPipelineHandler> Cannot assign role %s to group %s: %s\n", ro.Name, g.Name, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func assignRoleOnEnvironmentHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	vars := mux.Vars(r)
+	key := vars["key"]
+	envName := vars["permEnvironmentName"]
+
+	env, err := environment.LoadEnvironmentByName(db, key, envName)
+	if err != nil {
+		log.Warning("assignRoleOnEnvironmentHandler> Cannot load environment %s: %s\n", envName, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	ro, g, err := loadRoleAndGroup(db, vars)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	ok, err := group.IsInEnvironment(db, env.ID, g.ID)
+	if err != nil {
+		log.Warning("assignRoleOnEnvironmentHandler> Cannot check group %s in environment %s: %s\n", g.Name, envName, err)
+		WriteError(w, r, err)
+		return
+	}
+	if !ok {
+		WriteError(w, r, sdk.ErrGroupNotFound)
+		return
+	}
+
+	writeGroups, err := group.LoadAllEnvironmentGroupByRole(db, env.ID, permission.PermissionReadWriteExecute)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	if err := checkWriteGroupLeft(writeGroups, g, ro); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	if err := role.AssignInEnvironment(db, env.ID, g.ID, ro); err != nil {
+		log.Warning("assignRoleOnEnvironmentHandler> Cannot assign role %s to group %s: %s\n", ro.Name, g.Name, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func explainAccessHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	vars := mux.Vars(r)
+	key := vars["permProjectKey"]
+
+	username := r.FormValue("user")
+	if username == "" {
+		username = c.User.Username
+	}
+	capability := r.FormValue("capability")
+	if capability == "" {
+		capability = sdk.CapabilityRead
+	}
+
+	// Only project managers may explain access of other users
+	if username != c.User.Username && !c.User.Admin && !checkProjectPermissions(key, c, sdk.CapabilityWrite, vars) {
+		WriteError(w, r, sdk.ErrForbidden)
+		return
+	}
+
+	u, err := user.LoadUserWithoutAuth(db, username)
+	if err != nil {
+		log.Warning("explainAccessHandler> Cannot load user %s: %s\n", username, err)
+		WriteError(w, r, sdk.ErrInvalidUsername)
+		return
+	}
+	if err := user.LoadUserPermissions(db, u); err != nil {
+		log.Warning("explainAccessHandler> Cannot load permissions of user %s: %s\n", username, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	e := permission.Explain(u, capability, key, r.FormValue("application"), r.FormValue("pipeline"), r.FormValue("environment"))
+	WriteJSON(w, r, e, http.StatusOK)
+}
@@ -0,0 +1,174 @@
+package role
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+// LoadAll returns all roles
+func LoadAll(db database.Querier) ([]sdk.Role, error) {
+	query := `SELECT id, name, description, builtin, capabilities FROM role ORDER BY name`
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []sdk.Role{}
+	for rows.Next() {
+		r, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, *r)
+	}
+	return roles, nil
+}
+
+// LoadByName returns the role with given name
+func LoadByName(db database.Querier, name string) (*sdk.Role, error) {
+	query := `SELECT id, name, description, builtin, capabilities FROM role WHERE name = $1`
+	r, err := scan(db.QueryRow(query, name))
+	if err == sql.ErrNoRows {
+		return nil, sdk.ErrRoleNotFound
+	}
+	return r, err
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scan(s scanner) (*sdk.Role, error) {
+	var r sdk.Role
+	var capabilities string
+	if err := s.Scan(&r.ID, &r.Name, &r.Description, &r.Builtin, &capabilities); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(capabilities), &r.Capabilities); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Granted returns the role granted by a group link. Links without role_id
+// fall back on the builtin role matching their legacy permission level
+func Granted(name, capabilities sql.NullString, perm int) (*sdk.Role, error) {
+	if !name.Valid {
+		return sdk.RoleFromPermission(perm), nil
+	}
+	r := &sdk.Role{Name: name.String}
+	if err := json.Unmarshal([]byte(capabilities.String), &r.Capabilities); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Insert creates a new role
+func Insert(db database.QueryExecuter, r *sdk.Role) error {
+	capabilities, err := json.Marshal(r.Capabilities)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO role (name, description, builtin, capabilities) VALUES ($1, $2, $3, $4) RETURNING id`
+	return db.QueryRow(query, r.Name, r.Description, r.Builtin, capabilities).Scan(&r.ID)
+}
+
+// Update modifies role description and capabilities, and legacy permission levels of groups it is granted to
+func Update(db database.Executer, r *sdk.Role) error {
+	capabilities, err := json.Marshal(r.Capabilities)
+	if err != nil {
+		return err
+	}
+	query := `UPDATE role SET description = $2, capabilities = $3 WHERE id = $1`
+	if _, err := db.Exec(query, r.ID, r.Description, capabilities); err != nil {
+		return err
+	}
+
+	for _, table := range []string{"project_group", "application_group", "pipeline_group", "environment_group"} {
+		query := `UPDATE ` + table + ` SET role = $2 WHERE role_id = $1`
+		if _, err := db.Exec(query, r.ID, r.Permission()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete removes a role if it is not granted to any group
+func Delete(db database.QueryExecuter, r *sdk.Role) error {
+	query := `
+		SELECT
+		(SELECT COUNT(*) FROM project_group WHERE role_id = $1) +
+		(SELECT COUNT(*) FROM application_group WHERE role_id = $1) +
+		(SELECT COUNT(*) FROM pipeline_group WHERE role_id = $1) +
+		(SELECT COUNT(*) FROM environment_group WHERE role_id = $1)
+	`
+	var nb int64
+	if err := db.QueryRow(query, r.ID).Scan(&nb); err != nil {
+		return err
+	}
+	if nb > 0 {
+		return sdk.ErrRoleUsed
+	}
+
+	_, err := db.Exec(`DELETE FROM role WHERE id = $1`, r.ID)
+	return err
+}
+
+// AssignInProject grants the role to the group on the project
+func AssignInProject(db database.Executer, projectID, groupID int64, r *sdk.Role) error {
+	query := `UPDATE project_group SET role = $1, role_id = $2 WHERE project_id = $3 AND group_id = $4`
+	_, err := db.Exec(query, r.Permission(), r.ID, projectID, groupID)
+	return err
+}
+
+// AssignInApplication grants the role to the group on the application
+func AssignInApplication(db database.Executer, applicationID, groupID int64, r *sdk.Role) error {
+	query := `UPDATE application_group SET role = $1, role_id = $2 WHERE application_id = $3 AND group_id = $4`
+	_, err := db.Exec(query, r.Permission(), r.ID, applicationID, groupID)
+	return err
+}
+
+// AssignInPipeline grants the role to the group on the pipeline
+func AssignInPipeline(db database.Executer, pipelineID, groupID int64, r *sdk.Role) error {
+	query := `UPDATE pipeline_group SET role = $1, role_id = $2 WHERE pipeline_id = $3 AND group_id = $4`
+	_, err := db.Exec(query, r.Permission(), r.ID, pipelineID, groupID)
+	return err
+}
+
+// AssignInEnvironment grants the role to the group on the environment
+func AssignInEnvironment(db database.Executer, environmentID, groupID int64, r *sdk.Role) error {
+	query := `UPDATE environment_group SET role = $1, role_id = $2 WHERE environment_id = $3 AND group_id = $4`
+	_, err := db.Exec(query, r.Permission(), r.ID, environmentID, groupID)
+	return err
+}
+
+// CreateBuiltinRoles inserts builtin roles if they don't exist
+func CreateBuiltinRoles(db *sql.DB) error {
+	builtins := []sdk.Role{
+		sdk.RoleRead,
+		sdk.RoleReadExecute,
+		sdk.RoleReadWriteExecute,
+		{Name: "variable-editor", Builtin: true, Description: "Edit variables without other write access", Capabilities: []string{sdk.CapabilityRead, sdk.CapabilityEditVariables}},
+		{Name: "secret-viewer", Builtin: true, Description: "Read secret variable values", Capabilities: []string{sdk.CapabilityRead, sdk.CapabilityReadSecrets}},
+	}
+
+	for i := range builtins {
+		r := &builtins[i]
+		if _, err := LoadByName(db, r.Name); err == nil {
+			continue
+		} else if err != sdk.ErrRoleNotFound {
+			return err
+		}
+
+		log.Notice("CreateBuiltinRoles> Creating role %s\n", r.Name)
+		if err := Insert(db, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/api/secret"
+	"github.com/ovh/cds/sdk"
+)
+
+var rotateSecretsCmd = &cobra.Command{
+	Use:   "rotate-secrets",
+	Short: "Encrypt secret values again with the current AES key",
+	Long: `Encrypt secret values again with the current AES key
+
+Project, application and environment password and key variables, and their audits, encrypted
+with an older key are encrypted again with the current key, which is the highest cds/aes-key-v<N> secret.
+API must have loaded this key before rotation: it's loaded on start and when secrets change.
+
+Once no value remains on an old key, this key can be removed from the secret backend.`,
+	Run: rotateSecretsCmdFunc,
+}
+
+var (
+	rotateSecretsStatus         bool
+	rotateSecretsDryRun         bool
+	rotateSecretsBackend        string
+	rotateSecretsBackendOptions []string
+)
+
+func init() {
+	rotateSecretsCmd.Flags().BoolVarP(&rotateSecretsStatus, "status", "", false, "Only display the number of values by key version")
+	rotateSecretsCmd.Flags().BoolVarP(&rotateSecretsDryRun, "dry-run", "", false, "Count values to encrypt again without updating them")
+	rotateSecretsCmd.Flags().StringVarP(&rotateSecretsBackend, "secret-backend", "", "", "Secret Backend plugin binary, or vault for embedded Vault Secret Backend")
+	rotateSecretsCmd.Flags().StringSliceVarP(&rotateSecretsBackendOptions, "secret-backend-option", "", []string{}, "Secret Backend plugin options")
+}
+
+func rotateSecretsCmdFunc(cmd *cobra.Command, args []string) {
+	backend := rotateSecretsBackend
+	if backend == "" {
+		backend = viper.GetString("secret_backend")
+	}
+	opts := rotateSecretsBackendOptions
+	if len(opts) == 0 {
+		opts = viper.GetStringSlice("secret_backend_option")
+	}
+
+	if err := secret.Init(backend, parseSecretBackendOptions(opts)); err != nil {
+		sdk.Exit("Cannot initialize secret manager: %s\n", err)
+	}
+
+	db, err := database.Init()
+	if err != nil {
+		sdk.Exit("Error: %s\n", err)
+	}
+
+	fmt.Printf("Current key version: %d\n", secret.CurrentKeyVersion())
+	fmt.Printf("Available key versions: %v\n", secret.KeyVersions())
+
+	if !rotateSecretsStatus {
+		res, err := secret.Rotate(db, rotateSecretsDryRun)
+		for table, n := range res {
+			if rotateSecretsDryRun {
+				fmt.Printf(" >> %d values to encrypt again in %s\n", n, table)
+			} else {
+				fmt.Printf(" >> %d values encrypted again in %s\n", n, table)
+			}
+		}
+		if err != nil {
+			sdk.Exit("Error: %s\n", err)
+		}
+	}
+
+	status, err := secret.LoadRotationStatus(db)
+	if err != nil {
+		sdk.Exit("Error: %s\n", err)
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Table", "Key version", "Values"})
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+
+	var outdated int64
+	for _, s := range status {
+		versions := []int{}
+		for v := range s.Versions {
+			versions = append(versions, v)
+		}
+		sort.Ints(versions)
+		for _, v := range versions {
+			table.Append([]string{s.Table, strconv.Itoa(v), strconv.FormatInt(s.Versions[v], 10)})
+		}
+		outdated += s.OutdatedValues()
+	}
+	table.Render()
+
+	fmt.Printf("%d values remain on old keys\n", outdated)
+}
@@ -2,8 +2,10 @@ package main
 
 import (
 	"compress/gzip"
+	"crypto/rand"
 	"database/sql"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -23,6 +25,7 @@ import (
 	"github.com/ovh/cds/engine/api/user"
 	"github.com/ovh/cds/engine/log"
 	"github.com/ovh/cds/sdk"
+	"github.com/ovh/cds/sdk/tracing"
 )
 
 var router *Router
@@ -40,13 +43,17 @@ type Handler func(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context
 type RouterConfigParam func(rc *routerConfig)
 
 type routerConfig struct {
-	get           Handler
-	post          Handler
-	put           Handler
-	deleteHandler Handler
-	auth          bool
-	isExecution   bool
-	needAdmin     bool
+	get             Handler
+	post            Handler
+	put             Handler
+	deleteHandler   Handler
+	auth            bool
+	audit           bool
+	isExecution     bool
+	needAdmin       bool
+	writeCapability string
+	readCapability  string
+	auditBefore     AuditLoader
 }
 
 // ServeAbsoluteFile Serve file to download
@@ -78,7 +85,7 @@ func recoverWrap(h http.HandlerFunc) http.HandlerFunc {
 				default:
 					err = sdk.ErrUnknownError
 				}
-				log.Critical("[PANIC_RECOVERY] Panic occured on %s:%s, recover %s", req.Method, req.URL.String(), err)
+				log.WithField(log.FieldRequestID, req.Header.Get(sdk.RequestIDHeader)).Critical("[PANIC_RECOVERY] Panic occured on %s:%s, recover %s", req.Method, req.URL.String(), err)
 				trace := make([]byte, 4096)
 				count := runtime.Stack(trace, true)
 				log.Critical("[PANIC_RECOVERY] Stacktrace of %d bytes\n%s\n", count, trace)
@@ -128,7 +135,7 @@ var mapRouterConfigs = map[string]*routerConfig{}
 // Handle adds all handler for their specific verb in gorilla router for given uri
 func (r *Router) Handle(uri string, handlers ...RouterConfigParam) {
 	uri = r.prefix + uri
-	rc := &routerConfig{auth: true, isExecution: false, needAdmin: false}
+	rc := &routerConfig{auth: true, audit: true, isExecution: false, needAdmin: false}
 	mapRouterConfigs[uri] = rc
 
 	for _, h := range handlers {
@@ -141,16 +148,26 @@ func (r *Router) Handle(uri string, handlers ...RouterConfigParam) {
 		// Authorization ?
 		w.Header().Add("Access-Control-Allow-Origin", "*")
 		w.Header().Add("Access-Control-Allow-Methods", "GET,OPTIONS,PUT,POST,DELETE")
-		w.Header().Add("Access-Control-Allow-Headers", "Accept, Origin, Referer, User-Agent, Content-Type, Authorization, Session-Token, Last-Event-Id")
-		w.Header().Add("Access-Control-Expose-Headers", "Accept, Origin, Referer, User-Agent, Content-Type, Authorization, Session-Token, Last-Event-Id")
+		w.Header().Add("Access-Control-Allow-Headers", "Accept, Origin, Referer, User-Agent, Content-Type, Authorization, Session-Token, Last-Event-Id, X-Request-Id")
+		w.Header().Add("Access-Control-Expose-Headers", "Accept, Origin, Referer, User-Agent, Content-Type, Authorization, Session-Token, Last-Event-Id, X-Request-Id")
 
-		c := &context.Context{}
+		c := &context.Context{RequestID: requestID(req)}
+		w.Header().Set(sdk.RequestIDHeader, c.RequestID)
 
 		if req.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
 			return
 		}
 
+		metricsRec := &statusRecorder{ResponseWriter: w}
+		w = metricsRec
+		defer metricsRec.observe(uri, req, time.Now())
+
+		// Requests of a traced pipeline build, like worker calls, continue its trace
+		span := tracing.Continue(req.Method+" "+uri, req.Header.Get(tracing.Header))
+		span.SetKind(tracing.KindServer)
+		defer metricsRec.trace(span)
+
 		//Check DB connection
 		db := database.DB()
 		if db == nil {
@@ -163,24 +180,35 @@ func (r *Router) Handle(uri string, handlers ...RouterConfigParam) {
 
 		if rc.auth {
 			if err := r.checkAuthentication(db, req.Header, c); err != nil {
-				log.Warning("Authorization denied on %s %s for %s: %s\n", req.Method, req.URL, req.RemoteAddr, err)
+				log.WithFields(c.Fields()).Warning("Authorization denied on %s %s for %s: %s\n", req.Method, req.URL, req.RemoteAddr, err)
 				WriteError(w, req, sdk.ErrUnauthorized)
 				return
 			}
 		}
 
+		var rec *auditRecorder
+		if (req.Method == "POST" || req.Method == "PUT" || req.Method == "DELETE") && rc.audit {
+			rec = newAuditRecorder(w, req, uri, c)
+			w = rec
+			defer rec.record()
+		}
+
 		permissionOk := true
 		if rc.auth && rc.needAdmin && !c.User.Admin {
 			permissionOk = false
 		} else if rc.auth && !rc.needAdmin && !c.User.Admin {
-			permissionOk = checkPermission(mux.Vars(req), c, getPermissionByMethod(req.Method, rc.isExecution))
+			permissionOk = checkPermission(mux.Vars(req), c, getCapabilityByMethod(req.Method, rc))
 		}
 		if permissionOk {
+			if rec != nil {
+				rec.captureBefore(req, rc, db, c)
+			}
+
 			start := time.Now()
 			defer func() {
 				end := time.Now()
 				latency := end.Sub(start)
-				log.Info("%-7s | %13v | %v", req.Method, latency, req.URL)
+				log.WithFields(requestFields(req, c)).Info("%-7s | %13v | %v", req.Method, latency, req.URL)
 			}()
 
 			if req.Method == "GET" && rc.get != nil {
@@ -210,6 +238,52 @@ func (r *Router) Handle(uri string, handlers ...RouterConfigParam) {
 	router.mux.HandleFunc(uri, compress(recoverWrap(f)))
 }
 
+// requestID returns the correlation ID of a request: the one sent by the client, like workers
+// calling the API for a pipeline build, a new one otherwise.
+// It is set in request headers to be logged on panic recovery.
+func requestID(req *http.Request) string {
+	id := req.Header.Get(sdk.RequestIDHeader)
+	if !validRequestID(id) {
+		b := make([]byte, 16)
+		rand.Read(b)
+		id = hex.EncodeToString(b)
+		req.Header.Set(sdk.RequestIDHeader, id)
+	}
+	return id
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// requestFields returns log fields of a call, with project, application and pipeline of its route
+func requestFields(req *http.Request, c *context.Context) log.Fields {
+	f := c.Fields()
+	vars := mux.Vars(req)
+	for _, k := range []string{"key", "permProjectKey"} {
+		if v, ok := vars[k]; ok {
+			f[log.FieldProject] = v
+		}
+	}
+	for _, k := range []string{"app", "permApplicationName"} {
+		if v, ok := vars[k]; ok {
+			f[log.FieldApplication] = v
+		}
+	}
+	if v, ok := vars["permPipelineKey"]; ok {
+		f[log.FieldPipeline] = v
+	}
+	return f
+}
+
 // GET will set given handler only for GET request
 func GET(h Handler) RouterConfigParam {
 	f := func(rc *routerConfig) {
@@ -253,6 +327,30 @@ func NeedAdmin(admin bool) RouterConfigParam {
 	return f
 }
 
+// WriteCapability overrides the capability needed by POST, PUT and DELETE requests
+func WriteCapability(capability string) RouterConfigParam {
+	f := func(rc *routerConfig) {
+		rc.writeCapability = capability
+	}
+	return f
+}
+
+// ReadCapability overrides the capability needed by GET requests
+func ReadCapability(capability string) RouterConfigParam {
+	f := func(rc *routerConfig) {
+		rc.readCapability = capability
+	}
+	return f
+}
+
+// AuditBefore sets the loader of the resource recorded in audit log before PUT and DELETE requests
+func AuditBefore(l AuditLoader) RouterConfigParam {
+	f := func(rc *routerConfig) {
+		rc.auditBefore = l
+	}
+	return f
+}
+
 // DELETE will set given handler only for DELETE request
 func DELETE(h Handler) RouterConfigParam {
 	f := func(rc *routerConfig) {
@@ -315,6 +413,15 @@ func Auth(v bool) RouterConfigParam {
 	return f
 }
 
+// Audit set manually whether POST, PUT and DELETE requests should be recorded in audit log
+// Audit is enabled by default, it is disabled on high volume agent calls like logs and heartbeats
+func Audit(v bool) RouterConfigParam {
+	f := func(rc *routerConfig) {
+		rc.audit = v
+	}
+	return f
+}
+
 func (r *Router) checkAuthHeader(db *sql.DB, headers http.Header, c *context.Context) error {
 	return r.authDriver.GetCheckAuthHeaderFunc(localCLientAuthMode)(db, headers, c)
 }
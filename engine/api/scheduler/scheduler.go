@@ -13,14 +13,20 @@ import (
 	"github.com/ovh/cds/engine/api/build"
 	"github.com/ovh/cds/engine/api/database"
 	"github.com/ovh/cds/engine/api/environment"
+	"github.com/ovh/cds/engine/api/leader"
 	"github.com/ovh/cds/engine/api/notification"
 	"github.com/ovh/cds/engine/api/pipeline"
 	"github.com/ovh/cds/engine/api/project"
 	"github.com/ovh/cds/engine/api/trigger"
 	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/engine/metrics"
 	"github.com/ovh/cds/sdk"
+	"github.com/ovh/cds/sdk/tracing"
 )
 
+var loopDuration = metrics.NewHistogram("cds_scheduler_loop_duration_seconds",
+	"Duration of a scheduling pass over all building pipelines", metrics.DefaultBuckets)
+
 // Schedule is a goroutine responsible for pushing actions of a building pipeline in queue, in the wanted order
 func Schedule() {
 
@@ -31,7 +37,8 @@ func Schedule() {
 		time.Sleep(2 * time.Second)
 
 		db := database.DB()
-		if db != nil {
+		if db != nil && leader.IsLeader() {
+			start := time.Now()
 			pipelines, err := pipeline.LoadBuildingPipelines(db)
 			if err != nil {
 				log.Warning("Schedule> Cannot load building pipelines: %s\n", err)
@@ -43,12 +50,23 @@ func Schedule() {
 			for i := range pipelines {
 				PipelineScheduler(db, pipelines[i])
 			}
+			loopDuration.Since(start)
 		}
 	}
 }
 
 // PipelineScheduler Schedule action for the given Build
 func PipelineScheduler(db *sql.DB, pb sdk.PipelineBuild) {
+	// Passes scheduling nothing are not exported, they run every few seconds
+	span := tracing.Continue("scheduler.PipelineScheduler", sdk.ParameterValue(pb.Parameters, tracing.Parameter))
+	span.SetAttribute("cds.build_number", pb.BuildNumber)
+	var scheduled bool
+	defer func() {
+		if scheduled {
+			span.Finish()
+		}
+	}()
+
 	tx, err := db.Begin()
 	if err != nil {
 		log.Warning("PipelineScheduler> cannot start tx for pb %d: %s\n", pb.ID, err)
@@ -82,6 +100,8 @@ func PipelineScheduler(db *sql.DB, pb sdk.PipelineBuild) {
 
 	var runningStage = -1
 	var doneStage = 0
+	// Action builds waiting for a worker, pushed to workers once committed
+	var waiting []int64
 	for stageIndex, s := range pb.Pipeline.Stages {
 		// Need len(s.Actions) on Success to go to next stage, count them
 		var numberOfActionSuccess int
@@ -112,6 +132,7 @@ func PipelineScheduler(db *sql.DB, pb sdk.PipelineBuild) {
 						return
 					}
 					runningStage = stageIndex
+					scheduled = true
 
 					if !s.Enabled {
 						status = sdk.StatusDisabled
@@ -131,12 +152,16 @@ func PipelineScheduler(db *sql.DB, pb sdk.PipelineBuild) {
 				// If no row, action should be scheduled if current stage is running
 				if errActionStatus != nil && errActionStatus == sql.ErrNoRows {
 					if runningStage == -1 || stageIndex == runningStage {
-						_, err = scheduleAction(tx, a, pb, s.ID)
+						actionBuild, err := scheduleAction(tx, a, pb, s.ID)
 						if err != nil {
 							log.Warning("PipelineScheduler> Cannot schedule action: %s\n", err)
 							return
 						}
+						if actionBuild.Status == sdk.StatusWaiting {
+							waiting = append(waiting, actionBuild.ID)
+						}
 						runningStage = stageIndex
+						scheduled = true
 						continue
 					}
 				}
@@ -147,6 +172,7 @@ func PipelineScheduler(db *sql.DB, pb sdk.PipelineBuild) {
 				//condition de sortie
 				if status == sdk.StatusFail {
 					//log.Info("PipelineScheduler> %s #%d: Action %s failed, stoping\n", pb.Pipeline.Name, pb.BuildNumber, a.Name)
+					scheduled = true
 					if err := pipeline.UpdatePipelineBuildStatus(tx, pb, status); err != nil {
 						log.Warning("PipelineScheduler> Cannot update pipeline status: %s\n", err)
 					} else {
@@ -173,6 +199,7 @@ func PipelineScheduler(db *sql.DB, pb sdk.PipelineBuild) {
 		if numberOfActionSuccess == len(s.Actions) { // Then go to next stage !
 			// But if current stage is the last one...
 			if doneStage == len(pb.Pipeline.Stages) { // Oh wait there is no next stage
+				scheduled = true
 				scheduleEnd(tx, pb)
 				return
 			}
@@ -184,6 +211,7 @@ func PipelineScheduler(db *sql.DB, pb sdk.PipelineBuild) {
 		log.Warning("PipelineScheduler>Cannot commit transaction: %s", err)
 		return
 	}
+	build.Push(waiting...)
 	return
 
 }
@@ -303,6 +331,22 @@ func ParentBuildInfos(pb sdk.PipelineBuild) ([]sdk.Parameter, error) {
 	}
 	params = append(params, p)
 
+	if tp := sdk.ParameterValue(pb.Parameters, tracing.Parameter); tp != "" {
+		params = append(params, sdk.Parameter{
+			Name:  tracing.ParentParameter,
+			Type:  sdk.StringParameter,
+			Value: tp,
+		})
+	}
+	// Triggered pipeline builds keep the request ID of the pipeline build which started them
+	if id := sdk.ParameterValue(pb.Parameters, sdk.RequestIDParameter); id != "" {
+		params = append(params, sdk.Parameter{
+			Name:  sdk.RequestIDParameter,
+			Type:  sdk.StringParameter,
+			Value: id,
+		})
+	}
+
 	return params, nil
 }
 
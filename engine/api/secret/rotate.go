@@ -0,0 +1,241 @@
+package secret
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+// encryptedTables lists tables storing encrypted values, which are password and key variables
+var encryptedTables = []string{"project_variable", "application_variable", "environment_variable"}
+
+// auditTables lists tables storing audits of variables, whose data keeps encrypted values
+// of password and key variables in base64
+var auditTables = []string{"project_variable_audit", "application_variable_audit", "environment_variable_audit"}
+
+// RotationStatus counts encrypted values of a table by key version
+type RotationStatus struct {
+	Table    string
+	Versions map[int]int64
+}
+
+// OutdatedValues returns the number of values encrypted with another key than the current one
+func (s RotationStatus) OutdatedValues() int64 {
+	current := CurrentKeyVersion()
+	var n int64
+	for v, count := range s.Versions {
+		if v != current {
+			n += count
+		}
+	}
+	return n
+}
+
+type encryptedValue struct {
+	id    int64
+	value []byte
+}
+
+func loadEncryptedValues(db database.Querier, table string) ([]encryptedValue, error) {
+	query := fmt.Sprintf(`SELECT id, cipher_value FROM %s WHERE cipher_value IS NOT NULL`, table)
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := []encryptedValue{}
+	for rows.Next() {
+		var v encryptedValue
+		if err := rows.Scan(&v.id, &v.value); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+type audit struct {
+	id   int64
+	data string
+}
+
+func loadAudits(db database.Querier, table string) ([]audit, error) {
+	query := fmt.Sprintf(`SELECT id, data FROM %s WHERE data IS NOT NULL`, table)
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	audits := []audit{}
+	for rows.Next() {
+		var a audit
+		if err := rows.Scan(&a.id, &a.data); err != nil {
+			return nil, err
+		}
+		audits = append(audits, a)
+	}
+	return audits, nil
+}
+
+// auditValues calls f with the encrypted value of each password and key variable of audit data,
+// and replaces the value with the one returned by f
+func auditValues(data string, f func(value []byte) ([]byte, error)) (string, error) {
+	var variables []sdk.Variable
+	if err := json.Unmarshal([]byte(data), &variables); err != nil {
+		return "", err
+	}
+	for i := range variables {
+		v := &variables[i]
+		if !sdk.NeedPlaceholder(v.Type) {
+			continue
+		}
+		value, err := base64.StdEncoding.DecodeString(v.Value)
+		if err != nil {
+			return "", err
+		}
+		if value, err = f(value); err != nil {
+			return "", err
+		}
+		v.Value = base64.StdEncoding.EncodeToString(value)
+	}
+	b, err := json.Marshal(variables)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// reencryptAudit encrypts again with the current key values of audit data encrypted with an older key.
+// It returns the new data and the number of values encrypted again
+func reencryptAudit(data string) (string, int64, error) {
+	var n int64
+	res, err := auditValues(data, func(value []byte) ([]byte, error) {
+		newValue, changed, err := Reencrypt(value)
+		if changed {
+			n++
+		}
+		return newValue, err
+	})
+	if err != nil || n == 0 {
+		return data, 0, err
+	}
+	return res, n, nil
+}
+
+// LoadRotationStatus counts encrypted values by key version
+func LoadRotationStatus(db database.Querier) ([]RotationStatus, error) {
+	res := []RotationStatus{}
+	for _, table := range encryptedTables {
+		values, err := loadEncryptedValues(db, table)
+		if err != nil {
+			return nil, err
+		}
+
+		s := RotationStatus{Table: table, Versions: map[int]int64{}}
+		for _, v := range values {
+			if version, ok := KeyVersion(v.value); ok {
+				s.Versions[version]++
+			}
+		}
+		res = append(res, s)
+	}
+
+	for _, table := range auditTables {
+		audits, err := loadAudits(db, table)
+		if err != nil {
+			return nil, err
+		}
+
+		s := RotationStatus{Table: table, Versions: map[int]int64{}}
+		for _, a := range audits {
+			_, err := auditValues(a.data, func(value []byte) ([]byte, error) {
+				if version, ok := KeyVersion(value); ok {
+					s.Versions[version]++
+				}
+				return value, nil
+			})
+			if err != nil {
+				return nil, fmt.Errorf("cannot read %s %d: %s", table, a.id, err)
+			}
+		}
+		res = append(res, s)
+	}
+	return res, nil
+}
+
+// Rotate encrypts again with the current key all values encrypted with an older key.
+// Each value is updated only if it didn't change meanwhile, so it can run while API is serving requests.
+// It returns the number of values encrypted again by table
+func Rotate(db database.QueryExecuter, dryRun bool) (map[string]int64, error) {
+	res := map[string]int64{}
+	for _, table := range encryptedTables {
+		values, err := loadEncryptedValues(db, table)
+		if err != nil {
+			return nil, err
+		}
+
+		query := fmt.Sprintf(`UPDATE %s SET cipher_value = $1 WHERE id = $2 AND cipher_value = $3`, table)
+		for _, v := range values {
+			newValue, changed, err := Reencrypt(v.value)
+			if err != nil {
+				return res, fmt.Errorf("cannot encrypt again %s %d: %s", table, v.id, err)
+			}
+			if !changed {
+				continue
+			}
+			if dryRun {
+				res[table]++
+				continue
+			}
+
+			r, err := db.Exec(query, newValue, v.id, v.value)
+			if err != nil {
+				return res, err
+			}
+			if n, _ := r.RowsAffected(); n == 0 {
+				log.Notice("secret.Rotate> %s %d changed meanwhile, skipped\n", table, v.id)
+				continue
+			}
+			res[table]++
+		}
+	}
+
+	for _, table := range auditTables {
+		audits, err := loadAudits(db, table)
+		if err != nil {
+			return nil, err
+		}
+
+		query := fmt.Sprintf(`UPDATE %s SET data = $1 WHERE id = $2 AND data = $3`, table)
+		for _, a := range audits {
+			data, n, err := reencryptAudit(a.data)
+			if err != nil {
+				return res, fmt.Errorf("cannot encrypt again %s %d: %s", table, a.id, err)
+			}
+			if n == 0 {
+				continue
+			}
+			if dryRun {
+				res[table] += n
+				continue
+			}
+
+			r, err := db.Exec(query, data, a.id, a.data)
+			if err != nil {
+				return res, err
+			}
+			if affected, _ := r.RowsAffected(); affected == 0 {
+				log.Notice("secret.Rotate> %s %d changed meanwhile, skipped\n", table, a.id)
+				continue
+			}
+			res[table] += n
+		}
+	}
+	return res, nil
+}
@@ -9,10 +9,14 @@ import (
 	"database/sql"
 	"fmt"
 	"io"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/ovh/cds/engine/api/secret/filesecretbackend"
 	"github.com/ovh/cds/engine/api/secret/secretbackend"
+	"github.com/ovh/cds/engine/api/secret/vaultsecretbackend"
 	"github.com/ovh/cds/engine/log"
 	"github.com/ovh/cds/sdk"
 )
@@ -31,10 +35,28 @@ var (
 	testingPrefix = "3IFCC4Ib"
 	//Client is a shared instance
 	Client secretbackend.Driver
+
+	// Versioned keys, fetched from cds/aes-key-v<N> secrets. Legacy cds/aes-key is version 0
+	keysMutex      sync.RWMutex
+	versionedKeys  = map[int][]byte{}
+	currentVersion int
 )
 
+const (
+	legacyKeyName    = "cds/aes-key"
+	versionedKeyName = "cds/aes-key-v"
+	// versionMarker replaces the last character of prefix in ciphertexts encrypted with a versioned key,
+	// followed by the key version and versionSeparator
+	versionMarker    = "v"
+	versionSeparator = "."
+)
+
+// VaultBackend is the name of the embedded Vault secret backend
+const VaultBackend = "vault"
+
 // Init password manager
 // if secretBackendBinary is empty, use default AES key and default file secret backend
+// if secretBackendBinary is "vault", use embedded Vault secret backend
 func Init(secretBackendBinary string, opts map[string]string) error {
 	//Initializing secret backend
 	var err error
@@ -45,6 +67,12 @@ func Init(secretBackendBinary string, opts map[string]string) error {
 		prefix = testingPrefix
 		log.Warning("Using default file secret backend")
 		Client = filesecretbackend.Client(opts)
+	} else if secretBackendBinary == VaultBackend {
+		log.Notice("Using Vault secret backend")
+		Client, err = vaultsecretbackend.Client(opts)
+		if err != nil {
+			return err
+		}
 	} else {
 		//Load the secretbackend plugin
 		log.Notice("Loading Secret Backend Plugin %s", secretBackendBinary)
@@ -57,28 +85,142 @@ func Init(secretBackendBinary string, opts map[string]string) error {
 
 	//If key hasn't been initilized with default key
 	if len(key) == 0 {
-		secrets := Client.GetSecrets()
-		if secrets.Err() != nil {
-			return secrets.Err()
+		return LoadKeys()
+	}
+
+	return nil
+}
+
+// LoadKeys fetches legacy and versioned AES keys from secret backend. The highest version is used to encrypt,
+// all versions are used to decrypt. Keys can be loaded again to add new versions without restart
+func LoadKeys() error {
+	secrets, err := Client.GetSecrets().All()
+	if err != nil {
+		return err
+	}
+
+	versions := map[int][]byte{}
+	current := 0
+	for k, v := range secrets {
+		if !strings.HasPrefix(k, versionedKeyName) || v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimPrefix(k, versionedKeyName))
+		if err != nil || n <= 0 {
+			log.Warning("secret.LoadKeys> Ignoring invalid key name %s\n", k)
+			continue
 		}
-		aesKey, _ := secrets.Get("cds/aes-key")
-		if aesKey == "" {
-			log.Critical("secret.Init> cds/aes-key not found\n")
-			return sdk.ErrSecretKeyFetchFailed
+		versions[n] = []byte(v)
+		if n > current {
+			current = n
 		}
-		key = []byte(aesKey)
 	}
 
+	legacy := secrets[legacyKeyName]
+	if legacy == "" && current == 0 {
+		log.Critical("secret.LoadKeys> %s not found\n", legacyKeyName)
+		return sdk.ErrSecretKeyFetchFailed
+	}
+
+	keysMutex.Lock()
+	defer keysMutex.Unlock()
+	if legacy != "" {
+		key = []byte(legacy)
+	}
+	versionedKeys = versions
+	currentVersion = current
 	return nil
 }
 
-// Encrypt data using aes+hmac algorithm
+// CurrentKeyVersion returns the version of the key used to encrypt, 0 being legacy key
+func CurrentKeyVersion() int {
+	keysMutex.RLock()
+	defer keysMutex.RUnlock()
+	return currentVersion
+}
+
+// KeyVersions returns versions of all keys available to decrypt
+func KeyVersions() []int {
+	keysMutex.RLock()
+	defer keysMutex.RUnlock()
+
+	res := []int{}
+	if len(key) > 0 {
+		res = append(res, 0)
+	}
+	for v := range versionedKeys {
+		res = append(res, v)
+	}
+	sort.Ints(res)
+	return res
+}
+
+// KeyVersion returns the version of the key data was encrypted with. ok is false if data is not encrypted
+func KeyVersion(data []byte) (version int, ok bool) {
+	_, version, ok = splitPrefix(data)
+	return version, ok
+}
+
+func versionedPrefix() string {
+	return prefix[:len(prefix)-1] + versionMarker
+}
+
+// splitPrefix removes prefix from ciphertext and returns the key version
+func splitPrefix(data []byte) ([]byte, int, bool) {
+	s := string(data)
+	if strings.HasPrefix(s, prefix) {
+		return []byte(strings.TrimPrefix(s, prefix)), 0, true
+	}
+
+	vp := versionedPrefix()
+	if !strings.HasPrefix(s, vp) {
+		return data, 0, false
+	}
+	s = strings.TrimPrefix(s, vp)
+	i := strings.Index(s, versionSeparator)
+	if i <= 0 {
+		return data, 0, false
+	}
+	version, err := strconv.Atoi(s[:i])
+	if err != nil {
+		return data, 0, false
+	}
+	return []byte(s[i+1:]), version, true
+}
+
+// getKey returns key of given version
+func getKey(version int) ([]byte, error) {
+	keysMutex.RLock()
+	defer keysMutex.RUnlock()
+
+	if version == 0 {
+		if key == nil {
+			log.Critical("Missing key, init failed?")
+			return nil, sdk.ErrSecretKeyFetchFailed
+		}
+		return key, nil
+	}
+
+	k, ok := versionedKeys[version]
+	if !ok {
+		log.Critical("Missing key version %d", version)
+		return nil, sdk.ErrSecretKeyFetchFailed
+	}
+	return k, nil
+}
+
+// Encrypt data using aes+hmac algorithm with the current key
 // Init() must be called before any encryption
 func Encrypt(data []byte) ([]byte, error) {
+	return encrypt(data, CurrentKeyVersion())
+}
+
+// encrypt data with the key of given version
+func encrypt(data []byte, version int) ([]byte, error) {
 	// Check key is ready
-	if key == nil {
-		log.Critical("Missing key, init failed?")
-		return nil, sdk.ErrSecretKeyFetchFailed
+	key, err := getKey(version)
+	if err != nil {
+		return nil, err
 	}
 	// generate nonce
 	nonce := make([]byte, nonceSize)
@@ -100,21 +242,43 @@ func Encrypt(data []byte) ([]byte, error) {
 	h.Write(ct)
 	ct = h.Sum(ct)
 
-	return append([]byte(prefix), ct...), nil
+	if version == 0 {
+		return append([]byte(prefix), ct...), nil
+	}
+	return append([]byte(versionedPrefix()+strconv.Itoa(version)+versionSeparator), ct...), nil
+}
+
+// Reencrypt decrypts data and encrypts it again with the current key if it was encrypted with an older key.
+// changed is false if data is not encrypted or already uses the current key
+func Reencrypt(data []byte) (res []byte, changed bool, err error) {
+	version, ok := KeyVersion(data)
+	current := CurrentKeyVersion()
+	if !ok || version == current {
+		return data, false, nil
+	}
+
+	clear, err := Decrypt(data)
+	if err != nil {
+		return nil, false, err
+	}
+	res, err = encrypt(clear, current)
+	if err != nil {
+		return nil, false, err
+	}
+	return res, true, nil
 }
 
 // Decrypt data using aes+hmac algorithm
 // Init() must be called before any decryption
 func Decrypt(data []byte) ([]byte, error) {
-
-	if !strings.HasPrefix(string(data), prefix) {
+	data, version, ok := splitPrefix(data)
+	if !ok {
 		return data, nil
 	}
-	data = []byte(strings.TrimPrefix(string(data), prefix))
 
-	if key == nil {
-		log.Critical("Missing key, init failed?")
-		return nil, sdk.ErrSecretKeyFetchFailed
+	key, err := getKey(version)
+	if err != nil {
+		return nil, err
 	}
 
 	if len(data) < (nonceSize + macSize) {
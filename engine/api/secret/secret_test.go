@@ -3,6 +3,8 @@ package secret
 import (
 	"bytes"
 	"database/sql"
+	"encoding/base64"
+	"encoding/json"
 	"testing"
 
 	"github.com/ovh/cds/sdk"
@@ -99,3 +101,108 @@ func TestDecryptS(t *testing.T) {
 	}
 
 }
+
+func TestKeyRotation(t *testing.T) {
+	key = []byte("78eKVxCGLm6gwoH9LAQ15ZD5AOABo1Xb")
+	versionedKeys = map[int][]byte{}
+	currentVersion = 0
+	defer func() {
+		versionedKeys = map[int][]byte{}
+		currentVersion = 0
+	}()
+	data := []byte("Hello world !")
+
+	legacy, err := Encrypt(data)
+	if err != nil {
+		t.Fatalf("Encrypt failed: %s", err)
+	}
+	if v, ok := KeyVersion(legacy); !ok || v != 0 {
+		t.Fatalf("Expected legacy key version, got %d (%v)", v, ok)
+	}
+
+	versionedKeys = map[int][]byte{2: []byte("A1Zu4G7Kd2r7qPm1sYx4LnB8dEf0Hj3w")}
+	currentVersion = 2
+
+	rotated, changed, err := Reencrypt(legacy)
+	if err != nil || !changed {
+		t.Fatalf("Reencrypt failed: %s (changed: %v)", err, changed)
+	}
+	if v, ok := KeyVersion(rotated); !ok || v != 2 {
+		t.Fatalf("Expected key version 2, got %d (%v)", v, ok)
+	}
+
+	clear, err := Decrypt(rotated)
+	if err != nil {
+		t.Fatalf("Decrypt failed: %s", err)
+	}
+	if bytes.Compare(clear, data) != 0 {
+		t.Fatalf("Fail: Expected '%s', got '%s'", data, clear)
+	}
+
+	// Old ciphertexts remain readable until rotation is done
+	clear, err = Decrypt(legacy)
+	if err != nil || bytes.Compare(clear, data) != 0 {
+		t.Fatalf("Decrypt of legacy value failed: %s", err)
+	}
+
+	if _, changed, _ := Reencrypt(rotated); changed {
+		t.Fatalf("Value encrypted with current key should not change")
+	}
+	if _, changed, _ := Reencrypt(data); changed {
+		t.Fatalf("Clear value should not change")
+	}
+
+	versionedKeys = map[int][]byte{}
+	if _, err := Decrypt(rotated); err == nil {
+		t.Fatalf("Decrypt should fail with unknown key version")
+	}
+}
+
+func TestReencryptAudit(t *testing.T) {
+	key = []byte("78eKVxCGLm6gwoH9LAQ15ZD5AOABo1Xb")
+	versionedKeys = map[int][]byte{}
+	currentVersion = 0
+	defer func() {
+		versionedKeys = map[int][]byte{}
+		currentVersion = 0
+	}()
+
+	legacy, err := Encrypt([]byte("secret"))
+	if err != nil {
+		t.Fatalf("Encrypt failed: %s", err)
+	}
+	data, err := json.Marshal([]sdk.Variable{
+		{Name: "password", Type: sdk.SecretVariable, Value: base64.StdEncoding.EncodeToString(legacy)},
+		{Name: "text", Type: sdk.StringVariable, Value: "clear"},
+	})
+	if err != nil {
+		t.Fatalf("Marshal failed: %s", err)
+	}
+
+	if _, n, err := reencryptAudit(string(data)); err != nil || n != 0 {
+		t.Fatalf("Audit on current key should not change: %s (%d values)", err, n)
+	}
+
+	versionedKeys = map[int][]byte{2: []byte("A1Zu4G7Kd2r7qPm1sYx4LnB8dEf0Hj3w")}
+	currentVersion = 2
+
+	rotated, n, err := reencryptAudit(string(data))
+	if err != nil || n != 1 {
+		t.Fatalf("reencryptAudit failed: %s (%d values)", err, n)
+	}
+	var variables []sdk.Variable
+	if err := json.Unmarshal([]byte(rotated), &variables); err != nil {
+		t.Fatalf("Unmarshal failed: %s", err)
+	}
+	if variables[1].Value != "clear" {
+		t.Fatalf("Clear value should not change, got '%s'", variables[1].Value)
+	}
+	value, _ := base64.StdEncoding.DecodeString(variables[0].Value)
+	if v, ok := KeyVersion(value); !ok || v != 2 {
+		t.Fatalf("Expected key version 2, got %d (%v)", v, ok)
+	}
+	clear, err := Decrypt(value)
+	if err != nil || string(clear) != "secret" {
+		t.Fatalf("Decrypt failed: %s", err)
+	}
+}
@@ -0,0 +1,391 @@
+package vaultsecretbackend
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ovh/cds/engine/api/secret/secretbackend"
+	"github.com/ovh/cds/engine/log"
+)
+
+// Default values of options
+const (
+	defaultMount      = "secret"
+	defaultPath       = "cds"
+	defaultAppRole    = "approle"
+	defaultKVVersion  = 1
+	defaultRefresh    = 300
+	minRenewInterval  = 5 * time.Second
+	secretValueField  = "value"
+	secretNamesPrefix = "cds/"
+)
+
+type vaultSecretBackend struct {
+	addr      string
+	mount     string
+	path      string
+	kvVersion int
+	roleID    string
+	secretID  string
+	appRole   string
+	useRole   bool
+	refresh   time.Duration
+	http      *http.Client
+
+	mutex         sync.RWMutex
+	token         string
+	leaseDuration time.Duration
+	renewable     bool
+	secrets       map[string]string
+	stop          chan struct{}
+}
+
+type vaultResponse struct {
+	Data     json.RawMessage `json:"data"`
+	Errors   []string        `json:"errors"`
+	Auth     *vaultAuth      `json:"auth"`
+	Warnings []string        `json:"warnings"`
+}
+
+type vaultAuth struct {
+	ClientToken   string `json:"client_token"`
+	LeaseDuration int    `json:"lease_duration"`
+	Renewable     bool   `json:"renewable"`
+}
+
+//Client returns a Vault SecretBackend. Secrets are fetched once, then every vault_refresh seconds
+func Client(opts map[string]string) (secretbackend.Driver, error) {
+	c := &vaultSecretBackend{}
+	if err := c.Init(secretbackend.NewOptions(opts)); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *vaultSecretBackend) Name() string {
+	return "Vault Secret Backend - CDS Embbeded"
+}
+
+//Init reads options, authenticates and fetches secrets. Options are:
+// - vault_addr: Vault address, i.e. https://vault.example.com:8200
+// - vault_token: token authentication
+// - vault_role_id, vault_secret_id: AppRole authentication, used if vault_token is empty
+// - vault_approle_path: AppRole auth method mount path (default approle)
+// - vault_mount: KV secrets engine mount path (default secret)
+// - vault_path: path of CDS secrets in the KV secrets engine (default cds)
+// - vault_kv_version: KV secrets engine version, 1 or 2 (default 1)
+// - vault_refresh: interval between two fetches of secrets, in seconds (default 300, 0 to disable)
+func (c *vaultSecretBackend) Init(opts secretbackend.MapVar) error {
+	c.addr = strings.TrimSuffix(opts.Get("vault_addr"), "/")
+	if c.addr == "" {
+		return fmt.Errorf("vault_addr option is mandatory")
+	}
+
+	c.token = opts.Get("vault_token")
+	c.roleID = opts.Get("vault_role_id")
+	c.secretID = opts.Get("vault_secret_id")
+	if c.token == "" && c.roleID == "" {
+		return fmt.Errorf("vault_token or vault_role_id option is mandatory")
+	}
+	c.useRole = c.token == ""
+
+	c.appRole = optionOrDefault(opts, "vault_approle_path", defaultAppRole)
+	c.mount = strings.Trim(optionOrDefault(opts, "vault_mount", defaultMount), "/")
+	c.path = strings.Trim(optionOrDefault(opts, "vault_path", defaultPath), "/")
+
+	var err error
+	if c.kvVersion, err = intOption(opts, "vault_kv_version", defaultKVVersion); err != nil {
+		return err
+	}
+	if c.kvVersion != 1 && c.kvVersion != 2 {
+		return fmt.Errorf("invalid vault_kv_version %d", c.kvVersion)
+	}
+	refresh, err := intOption(opts, "vault_refresh", defaultRefresh)
+	if err != nil {
+		return err
+	}
+	c.refresh = time.Duration(refresh) * time.Second
+
+	c.http = &http.Client{Timeout: 30 * time.Second}
+	c.stop = make(chan struct{})
+
+	if err := c.login(); err != nil {
+		return err
+	}
+	if err := c.fetch(); err != nil {
+		return err
+	}
+
+	go c.renewRoutine()
+	if c.refresh > 0 {
+		go c.refreshRoutine()
+	}
+	return nil
+}
+
+//GetSecrets returns last fetched secrets
+func (c *vaultSecretBackend) GetSecrets() secretbackend.Secrets {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	secrets := make(map[string]string, len(c.secrets))
+	for k, v := range c.secrets {
+		secrets[k] = v
+	}
+	return *secretbackend.NewSecrets(secrets)
+}
+
+//Close stops renewal and refresh routines
+func (c *vaultSecretBackend) Close() {
+	close(c.stop)
+}
+
+//login authenticates with AppRole, or looks up lease of the given token
+func (c *vaultSecretBackend) login() error {
+	if c.useRole {
+		body := map[string]string{"role_id": c.roleID, "secret_id": c.secretID}
+		res, err := c.request("POST", fmt.Sprintf("/v1/auth/%s/login", c.appRole), body, false)
+		if err != nil {
+			return fmt.Errorf("AppRole login failed: %s", err)
+		}
+		if res.Auth == nil || res.Auth.ClientToken == "" {
+			return fmt.Errorf("AppRole login failed: no token returned")
+		}
+		c.setAuth(res.Auth)
+		return nil
+	}
+
+	res, err := c.request("GET", "/v1/auth/token/lookup-self", nil, true)
+	if err != nil {
+		return fmt.Errorf("token lookup failed: %s", err)
+	}
+	var data struct {
+		TTL       int  `json:"ttl"`
+		Renewable bool `json:"renewable"`
+	}
+	if err := json.Unmarshal(res.Data, &data); err != nil {
+		return fmt.Errorf("token lookup failed: %s", err)
+	}
+	c.mutex.Lock()
+	c.leaseDuration = time.Duration(data.TTL) * time.Second
+	c.renewable = data.Renewable
+	c.mutex.Unlock()
+	return nil
+}
+
+func (c *vaultSecretBackend) setAuth(auth *vaultAuth) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.token = auth.ClientToken
+	c.leaseDuration = time.Duration(auth.LeaseDuration) * time.Second
+	c.renewable = auth.Renewable
+}
+
+//renew extends token lease. Tokens which can't be renewed are replaced by a new AppRole login
+func (c *vaultSecretBackend) renew() error {
+	c.mutex.RLock()
+	renewable := c.renewable
+	c.mutex.RUnlock()
+
+	if renewable {
+		res, err := c.request("POST", "/v1/auth/token/renew-self", nil, true)
+		if err == nil && res.Auth != nil {
+			c.setAuth(res.Auth)
+			return nil
+		}
+		if !c.useRole {
+			return fmt.Errorf("token renewal failed: %v", err)
+		}
+		log.Warning("Vault> Token renewal failed, login again: %v", err)
+	}
+
+	if !c.useRole {
+		return fmt.Errorf("token is not renewable and will expire")
+	}
+	return c.login()
+}
+
+//renewRoutine renews token when half of its lease is elapsed
+func (c *vaultSecretBackend) renewRoutine() {
+	for {
+		c.mutex.RLock()
+		lease := c.leaseDuration
+		c.mutex.RUnlock()
+
+		// Root tokens and tokens without TTL never expire
+		if lease == 0 {
+			return
+		}
+
+		wait := lease / 2
+		if wait < minRenewInterval {
+			wait = minRenewInterval
+		}
+
+		select {
+		case <-c.stop:
+			return
+		case <-time.After(wait):
+		}
+
+		if err := c.renew(); err != nil {
+			log.Warning("Vault> %s", err)
+		}
+	}
+}
+
+//refreshRoutine fetches secrets periodically so that rotated secrets are taken into account
+func (c *vaultSecretBackend) refreshRoutine() {
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-time.After(c.refresh):
+		}
+
+		if err := c.fetch(); err != nil {
+			log.Warning("Vault> Cannot refresh secrets: %s", err)
+		}
+	}
+}
+
+//fetch lists and reads all secrets under path. Secrets are named cds/<key> and their value is the value field
+func (c *vaultSecretBackend) fetch() error {
+	listPath := fmt.Sprintf("/v1/%s/%s", c.mount, c.path)
+	if c.kvVersion == 2 {
+		listPath = fmt.Sprintf("/v1/%s/metadata/%s", c.mount, c.path)
+	}
+
+	res, err := c.request("LIST", listPath, nil, true)
+	if err != nil {
+		return fmt.Errorf("cannot list secrets: %s", err)
+	}
+	var list struct {
+		Keys []string `json:"keys"`
+	}
+	if err := json.Unmarshal(res.Data, &list); err != nil {
+		return fmt.Errorf("cannot list secrets: %s", err)
+	}
+
+	secrets := map[string]string{}
+	for _, k := range list.Keys {
+		// Sub directories are not walked
+		if strings.HasSuffix(k, "/") {
+			continue
+		}
+		v, err := c.read(k)
+		if err != nil {
+			return fmt.Errorf("cannot read secret %s: %s", k, err)
+		}
+		secrets[secretNamesPrefix+k] = v
+	}
+
+	c.mutex.Lock()
+	c.secrets = secrets
+	c.mutex.Unlock()
+	return nil
+}
+
+func (c *vaultSecretBackend) read(k string) (string, error) {
+	readPath := fmt.Sprintf("/v1/%s/%s/%s", c.mount, c.path, k)
+	if c.kvVersion == 2 {
+		readPath = fmt.Sprintf("/v1/%s/data/%s/%s", c.mount, c.path, k)
+	}
+
+	res, err := c.request("GET", readPath, nil, true)
+	if err != nil {
+		return "", err
+	}
+
+	data := res.Data
+	if c.kvVersion == 2 {
+		var v2 struct {
+			Data json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(res.Data, &v2); err != nil {
+			return "", err
+		}
+		data = v2.Data
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return "", err
+	}
+	v, ok := fields[secretValueField].(string)
+	if !ok {
+		return "", fmt.Errorf("missing %s field", secretValueField)
+	}
+	return v, nil
+}
+
+func (c *vaultSecretBackend) request(method, path string, body interface{}, auth bool) (*vaultResponse, error) {
+	var reader *bytes.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(b)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequest(method, c.addr+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if auth {
+		c.mutex.RLock()
+		req.Header.Set("X-Vault-Token", c.token)
+		c.mutex.RUnlock()
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var res vaultResponse
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &res); err != nil {
+			return nil, fmt.Errorf("invalid response [%d]: %s", resp.StatusCode, err)
+		}
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("error [%d]: %s", resp.StatusCode, strings.Join(res.Errors, ", "))
+	}
+	return &res, nil
+}
+
+func optionOrDefault(opts secretbackend.MapVar, k, def string) string {
+	if v := opts.Get(k); v != "" {
+		return v
+	}
+	return def
+}
+
+func intOption(opts secretbackend.MapVar, k string, def int) (int, error) {
+	v := opts.Get(k)
+	if v == "" {
+		return def, nil
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s option: %s", k, v)
+	}
+	return i, nil
+}
@@ -0,0 +1,157 @@
+package vaultsecretbackend
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovh/cds/engine/api/secret/secretbackend"
+)
+
+// fakeVault serves a minimal subset of Vault HTTP API
+type fakeVault struct {
+	sync.Mutex
+	kvVersion int
+	secrets   map[string]string
+	renewals  int
+	logins    int
+}
+
+func (f *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	write := func(v interface{}) {
+		json.NewEncoder(w).Encode(v)
+	}
+
+	if r.URL.Path == "/v1/auth/approle/login" {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["role_id"] != "role" || body["secret_id"] != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			write(map[string]interface{}{"errors": []string{"invalid role or secret ID"}})
+			return
+		}
+		f.logins++
+		write(map[string]interface{}{"auth": map[string]interface{}{"client_token": "approle-token", "lease_duration": 2, "renewable": true}})
+		return
+	}
+
+	token := r.Header.Get("X-Vault-Token")
+	if token != "root" && token != "approle-token" {
+		w.WriteHeader(http.StatusForbidden)
+		write(map[string]interface{}{"errors": []string{"permission denied"}})
+		return
+	}
+
+	listPath, readPrefix := "/v1/secret/cds", "/v1/secret/cds/"
+	if f.kvVersion == 2 {
+		listPath, readPrefix = "/v1/secret/metadata/cds", "/v1/secret/data/cds/"
+	}
+
+	switch {
+	case r.URL.Path == "/v1/auth/token/lookup-self":
+		write(map[string]interface{}{"data": map[string]interface{}{"ttl": 0, "renewable": false}})
+	case r.URL.Path == "/v1/auth/token/renew-self":
+		f.renewals++
+		write(map[string]interface{}{"auth": map[string]interface{}{"client_token": token, "lease_duration": 2, "renewable": true}})
+	case r.Method == "LIST" && r.URL.Path == listPath:
+		keys := []string{"sub/"}
+		for k := range f.secrets {
+			keys = append(keys, k)
+		}
+		write(map[string]interface{}{"data": map[string]interface{}{"keys": keys}})
+	case r.Method == "GET" && strings.HasPrefix(r.URL.Path, readPrefix):
+		v, ok := f.secrets[strings.TrimPrefix(r.URL.Path, readPrefix)]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			write(map[string]interface{}{"errors": []string{}})
+			return
+		}
+		data := map[string]interface{}{"value": v}
+		if f.kvVersion == 2 {
+			data = map[string]interface{}{"data": data, "metadata": map[string]interface{}{"version": 1}}
+		}
+		write(map[string]interface{}{"data": data})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		write(map[string]interface{}{"errors": []string{fmt.Sprintf("unsupported %s %s", r.Method, r.URL.Path)}})
+	}
+}
+
+func (f *fakeVault) set(k, v string) {
+	f.Lock()
+	defer f.Unlock()
+	f.secrets[k] = v
+}
+
+func get(t *testing.T, d secretbackend.Driver, k string) string {
+	v, err := d.GetSecrets().Get(k)
+	assert.NoError(t, err)
+	return v
+}
+
+func TestTokenKVv1(t *testing.T) {
+	f := &fakeVault{kvVersion: 1, secrets: map[string]string{"aes-key": "foo"}}
+	s := httptest.NewServer(f)
+	defer s.Close()
+
+	d, err := Client(map[string]string{"vault_addr": s.URL, "vault_token": "root", "vault_refresh": "0"})
+	assert.NoError(t, err)
+	defer d.(*vaultSecretBackend).Close()
+
+	all, err := d.GetSecrets().All()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"cds/aes-key": "foo"}, all)
+}
+
+func TestAppRoleKVv2WithRefreshAndRenewal(t *testing.T) {
+	f := &fakeVault{kvVersion: 2, secrets: map[string]string{"repositoriesmanager-secrets-github-client-secret": "foo"}}
+	s := httptest.NewServer(f)
+	defer s.Close()
+
+	d, err := Client(map[string]string{
+		"vault_addr":       s.URL,
+		"vault_role_id":    "role",
+		"vault_secret_id":  "secret",
+		"vault_kv_version": "2",
+		"vault_refresh":    "1",
+	})
+	assert.NoError(t, err)
+	defer d.(*vaultSecretBackend).Close()
+	assert.Equal(t, "foo", get(t, d, "cds/repositoriesmanager-secrets-github-client-secret"))
+
+	f.set("repositoriesmanager-secrets-github-client-secret", "bar")
+	time.Sleep(minRenewInterval + 500*time.Millisecond)
+
+	assert.Equal(t, "bar", get(t, d, "cds/repositoriesmanager-secrets-github-client-secret"))
+	f.Lock()
+	assert.Equal(t, 1, f.logins)
+	assert.True(t, f.renewals > 0)
+	f.Unlock()
+}
+
+func TestInvalidOptions(t *testing.T) {
+	f := &fakeVault{kvVersion: 1, secrets: map[string]string{}}
+	s := httptest.NewServer(f)
+	defer s.Close()
+
+	for _, opts := range []map[string]string{
+		{"vault_token": "root"},
+		{"vault_addr": s.URL},
+		{"vault_addr": s.URL, "vault_token": "root", "vault_kv_version": "3"},
+		{"vault_addr": s.URL, "vault_token": "invalid"},
+		{"vault_addr": s.URL, "vault_role_id": "role", "vault_secret_id": "invalid"},
+	} {
+		_, err := Client(opts)
+		assert.Error(t, err, "%v", opts)
+	}
+}
@@ -0,0 +1,66 @@
+package secret
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/ovh/cds/engine/log"
+)
+
+// WatchRoutine compares secrets of the backend every interval seconds and calls onChange
+// when they changed, so that rotated secrets are used without restarting the API
+func WatchRoutine(interval int, onChange func()) {
+	defer log.Critical("secret.WatchRoutine exited")
+
+	if Client == nil {
+		return
+	}
+	last, err := Client.GetSecrets().All()
+	if err != nil {
+		log.Warning("secret.WatchRoutine> Cannot get secrets: %s\n", err)
+	}
+
+	for {
+		time.Sleep(time.Duration(interval) * time.Second)
+
+		current, err := Client.GetSecrets().All()
+		if err != nil {
+			log.Warning("secret.WatchRoutine> Cannot get secrets: %s\n", err)
+			continue
+		}
+		if reflect.DeepEqual(last, current) {
+			continue
+		}
+
+		log.Notice("secret.WatchRoutine> Secrets changed\n")
+		if prefix != testingPrefix {
+			reloadKeys(last, current)
+		}
+		last = current
+		onChange()
+	}
+}
+
+// reloadKeys loads new AES key versions. Existing keys must never change since values encrypted
+// with them could not be decrypted anymore: a new version has to be added instead
+func reloadKeys(last, current map[string]string) {
+	for k, v := range last {
+		if k != legacyKeyName && !strings.HasPrefix(k, versionedKeyName) {
+			continue
+		}
+		if current[k] != "" && current[k] != v {
+			log.Critical("secret.WatchRoutine> %s changed: add a new key version instead\n", k)
+			return
+		}
+	}
+
+	before := CurrentKeyVersion()
+	if err := LoadKeys(); err != nil {
+		log.Warning("secret.WatchRoutine> Cannot load keys: %s\n", err)
+		return
+	}
+	if after := CurrentKeyVersion(); after != before {
+		log.Notice("secret.WatchRoutine> Now encrypting with key version %d\n", after)
+	}
+}
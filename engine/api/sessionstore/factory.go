@@ -14,7 +14,8 @@ var Status string
 func Get(mode, redisHost, redisPassword string, ttl int) (Store, error) {
 	log.Notice("SessionStore> Intializing store (%s)\n", mode)
 	switch mode {
-	case "redis":
+	// Sessions are always shared with a twolevel cache
+	case "redis", "twolevel":
 		Status = "Redis "
 		r, err := NewRedis(redisHost, redisPassword, ttl)
 		if err != nil {
@@ -0,0 +1,40 @@
+package main
+
+import (
+	stdcontext "context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/api/leader"
+	"github.com/ovh/cds/engine/log"
+)
+
+// shuttingDown is set to 1 to make /mon/status fail, for load balancers to stop sending requests
+var shuttingDown int32
+
+func isShuttingDown() bool {
+	return atomic.LoadInt32(&shuttingDown) == 1
+}
+
+// shutdown hands singleton routines over to another instance, keeps serving requests for delay
+// while load balancers check /mon/status, drains requests in progress until timeout, then closes sql connections
+func shutdown(s *http.Server, delay, timeout time.Duration) {
+	atomic.StoreInt32(&shuttingDown, 1)
+	log.Warning("Shutting down\n")
+
+	leader.Resign()
+	time.Sleep(delay)
+
+	ctx, cancel := stdcontext.WithTimeout(stdcontext.Background(), timeout)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		log.Warning("Cannot drain requests in progress: %s\n", err)
+	}
+
+	log.Warning("Cleanup SQL connections\n")
+	if db := database.DB(); db != nil {
+		db.Close()
+	}
+}
@@ -0,0 +1,119 @@
+package stats
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/ovh/cds/engine/api/cache"
+	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/api/leader"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/engine/metrics"
+	"github.com/ovh/cds/sdk"
+)
+
+var (
+	queueDepth   = metrics.NewGauge("cds_queue_depth", "Number of action builds waiting in queue")
+	queueOldest  = metrics.NewGauge("cds_queue_oldest_wait_seconds", "Time the oldest action build in queue has been waiting")
+	workersCount = metrics.NewGauge("cds_workers", "Number of registered workers by model and status", "model", "status")
+)
+
+// metricsKey is the cache key of gauges computed by the leader, shared by all API instances
+var metricsKey = cache.Key("stats", "metrics")
+
+// metricsInterval is how often the leader computes gauges from database
+const metricsInterval = 15 * time.Second
+
+// dbMetrics are the values of gauges computed from database
+type dbMetrics struct {
+	QueueDepth  int64         `json:"queue_depth"`
+	QueueOldest time.Time     `json:"queue_oldest"`
+	Workers     []workerCount `json:"workers"`
+}
+
+type workerCount struct {
+	Model  string `json:"model"`
+	Status string `json:"status"`
+	Count  int64  `json:"count"`
+}
+
+func init() {
+	metrics.OnCollect(collectMetrics)
+}
+
+// MetricsRoutine computes gauges from database on the leader, so that scrapes only read them from cache
+func MetricsRoutine() {
+	defer sdk.Exit("MetricsRoutine exited")
+
+	for {
+		db := database.DB()
+		if db != nil && leader.IsLeader() {
+			var m dbMetrics
+			var err error
+			if m.QueueDepth, m.QueueOldest, err = loadQueueMetrics(db); err != nil {
+				log.Warning("MetricsRoutine> Cannot count queue: %s\n", err)
+			} else if m.Workers, err = loadWorkerMetrics(db); err != nil {
+				log.Warning("MetricsRoutine> Cannot count workers: %s\n", err)
+			} else {
+				cache.SetWithTTL(metricsKey, m, int(4*metricsInterval/time.Second))
+			}
+		}
+		time.Sleep(metricsInterval)
+	}
+}
+
+// collectMetrics updates gauges with the values computed by the leader when metrics are scraped
+func collectMetrics() {
+	var m dbMetrics
+	if !cache.Get(metricsKey, &m) {
+		return
+	}
+
+	queueDepth.Set(float64(m.QueueDepth))
+	if m.QueueOldest.IsZero() {
+		queueOldest.Set(0)
+	} else {
+		queueOldest.Set(time.Since(m.QueueOldest).Seconds())
+	}
+
+	workersCount.Reset()
+	for _, w := range m.Workers {
+		workersCount.Set(float64(w.Count), w.Model, w.Status)
+	}
+}
+
+// loadQueueMetrics returns the number of action builds waiting in queue and when the oldest was queued
+func loadQueueMetrics(db *sql.DB) (int64, time.Time, error) {
+	var depth int64
+	var oldest pq.NullTime
+	query := `SELECT COUNT(id), MIN(queued) FROM action_build WHERE status = $1`
+	if err := db.QueryRow(query, sdk.StatusWaiting.String()).Scan(&depth, &oldest); err != nil {
+		return 0, time.Time{}, err
+	}
+	return depth, oldest.Time, nil
+}
+
+// loadWorkerMetrics counts registered workers by model and status
+func loadWorkerMetrics(db *sql.DB) ([]workerCount, error) {
+	query := `SELECT COALESCE(worker_model.name, ''), worker.status, COUNT(worker.id)
+		FROM worker
+		LEFT JOIN worker_model ON worker_model.id = worker.model
+		GROUP BY worker_model.name, worker.status`
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []workerCount{}
+	for rows.Next() {
+		var c workerCount
+		if err := rows.Scan(&c.Model, &c.Status, &c.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, nil
+}
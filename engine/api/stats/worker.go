@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/api/leader"
 	"github.com/ovh/cds/engine/log"
 	"github.com/ovh/cds/sdk"
 )
@@ -20,7 +21,7 @@ func StartRoutine() {
 			time.Sleep(2 * time.Second)
 
 			db := database.DB()
-			if db != nil {
+			if db != nil && leader.IsLeader() {
 				err := createTodaysRow(db)
 				if err != nil {
 					log.Critical("StatsRoutine: Cannot create today's row: %s\n", err)
@@ -11,6 +11,7 @@ import (
 	"github.com/ovh/cds/engine/api/context"
 	"github.com/ovh/cds/engine/api/database"
 	"github.com/ovh/cds/engine/api/internal"
+	"github.com/ovh/cds/engine/api/leader"
 	"github.com/ovh/cds/engine/api/mail"
 	"github.com/ovh/cds/engine/api/notification"
 	"github.com/ovh/cds/engine/api/objectstore"
@@ -53,6 +54,7 @@ func statusHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *contex
 
 	// Check vault
 	output = append(output, fmt.Sprintf("Secret Backend: %s", secret.Status()))
+	output = append(output, fmt.Sprintf("Secret Key Version: %d (available: %v)", secret.CurrentKeyVersion(), secret.KeyVersions()))
 
 	// Check redis
 	output = append(output, fmt.Sprintf("Cache: %s", cache.Status))
@@ -72,8 +74,14 @@ func statusHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *contex
 	// Check database
 	output = append(output, database.Status())
 
+	// Check leader election
+	output = append(output, leader.Status())
+
 	var status = http.StatusOK
-	if panicked {
+	if isShuttingDown() {
+		output = append(output, "Shutting down")
+	}
+	if panicked || isShuttingDown() {
 		status = http.StatusServiceUnavailable
 	}
 	WriteJSON(w, r, output, status)
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/ovh/cds/engine/api/build"
+	"github.com/ovh/cds/engine/api/context"
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+// Number of last builds analysed when not given
+const (
+	defaultTestHistoryBuilds   = 20
+	defaultTestAnalyticsBuilds = 50
+	defaultSlowestTestsLimit   = 10
+)
+
+// getTestHistoryFilter reads pipeline, application, environment and branch of test history requests
+func getTestHistoryFilter(r *http.Request, db *sql.DB, c *context.Context, defaultBuilds int) (build.TestHistoryFilter, error) {
+	f := build.TestHistoryFilter{
+		Branch: r.FormValue("branch"),
+		Suite:  r.FormValue("suite"),
+		Name:   r.FormValue("name"),
+		Builds: defaultBuilds,
+	}
+
+	if b := r.FormValue("builds"); b != "" {
+		builds, err := strconv.Atoi(b)
+		if err != nil || builds <= 0 {
+			return f, sdk.ErrWrongRequest
+		}
+		f.Builds = builds
+	}
+
+	p, a, env, err := loadTestedPipeline(r, db, c)
+	if err != nil {
+		return f, err
+	}
+	f.PipelineID = p.ID
+	f.ApplicationID = a.ID
+	f.EnvironmentID = env.ID
+
+	return f, nil
+}
+
+func getTestCaseHistoryHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	f, err := getTestHistoryFilter(r, db, c, defaultTestHistoryBuilds)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	results, err := build.LoadTestCaseHistory(db, f)
+	if err != nil {
+		log.Warning("getTestCaseHistoryHandler> Cannot load test case history: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteJSON(w, r, results, http.StatusOK)
+}
+
+func getFlakyTestsHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	f, err := getTestHistoryFilter(r, db, c, defaultTestAnalyticsBuilds)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	results, err := build.LoadTestCaseHistory(db, f)
+	if err != nil {
+		log.Warning("getFlakyTestsHandler> Cannot load test case history: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteJSON(w, r, build.FlakyTests(results), http.StatusOK)
+}
+
+func getSlowestTestsHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	f, err := getTestHistoryFilter(r, db, c, defaultTestAnalyticsBuilds)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	limit := defaultSlowestTestsLimit
+	if l := r.FormValue("limit"); l != "" {
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit <= 0 {
+			WriteError(w, r, sdk.ErrWrongRequest)
+			return
+		}
+	}
+
+	results, err := build.LoadTestCaseHistory(db, f)
+	if err != nil {
+		log.Warning("getSlowestTestsHandler> Cannot load test case history: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteJSON(w, r, build.SlowestTests(results, limit), http.StatusOK)
+}
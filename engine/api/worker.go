@@ -157,11 +157,49 @@ func refreshWorkerHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c
 	}
 }
 
+func declareWorkerCapabilitiesHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
+	if c.Agent != sdk.WorkerAgent || c.Worker.ID == "" {
+		WriteError(w, r, sdk.ErrForbidden)
+		return
+	}
+
+	data, errRead := ioutil.ReadAll(r.Body)
+	if errRead != nil {
+		WriteError(w, r, sdk.ErrWrongRequest)
+		return
+	}
+
+	var capas sdk.WorkerCapabilities
+	if err := json.Unmarshal(data, &capas); err != nil {
+		log.Warning("declareWorkerCapabilitiesHandler> Cannot unmarshal capabilities: %s\n", err)
+		WriteError(w, r, sdk.ErrWrongRequest)
+		return
+	}
+
+	worker.SetCapabilities(c.Worker.ID, capas)
+	log.Debug("declareWorkerCapabilitiesHandler> worker %s declared %d capabilities\n", c.Worker.ID, len(capas.Requirements))
+}
+
 func unregisterWorkerHandler(w http.ResponseWriter, r *http.Request, db *sql.DB, c *context.Context) {
-	err := worker.DeleteWorker(db, c.Worker.ID)
+	// Single use workers are kept disabled so that their hatchery destroys them,
+	// they are deleted once they stop beating
+	singleUse, err := worker.IsSingleUse(db, c.Worker.ID)
+	if err != nil {
+		log.Warning("unregisterWorkerHandler> cannot check if worker %s is single use: %s\n", c.Worker.ID, err)
+	}
+	if singleUse {
+		if err := worker.UpdateWorkerStatus(db, c.Worker.ID, sdk.StatusDisabled); err != nil {
+			log.Warning("unregisterWorkerHandler> cannot disable worker %s: %s\n", c.Worker.ID, err)
+			WriteError(w, r, err)
+		}
+		return
+	}
+
+	err = worker.DeleteWorker(db, c.Worker.ID)
 	if err != nil {
 		log.Warning("unregisterWorkerHandler> cannot delete worker %s\n", err)
 		WriteError(w, r, err)
 		return
 	}
+	worker.DeleteCapabilities(c.Worker.ID)
 }
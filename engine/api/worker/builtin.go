@@ -66,15 +66,154 @@ Check CDS Documentation for available type. Default to all`,
 	junit := sdk.NewAction(sdk.JUnitAction)
 	junit.Type = sdk.BuiltinAction
 	junit.Description = `CDS Builtin Action.
-Parse given file to extract Unit Test results.`
+Parse given file to extract Unit Test results and code coverage.
+Supported formats are JUnit, xUnit.net, NUnit, TAP and go test -json
+for tests, Cobertura and LCOV for coverage.`
 	junit.Parameter(sdk.Parameter{
 		Name:        "path",
-		Description: `Path to junit xml file.`,
+		Description: `Path to report files, may be a pattern.`,
 		Type:        sdk.TextParameter})
 	if err := checkBuiltinAction(db, junit); err != nil {
 		return err
 	}
 
+	// ----------------------------------- GitClone ---------------------------
+	gitclone := sdk.NewAction(sdk.GitCloneAction)
+	gitclone.Type = sdk.BuiltinAction
+	gitclone.Description = `CDS Builtin Action.
+Clone the repository of the application at the built branch and commit.
+SSH keys of project, application or environment are used for ssh urls,
+credentials of the repositories manager for https urls.`
+	gitclone.Requirement("git", sdk.BinaryRequirement, "git")
+	gitclone.Parameter(sdk.Parameter{
+		Name: "url",
+		Description: `Url of the repository. Default to the ssh url of the application repository
+(or git.url) if a SSH key is available, its https url (or git.http_url) otherwise.`,
+		Type: sdk.StringParameter})
+	gitclone.Parameter(sdk.Parameter{
+		Name:        "branch",
+		Description: "Branch to clone",
+		Value:       "{{.git.branch}}",
+		Type:        sdk.StringParameter})
+	gitclone.Parameter(sdk.Parameter{
+		Name:        "commit",
+		Description: "Commit to checkout, head of branch if empty",
+		Value:       "{{.git.hash}}",
+		Type:        sdk.StringParameter})
+	gitclone.Parameter(sdk.Parameter{
+		Name:        "directory",
+		Description: "Directory of the clone. Default to the name of the repository",
+		Type:        sdk.StringParameter})
+	gitclone.Parameter(sdk.Parameter{
+		Name:        "depth",
+		Description: "Number of commits to fetch, full history if empty",
+		Value:       "50",
+		Type:        sdk.StringParameter})
+	gitclone.Parameter(sdk.Parameter{
+		Name:        "submodules",
+		Description: "Clone submodules recursively",
+		Value:       "true",
+		Type:        sdk.BooleanParameter})
+	gitclone.Parameter(sdk.Parameter{
+		Name:        "lfs",
+		Description: "Fetch Git LFS files, git-lfs must be installed",
+		Value:       "false",
+		Type:        sdk.BooleanParameter})
+	gitclone.Parameter(sdk.Parameter{
+		Name:        "sparseCheckout",
+		Description: "Paths to checkout, one per line. Whole repository if empty",
+		Type:        sdk.TextParameter})
+	if err := checkBuiltinAction(db, gitclone); err != nil {
+		return err
+	}
+
+	// ----------------------------------- DockerBuild ---------------------------
+	docker := sdk.NewAction(sdk.DockerBuildAction)
+	docker.Type = sdk.BuiltinAction
+	docker.Description = `CDS Builtin Action.
+Build a Docker image, tag it then push it to a registry.
+The digest of the pushed image is exported as build variable docker.digest.`
+	docker.Requirement("docker", sdk.BinaryRequirement, "docker")
+	docker.Parameter(sdk.Parameter{
+		Name:        "image",
+		Description: "Name of the image, with its registry. For example registry.example.com/team/app",
+		Type:        sdk.StringParameter})
+	docker.Parameter(sdk.Parameter{
+		Name:        "dockerfile",
+		Description: "Path of the Dockerfile",
+		Value:       "Dockerfile",
+		Type:        sdk.StringParameter})
+	docker.Parameter(sdk.Parameter{
+		Name:        "context",
+		Description: "Directory sent as build context",
+		Value:       ".",
+		Type:        sdk.StringParameter})
+	docker.Parameter(sdk.Parameter{
+		Name:        "buildArgs",
+		Description: "Build arguments, one KEY=VALUE per line",
+		Type:        sdk.TextParameter})
+	docker.Parameter(sdk.Parameter{
+		Name: "tags",
+		Description: `Tags of the image, one per line.
+Characters not allowed in Docker tags are replaced by '-'.`,
+		Value: "{{.cds.version}}\n{{.git.branch}}",
+		Type:  sdk.TextParameter})
+	docker.Parameter(sdk.Parameter{
+		Name:        "registry",
+		Description: "Registry to log in. Default to the registry of the image",
+		Type:        sdk.StringParameter})
+	docker.Parameter(sdk.Parameter{
+		Name:        "username",
+		Description: "User of the registry, no login if empty",
+		Type:        sdk.StringParameter})
+	docker.Parameter(sdk.Parameter{
+		Name: "password",
+		Description: `Password of the registry. Use a project variable of type password or key,
+for example {{.cds.proj.registry_password}}`,
+		Type: sdk.StringParameter})
+	docker.Parameter(sdk.Parameter{
+		Name:        "push",
+		Description: "Push tags to the registry",
+		Value:       "true",
+		Type:        sdk.BooleanParameter})
+	if err := checkBuiltinAction(db, docker); err != nil {
+		return err
+	}
+
+	// ----------------------------------- Cache Save ---------------------------
+	cacheSave := sdk.NewAction(sdk.CacheSave)
+	cacheSave.Type = sdk.BuiltinAction
+	cacheSave.Description = `CDS Builtin Action.
+Archive given paths and store them as a cache of the project,
+to be restored by next builds with Cache Restore.
+Nothing is stored if a cache already exists with this key.`
+	cacheSave.Parameter(sdk.Parameter{
+		Name: "key",
+		Description: `Key of the cache, for example go-{{.git.branch}}-{{checksum "go.sum"}}.
+checksum is replaced by the hash of files matching given pattern.`,
+		Type: sdk.StringParameter})
+	cacheSave.Parameter(sdk.Parameter{
+		Name:        "path",
+		Description: `Paths to archive, one per line, relative to working directory.`,
+		Type:        sdk.TextParameter})
+	if err := checkBuiltinAction(db, cacheSave); err != nil {
+		return err
+	}
+
+	// ----------------------------------- Cache Restore ---------------------------
+	cacheRestore := sdk.NewAction(sdk.CacheRestore)
+	cacheRestore.Type = sdk.BuiltinAction
+	cacheRestore.Description = `CDS Builtin Action.
+Restore paths stored by Cache Save with the same key.
+A missing cache is not an error.`
+	cacheRestore.Parameter(sdk.Parameter{
+		Name:        "key",
+		Description: `Key of the cache, see Cache Save.`,
+		Type:        sdk.StringParameter})
+	if err := checkBuiltinAction(db, cacheRestore); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -0,0 +1,27 @@
+package worker
+
+import (
+	"github.com/ovh/cds/engine/api/cache"
+	"github.com/ovh/cds/sdk"
+)
+
+// CapabilitiesTTL is the time in seconds capabilities declared by a worker are kept,
+// workers declare them again before they expire
+const CapabilitiesTTL = 15 * 60
+
+//SetCapabilities keeps capabilities declared by a worker
+func SetCapabilities(workerID string, c sdk.WorkerCapabilities) {
+	cache.SetWithTTL(cache.Key("worker", "capabilities", workerID), c, CapabilitiesTTL)
+}
+
+//GetCapabilities returns capabilities declared by a worker, if not expired
+func GetCapabilities(workerID string) (sdk.WorkerCapabilities, bool) {
+	var c sdk.WorkerCapabilities
+	ok := cache.Get(cache.Key("worker", "capabilities", workerID), &c)
+	return c, ok
+}
+
+//DeleteCapabilities forgets capabilities declared by a worker
+func DeleteCapabilities(workerID string) {
+	cache.Delete(cache.Key("worker", "capabilities", workerID))
+}
@@ -8,9 +8,11 @@ import (
 	"encoding/json"
 
 	"github.com/ovh/cds/engine/api/action"
+	"github.com/ovh/cds/engine/api/build"
 	"github.com/ovh/cds/engine/api/group"
 	"github.com/ovh/cds/engine/log"
 	"github.com/ovh/cds/sdk"
+	"github.com/ovh/cds/sdk/tracing"
 )
 
 func logTime(name string, then time.Time) {
@@ -33,6 +35,8 @@ func LoadWorkerModelStatusForAdminUser(db *sql.DB, userID int64) ([]sdk.ModelSta
 	query := `
 		SELECT  worker_model.id, 
 				worker_model.name, 
+				worker_model.autoscaling,
+				worker_model.single_use,
 				COALESCE(waiting.count, 0) as waiting, 
 				COALESCE(building.count,0) as building 
 		FROM worker_model
@@ -59,10 +63,14 @@ func LoadWorkerModelStatusForAdminUser(db *sql.DB, userID int64) ([]sdk.ModelSta
 	var status []sdk.ModelStatus
 	for rows.Next() {
 		var ms sdk.ModelStatus
-		err := rows.Scan(&ms.ModelID, &ms.ModelName, &ms.CurrentCount, &ms.BuildingCount)
+		var autoscaling sql.NullString
+		err := rows.Scan(&ms.ModelID, &ms.ModelName, &autoscaling, &ms.SingleUse, &ms.CurrentCount, &ms.BuildingCount)
 		if err != nil {
 			return nil, err
 		}
+		if err := loadAutoscaling(&ms, autoscaling); err != nil {
+			return nil, err
+		}
 		status = append(status, ms)
 	}
 	return status, nil
@@ -83,6 +91,8 @@ func LoadWorkerModelStatusForGroup(db *sql.DB, groupID int64) ([]sdk.ModelStatus
 		SELECT  worker_model.id, 
 				worker_model.name, 
 				worker_model.group_id,
+				worker_model.autoscaling,
+				worker_model.single_use,
 				COALESCE(waiting.count, 0) as waiting, 
 				COALESCE(building.count,0) as building 
 		FROM worker_model
@@ -125,34 +135,52 @@ func LoadWorkerModelStatusForGroup(db *sql.DB, groupID int64) ([]sdk.ModelStatus
 	var status []sdk.ModelStatus
 	for rows.Next() {
 		var ms sdk.ModelStatus
-		err := rows.Scan(&ms.ModelID, &ms.ModelName, &ms.ModelGroupID, &ms.CurrentCount, &ms.BuildingCount)
+		var autoscaling sql.NullString
+		err := rows.Scan(&ms.ModelID, &ms.ModelName, &ms.ModelGroupID, &autoscaling, &ms.SingleUse, &ms.CurrentCount, &ms.BuildingCount)
 		if err != nil {
 			log.Warning("LoadWorkerModelStatusForGroup> Error : %s", err)
 			return nil, err
 		}
+		if err := loadAutoscaling(&ms, autoscaling); err != nil {
+			log.Warning("LoadWorkerModelStatusForGroup> Cannot unmarshal autoscaling of %s: %s", ms.ModelName, err)
+			return nil, err
+		}
 		status = append(status, ms)
 	}
 	return status, nil
 }
 
+func loadAutoscaling(ms *sdk.ModelStatus, autoscaling sql.NullString) error {
+	if !autoscaling.Valid || autoscaling.String == "" {
+		return nil
+	}
+	ms.Autoscaling = &sdk.ModelAutoscaling{}
+	return json.Unmarshal([]byte(autoscaling.String), ms.Autoscaling)
+}
+
 //ActionCount represents a count of action
 type ActionCount struct {
-	Action sdk.Action
-	Count  int64
+	Action       sdk.Action
+	Count        int64
+	OldestQueued time.Time
+	TraceParents []string
 }
 
+// maxTraceParents is the number of traced action builds given to hatcheries for each action, as parents of spawns
+const maxTraceParents = 10
+
 //LoadGroupActionCount counts waiting action for group
 func LoadGroupActionCount(db *sql.DB, groupID int64) ([]ActionCount, error) {
 
 	log.Debug("LoadGroupActionCount> Counting pending action for group %d", groupID)
 
-	acs := []ActionCount{}
 	query := `
-	SELECT COUNT(action_build.id), pipeline_action.action_id
+	SELECT action_build.id, pipeline_action.action_id, COALESCE(action_build.queued, NOW()), pipeline.type, pipeline.priority, COALESCE(owner.group_id, 0)
 	FROM action_build
 	JOIN pipeline_action ON pipeline_action.id = action_build.pipeline_action_id
   	JOIN pipeline_build ON pipeline_build.id = action_build.pipeline_build_id
   	JOIN pipeline ON pipeline.id = pipeline_build.pipeline_id
+	` + build.OwnerGroupJoin + `
 	JOIN pipeline_group ON pipeline_group.pipeline_id = pipeline.id
 	WHERE action_build.status = $1 
 	AND (
@@ -160,8 +188,8 @@ func LoadGroupActionCount(db *sql.DB, groupID int64) ([]ActionCount, error) {
 		OR
 		(select id from "group" where name = $3) = $2
 	)
-	GROUP BY pipeline_action.action_id
-	LIMIT 1000
+	ORDER BY action_build.pipeline_build_id, action_build.id
+	LIMIT 10000
 	`
 
 	rows, err := db.Query(query, string(sdk.StatusWaiting), groupID, group.SharedInfraGroup)
@@ -170,32 +198,21 @@ func LoadGroupActionCount(db *sql.DB, groupID int64) ([]ActionCount, error) {
 	}
 	defer rows.Close()
 
-	for rows.Next() {
-		ac := ActionCount{}
-		if err := rows.Scan(&ac.Count, &ac.Action.ID); err != nil {
-			return nil, err
-		}
-		ac.Action.Requirements, err = action.GetRequirements(db, ac.Action.ID)
-		if err != nil {
-			return nil, err
-		}
-		acs = append(acs, ac)
-	}
-	return acs, nil
+	return countActions(db, rows)
 }
 
 //LoadAllActionCount counts all waiting actions
 func LoadAllActionCount(db *sql.DB, userID int64) ([]ActionCount, error) {
-	acs := []ActionCount{}
 	query := `
-	SELECT COUNT(action_build.id), pipeline_action.action_id
+	SELECT action_build.id, pipeline_action.action_id, COALESCE(action_build.queued, NOW()), pipeline.type, pipeline.priority, COALESCE(owner.group_id, 0)
 	FROM action_build
 	JOIN pipeline_action ON pipeline_action.id = action_build.pipeline_action_id
   	JOIN pipeline_build ON pipeline_build.id = action_build.pipeline_build_id
   	JOIN pipeline ON pipeline.id = pipeline_build.pipeline_id
+	` + build.OwnerGroupJoin + `
 	WHERE action_build.status = $1 
-	GROUP BY pipeline_action.action_id
-	LIMIT 1000
+	ORDER BY action_build.pipeline_build_id, action_build.id
+	LIMIT 10000
 	`
 
 	rows, err := db.Query(query, string(sdk.StatusWaiting))
@@ -204,16 +221,69 @@ func LoadAllActionCount(db *sql.DB, userID int64) ([]ActionCount, error) {
 	}
 	defer rows.Close()
 
+	return countActions(db, rows)
+}
+
+//countActions counts waiting action builds by action, in fair share order.
+//Action builds of groups which reached their quota are not counted, so that no worker is spawned for them
+func countActions(db *sql.DB, rows *sql.Rows) ([]ActionCount, error) {
+	var queue []sdk.ActionBuild
+	actionIDs := map[int64]int64{}
 	for rows.Next() {
-		ac := ActionCount{}
-		if err := rows.Scan(&ac.Count, &ac.Action.ID); err != nil {
+		var ab sdk.ActionBuild
+		var actionID int64
+		var pType string
+		var priority sql.NullInt64
+		if err := rows.Scan(&ab.ID, &actionID, &ab.Queued, &pType, &priority, &ab.GroupID); err != nil {
 			return nil, err
 		}
-		ac.Action.Requirements, err = action.GetRequirements(db, ac.Action.ID)
+		ab.Priority = sdk.PipelinePriority(sdk.PipelineTypeFromString(pType), build.NullInt(priority))
+		actionIDs[ab.ID] = actionID
+		queue = append(queue, ab)
+	}
+	rows.Close()
+
+	usages, err := build.LoadGroupsUsage(db)
+	if err != nil {
+		return nil, err
+	}
+	queue, _ = build.FairShare(queue, usages)
+
+	acs := []ActionCount{}
+	index := map[int64]int{}
+	traced := map[int64][]int64{}
+	for _, ab := range queue {
+		actionID := actionIDs[ab.ID]
+		i, ok := index[actionID]
+		if !ok {
+			i = len(acs)
+			index[actionID] = i
+			acs = append(acs, ActionCount{Action: sdk.Action{ID: actionID}, OldestQueued: ab.Queued})
+		}
+		acs[i].Count++
+		if ab.Queued.Before(acs[i].OldestQueued) {
+			acs[i].OldestQueued = ab.Queued
+		}
+		if tracing.Enabled() && len(traced[actionID]) < maxTraceParents {
+			traced[actionID] = append(traced[actionID], ab.ID)
+		}
+	}
+
+	for i := range acs {
+		acs[i].Action.Requirements, err = action.GetRequirements(db, acs[i].Action.ID)
 		if err != nil {
 			return nil, err
 		}
-		acs = append(acs, ac)
+		for _, id := range traced[acs[i].Action.ID] {
+			tp, err := build.LoadTraceParent(db, id)
+			if err != nil {
+				log.Warning("countActions> Cannot load trace context of action build %d: %s\n", id, err)
+				continue
+			}
+			if tp != "" {
+				acs[i].TraceParents = append(acs[i].TraceParents, tp)
+			}
+		}
 	}
 	return acs, nil
 }
@@ -267,10 +337,22 @@ func EstimateWorkerModelNeeds(db *sql.DB, uid int64, workerModelStatus ModelStat
 				}
 
 				if modelCanRun(db, ms[i].ModelName, ac.Action.Requirements, capas) {
+					//Queue wait is the wait of the oldest action the model can run
+					if !ac.OldestQueued.IsZero() {
+						if wait := int64(time.Since(ac.OldestQueued).Seconds()); wait > ms[i].QueueWait {
+							ms[i].QueueWait = wait
+						}
+					}
+
 					if ac.Count > 0 {
 						ms[i].WantedCount++
 						ac.Count--
 						loopModels = true
+						//Spawn of wanted worker continues the trace of the action build
+						if len(ac.TraceParents) > 0 {
+							ms[i].TraceParents = append(ms[i].TraceParents, ac.TraceParents[0])
+							ac.TraceParents = ac.TraceParents[1:]
+						}
 					}
 
 					//Add model requirement if action has specific kind of requirements
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/api/leader"
 	"github.com/ovh/cds/engine/log"
 )
 
@@ -18,7 +19,7 @@ func Heartbeat() {
 
 	for {
 		time.Sleep(10 * time.Second)
-		if db := database.DB(); db != nil {
+		if db := database.DB(); db != nil && leader.IsLeader() {
 			w, err := LoadDeadWorkers(db, WorkerHeartbeatTimeout)
 			if err != nil {
 				log.Warning("WorkerHeartbeat> Cannot load dead workers: %s\n", err)
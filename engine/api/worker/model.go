@@ -28,6 +28,7 @@ func UpdateWorkerModel(db gorp.SqlExecutor, model sdk.Model) error {
 	if _, err := db.Update(&dbmodel); err != nil {
 		return err
 	}
+	invalidateModelCapabilities(model.ID)
 	return nil
 }
 
@@ -146,6 +147,7 @@ func DeleteWorkerModel(db gorp.SqlExecutor, ID int64) error {
 	if count == 0 {
 		return sdk.ErrNoWorkerModel
 	}
+	invalidateModelCapabilities(ID)
 	return nil
 }
 
@@ -195,6 +197,7 @@ func DeleteWorkerModelCapability(db database.Executer, workerID int64, capaName
 	if rows <= 0 {
 		return sdk.ErrNoWorkerModelCapa
 	}
+	invalidateModelCapabilities(workerID)
 
 	return nil
 }
@@ -213,6 +216,7 @@ func UpdateWorkerModelCapability(db database.Executer, capa sdk.Requirement, mod
 	if rows <= 0 {
 		return sdk.ErrNoWorkerModelCapa
 	}
+	invalidateModelCapabilities(modelID)
 
 	return nil
 }
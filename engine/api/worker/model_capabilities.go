@@ -6,31 +6,28 @@ import (
 
 	"github.com/ovh/cds/engine/api/cache"
 	"github.com/ovh/cds/engine/api/database"
+	"github.com/ovh/cds/engine/api/leader"
 	"github.com/ovh/cds/engine/log"
 	"github.com/ovh/cds/sdk"
 )
 
-//ModelCapabilititiesCacheLoader set all model Capabilities in the cache
+// modelCapabilities of worker models are cached by model ID
+var modelCapabilities = cache.NewKind(0, "worker", "modelcapabilitites")
+
+//ModelCapabilititiesCacheLoader set all model Capabilities in the cache.
+//With a shared cache, only the leader loads them.
 func ModelCapabilititiesCacheLoader(delay time.Duration) {
 	for {
 		time.Sleep(delay * time.Second)
 		db := database.DB()
-		dbmap := database.DBMap(db)
-		if db != nil {
-			var mayIWork string
-			loaderKey := cache.Key("worker", "modelcapabilitites", "loading")
-			if cache.Get(loaderKey, &mayIWork) {
-				cache.SetWithTTL(loaderKey, "true", 60)
-				wms, err := LoadWorkerModels(dbmap)
-				if err != nil {
-					log.Warning("ModelCapabilititiesCacheLoader> Unable to load worker models: %s", err)
-					continue
-				}
-				for _, wm := range wms {
-					modelKey := cache.Key("worker", "modelcapabilitites", fmt.Sprintf("%d", wm.ID))
-					cache.Set(modelKey, wm.Capabilities)
-				}
-				cache.Delete(loaderKey)
+		if db != nil && (!cache.Shared() || leader.IsLeader()) {
+			wms, err := LoadWorkerModels(database.DBMap(db))
+			if err != nil {
+				log.Warning("ModelCapabilititiesCacheLoader> Unable to load worker models: %s", err)
+				continue
+			}
+			for _, wm := range wms {
+				modelCapabilities.Refresh(fmt.Sprintf("%d", wm.ID), wm.Capabilities)
 			}
 		}
 	}
@@ -38,16 +35,20 @@ func ModelCapabilititiesCacheLoader(delay time.Duration) {
 
 //GetModelCapabilities load model capabilities from cache
 func GetModelCapabilities(db database.Querier, modelID int64) ([]sdk.Requirement, error) {
-	modelKey := cache.Key("worker", "modelcapabilitites", fmt.Sprintf("%d", modelID))
 	req := []sdk.Requirement{}
 	//if we didn't got any data, try to load from DB
-	if !cache.Get(modelKey, &req) {
+	err := modelCapabilities.Load(fmt.Sprintf("%d", modelID), &req, func() error {
 		var err error
 		req, err = LoadWorkerModelCapabilities(db, modelID)
-		if err != nil {
-			return nil, fmt.Errorf("GetModelCapabilities> cannot loadWorkerModelCapabilities: %s\n", err)
-		}
-		cache.Set(modelKey, req)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("GetModelCapabilities> cannot loadWorkerModelCapabilities: %s\n", err)
 	}
 	return req, nil
 }
+
+// invalidateModelCapabilities removes capabilities of worker model from cache, on all instances
+func invalidateModelCapabilities(modelID int64) {
+	modelCapabilities.Delete(fmt.Sprintf("%d", modelID))
+}
@@ -97,6 +97,19 @@ func LoadWorker(db database.Querier, id string) (*sdk.Worker, error) {
 	return w, nil
 }
 
+// IsSingleUse returns true if given worker has been spawned by an hatchery from a single use model
+func IsSingleUse(db database.Querier, id string) (bool, error) {
+	query := `SELECT worker.hatchery_id <> 0 AND COALESCE(worker_model.single_use, false)
+	          FROM worker
+	          LEFT JOIN worker_model ON worker_model.id = worker.model
+	          WHERE worker.id = $1`
+	var singleUse bool
+	if err := db.QueryRow(query, id).Scan(&singleUse); err != nil {
+		return false, err
+	}
+	return singleUse, nil
+}
+
 // LoadWorkersByModel load workers by model
 func LoadWorkersByModel(db database.Querier, modelID int64) ([]sdk.Worker, error) {
 	w := []sdk.Worker{}
@@ -284,6 +297,7 @@ func RegisterWorker(db *sql.DB, name string, key string, modelID int64, h *sdk.H
 		HatcheryID: h.ID,
 		Status:     sdk.StatusWaiting,
 		GroupID:    t.GroupID,
+		SingleUse:  m.SingleUse,
 	}
 
 	if h != nil {
@@ -351,6 +365,10 @@ func RegisterWorker(db *sql.DB, name string, key string, modelID int64, h *sdk.H
 			}
 			if err := ntx.Commit(); err != nil {
 				log.Warning("RegisterWorker> Unable to commit transaction : %s", err)
+				return
+			}
+			if len(newCapas) > 0 {
+				invalidateModelCapabilities(modelID)
 			}
 		}()
 	}
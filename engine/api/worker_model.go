@@ -53,6 +53,14 @@ func addWorkerModel(w http.ResponseWriter, r *http.Request, db *sql.DB, c *conte
 		return
 	}
 
+	if model.Autoscaling != nil {
+		if err := model.Autoscaling.IsValid(); err != nil {
+			log.Warning("addWorkerModel> invalid autoscaling: %s\n", err)
+			WriteError(w, r, err)
+			return
+		}
+	}
+
 	//User must be admin of the group set in the model
 	var ok bool
 	for _, g := range c.User.Groups {
@@ -153,6 +161,30 @@ func updateWorkerModel(w http.ResponseWriter, r *http.Request, db *sql.DB, c *co
 		model.ID = old.ID
 	}
 
+	//If the model SingleUse or KeepOnFailure has not been set, keep the old ones
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err == nil {
+		if _, ok := fields["single_use"]; !ok {
+			model.SingleUse = old.SingleUse
+		}
+		if _, ok := fields["keep_on_failure"]; !ok {
+			model.KeepOnFailure = old.KeepOnFailure
+		}
+	}
+	if model.KeepOnFailure < 0 {
+		WriteError(w, r, sdk.ErrWrongRequest)
+		return
+	}
+
+	//If the model Autoscaling has not been set, keep the old Autoscaling
+	if model.Autoscaling == nil {
+		model.Autoscaling = old.Autoscaling
+	} else if err := model.Autoscaling.IsValid(); err != nil {
+		log.Warning("updateWorkerModel> invalid autoscaling: %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
 	//User must be admin of the group set in the new model
 	var ok bool
 	for _, g := range c.User.Groups {
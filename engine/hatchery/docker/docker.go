@@ -186,7 +186,12 @@ func (hd *HatcheryDocker) SpawnWorker(wm *sdk.Model, req []sdk.Requirement) erro
 	var args []string
 	args = append(args, "run", "--rm", "-a", "STDOUT", "-a", "STDERR")
 	args = append(args, fmt.Sprintf("--name=%s", name))
-	args = append(args, "-e", "CDS_SINGLE_USE=1")
+	if wm.SingleUse {
+		args = append(args, "-e", "CDS_SINGLE_USE=1")
+	}
+	if wm.KeepOnFailure > 0 {
+		args = append(args, "-e", fmt.Sprintf("CDS_KEEP_ON_FAILURE=%d", wm.KeepOnFailure))
+	}
 	args = append(args, "-e", fmt.Sprintf("CDS_API=%s", sdk.Host))
 	args = append(args, "-e", fmt.Sprintf("CDS_NAME=%s", name))
 	args = append(args, "-e", fmt.Sprintf("CDS_KEY=%s", viper.GetString("token")))
@@ -86,7 +86,9 @@ func (h *HatcheryLocal) SpawnWorker(wm *sdk.Model, req []sdk.Requirement) error
 	args = append(args, fmt.Sprintf("--model=%d", h.Hatchery().Model.ID))
 	args = append(args, fmt.Sprintf("--name=%s", wName))
 	args = append(args, fmt.Sprintf("--hatchery=%d", h.hatch.ID))
-	args = append(args, "--single-use")
+	if wm.SingleUse {
+		args = append(args, "--single-use")
+	}
 
 	cmd := exec.Command("worker", args...)
 
@@ -98,6 +100,9 @@ func (h *HatcheryLocal) SpawnWorker(wm *sdk.Model, req []sdk.Requirement) error
 			cmd.Env = append(cmd.Env, e)
 		}
 	}
+	if wm.KeepOnFailure > 0 {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("CDS_KEEP_ON_FAILURE=%d", wm.KeepOnFailure))
+	}
 
 	if err = cmd.Start(); err != nil {
 		return err
@@ -10,7 +10,9 @@ import (
 	"github.com/ovh/cds/engine/hatchery/openstack"
 	"github.com/ovh/cds/engine/hatchery/swarm"
 	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/engine/metrics"
 	"github.com/ovh/cds/sdk"
+	"github.com/ovh/cds/sdk/tracing"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -38,9 +40,20 @@ var rootCmd = &cobra.Command{
 		if viper.GetString("token") == "" {
 			sdk.Exit("Worker token not provided. See help on flag --token\n")
 		}
+
+		if addr := viper.GetString("metrics_listen"); addr != "" {
+			info.Set(1, cmd.Name())
+			metrics.Serve(addr)
+		}
+
+		if err := tracing.Initialize("cds-hatchery-"+cmd.Name(), viper.GetString("tracing_exporter")); err != nil {
+			sdk.Exit("Cannot initialize tracing: %s\n", err)
+		}
 	},
 }
 
+var info = metrics.NewGauge("cds_hatchery_info", "Mode of the running hatchery", "mode")
+
 func main() {
 	addFlags()
 	addCommands()
@@ -66,6 +79,9 @@ func addFlags() {
 	rootCmd.PersistentFlags().String("log-level", "notice", "Log Level: debug, info, warning, notice, critical")
 	viper.BindPFlag("log_level", rootCmd.PersistentFlags().Lookup("log-level"))
 
+	rootCmd.PersistentFlags().String("log-format", "text", "Log Format: text or json")
+	viper.BindPFlag("log_format", rootCmd.PersistentFlags().Lookup("log-format"))
+
 	rootCmd.PersistentFlags().String("api", "", "CDS api endpoint")
 	viper.BindPFlag("api", rootCmd.PersistentFlags().Lookup("api"))
 
@@ -80,4 +96,10 @@ func addFlags() {
 
 	rootCmd.PersistentFlags().Int("max-worker", 10, "Maximum allowed simultaenous workers")
 	viper.BindPFlag("max-worker", rootCmd.PersistentFlags().Lookup("max-worker"))
+
+	rootCmd.PersistentFlags().String("metrics-listen", "", "Expose Prometheus metrics on /metrics of this address (ie: :8086), disabled if empty")
+	viper.BindPFlag("metrics_listen", rootCmd.PersistentFlags().Lookup("metrics-listen"))
+
+	rootCmd.PersistentFlags().String("tracing-exporter", "", "Export traces to an OpenTelemetry collector (http://collector:4318) or a file (file:///var/log/cds/traces.json), disabled if empty")
+	viper.BindPFlag("tracing_exporter", rootCmd.PersistentFlags().Lookup("tracing-exporter"))
 }
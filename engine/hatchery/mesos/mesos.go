@@ -28,6 +28,8 @@ type marathonPOSTAppParams struct {
 	WorkerName    string
 	WorkerModelID int64
 	HatcheryID    int64
+	SingleUse     bool
+	KeepOnFailure int64
 
 	MarathonID    string
 	MarathonVHOST string
@@ -53,7 +55,8 @@ const marathonPOSTAppTemplate = `
         "CDS_NAME": "{{.WorkerName}}",
         "CDS_MODEL": "{{.WorkerModelID}}",
         "CDS_HATCHERY": "{{.HatcheryID}}",
-        "CDS_SINGLE_USE": "1"
+        "CDS_SINGLE_USE": "{{.SingleUse}}",
+        "CDS_KEEP_ON_FAILURE": "{{.KeepOnFailure}}"
     },
     "id": "{{.MarathonID}}/{{.WorkerName}}",
     "instances": 1,
@@ -223,6 +226,8 @@ func (m *HatcheryMesos) spawnMesosDockerWorker(model *sdk.Model, hatcheryID int6
 			WorkerName:    fmt.Sprintf("%s-%s", strings.ToLower(model.Name), strings.Replace(namesgenerator.GetRandomName(0), "_", "-", -1)),
 			WorkerModelID: model.ID,
 			HatcheryID:    hatcheryID,
+			SingleUse:     model.SingleUse,
+			KeepOnFailure: model.KeepOnFailure,
 			MarathonID:    hatcheryMesos.marathonID,
 			MarathonVHOST: hatcheryMesos.marathonVHOST,
 			Memory:        memory * 110 / 100,
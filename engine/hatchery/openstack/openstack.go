@@ -362,7 +362,7 @@ cd $HOME
 # Download and start worker with curl
 curl  "{{.API}}/download/worker/$(uname -m)" -o worker --retry 10 --retry-max-time 0 -C - >> /tmp/user_data 2>&1
 chmod +x worker
-CDS_SINGLE_USE=1 ./worker --api={{.API}} --key={{.Key}} --name={{.Name}} --model={{.Model}} --hatchery={{.Hatchery}} --single-use && exit 0
+CDS_KEEP_ON_FAILURE={{.KeepOnFailure}} ./worker --api={{.API}} --key={{.Key}} --name={{.Name}} --model={{.Model}} --hatchery={{.Hatchery}} --single-use={{.SingleUse}} && exit 0
 `
 	var udata = udataBegin + string(udataModel) + udataEnd
 
@@ -371,17 +371,21 @@ CDS_SINGLE_USE=1 ./worker --api={{.API}} --key={{.Key}} --name={{.Name}} --model
 		return err
 	}
 	udataParam := struct {
-		API      string
-		Name     string
-		Key      string
-		Model    int64
-		Hatchery int64
+		API           string
+		Name          string
+		Key           string
+		Model         int64
+		Hatchery      int64
+		SingleUse     bool
+		KeepOnFailure int64
 	}{
-		API:      viper.GetString("api"),
-		Name:     name,
-		Key:      viper.GetString("token"),
-		Model:    model.ID,
-		Hatchery: h.hatch.ID,
+		API:           viper.GetString("api"),
+		Name:          name,
+		Key:           viper.GetString("token"),
+		Model:         model.ID,
+		Hatchery:      h.hatch.ID,
+		SingleUse:     model.SingleUse,
+		KeepOnFailure: model.KeepOnFailure,
 	}
 	var buffer bytes.Buffer
 	if err = tmpl.Execute(&buffer, udataParam); err != nil {
@@ -218,6 +218,12 @@ func (h *HatcherySwarm) SpawnWorker(model *sdk.Model, req []sdk.Requirement) err
 		"CDS_MODEL" + "=" + strconv.FormatInt(model.ID, 10),
 		"CDS_HATCHERY" + "=" + strconv.FormatInt(h.hatch.ID, 10),
 	}
+	if model.SingleUse {
+		env = append(env, "CDS_SINGLE_USE=1")
+	}
+	if model.KeepOnFailure > 0 {
+		env = append(env, "CDS_KEEP_ON_FAILURE="+strconv.FormatInt(model.KeepOnFailure, 10))
+	}
 
 	//labels are used to make container cleanup easier
 	labels := map[string]string{
@@ -0,0 +1,138 @@
+package log
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Names of fields correlating log lines
+const (
+	FieldRequestID       = "request_id"
+	FieldUser            = "user"
+	FieldProject         = "project"
+	FieldApplication     = "application"
+	FieldPipeline        = "pipeline"
+	FieldEnvironment     = "environment"
+	FieldPipelineBuildID = "pipeline_build_id"
+	FieldActionBuildID   = "action_build_id"
+	FieldWorkerID        = "worker_id"
+	FieldHatchery        = "hatchery"
+	FieldModel           = "model"
+)
+
+// Fields are key/value pairs added to log lines, as JSON fields or key=value text
+type Fields map[string]interface{}
+
+// merge returns fields of f overridden by fields of o
+func (f Fields) merge(o Fields) Fields {
+	if len(f) == 0 {
+		return o
+	}
+	if len(o) == 0 {
+		return f
+	}
+	m := make(Fields, len(f)+len(o))
+	for k, v := range f {
+		m[k] = v
+	}
+	for k, v := range o {
+		m[k] = v
+	}
+	return m
+}
+
+// appendTo appends fields sorted by key to a text log line, before its trailing newline
+func (f Fields) appendTo(line string) string {
+	keys := make([]string, 0, len(f))
+	for k := range f {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	trimmed := strings.TrimRight(line, "\n")
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = fmt.Sprintf("%s=%v", k, f[k])
+	}
+	return trimmed + " " + strings.Join(pairs, " ") + line[len(trimmed):]
+}
+
+var processFields Fields
+
+// SetField adds a field to all log lines of the process, like the ID of a worker
+func SetField(key string, value interface{}) {
+	mu.Lock()
+	processFields = processFields.merge(Fields{key: value})
+	mu.Unlock()
+}
+
+// DeleteField removes a field added with SetField
+func DeleteField(key string) {
+	mu.Lock()
+	f := make(Fields, len(processFields))
+	for k, v := range processFields {
+		if k != key {
+			f[k] = v
+		}
+	}
+	processFields = f
+	mu.Unlock()
+}
+
+// defaultFields returns fields of all log lines. The returned map must not be modified.
+func defaultFields() Fields {
+	mu.Lock()
+	defer mu.Unlock()
+	return processFields
+}
+
+// Entry is a log line with fields
+type Entry struct {
+	fields Fields
+}
+
+// WithFields returns an entry logging given fields
+func WithFields(f Fields) *Entry {
+	return &Entry{fields: f}
+}
+
+// WithField returns an entry logging given field
+func WithField(key string, value interface{}) *Entry {
+	return WithFields(Fields{key: value})
+}
+
+// WithFields returns an entry logging fields of e and given fields
+func (e *Entry) WithFields(f Fields) *Entry {
+	return &Entry{fields: e.fields.merge(f)}
+}
+
+// WithField returns an entry logging fields of e and given field
+func (e *Entry) WithField(key string, value interface{}) *Entry {
+	return e.WithFields(Fields{key: value})
+}
+
+// Debug prints debug log with fields of e
+func (e *Entry) Debug(format string, values ...interface{}) {
+	logf(DebugLevel, e.fields, format, values)
+}
+
+// Info prints information log with fields of e
+func (e *Entry) Info(format string, values ...interface{}) {
+	logf(InfoLevel, e.fields, format, values)
+}
+
+// Notice prints information that should be seen with fields of e
+func (e *Entry) Notice(format string, values ...interface{}) {
+	logf(NoticeLevel, e.fields, format, values)
+}
+
+// Warning prints warnings for user with fields of e
+func (e *Entry) Warning(format string, values ...interface{}) {
+	logf(WarningLevel, e.fields, format, values)
+}
+
+// Critical prints error informations with fields of e
+func (e *Entry) Critical(format string, values ...interface{}) {
+	logf(CriticalLevel, e.fields, format, values)
+}
@@ -0,0 +1,68 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Format of log lines written on standard output
+type Format string
+
+// Available log formats
+const (
+	TextFormat Format = "text"
+	JSONFormat Format = "json"
+)
+
+var jsonOutput = struct {
+	sync.Mutex
+	w io.Writer
+}{w: os.Stderr}
+
+// SetFormat sets the format of log lines. JSON lines are only written by the default logger,
+// database logger keeps text lines.
+func SetFormat(f Format) error {
+	if f != TextFormat && f != JSONFormat {
+		return fmt.Errorf("unknown log format %s", f)
+	}
+	mu.Lock()
+	logFormat = f
+	mu.Unlock()
+	return nil
+}
+
+// GetFormat returns the format of log lines
+func GetFormat() Format {
+	mu.Lock()
+	defer mu.Unlock()
+	return logFormat
+}
+
+// writeJSON writes a log line as a JSON object. Fields cannot override time, level, package and message.
+func writeJSON(lvl Level, pkg string, fields Fields, msg string) {
+	line := make(map[string]interface{}, len(fields)+4)
+	for k, v := range fields {
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		line[k] = v
+	}
+	line["time"] = time.Now().Format(time.RFC3339Nano)
+	line["level"] = lvl.String()
+	line["package"] = pkg
+	line["message"] = strings.TrimRight(msg, "\n")
+
+	b, err := json.Marshal(line)
+	if err != nil {
+		b, _ = json.Marshal(map[string]string{"level": lvl.String(), "package": pkg, "message": line["message"].(string)})
+	}
+
+	jsonOutput.Lock()
+	jsonOutput.w.Write(append(b, '\n'))
+	jsonOutput.Unlock()
+}
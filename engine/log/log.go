@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path"
+	"runtime"
 	"strings"
 	"sync"
 	"testing"
@@ -32,10 +34,36 @@ const (
 	CriticalLevel
 )
 
+// fatalLevel is the level of Fatalf, always written
+const fatalLevel = CriticalLevel + 1
+
+var levelNames = map[Level]string{
+	DebugLevel:    "debug",
+	InfoLevel:     "info",
+	NoticeLevel:   "notice",
+	WarningLevel:  "warning",
+	CriticalLevel: "critical",
+	fatalLevel:    "fatal",
+}
+
+var levelPrefixes = map[Level]string{
+	DebugLevel:    "[DEBUG]    ",
+	InfoLevel:     "[INFO]     ",
+	NoticeLevel:   "[NOTICE]   ",
+	WarningLevel:  "[WARNING]  ",
+	CriticalLevel: "[CRITICAL] ",
+	fatalLevel:    "[FATAL] ",
+}
+
+// modulePath prefixes directories of CDS packages
+const modulePath = "github.com/ovh/cds/"
+
 var (
-	logger Logger
-	level  Level
-	mu     sync.Mutex
+	logger        Logger
+	level         Level
+	logFormat     = TextFormat
+	packageLevels = map[string]Level{}
+	mu            sync.Mutex
 )
 
 // Logger defines the logs levels used by RamSQL engine
@@ -43,23 +71,63 @@ type Logger interface {
 	Logf(fmt string, values ...interface{})
 }
 
-// Initialize initializes log level with flag --log-level
+// Initialize initializes log level with flag --log-level and log format with flag --log-format.
+// Log level may be followed by levels of packages, like "notice,engine/api/scheduler=debug"
 func Initialize() {
-	switch viper.GetString("log_level") {
-	case "debug":
-		SetLevel(DebugLevel)
-	case "info":
-		SetLevel(InfoLevel)
-	case "warning":
-		SetLevel(WarningLevel)
-	case "critical":
-		SetLevel(CriticalLevel)
-	case "notice":
-		SetLevel(NoticeLevel)
-	default:
+	lvl, pkgLevels, err := parseLevels(viper.GetString("log_level"))
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Invalid Log Level %s", viper.GetString("log_level"))
 		os.Exit(1)
 	}
+	SetLevel(lvl)
+	for pkg, l := range pkgLevels {
+		SetPackageLevel(pkg, l)
+	}
+
+	f := viper.GetString("log_format")
+	if f == "" {
+		f = string(TextFormat)
+	}
+	if err := SetFormat(Format(f)); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid Log Format %s", f)
+		os.Exit(1)
+	}
+}
+
+// ParseLevel returns the level named s
+func ParseLevel(s string) (Level, error) {
+	for l, name := range levelNames {
+		if name == s && l != fatalLevel {
+			return l, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level %s", s)
+}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// parseLevels parses a level followed by levels of packages, separated by commas
+func parseLevels(s string) (Level, map[string]Level, error) {
+	parts := strings.Split(s, ",")
+	lvl, err := ParseLevel(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, nil, err
+	}
+	pkgLevels := map[string]Level{}
+	for _, p := range parts[1:] {
+		kv := strings.SplitN(strings.TrimSpace(p), "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return 0, nil, fmt.Errorf("invalid package log level %s", p)
+		}
+		l, err := ParseLevel(kv[1])
+		if err != nil {
+			return 0, nil, err
+		}
+		pkgLevels[kv[0]] = l
+	}
+	return lvl, pkgLevels, nil
 }
 
 // SetLevel controls the categories of logs written
@@ -69,12 +137,76 @@ func SetLevel(lvl Level) {
 	mu.Unlock()
 }
 
+// GetLevel returns the level of packages without specific level
+func GetLevel() Level {
+	return lvl()
+}
+
 func lvl() Level {
 	mu.Lock()
 	defer mu.Unlock()
 	return level
 }
 
+// SetPackageLevel overrides log level of a package, given by its path in CDS repository
+// like "engine/api/scheduler", or by its last elements like "scheduler"
+func SetPackageLevel(pkg string, lvl Level) {
+	mu.Lock()
+	packageLevels[normalizePackage(pkg)] = lvl
+	mu.Unlock()
+}
+
+// DeletePackageLevel removes log level override of a package, removing all of them if pkg is empty
+func DeletePackageLevel(pkg string) {
+	mu.Lock()
+	if pkg == "" {
+		packageLevels = map[string]Level{}
+	} else {
+		delete(packageLevels, normalizePackage(pkg))
+	}
+	mu.Unlock()
+}
+
+// PackageLevels returns log level overrides of packages
+func PackageLevels() map[string]Level {
+	mu.Lock()
+	defer mu.Unlock()
+	levels := make(map[string]Level, len(packageLevels))
+	for pkg, l := range packageLevels {
+		levels[pkg] = l
+	}
+	return levels
+}
+
+func normalizePackage(pkg string) string {
+	if i := strings.Index(pkg, modulePath); i >= 0 {
+		pkg = pkg[i+len(modulePath):]
+	}
+	return strings.Trim(pkg, "/")
+}
+
+// callerPackage returns the directory in CDS repository of the caller skip frames above
+func callerPackage(skip int) string {
+	_, file, _, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return ""
+	}
+	return normalizePackage(path.Dir(file))
+}
+
+// levelOf returns log level of package pkg, the most specific override matching it if any
+func levelOf(pkg string) Level {
+	mu.Lock()
+	defer mu.Unlock()
+	lvl, matched := level, ""
+	for p, l := range packageLevels {
+		if (pkg == p || strings.HasSuffix(pkg, "/"+p)) && len(p) > len(matched) {
+			lvl, matched = l, p
+		}
+	}
+	return lvl
+}
+
 // IsDebug returns true if current level is DebugLevel
 func IsDebug() bool {
 	return lvl() <= DebugLevel
@@ -82,45 +214,60 @@ func IsDebug() bool {
 
 // Debug prints debug log
 func Debug(format string, values ...interface{}) {
-	if lvl() <= DebugLevel {
-		logger.Logf("[DEBUG]    "+format, values...)
-	}
+	logf(DebugLevel, nil, format, values)
 }
 
 // Info prints information log
 func Info(format string, values ...interface{}) {
-	if lvl() <= InfoLevel {
-		logger.Logf("[INFO]     "+format, values...)
-	}
+	logf(InfoLevel, nil, format, values)
 }
 
 // Notice prints information that should be seen
 func Notice(format string, values ...interface{}) {
-	if lvl() <= NoticeLevel {
-		logger.Logf("[NOTICE]   "+format, values...)
-	}
+	logf(NoticeLevel, nil, format, values)
 }
 
 // Warning prints warnings for user
 func Warning(format string, values ...interface{}) {
-	if lvl() <= WarningLevel {
-		logger.Logf("[WARNING]  "+format, values...)
-	}
+	logf(WarningLevel, nil, format, values)
 }
 
 // Critical prints error informations
 func Critical(format string, values ...interface{}) {
-	mu.Lock()
-	logger.Logf("[CRITICAL] "+format, values...)
-	mu.Unlock()
+	logf(CriticalLevel, nil, format, values)
 }
 
 // Fatalf prints fatal informations, then os.Exit(1)
 func Fatalf(format string, values ...interface{}) {
+	logf(fatalLevel, nil, format, values)
+	os.Exit(1)
+}
+
+// logf writes a log line if lvl is enabled for the package calling exported logging functions.
+// It must be called directly by them, to find their caller.
+func logf(lvl Level, fields Fields, format string, values []interface{}) {
 	mu.Lock()
-	logger.Logf("[FATAL] "+format, values...)
+	l, f, byPackage := logger, logFormat, len(packageLevels) > 0
 	mu.Unlock()
-	os.Exit(1)
+
+	var pkg string
+	if byPackage || f == JSONFormat {
+		pkg = callerPackage(2)
+	}
+	if lvl < CriticalLevel && lvl < levelOf(pkg) {
+		return
+	}
+
+	fields = defaultFields().merge(fields)
+	if _, ok := l.(BaseLogger); ok && f == JSONFormat {
+		writeJSON(lvl, pkg, fields, fmt.Sprintf(format, values...))
+		return
+	}
+	if len(fields) == 0 {
+		l.Logf(levelPrefixes[lvl]+format, values...)
+		return
+	}
+	l.Logf(levelPrefixes[lvl]+"%s", fields.appendTo(fmt.Sprintf(format, values...)))
 }
 
 // BaseLogger logs on stdout
@@ -0,0 +1,64 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONFormat(t *testing.T) {
+	buf := &bytes.Buffer{}
+	jsonOutput.w = buf
+	SetLevel(NoticeLevel)
+	assert.NoError(t, SetFormat(JSONFormat))
+	defer SetFormat(TextFormat)
+
+	SetField(FieldWorkerID, "w1")
+	defer DeleteField(FieldWorkerID)
+
+	Info("hidden\n")
+	WithField(FieldRequestID, "abc").Warning("Cannot take action %d\n", 42)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 1)
+
+	var line map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &line))
+	assert.Equal(t, "warning", line["level"])
+	assert.Equal(t, "engine/log", line["package"])
+	assert.Equal(t, "Cannot take action 42", line["message"])
+	assert.Equal(t, "abc", line[FieldRequestID])
+	assert.Equal(t, "w1", line[FieldWorkerID])
+}
+
+func TestPackageLevels(t *testing.T) {
+	lvl, pkgLevels, err := parseLevels("notice,engine/api/scheduler=debug,log=info")
+	assert.NoError(t, err)
+	assert.Equal(t, NoticeLevel, lvl)
+	assert.Equal(t, map[string]Level{"engine/api/scheduler": DebugLevel, "log": InfoLevel}, pkgLevels)
+
+	_, _, err = parseLevels("notice,scheduler")
+	assert.Error(t, err)
+
+	SetLevel(WarningLevel)
+	SetPackageLevel("github.com/ovh/cds/engine/api", InfoLevel)
+	SetPackageLevel("scheduler", DebugLevel)
+	defer DeletePackageLevel("")
+
+	assert.Equal(t, DebugLevel, levelOf("engine/api/scheduler"))
+	assert.Equal(t, InfoLevel, levelOf("engine/api"))
+	assert.Equal(t, WarningLevel, levelOf("engine/api/pipeline"))
+	assert.Equal(t, WarningLevel, levelOf("engine/worker"))
+
+	DeletePackageLevel("scheduler")
+	assert.Equal(t, map[string]Level{"engine/api": InfoLevel}, PackageLevels())
+}
+
+func TestFieldsAppendTo(t *testing.T) {
+	f := Fields{FieldProject: "KEY", FieldPipelineBuildID: 3}
+	assert.Equal(t, "Build started pipeline_build_id=3 project=KEY\n", f.appendTo("Build started\n"))
+	assert.Equal(t, "Build started pipeline_build_id=3 project=KEY", f.appendTo("Build started"))
+}
@@ -0,0 +1,329 @@
+// Package metrics exposes CDS engine metrics in Prometheus text format.
+//
+// Metrics are registered once at package initialization, then updated by the API,
+// hatcheries and workers. Each process exposes its own metrics on /metrics.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ovh/cds/engine/log"
+)
+
+// ContentType of metrics exposition
+const ContentType = "text/plain; version=0.0.4"
+
+var (
+	// DefaultBuckets are histogram buckets for request latencies, in seconds
+	DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+	// DurationBuckets are histogram buckets for long running jobs, in seconds
+	DurationBuckets = []float64{1, 5, 10, 30, 60, 120, 300, 600, 1200, 1800, 3600, 7200}
+)
+
+var registry = struct {
+	sync.Mutex
+	metrics    map[string]metric
+	collectors []func()
+}{metrics: map[string]metric{}}
+
+type metric interface {
+	write(w io.Writer)
+}
+
+func register(name string, m metric) {
+	registry.Lock()
+	defer registry.Unlock()
+	if _, exists := registry.metrics[name]; exists {
+		panic(fmt.Sprintf("metrics: %s registered twice", name))
+	}
+	registry.metrics[name] = m
+}
+
+// OnCollect registers a function called before each exposition, to update gauges
+// whose value is computed at scrape time
+func OnCollect(f func()) {
+	registry.Lock()
+	registry.collectors = append(registry.collectors, f)
+	registry.Unlock()
+}
+
+// WriteTo writes all registered metrics sorted by name
+func WriteTo(w io.Writer) error {
+	registry.Lock()
+	collectors := registry.collectors
+	registry.Unlock()
+	for _, f := range collectors {
+		f()
+	}
+
+	registry.Lock()
+	names := make([]string, 0, len(registry.metrics))
+	for n := range registry.metrics {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	metrics := make([]metric, len(names))
+	for i, n := range names {
+		metrics[i] = registry.metrics[n]
+	}
+	registry.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler returns the HTTP handler exposing metrics
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		if err := WriteTo(w); err != nil {
+			log.Warning("metrics> cannot write metrics: %s\n", err)
+		}
+	})
+}
+
+// Serve exposes metrics on /metrics of given address, in background
+func Serve(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	srv := &http.Server{
+		Addr:         addr,
+		Handler:      mux,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+
+	log.Notice("Metrics> Listening on %s/metrics\n", addr)
+	go func() {
+		if err := srv.ListenAndServe(); err != nil {
+			log.Warning("Metrics> Cannot serve metrics on %s: %s\n", addr, err)
+		}
+	}()
+}
+
+// vec holds the values of a metric for each combination of label values
+type vec struct {
+	sync.Mutex
+	name   string
+	help   string
+	kind   string
+	labels []string
+	values map[string][]string
+}
+
+func newVec(name, help, kind string, labels []string) vec {
+	return vec{name: name, help: help, kind: kind, labels: labels, values: map[string][]string{}}
+}
+
+// key returns the series key of label values, recording them on first use
+func (v *vec) key(values []string) string {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.name, len(v.labels), len(values)))
+	}
+	k := strings.Join(values, "\xff")
+	if _, ok := v.values[k]; !ok {
+		v.values[k] = append([]string(nil), values...)
+	}
+	return k
+}
+
+// sortedKeys returns series keys in a stable order
+func (v *vec) sortedKeys() []string {
+	keys := make([]string, 0, len(v.values))
+	for k := range v.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (v *vec) header(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", v.name, strings.Replace(v.help, "\n", " ", -1))
+	fmt.Fprintf(w, "# TYPE %s %s\n", v.name, v.kind)
+}
+
+// series formats a series name with labels of key, plus extra label if any
+func (v *vec) series(suffix, key string, extra ...string) string {
+	var pairs []string
+	for i, value := range v.values[key] {
+		pairs = append(pairs, v.labels[i]+`="`+escape(value)+`"`)
+	}
+	if len(extra) == 2 {
+		pairs = append(pairs, extra[0]+`="`+escape(extra[1])+`"`)
+	}
+	if len(pairs) == 0 {
+		return v.name + suffix
+	}
+	return v.name + suffix + "{" + strings.Join(pairs, ",") + "}"
+}
+
+func escape(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, "\n", `\n`, -1)
+	return strings.Replace(s, `"`, `\"`, -1)
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// Counter is a monotonic counter partitioned by labels
+type Counter struct {
+	vec
+	counts map[string]float64
+}
+
+// NewCounter registers a new counter
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{vec: newVec(name, help, "counter", labels), counts: map[string]float64{}}
+	register(name, c)
+	return c
+}
+
+// Inc increments counter of given label values
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add adds v to counter of given label values
+func (c *Counter) Add(v float64, values ...string) {
+	if v < 0 {
+		return
+	}
+	c.Lock()
+	c.counts[c.key(values)] += v
+	c.Unlock()
+}
+
+func (c *Counter) write(w io.Writer) {
+	c.Lock()
+	defer c.Unlock()
+	c.header(w)
+	for _, k := range c.sortedKeys() {
+		fmt.Fprintf(w, "%s %s\n", c.series("", k), formatFloat(c.counts[k]))
+	}
+}
+
+// Gauge is a value which can go up and down, partitioned by labels
+type Gauge struct {
+	vec
+	gauges map[string]float64
+}
+
+// NewGauge registers a new gauge
+func NewGauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{vec: newVec(name, help, "gauge", labels), gauges: map[string]float64{}}
+	register(name, g)
+	return g
+}
+
+// Set sets gauge of given label values
+func (g *Gauge) Set(v float64, values ...string) {
+	g.Lock()
+	g.gauges[g.key(values)] = v
+	g.Unlock()
+}
+
+// Add adds v to gauge of given label values
+func (g *Gauge) Add(v float64, values ...string) {
+	g.Lock()
+	g.gauges[g.key(values)] += v
+	g.Unlock()
+}
+
+// Reset removes all label values, to be used before setting all of them again
+func (g *Gauge) Reset() {
+	g.Lock()
+	g.values = map[string][]string{}
+	g.gauges = map[string]float64{}
+	g.Unlock()
+}
+
+func (g *Gauge) write(w io.Writer) {
+	g.Lock()
+	defer g.Unlock()
+	g.header(w)
+	for _, k := range g.sortedKeys() {
+		fmt.Fprintf(w, "%s %s\n", g.series("", k), formatFloat(g.gauges[k]))
+	}
+}
+
+// Histogram samples observations in buckets, partitioned by labels
+type Histogram struct {
+	vec
+	buckets []float64
+	series  map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// NewHistogram registers a new histogram with given upper bounds of buckets
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	b := append([]float64(nil), buckets...)
+	sort.Float64s(b)
+	h := &Histogram{vec: newVec(name, help, "histogram", labels), buckets: b, series: map[string]*histogramSeries{}}
+	register(name, h)
+	return h
+}
+
+// Observe adds an observation to histogram of given label values
+func (h *Histogram) Observe(v float64, values ...string) {
+	h.Lock()
+	defer h.Unlock()
+
+	k := h.key(values)
+	s := h.series[k]
+	if s == nil {
+		s = &histogramSeries{counts: make([]uint64, len(h.buckets))}
+		h.series[k] = s
+	}
+	for i, b := range h.buckets {
+		if v <= b {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.sum += v
+}
+
+// Since observes seconds elapsed since start
+func (h *Histogram) Since(start time.Time, values ...string) {
+	h.Observe(time.Since(start).Seconds(), values...)
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.Lock()
+	defer h.Unlock()
+	h.header(w)
+	for _, k := range h.sortedKeys() {
+		s := h.series[k]
+		for i, b := range h.buckets {
+			fmt.Fprintf(w, "%s %d\n", h.vec.series("_bucket", k, "le", formatFloat(b)), s.counts[i])
+		}
+		fmt.Fprintf(w, "%s %d\n", h.vec.series("_bucket", k, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s %s\n", h.vec.series("_sum", k), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s %d\n", h.vec.series("_count", k), s.count)
+	}
+}
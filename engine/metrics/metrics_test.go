@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteTo(t *testing.T) {
+	c := NewCounter("test_requests_total", "Requests", "route", "code")
+	c.Inc("/project/{key}", "200")
+	c.Add(2, "/project/{key}", "200")
+	c.Inc(`/a"b`, "500")
+
+	g := NewGauge("test_workers", "Workers", "model")
+	g.Set(3, "docker")
+	g.Reset()
+	g.Set(1, "openstack")
+
+	h := NewHistogram("test_duration_seconds", "Duration", []float64{1, 0.1})
+	h.Observe(0.05)
+	h.Observe(0.5)
+
+	collected := false
+	OnCollect(func() { collected = true })
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, WriteTo(buf))
+	assert.True(t, collected)
+
+	expected := `# HELP test_duration_seconds Duration
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{le="0.1"} 1
+test_duration_seconds_bucket{le="1"} 2
+test_duration_seconds_bucket{le="+Inf"} 2
+test_duration_seconds_sum 0.55
+test_duration_seconds_count 2
+# HELP test_requests_total Requests
+# TYPE test_requests_total counter
+test_requests_total{route="/a\"b",code="500"} 1
+test_requests_total{route="/project/{key}",code="200"} 3
+# HELP test_workers Workers
+# TYPE test_workers gauge
+test_workers{model="openstack"} 1
+`
+	assert.Equal(t, expected, buf.String())
+}
@@ -16,6 +16,10 @@ select create_foreign_key('FK_ACTION_EDGE_PARAMETER_ACTION_EDGE', 'action_edge_p
 -- ACTION PARAMETER
 select create_foreign_key('FK_ACTION_PARAMETER_ACTION', 'action_parameter', 'action', 'action_id', 'id');
 
+-- BUILD CACHE
+select create_foreign_key('FK_BUILD_CACHE_PROJECT', 'build_cache', 'project', 'project_id', 'id');
+select create_foreign_key('FK_BUILD_CACHE_LIMIT_PROJECT', 'build_cache_limit', 'project', 'project_id', 'id');
+
 -- ARTIFACT
 select create_foreign_key('FK_ARTIFACT_PIPELINE_BUILD', 'artifact', 'pipeline', 'pipeline_id', 'id');
 select create_foreign_key('FK_ARTIFACT_APPLICATION', 'artifact', 'application', 'application_id', 'id');
@@ -53,6 +57,9 @@ select create_foreign_key('FK_ENVIRONMENT_GROUP_GROUP', 'environment_group', 'gr
 -- ENVIRONMENT_VARIABLE
 select create_foreign_key('FK_ENVIRONMENT_VARIABLE_ENV', 'environment_variable', 'environment', 'environment_id', 'id');
 
+-- GROUP QUOTA
+select create_foreign_key('FK_GROUP_QUOTA_GROUP', 'group_quota', 'group', 'group_id', 'id');
+
 -- GROUP USER
 select create_foreign_key('FK_GROUP_USER_GROUP', 'group_user', 'group', 'group_id', 'id');
 select create_foreign_key('FK_GROUP_USER_USER', 'group_user', 'user', 'user_id', 'id');
@@ -145,10 +152,17 @@ ALTER TABLE warning ADD CONSTRAINT fk_pipeline FOREIGN KEY (pip_id) references p
 ALTER TABLE warning ADD CONSTRAINT fk_environment FOREIGN KEY (env_id) references environment (id) ON delete cascade;
 ALTER TABLE warning ADD CONSTRAINT fk_action FOREIGN KEY (action_id) references action (id) ON delete cascade;
 
+-- ROLE
+ALTER TABLE project_group ADD CONSTRAINT fk_project_group_role FOREIGN KEY (role_id) references role (id);
+ALTER TABLE application_group ADD CONSTRAINT fk_application_group_role FOREIGN KEY (role_id) references role (id);
+ALTER TABLE pipeline_group ADD CONSTRAINT fk_pipeline_group_role FOREIGN KEY (role_id) references role (id);
+ALTER TABLE environment_group ADD CONSTRAINT fk_environment_group_role FOREIGN KEY (role_id) references role (id);
+
 -- AUDIT
 ALTER TABLE project_variable_audit ADD CONSTRAINT fk_project FOREIGN KEY (project_id) references project (id) ON delete cascade;
 ALTER TABLE application_variable_audit ADD CONSTRAINT fk_application FOREIGN KEY (application_id) references application (id) ON delete cascade;
 ALTER TABLE environment_variable_audit ADD CONSTRAINT fk_environment FOREIGN KEY (environment_id) references environment (id) ON delete cascade;
+ALTER TABLE group_audit ADD CONSTRAINT fk_group_audit_group FOREIGN KEY (group_id) references "group" (id) ON delete cascade;
 
 -- TEMPLATES
 SELECT create_foreign_key('FK_TEMPLATE_PARAMS_TEMPLATE', 'template_params', 'template', 'template_id', 'id');
@@ -20,10 +20,25 @@ select create_index('action_build', 'IDX_ACTION_BUILD_PIPELINE_BUILD_ID', 'pipel
 select create_index('action_build', 'IDX_ACTION_BUILD_PIPELINE_ACTION_ID', 'pipeline_action_id');
 select create_unique_index('action_build', 'IDX_ACTION_BUILD_PIPELINE_ACTION_ID_BUILD_ID', 'pipeline_build_id,pipeline_action_id');
 
+-- AUDIT LOG
+select create_index('audit_log', 'IDX_AUDIT_LOG_CREATED', 'created');
+select create_index('audit_log', 'IDX_AUDIT_LOG_PROJECT_KEY', 'project_key,created');
+select create_index('audit_log', 'IDX_AUDIT_LOG_USERNAME', 'username,created');
+
+-- BUILD CACHE
+select create_unique_index('build_cache', 'IDX_BUILD_CACHE_PROJECT_KEY', 'project_id,cache_key');
+
+-- TEST CASE RESULT
+select create_index('test_case_result', 'IDX_TEST_CASE_RESULT_PIPELINE_BUILD_ID', 'pipeline_build_id');
+select create_index('test_case_result', 'IDX_TEST_CASE_RESULT_BUILD', 'application_id,pipeline_id,environment_id,build_number');
+
 -- ARTIFACT
 select create_index('artifact', 'IDX_ARTIFACT_PIPELINE_ID', 'pipeline_id');
 select create_index('artifact', 'IDX_ARTIFACT_APPLICATION_ID', 'application_id');
 select create_index('artifact','IDX_ARTIFACT_ENVIRONMENT', 'environment_id');
+select create_index('artifact', 'IDX_ARTIFACT_CREATED', 'created');
+select create_index('artifact_upload', 'IDX_ARTIFACT_UPLOAD_BUILD', 'application_id,pipeline_id,environment_id,build_number');
+select create_index('artifact_upload', 'IDX_ARTIFACT_UPLOAD_CREATED', 'created');
 
 -- APPLICATION
 select create_unique_index('application', 'IDX_APPLICATION_PROJECT_ID_NAME', 'project_id,name');
@@ -47,6 +62,7 @@ select create_unique_index('environment_variable','IDX_ENVIRONMENT_VARIABLE_ID_N
 
 -- GROUP
 select create_unique_index('group', 'IDX_GROUP_NAME', 'name');
+select create_index('group_audit', 'IDX_GROUP_AUDIT_GROUP_ID', 'group_id');
 
 -- HOOK
 select create_index('hook','IDX_HOOK_PIPELINE_ID','pipeline_id');
@@ -90,6 +106,9 @@ select create_unique_index('plugin','IDX_PLUGIN_NAME', 'name');
 -- PROJECT
 select create_unique_index('project','IDX_PROJECT_KEY','projectKey');
 
+-- ROLE
+select create_unique_index('role', 'IDX_ROLE_NAME', 'name');
+
 -- SYSTEM_LOG
 select create_index('system_log','IDX_SYS_LOG_LOGGED','logged');
 
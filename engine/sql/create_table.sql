@@ -7,12 +7,17 @@ CREATE TABLE IF NOT EXISTS "action_parameter" (id BIGSERIAL PRIMARY KEY, action_
 CREATE TABLE IF NOT EXISTS "action_build" (id BIGSERIAL PRIMARY KEY, pipeline_action_id INT, args TEXT, status TEXT, pipeline_build_id INT, queued TIMESTAMP WITH TIME ZONE, start TIMESTAMP WITH TIME ZONE, done TIMESTAMP WITH TIME ZONE, worker_model_name TEXT);
 CREATE TABLE IF NOT EXISTS "action_audit" (action_id BIGINT, user_id BIGINT, change TEXT, versionned TIMESTAMP WITH TIME ZONE, action_json JSONB);
 
-CREATE TABLE IF NOT EXISTS "artifact" (id BIGSERIAL PRIMARY KEY, name TEXT, tag TEXT, pipeline_id INT, application_id INT, environment_id INT, build_number INT, download_hash TEXT, size BIGINT, perm INT, md5sum TEXT, object_path TEXT, created TIMESTAMP WITH TIME ZONE DEFAULT LOCALTIMESTAMP);
+CREATE TABLE IF NOT EXISTS "artifact" (id BIGSERIAL PRIMARY KEY, name TEXT, tag TEXT, pipeline_id INT, application_id INT, environment_id INT, build_number INT, download_hash TEXT, size BIGINT, perm INT, md5sum TEXT, sha256 TEXT, object_path TEXT, created TIMESTAMP WITH TIME ZONE DEFAULT LOCALTIMESTAMP, promoted BOOLEAN NOT NULL DEFAULT false, promoted_at TIMESTAMP WITH TIME ZONE, promoted_by TEXT);
 
 CREATE TABLE IF NOT EXISTS "activity" (day DATE, project_id BIGINT, application_id BIGINT, build BIGINT, unit_test BIGINT, testing BIGINT, deployment BIGINT, PRIMARY KEY(day, project_id, application_id));
 
+CREATE TABLE IF NOT EXISTS "audit_log" (id BIGSERIAL PRIMARY KEY, created TIMESTAMP WITH TIME ZONE, method TEXT, path TEXT, route TEXT, project_key TEXT, username TEXT, worker TEXT, ip TEXT, status INT, before TEXT, after TEXT, diff JSONB);
+
+CREATE TABLE IF NOT EXISTS "build_cache" (id BIGSERIAL PRIMARY KEY, project_id BIGINT NOT NULL, cache_key TEXT NOT NULL, size BIGINT NOT NULL DEFAULT 0, md5sum TEXT, object_path TEXT, created TIMESTAMP WITH TIME ZONE DEFAULT LOCALTIMESTAMP, last_used TIMESTAMP WITH TIME ZONE DEFAULT LOCALTIMESTAMP);
+CREATE TABLE IF NOT EXISTS "build_cache_limit" (project_id BIGINT PRIMARY KEY, max_size BIGINT NOT NULL);
+
 CREATE TABLE IF NOT EXISTS "application" (id BIGSERIAL PRIMARY KEY, name TEXT, project_id INT, description TEXT, repo_fullname TEXT, repositories_manager_id BIGINT, last_modified TIMESTAMP WITH TIME ZONE DEFAULT  LOCALTIMESTAMP);
-CREATE TABLE IF NOT EXISTS "application_group" (application_id INT, group_id INT, role INT, PRIMARY KEY(group_id, application_id));
+CREATE TABLE IF NOT EXISTS "application_group" (application_id INT, group_id INT, role INT, role_id BIGINT, PRIMARY KEY(group_id, application_id));
 CREATE TABLE IF NOT EXISTS "application_pipeline" (id BIGSERIAL PRIMARY KEY, application_id INT, pipeline_id INT, args TEXT, last_modified TIMESTAMP WITH TIME ZONE DEFAULT LOCALTIMESTAMP);
 CREATE TABLE IF NOT EXISTS "application_variable" (id BIGSERIAL, application_id INT, var_name TEXT, var_value TEXT, cipher_value BYTEA, var_type TEXT,PRIMARY KEY(application_id, var_name) );
 CREATE TABLE IF NOT EXISTS "application_variable_audit" (id BIGSERIAL PRIMARY KEY, application_id BIGINT, data TEXT, author TEXT, versionned TIMESTAMP WITH TIME ZONE);
@@ -23,20 +28,22 @@ CREATE TABLE IF NOT EXISTS "build_log" (id BIGSERIAL PRIMARY KEY, action_build_i
 CREATE TABLE IF NOT EXISTS "environment" (id BIGSERIAL PRIMARY KEY, name TEXT, project_id INT, created TIMESTAMP WITH TIME ZONE DEFAULT LOCALTIMESTAMP, last_modified TIMESTAMP WITH TIME ZONE DEFAULT LOCALTIMESTAMP);
 CREATE TABLE IF NOT EXISTS "environment_variable" (id BIGSERIAL, environment_id INT, name TEXT, value TEXT, cipher_value BYTEA, type TEXT,description TEXT, PRIMARY KEY(environment_id, name) );
 CREATE TABLE IF NOT EXISTS "environment_variable_audit" (id BIGSERIAL PRIMARY KEY, environment_id BIGINT, versionned TIMESTAMP WITH TIME ZONE, data TEXT, author TEXT);
-CREATE TABLE IF NOT EXISTS "environment_group" (id BIGSERIAL, environment_id INT, group_id INT, role INT, PRIMARY KEY(group_id, environment_id));
+CREATE TABLE IF NOT EXISTS "environment_group" (id BIGSERIAL, environment_id INT, group_id INT, role INT, role_id BIGINT, PRIMARY KEY(group_id, environment_id));
 
 CREATE TABLE IF NOT EXISTS "group" (id BIGSERIAL PRIMARY KEY, name TEXT);
+CREATE TABLE IF NOT EXISTS "group_quota" (group_id BIGINT PRIMARY KEY, max_building BIGINT NOT NULL DEFAULT 0, weight BIGINT NOT NULL DEFAULT 1);
 CREATE TABLE IF NOT EXISTS "group_user" (id BIGSERIAL, group_id INT, user_id INT, group_admin BOOL, PRIMARY KEY(group_id, user_id));
+CREATE TABLE IF NOT EXISTS "group_audit" (id BIGSERIAL PRIMARY KEY, group_id BIGINT, versionned TIMESTAMP WITH TIME ZONE, author TEXT, username TEXT, change TEXT);
 
 CREATE TABLE IF NOT EXISTS "hatchery" (id BIGSERIAL PRIMARY KEY, name TEXT, last_beat TIMESTAMP WITH TIME ZONE, uid TEXT, group_id INT, status TEXT);
 CREATE TABLE IF NOT EXISTS "hatchery_model" (hatchery_id BIGINT, worker_model_id BIGINT, PRIMARY KEY(hatchery_id, worker_model_id));
 CREATE TABLE IF NOT EXISTS "hook" (id BIGSERIAL PRIMARY KEY, pipeline_id BIGINT, application_id INT,  kind TEXT, host TEXT, project TEXT, repository TEXT, uid TEXT, enabled BOOL);
 
-CREATE TABLE IF NOT EXISTS "pipeline" (id BIGSERIAL PRIMARY KEY, name TEXT, project_id INT, type TEXT, created TIMESTAMP WITH TIME ZONE DEFAULT LOCALTIMESTAMP, last_modified TIMESTAMP WITH TIME ZONE DEFAULT  LOCALTIMESTAMP);
+CREATE TABLE IF NOT EXISTS "pipeline" (id BIGSERIAL PRIMARY KEY, name TEXT, project_id INT, type TEXT, created TIMESTAMP WITH TIME ZONE DEFAULT LOCALTIMESTAMP, last_modified TIMESTAMP WITH TIME ZONE DEFAULT  LOCALTIMESTAMP, priority INT);
 CREATE TABLE IF NOT EXISTS "pipeline_action" (id BIGSERIAL PRIMARY KEY, pipeline_stage_id INT, action_id INT, args TEXT, enabled BOOLEAN, last_modified TIMESTAMP WITH TIME ZONE DEFAULT  LOCALTIMESTAMP);
 CREATE TABLE IF NOT EXISTS "pipeline_build" (id BIGSERIAL PRIMARY KEY, environment_id INT, application_id INT, pipeline_id INT, build_number INT, version BIGINT, status TEXT, args TEXT, start TIMESTAMP WITH TIME ZONE, done TIMESTAMP WITH TIME ZONE, manual_trigger BOOLEAN, triggered_by BIGINT, parent_pipeline_build_id BIGINT, vcs_changes_branch TEXT, vcs_changes_hash TEXT, vcs_changes_author TEXT);
-CREATE TABLE IF NOT EXISTS "pipeline_build_test" (pipeline_build_id BIGINT PRIMARY KEY, tests TEXT);
-CREATE TABLE IF NOT EXISTS "pipeline_group" (id BIGSERIAL, pipeline_id INT, group_id INT, role INT, PRIMARY KEY(group_id, pipeline_id));
+CREATE TABLE IF NOT EXISTS "pipeline_build_test" (pipeline_build_id BIGINT PRIMARY KEY, tests TEXT, coverage FLOAT);
+CREATE TABLE IF NOT EXISTS "pipeline_group" (id BIGSERIAL, pipeline_id INT, group_id INT, role INT, role_id BIGINT, PRIMARY KEY(group_id, pipeline_id));
 CREATE TABLE IF NOT EXISTS "pipeline_history" (pipeline_build_id BIGINT, pipeline_id INT, application_id INT, environment_id INT, build_number INT, version BIGINT, status TEXT, start TIMESTAMP WITH TIME ZONE, done TIMESTAMP WITH TIME ZONE, data json, manual_trigger BOOLEAN, triggered_by BIGINT, parent_pipeline_build_id BIGINT, vcs_changes_branch TEXT, vcs_changes_hash TEXT, vcs_changes_author TEXT, PRIMARY KEY(pipeline_id, application_id, build_number, environment_id));
 CREATE TABLE IF NOT EXISTS "pipeline_stage" (id BIGSERIAL PRIMARY KEY, pipeline_id INT, name TEXT, build_order INT, enabled BOOLEAN, last_modified TIMESTAMP WITH TIME ZONE DEFAULT  LOCALTIMESTAMP);
 CREATE TABLE IF NOT EXISTS "pipeline_stage_prerequisite" (id BIGSERIAL PRIMARY KEY, pipeline_stage_id BIGINT, parameter TEXT, expected_value TEXT);
@@ -53,10 +60,12 @@ CREATE TABLE IF NOT EXISTS "poller" (application_id BIGINT, pipeline_id BIGINT,
 CREATE TABLE IF NOT EXISTS "poller_execution" (id BIGSERIAL PRIMARY KEY, application_id BIGINT, pipeline_id BIGINT, execution_date TIMESTAMP WITH TIME ZONE, status TEXT, data JSONB);
 
 CREATE TABLE IF NOT EXISTS "project" (id BIGSERIAL PRIMARY KEY, projectKey TEXT , name TEXT, created TIMESTAMP WITH TIME ZONE DEFAULT LOCALTIMESTAMP, last_modified TIMESTAMP WITH TIME ZONE DEFAULT  LOCALTIMESTAMP);
-CREATE TABLE IF NOT EXISTS "project_group" (id BIGSERIAL, project_id INT, group_id INT, role INT,PRIMARY KEY(group_id, project_id));
+CREATE TABLE IF NOT EXISTS "project_group" (id BIGSERIAL, project_id INT, group_id INT, role INT, role_id BIGINT, PRIMARY KEY(group_id, project_id));
 CREATE TABLE IF NOT EXISTS "project_variable" (id BIGSERIAL, project_id INT, var_name TEXT, var_value TEXT, cipher_value BYTEA, var_type TEXT,PRIMARY KEY(project_id, var_name));
 CREATE TABLE IF NOT EXISTS "project_variable_audit" (id BIGSERIAL PRIMARY KEY, project_id BIGINT, versionned TIMESTAMP WITH TIME ZONE, data TEXT, author TEXT);
 
+CREATE TABLE IF NOT EXISTS "role" (id BIGSERIAL PRIMARY KEY, name TEXT, description TEXT, builtin BOOLEAN DEFAULT false, capabilities JSONB);
+
 CREATE TABLE IF NOT EXISTS "received_hook" (id BIGSERIAL PRIMARY KEY, link TEXT, data TEXT);
 
 CREATE TABLE IF NOT EXISTS "repositories_manager" (id BIGSERIAL PRIMARY KEY , type TEXT, name TEXT UNIQUE, url TEXT UNIQUE, data JSONB );
@@ -69,6 +78,9 @@ CREATE TABLE IF NOT EXISTS "system_log" (id BIGSERIAL PRIMARY KEY, logged TIMEST
 CREATE TABLE IF NOT EXISTS "template" (id BIGSERIAL PRIMARY KEY, name TEXT, type TEXT,author TEXT, description TEXT, identifier TEXT, size INTEGER, perm INTEGER, md5sum TEXT, object_path TEXT);
 CREATE TABLE IF NOT EXISTS "template_params" (template_id BIGINT NOT NULL, params JSONB);
 CREATE TABLE IF NOT EXISTS "template_action" (template_id BIGINT NOT NULL, action_id BIGINT NOT NULL);
+CREATE TABLE IF NOT EXISTS "test_case_result" (id BIGSERIAL PRIMARY KEY, pipeline_build_id BIGINT NOT NULL, pipeline_id BIGINT NOT NULL, application_id BIGINT NOT NULL, environment_id BIGINT NOT NULL, build_number BIGINT NOT NULL, branch TEXT, hash TEXT, suite TEXT NOT NULL, name TEXT NOT NULL, status TEXT NOT NULL, duration FLOAT, created TIMESTAMP WITH TIME ZONE DEFAULT LOCALTIMESTAMP, attempt INT NOT NULL DEFAULT 0);
+CREATE TABLE IF NOT EXISTS "artifact_upload" (id TEXT PRIMARY KEY, pipeline_id BIGINT NOT NULL, application_id BIGINT NOT NULL, environment_id BIGINT NOT NULL, build_number BIGINT NOT NULL, tag TEXT NOT NULL, name TEXT NOT NULL, size BIGINT NOT NULL, perm INT, sha256 TEXT NOT NULL, chunk_size BIGINT NOT NULL, created TIMESTAMP WITH TIME ZONE DEFAULT LOCALTIMESTAMP);
+CREATE TABLE IF NOT EXISTS "artifact_upload_chunk" (upload_id TEXT NOT NULL, chunk INT NOT NULL, size BIGINT NOT NULL, sha256 TEXT NOT NULL, PRIMARY KEY (upload_id, chunk));
 CREATE TABLE IF NOT EXISTS "token" (group_id INT, token TEXT, expiration INT, created TIMESTAMP WITH TIME ZONE);
 
 CREATE TABLE IF NOT EXISTS "user" (id BIGSERIAL PRIMARY KEY, username TEXT, admin BOOL, data TEXT, auth TEXT, created TIMESTAMP WITH TIME ZONE, origin TEXT);
@@ -79,7 +91,7 @@ CREATE TABLE IF NOT EXISTS "warning" (id BIGSERIAL PRIMARY KEY, project_id BIGIN
 
 CREATE TABLE IF NOT EXISTS "worker" (id TEXT PRIMARY KEY, name TEXT, last_beat TIMESTAMP WITH TIME ZONE, owner_id INT, group_id INT, model INT, status TEXT, action_build_id BIGINT, hatchery_id BIGINT DEFAULT 0);
 CREATE TABLE IF NOT EXISTS "worker_capability" (worker_model_id INT, type TEXT, name TEXT, argument TEXT);
-CREATE TABLE IF NOT EXISTS "worker_model" (id BIGSERIAL PRIMARY KEY, type TEXT, name TEXT, image TEXT, created_by JSONB, GROUP_ID BIGINT);
+CREATE TABLE IF NOT EXISTS "worker_model" (id BIGSERIAL PRIMARY KEY, type TEXT, name TEXT, image TEXT, created_by JSONB, GROUP_ID BIGINT, autoscaling JSONB, single_use BOOLEAN NOT NULL DEFAULT false, keep_on_failure INT NOT NULL DEFAULT 0);
 
 GRANT SELECT, INSERT, UPDATE, DELETE on ALL TABLES IN SCHEMA public TO "cds";
 GRANT ALL ON ALL SEQUENCES IN SCHEMA public TO "cds";
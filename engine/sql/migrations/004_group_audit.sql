@@ -0,0 +1,13 @@
+-- +migrate Up
+CREATE TABLE group_audit (id BIGSERIAL PRIMARY KEY, group_id BIGINT, versionned TIMESTAMP WITH TIME ZONE, author TEXT, username TEXT, change TEXT);
+
+select create_index('group_audit', 'IDX_GROUP_AUDIT_GROUP_ID', 'group_id');
+
+ALTER TABLE group_audit ADD CONSTRAINT fk_group_audit_group FOREIGN KEY (group_id) references "group" (id) ON delete cascade;
+
+GRANT SELECT, INSERT, UPDATE, DELETE on ALL TABLES IN SCHEMA public TO "cds";
+
+GRANT ALL ON ALL SEQUENCES IN SCHEMA public TO "cds";
+
+-- +migrate Down
+DROP TABLE group_audit;
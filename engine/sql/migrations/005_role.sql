@@ -0,0 +1,44 @@
+-- +migrate Up
+CREATE TABLE role (id BIGSERIAL PRIMARY KEY, name TEXT, description TEXT, builtin BOOLEAN DEFAULT false, capabilities JSONB);
+
+select create_unique_index('role', 'IDX_ROLE_NAME', 'name');
+
+INSERT INTO role (name, description, builtin, capabilities) VALUES ('read', '', true, '["read"]');
+INSERT INTO role (name, description, builtin, capabilities) VALUES ('read-execute', '', true, '["read","execute"]');
+INSERT INTO role (name, description, builtin, capabilities) VALUES ('read-write-execute', '', true, '["read","execute","edit_variables","write"]');
+INSERT INTO role (name, description, builtin, capabilities) VALUES ('variable-editor', 'Edit variables without other write access', true, '["read","edit_variables"]');
+INSERT INTO role (name, description, builtin, capabilities) VALUES ('secret-viewer', 'Read secret variable values', true, '["read","read_secrets"]');
+
+ALTER TABLE project_group ADD COLUMN role_id BIGINT;
+ALTER TABLE application_group ADD COLUMN role_id BIGINT;
+ALTER TABLE pipeline_group ADD COLUMN role_id BIGINT;
+ALTER TABLE environment_group ADD COLUMN role_id BIGINT;
+
+ALTER TABLE project_group ADD CONSTRAINT fk_project_group_role FOREIGN KEY (role_id) references role (id);
+ALTER TABLE application_group ADD CONSTRAINT fk_application_group_role FOREIGN KEY (role_id) references role (id);
+ALTER TABLE pipeline_group ADD CONSTRAINT fk_pipeline_group_role FOREIGN KEY (role_id) references role (id);
+ALTER TABLE environment_group ADD CONSTRAINT fk_environment_group_role FOREIGN KEY (role_id) references role (id);
+
+UPDATE project_group SET role_id = (SELECT id FROM role WHERE name = 'read') WHERE role = 4;
+UPDATE project_group SET role_id = (SELECT id FROM role WHERE name = 'read-execute') WHERE role = 5;
+UPDATE project_group SET role_id = (SELECT id FROM role WHERE name = 'read-write-execute') WHERE role = 7;
+UPDATE application_group SET role_id = (SELECT id FROM role WHERE name = 'read') WHERE role = 4;
+UPDATE application_group SET role_id = (SELECT id FROM role WHERE name = 'read-execute') WHERE role = 5;
+UPDATE application_group SET role_id = (SELECT id FROM role WHERE name = 'read-write-execute') WHERE role = 7;
+UPDATE pipeline_group SET role_id = (SELECT id FROM role WHERE name = 'read') WHERE role = 4;
+UPDATE pipeline_group SET role_id = (SELECT id FROM role WHERE name = 'read-execute') WHERE role = 5;
+UPDATE pipeline_group SET role_id = (SELECT id FROM role WHERE name = 'read-write-execute') WHERE role = 7;
+UPDATE environment_group SET role_id = (SELECT id FROM role WHERE name = 'read') WHERE role = 4;
+UPDATE environment_group SET role_id = (SELECT id FROM role WHERE name = 'read-execute') WHERE role = 5;
+UPDATE environment_group SET role_id = (SELECT id FROM role WHERE name = 'read-write-execute') WHERE role = 7;
+
+GRANT SELECT, INSERT, UPDATE, DELETE on ALL TABLES IN SCHEMA public TO "cds";
+
+GRANT ALL ON ALL SEQUENCES IN SCHEMA public TO "cds";
+
+-- +migrate Down
+ALTER TABLE project_group DROP COLUMN role_id;
+ALTER TABLE application_group DROP COLUMN role_id;
+ALTER TABLE pipeline_group DROP COLUMN role_id;
+ALTER TABLE environment_group DROP COLUMN role_id;
+DROP TABLE role;
@@ -0,0 +1,13 @@
+-- +migrate Up
+CREATE TABLE audit_log (id BIGSERIAL PRIMARY KEY, created TIMESTAMP WITH TIME ZONE, method TEXT, path TEXT, route TEXT, project_key TEXT, username TEXT, worker TEXT, ip TEXT, status INT, before TEXT, after TEXT, diff JSONB);
+
+select create_index('audit_log', 'IDX_AUDIT_LOG_CREATED', 'created');
+select create_index('audit_log', 'IDX_AUDIT_LOG_PROJECT_KEY', 'project_key,created');
+select create_index('audit_log', 'IDX_AUDIT_LOG_USERNAME', 'username,created');
+
+GRANT SELECT, INSERT, UPDATE, DELETE on ALL TABLES IN SCHEMA public TO "cds";
+
+GRANT ALL ON ALL SEQUENCES IN SCHEMA public TO "cds";
+
+-- +migrate Down
+DROP TABLE audit_log;
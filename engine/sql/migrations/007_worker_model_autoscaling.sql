@@ -0,0 +1,7 @@
+-- +migrate Up
+ALTER TABLE worker_model ADD COLUMN autoscaling JSONB;
+
+GRANT SELECT, INSERT, UPDATE, DELETE on ALL TABLES IN SCHEMA public TO "cds";
+
+-- +migrate Down
+ALTER TABLE worker_model DROP COLUMN autoscaling;
@@ -0,0 +1,11 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS "group_quota" (group_id BIGINT PRIMARY KEY, max_building BIGINT NOT NULL DEFAULT 0, weight BIGINT NOT NULL DEFAULT 1);
+select create_foreign_key('FK_GROUP_QUOTA_GROUP', 'group_quota', 'group', 'group_id', 'id');
+
+ALTER TABLE pipeline ADD COLUMN priority INT;
+
+GRANT SELECT, INSERT, UPDATE, DELETE on ALL TABLES IN SCHEMA public TO "cds";
+
+-- +migrate Down
+DROP TABLE group_quota;
+ALTER TABLE pipeline DROP COLUMN priority;
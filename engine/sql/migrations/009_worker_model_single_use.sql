@@ -0,0 +1,9 @@
+-- +migrate Up
+ALTER TABLE worker_model ADD COLUMN single_use BOOLEAN NOT NULL DEFAULT false;
+-- docker, mesos, local and openstack hatcheries used to spawn single use workers of docker, host and openstack models
+UPDATE worker_model SET single_use = true WHERE type IN ('docker', 'host', 'openstack');
+
+GRANT SELECT, INSERT, UPDATE, DELETE on ALL TABLES IN SCHEMA public TO "cds";
+
+-- +migrate Down
+ALTER TABLE worker_model DROP COLUMN single_use;
@@ -0,0 +1,15 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS "build_cache" (id BIGSERIAL PRIMARY KEY, project_id BIGINT NOT NULL, cache_key TEXT NOT NULL, size BIGINT NOT NULL DEFAULT 0, md5sum TEXT, object_path TEXT, created TIMESTAMP WITH TIME ZONE DEFAULT LOCALTIMESTAMP, last_used TIMESTAMP WITH TIME ZONE DEFAULT LOCALTIMESTAMP);
+CREATE TABLE IF NOT EXISTS "build_cache_limit" (project_id BIGINT PRIMARY KEY, max_size BIGINT NOT NULL);
+
+select create_unique_index('build_cache', 'IDX_BUILD_CACHE_PROJECT_KEY', 'project_id,cache_key');
+select create_foreign_key('FK_BUILD_CACHE_PROJECT', 'build_cache', 'project', 'project_id', 'id');
+select create_foreign_key('FK_BUILD_CACHE_LIMIT_PROJECT', 'build_cache_limit', 'project', 'project_id', 'id');
+
+GRANT SELECT, INSERT, UPDATE, DELETE on ALL TABLES IN SCHEMA public TO "cds";
+
+GRANT ALL ON ALL SEQUENCES IN SCHEMA public TO "cds";
+
+-- +migrate Down
+DROP TABLE build_cache;
+DROP TABLE build_cache_limit;
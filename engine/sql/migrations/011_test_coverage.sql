@@ -0,0 +1,7 @@
+-- +migrate Up
+ALTER TABLE pipeline_build_test ADD COLUMN coverage DOUBLE PRECISION;
+
+GRANT SELECT, INSERT, UPDATE, DELETE on ALL TABLES IN SCHEMA public TO "cds";
+
+-- +migrate Down
+ALTER TABLE pipeline_build_test DROP COLUMN coverage;
@@ -0,0 +1,12 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS "test_case_result" (id BIGSERIAL PRIMARY KEY, pipeline_build_id BIGINT NOT NULL, pipeline_id BIGINT NOT NULL, application_id BIGINT NOT NULL, environment_id BIGINT NOT NULL, build_number BIGINT NOT NULL, branch TEXT, hash TEXT, suite TEXT NOT NULL, name TEXT NOT NULL, status TEXT NOT NULL, duration DOUBLE PRECISION, created TIMESTAMP WITH TIME ZONE DEFAULT LOCALTIMESTAMP);
+
+select create_index('test_case_result', 'IDX_TEST_CASE_RESULT_PIPELINE_BUILD_ID', 'pipeline_build_id');
+select create_index('test_case_result', 'IDX_TEST_CASE_RESULT_BUILD', 'application_id,pipeline_id,environment_id,build_number');
+
+GRANT SELECT, INSERT, UPDATE, DELETE on ALL TABLES IN SCHEMA public TO "cds";
+
+GRANT ALL ON ALL SEQUENCES IN SCHEMA public TO "cds";
+
+-- +migrate Down
+DROP TABLE test_case_result;
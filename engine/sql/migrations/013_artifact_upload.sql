@@ -0,0 +1,17 @@
+-- +migrate Up
+ALTER TABLE artifact ADD COLUMN sha256 TEXT;
+
+CREATE TABLE IF NOT EXISTS "artifact_upload" (id TEXT PRIMARY KEY, pipeline_id BIGINT NOT NULL, application_id BIGINT NOT NULL, environment_id BIGINT NOT NULL, build_number BIGINT NOT NULL, tag TEXT NOT NULL, name TEXT NOT NULL, size BIGINT NOT NULL, perm INT, sha256 TEXT NOT NULL, chunk_size BIGINT NOT NULL, created TIMESTAMP WITH TIME ZONE DEFAULT LOCALTIMESTAMP);
+CREATE TABLE IF NOT EXISTS "artifact_upload_chunk" (upload_id TEXT NOT NULL, chunk INT NOT NULL, size BIGINT NOT NULL, sha256 TEXT NOT NULL, PRIMARY KEY (upload_id, chunk));
+
+select create_index('artifact_upload', 'IDX_ARTIFACT_UPLOAD_BUILD', 'application_id,pipeline_id,environment_id,build_number');
+select create_index('artifact_upload', 'IDX_ARTIFACT_UPLOAD_CREATED', 'created');
+
+GRANT SELECT, INSERT, UPDATE, DELETE on ALL TABLES IN SCHEMA public TO "cds";
+
+GRANT ALL ON ALL SEQUENCES IN SCHEMA public TO "cds";
+
+-- +migrate Down
+DROP TABLE artifact_upload_chunk;
+DROP TABLE artifact_upload;
+ALTER TABLE artifact DROP COLUMN sha256;
@@ -0,0 +1,14 @@
+-- +migrate Up
+ALTER TABLE artifact ADD COLUMN promoted BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE artifact ADD COLUMN promoted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE artifact ADD COLUMN promoted_by TEXT;
+
+select create_index('artifact', 'IDX_ARTIFACT_CREATED', 'created');
+
+GRANT SELECT, INSERT, UPDATE, DELETE on ALL TABLES IN SCHEMA public TO "cds";
+
+-- +migrate Down
+DROP INDEX IDX_ARTIFACT_CREATED;
+ALTER TABLE artifact DROP COLUMN promoted_by;
+ALTER TABLE artifact DROP COLUMN promoted_at;
+ALTER TABLE artifact DROP COLUMN promoted;
@@ -0,0 +1,7 @@
+-- +migrate Up
+ALTER TABLE test_case_result ADD COLUMN attempt INT NOT NULL DEFAULT 0;
+
+GRANT SELECT, INSERT, UPDATE, DELETE on ALL TABLES IN SCHEMA public TO "cds";
+
+-- +migrate Down
+ALTER TABLE test_case_result DROP COLUMN attempt;
@@ -0,0 +1,7 @@
+-- +migrate Up
+ALTER TABLE worker_model ADD COLUMN keep_on_failure INT NOT NULL DEFAULT 0;
+
+GRANT SELECT, INSERT, UPDATE, DELETE on ALL TABLES IN SCHEMA public TO "cds";
+
+-- +migrate Down
+ALTER TABLE worker_model DROP COLUMN keep_on_failure;
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/url"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/ovh/cds/engine/log"
@@ -78,6 +79,15 @@ func runArtifactUpload(filePattern, tag string, actionBuild sdk.ActionBuild) sdk
 
 	for _, filePath := range filesPath {
 		filename := filepath.Base(filePath)
+		if localDir != "" {
+			sendLog(actionBuild.ID, sdk.ArtifactUpload, fmt.Sprintf("Copying '%s' into %s...\n", filename, localArtifactsDir(tag)))
+			if err := saveLocalArtifact(tag, filePath); err != nil {
+				res.Status = sdk.StatusFail
+				sendLog(actionBuild.ID, sdk.ArtifactUpload, fmt.Sprintf("Error while copying artefact: %s\n", err))
+				return res
+			}
+			continue
+		}
 		sendLog(actionBuild.ID, sdk.ArtifactUpload, fmt.Sprintf("Uploading '%s' into %s-%s-%s/%s...\n", filename, project, application, pipeline, tag))
 		if err := sdk.UploadArtifact(project, pipeline, application, tag, filePath, actionBuild.BuildNumber, environment); err != nil {
 			res.Status = sdk.StatusFail
@@ -136,6 +146,11 @@ func runArtifactDownload(a *sdk.Action, actionBuild sdk.ActionBuild) sdk.Result
 		case "application":
 			fmt.Printf("runArtifactDownload: application=%s\n", p.Value)
 			application = p.Value
+		case "environment":
+			fmt.Printf("runArtifactDownload: environment=%s\n", p.Value)
+			if p.Value != "" {
+				environment = p.Value
+			}
 		}
 	}
 
@@ -144,6 +159,38 @@ func runArtifactDownload(a *sdk.Action, actionBuild sdk.ActionBuild) sdk.Result
 		return res
 	}
 
+	if localDir != "" {
+		return runLocalArtifactDownload(tag, filePath, actionBuild)
+	}
+
+	if pipeline == "" {
+		res.Status = sdk.StatusFail
+		sendLog(actionBuild.ID, sdk.ArtifactDownload, fmt.Sprintf("pipeline variable is empty. aborting\n"))
+		return res
+	}
+
+	selector, err := getArtifactSelector(a)
+	if err != nil {
+		res.Status = sdk.StatusFail
+		sendLog(actionBuild.ID, sdk.ArtifactDownload, fmt.Sprintf("%s\n", err))
+		return res
+	}
+
+	if selector != (sdk.ArtifactSelector{}) {
+		sendLog(actionBuild.ID, sdk.ArtifactDownload, fmt.Sprintf("Downloading artifacts from %s-%s-%s (%s) into '%s'...\n", project, application, pipeline, artifactSelectorString(selector), filePath))
+		arts, err := sdk.DownloadArtifactsFrom(project, application, pipeline, environment, selector, filePath)
+		if err != nil {
+			res.Status = sdk.StatusFail
+			log.Warning("Cannot download artifacts: %s\n", err)
+			sendLog(actionBuild.ID, sdk.ArtifactDownload, fmt.Sprintf("%s\n", err))
+			return res
+		}
+		for _, a := range arts {
+			sendLog(actionBuild.ID, sdk.ArtifactDownload, fmt.Sprintf("Downloaded %s of build %d\n", a.Name, a.BuildNumber))
+		}
+		return res
+	}
+
 	if tag == "" {
 		res.Status = sdk.StatusFail
 		sendLog(actionBuild.ID, sdk.ArtifactDownload, fmt.Sprintf("tag variable is empty. aborting\n"))
@@ -152,20 +199,85 @@ func runArtifactDownload(a *sdk.Action, actionBuild sdk.ActionBuild) sdk.Result
 	tag = strings.Replace(tag, "/", "-", -1)
 	tag = url.QueryEscape(tag)
 
-	if pipeline == "" {
+	sendLog(actionBuild.ID, sdk.ArtifactDownload, fmt.Sprintf("Downloading artifacts from %s-%s-%s/%s into '%s'...\n", project, application, pipeline, tag, filePath))
+	err = sdk.DownloadArtifacts(project, application, pipeline, tag, filePath, environment)
+	if err != nil {
 		res.Status = sdk.StatusFail
-		sendLog(actionBuild.ID, sdk.ArtifactDownload, fmt.Sprintf("pipeline variable is empty. aborting\n"))
+		log.Warning("Cannot download artifacts: %s\n", err)
+		sendLog(actionBuild.ID, sdk.ArtifactDownload, fmt.Sprintf("%s\n", err))
 		return res
 	}
 
-	sendLog(actionBuild.ID, sdk.ArtifactDownload, fmt.Sprintf("Downloading artifacts from %s-%s-%s/%s into '%s'...\n", project, application, pipeline, tag, filePath))
-	err := sdk.DownloadArtifacts(project, application, pipeline, tag, filePath, environment)
+	return res
+}
+
+// runLocalArtifactDownload copies artifacts of tag from local directory, build selectors are ignored
+func runLocalArtifactDownload(tag, filePath string, actionBuild sdk.ActionBuild) sdk.Result {
+	res := sdk.Result{Status: sdk.StatusSuccess}
+	if tag == "" {
+		res.Status = sdk.StatusFail
+		sendLog(actionBuild.ID, sdk.ArtifactDownload, fmt.Sprintf("tag variable is empty. aborting\n"))
+		return res
+	}
+	tag = strings.Replace(tag, "/", "-", -1)
+	tag = url.QueryEscape(tag)
+
+	sendLog(actionBuild.ID, sdk.ArtifactDownload, fmt.Sprintf("Copying artifacts from %s into '%s'...\n", localArtifactsDir(tag), filePath))
+	names, err := restoreLocalArtifacts(tag, filePath)
 	if err != nil {
 		res.Status = sdk.StatusFail
-		log.Warning("Cannot download artifacts: %s\n", err)
 		sendLog(actionBuild.ID, sdk.ArtifactDownload, fmt.Sprintf("%s\n", err))
 		return res
 	}
-
+	for _, n := range names {
+		sendLog(actionBuild.ID, sdk.ArtifactDownload, fmt.Sprintf("Copied %s\n", n))
+	}
 	return res
 }
+
+// getArtifactSelector reads parameters selecting the build whose artifacts are downloaded.
+// Unknown variables are considered empty.
+func getArtifactSelector(a *sdk.Action) (sdk.ArtifactSelector, error) {
+	var s sdk.ArtifactSelector
+	for _, p := range a.Parameters {
+		value := strings.TrimSpace(p.Value)
+		if strings.Contains(value, "{{.") {
+			continue
+		}
+		switch p.Name {
+		case "version":
+			if value == "" {
+				continue
+			}
+			v, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || v <= 0 {
+				return s, fmt.Errorf("invalid version '%s'", value)
+			}
+			s.Version = v
+		case "branch":
+			s.Branch = value
+		case "lastSuccess":
+			s.LastSuccess = value == "true"
+		case "promoted":
+			s.Promoted = value == "true"
+		}
+	}
+	return s, nil
+}
+
+func artifactSelectorString(s sdk.ArtifactSelector) string {
+	var criteria []string
+	if s.Version > 0 {
+		criteria = append(criteria, fmt.Sprintf("version %d", s.Version))
+	}
+	if s.Branch != "" {
+		criteria = append(criteria, "branch "+s.Branch)
+	}
+	if s.LastSuccess {
+		criteria = append(criteria, "last successful build")
+	}
+	if s.Promoted {
+		criteria = append(criteria, "promoted")
+	}
+	return strings.Join(criteria, ", ")
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func TestGetArtifactSelector(t *testing.T) {
+	a := sdk.NewAction(sdk.ArtifactDownload)
+	a.Parameter(sdk.Parameter{Name: "version", Value: " 42 "})
+	a.Parameter(sdk.Parameter{Name: "branch", Value: "{{.git.branch}}"})
+	a.Parameter(sdk.Parameter{Name: "lastSuccess", Value: "true"})
+	a.Parameter(sdk.Parameter{Name: "promoted", Value: "false"})
+
+	s, err := getArtifactSelector(a)
+	assert.NoError(t, err)
+	assert.Equal(t, sdk.ArtifactSelector{Version: 42, LastSuccess: true}, s)
+
+	a = sdk.NewAction(sdk.ArtifactDownload)
+	a.Parameter(sdk.Parameter{Name: "version", Value: "v1"})
+	_, err = getArtifactSelector(a)
+	assert.Error(t, err)
+}
@@ -0,0 +1,398 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ovh/cds/sdk"
+)
+
+// checksumPattern matches {{checksum "pattern"}} in cache keys
+var checksumPattern = regexp.MustCompile(`{{\s*checksum\s+"([^"]+)"\s*}}`)
+
+func getCacheParams(a *sdk.Action, ab sdk.ActionBuild) (project, key string, paths []string) {
+	for _, p := range ab.Args {
+		if p.Name == "cds.project" {
+			project = p.Value
+		}
+	}
+
+	for _, p := range a.Parameters {
+		switch p.Name {
+		case "key":
+			key = p.Value
+		case "path":
+			for _, s := range strings.Split(p.Value, "\n") {
+				if s = strings.TrimSpace(s); s != "" {
+					paths = append(paths, s)
+				}
+			}
+		}
+	}
+	return project, key, paths
+}
+
+func runCacheSave(a *sdk.Action, ab sdk.ActionBuild) sdk.Result {
+	res := sdk.Result{Status: sdk.StatusFail}
+	project, key, paths := getCacheParams(a, ab)
+
+	key, err := expandCacheKey(key)
+	if err != nil {
+		sendLog(ab.ID, sdk.CacheSave, fmt.Sprintf("Invalid cache key: %s\n", err))
+		return res
+	}
+
+	if len(paths) == 0 {
+		sendLog(ab.ID, sdk.CacheSave, fmt.Sprintf("path variable is empty. aborting\n"))
+		return res
+	}
+
+	if localDir != "" {
+		return runLocalCacheSave(key, paths, ab)
+	}
+
+	if _, err := sdk.GetCache(project, key); err == nil {
+		sendLog(ab.ID, sdk.CacheSave, fmt.Sprintf("Cache %s already exists, skipping\n", key))
+		res.Status = sdk.StatusSuccess
+		return res
+	} else if err != sdk.ErrCacheNotFound {
+		sendLog(ab.ID, sdk.CacheSave, fmt.Sprintf("Cannot check cache %s: %s\n", key, err))
+		return res
+	}
+
+	f, err := ioutil.TempFile("", "cds-cache")
+	if err != nil {
+		sendLog(ab.ID, sdk.CacheSave, fmt.Sprintf("Cannot create archive: %s\n", err))
+		return res
+	}
+	defer os.Remove(f.Name())
+
+	hash := md5.New()
+	err = archiveCache(io.MultiWriter(f, hash), paths)
+	f.Close()
+	if err != nil {
+		sendLog(ab.ID, sdk.CacheSave, fmt.Sprintf("Cannot create archive: %s\n", err))
+		return res
+	}
+
+	sendLog(ab.ID, sdk.CacheSave, fmt.Sprintf("Uploading cache %s...\n", key))
+	if err := sdk.UploadCache(project, key, f.Name(), hex.EncodeToString(hash.Sum(nil))); err != nil {
+		sendLog(ab.ID, sdk.CacheSave, fmt.Sprintf("Error while uploading cache: %s\n", err))
+		return res
+	}
+
+	res.Status = sdk.StatusSuccess
+	return res
+}
+
+func runCacheRestore(a *sdk.Action, ab sdk.ActionBuild) sdk.Result {
+	res := sdk.Result{Status: sdk.StatusFail}
+	project, key, _ := getCacheParams(a, ab)
+
+	key, err := expandCacheKey(key)
+	if err != nil {
+		sendLog(ab.ID, sdk.CacheRestore, fmt.Sprintf("Invalid cache key: %s\n", err))
+		return res
+	}
+
+	var reader io.ReadCloser
+	if localDir != "" {
+		reader, err = os.Open(localCachePath(key))
+		if os.IsNotExist(err) {
+			err = sdk.ErrCacheNotFound
+		}
+	} else {
+		reader, err = sdk.DownloadCache(project, key)
+	}
+	if err == sdk.ErrCacheNotFound {
+		sendLog(ab.ID, sdk.CacheRestore, fmt.Sprintf("Cache %s not found\n", key))
+		res.Status = sdk.StatusSuccess
+		return res
+	}
+	if err != nil {
+		sendLog(ab.ID, sdk.CacheRestore, fmt.Sprintf("Cannot download cache %s: %s\n", key, err))
+		return res
+	}
+	defer reader.Close()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		sendLog(ab.ID, sdk.CacheRestore, fmt.Sprintf("Cannot get working directory: %s\n", err))
+		return res
+	}
+
+	sendLog(ab.ID, sdk.CacheRestore, fmt.Sprintf("Restoring cache %s...\n", key))
+	if err := extractCache(reader, wd); err != nil {
+		sendLog(ab.ID, sdk.CacheRestore, fmt.Sprintf("Cannot extract cache %s: %s\n", key, err))
+		return res
+	}
+
+	res.Status = sdk.StatusSuccess
+	return res
+}
+
+// runLocalCacheSave archives paths into local directory
+func runLocalCacheSave(key string, paths []string, ab sdk.ActionBuild) sdk.Result {
+	res := sdk.Result{Status: sdk.StatusFail}
+	file := localCachePath(key)
+	if _, err := os.Stat(file); err == nil {
+		sendLog(ab.ID, sdk.CacheSave, fmt.Sprintf("Cache %s already exists, skipping\n", key))
+		res.Status = sdk.StatusSuccess
+		return res
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		sendLog(ab.ID, sdk.CacheSave, fmt.Sprintf("Cannot create cache directory: %s\n", err))
+		return res
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		sendLog(ab.ID, sdk.CacheSave, fmt.Sprintf("Cannot create archive: %s\n", err))
+		return res
+	}
+
+	sendLog(ab.ID, sdk.CacheSave, fmt.Sprintf("Saving cache %s into %s...\n", key, file))
+	err = archiveCache(f, paths)
+	f.Close()
+	if err != nil {
+		os.Remove(file)
+		sendLog(ab.ID, sdk.CacheSave, fmt.Sprintf("Cannot create archive: %s\n", err))
+		return res
+	}
+
+	res.Status = sdk.StatusSuccess
+	return res
+}
+
+// expandCacheKey replaces {{checksum "pattern"}} with the hash of files matching pattern
+func expandCacheKey(key string) (string, error) {
+	var errExpand error
+	key = checksumPattern.ReplaceAllStringFunc(key, func(s string) string {
+		pattern := checksumPattern.FindStringSubmatch(s)[1]
+		sum, err := checksumFiles(pattern)
+		if err != nil {
+			errExpand = err
+		}
+		return sum
+	})
+	if errExpand != nil {
+		return "", errExpand
+	}
+
+	if strings.Contains(key, "{{") {
+		return "", fmt.Errorf("unknown variable in %s", key)
+	}
+
+	key = sdk.CacheKey(key)
+	if key == "" {
+		return "", fmt.Errorf("key variable is empty")
+	}
+	return key, nil
+}
+
+func checksumFiles(pattern string) (string, error) {
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("pattern '%s' matched no file", pattern)
+	}
+	sort.Strings(files)
+
+	hash := sha256.New()
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(hash, f)
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16], nil
+}
+
+// relativeCachePath cleans name, a slash separated path of a cache archive,
+// and returns an error if it's absolute or goes up from the directory it's relative to
+func relativeCachePath(name string) (string, error) {
+	p := filepath.Clean(filepath.FromSlash(name))
+	if filepath.IsAbs(p) || filepath.VolumeName(p) != "" || strings.HasPrefix(name, "/") {
+		return "", fmt.Errorf("absolute path %s in cache", name)
+	}
+	for _, e := range strings.Split(filepath.ToSlash(p), "/") {
+		if e == ".." {
+			return "", fmt.Errorf("path %s goes out of working directory", name)
+		}
+	}
+	return p, nil
+}
+
+// checkNoSymlink returns an error if target, or one of its parent directories under dir, is a symbolic link
+func checkNoSymlink(dir, target string) error {
+	rel, err := filepath.Rel(dir, target)
+	if err != nil {
+		return err
+	}
+	p := dir
+	for _, e := range strings.Split(rel, string(filepath.Separator)) {
+		if e == "." {
+			continue
+		}
+		p = filepath.Join(p, e)
+		fi, err := os.Lstat(p)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("%s is a symbolic link", p)
+		}
+	}
+	return nil
+}
+
+// archiveCache writes a tar.gz of given paths, relative to working directory, into w
+func archiveCache(w io.Writer, paths []string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	for _, root := range paths {
+		root, err := relativeCachePath(root)
+		if err != nil {
+			return err
+		}
+		err = filepath.Walk(root, func(file string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			var link string
+			if fi.Mode()&os.ModeSymlink != 0 {
+				if link, err = os.Readlink(file); err != nil {
+					return err
+				}
+			}
+
+			hdr, err := tar.FileInfoHeader(fi, link)
+			if err != nil {
+				return err
+			}
+			hdr.Name = filepath.ToSlash(file)
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+
+			if !fi.Mode().IsRegular() {
+				return nil
+			}
+
+			f, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = io.Copy(tw, f)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// extractCache extracts a tar.gz read from r in dir. Files are never written out of dir,
+// nor through symbolic links, and symbolic links must point into dir.
+func extractCache(r io.Reader, dir string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name, err := relativeCachePath(hdr.Name)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dir, name)
+		if err := checkNoSymlink(dir, filepath.Dir(target)); err != nil {
+			return err
+		}
+
+		mode := os.FileMode(hdr.Mode).Perm()
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := checkNoSymlink(dir, target); err != nil {
+				return err
+			}
+			if err := os.MkdirAll(target, mode|0700); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			link := filepath.FromSlash(hdr.Linkname)
+			if filepath.IsAbs(link) {
+				return fmt.Errorf("symbolic link %s points to absolute path %s", hdr.Name, hdr.Linkname)
+			}
+			if _, err := relativeCachePath(filepath.ToSlash(filepath.Join(filepath.Dir(name), link))); err != nil {
+				return fmt.Errorf("symbolic link %s points out of working directory", hdr.Name)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			os.Remove(target)
+			if err := os.Symlink(link, target); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			// Replace symbolic links instead of writing through them
+			if fi, err := os.Lstat(target); err == nil && fi.Mode()&os.ModeSymlink != 0 {
+				if err := os.Remove(target); err != nil {
+					return err
+				}
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
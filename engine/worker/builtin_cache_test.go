@@ -0,0 +1,106 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheArchive(t *testing.T) {
+	src, err := ioutil.TempDir("", "cds-cache-src")
+	assert.NoError(t, err)
+	defer os.RemoveAll(src)
+	dst, err := ioutil.TempDir("", "cds-cache-dst")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dst)
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(src, "deps", "lib"), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(src, "deps", "lib", "a.txt"), []byte("foo"), 0644))
+	assert.NoError(t, os.Symlink("lib/a.txt", filepath.Join(src, "deps", "link")))
+
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(src)
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, archiveCache(buf, []string{"deps"}))
+	assert.NoError(t, extractCache(buf, dst))
+
+	content, err := ioutil.ReadFile(filepath.Join(dst, "deps", "link"))
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", string(content))
+}
+
+// maliciousCache returns a tar.gz of given entries, symbolic links if link is set, files otherwise
+func maliciousCache(t *testing.T, entries ...[2]string) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e[0], Mode: 0644, Typeflag: tar.TypeReg, Size: 3}
+		if e[1] != "" {
+			hdr = &tar.Header{Name: e[0], Mode: 0777, Typeflag: tar.TypeSymlink, Linkname: e[1]}
+		}
+		assert.NoError(t, tw.WriteHeader(hdr))
+		if e[1] == "" {
+			tw.Write([]byte("bad"))
+		}
+	}
+	assert.NoError(t, tw.Close())
+	assert.NoError(t, gz.Close())
+	return buf
+}
+
+func TestExtractMaliciousCache(t *testing.T) {
+	root, err := ioutil.TempDir("", "cds-cache-malicious")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+	dst := filepath.Join(root, "dst")
+	outside := filepath.Join(root, "outside")
+	assert.NoError(t, os.MkdirAll(dst, 0755))
+	assert.NoError(t, os.MkdirAll(outside, 0755))
+	victim := filepath.Join(outside, "victim")
+	assert.NoError(t, ioutil.WriteFile(victim, []byte("foo"), 0644))
+
+	assert.Error(t, extractCache(maliciousCache(t, [2]string{victim, ""}), dst))
+	assert.Error(t, extractCache(maliciousCache(t, [2]string{"../outside/victim", ""}), dst))
+	assert.Error(t, extractCache(maliciousCache(t, [2]string{"deps/../../outside/victim", ""}), dst))
+	assert.Error(t, extractCache(maliciousCache(t, [2]string{"link", outside}), dst))
+	assert.Error(t, extractCache(maliciousCache(t, [2]string{"link", "../outside"}), dst))
+
+	// A symbolic link in working directory is never written through
+	assert.NoError(t, os.Symlink(outside, filepath.Join(dst, "link")))
+	assert.Error(t, extractCache(maliciousCache(t, [2]string{"link/victim", ""}), dst))
+	assert.NoError(t, os.Symlink(victim, filepath.Join(dst, "file")))
+	assert.NoError(t, extractCache(maliciousCache(t, [2]string{"file", ""}), dst))
+
+	content, err := ioutil.ReadFile(victim)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", string(content))
+	content, err = ioutil.ReadFile(filepath.Join(dst, "file"))
+	assert.NoError(t, err)
+	assert.Equal(t, "bad", string(content))
+}
+
+func TestExpandCacheKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cds-cache-key")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "go.sum"), []byte("foo"), 0644))
+
+	key, err := expandCacheKey(`go-feat/x-{{checksum "` + filepath.Join(dir, "go.sum") + `"}}`)
+	assert.NoError(t, err)
+	assert.Equal(t, "go-feat-x-2c26b46b68ffc68f", key)
+
+	_, err = expandCacheKey(`go-{{checksum "` + filepath.Join(dir, "package-lock.json") + `"}}`)
+	assert.Error(t, err)
+
+	_, err = expandCacheKey("go-{{.git.branch}}")
+	assert.Error(t, err)
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/ovh/cds/sdk"
+)
+
+var (
+	// dockerTagForbiddenChars matches characters not allowed in Docker tags
+	dockerTagForbiddenChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+	// dockerDigestPattern matches digest printed by docker push
+	dockerDigestPattern = regexp.MustCompile(`digest: (sha256:[a-f0-9]{64})`)
+)
+
+type dockerBuildOptions struct {
+	image      string
+	dockerfile string
+	context    string
+	buildArgs  []string
+	tags       []string
+	registry   string
+	username   string
+	password   string
+	push       bool
+}
+
+func getDockerBuildOptions(a *sdk.Action) (dockerBuildOptions, error) {
+	opts := dockerBuildOptions{dockerfile: "Dockerfile", context: "."}
+
+	for _, p := range a.Parameters {
+		// Unknown variables are considered empty
+		if strings.Contains(p.Value, "{{.") && p.Name != "tags" {
+			continue
+		}
+		switch p.Name {
+		case "image":
+			opts.image = strings.TrimSpace(p.Value)
+		case "dockerfile":
+			if s := strings.TrimSpace(p.Value); s != "" {
+				opts.dockerfile = s
+			}
+		case "context":
+			if s := strings.TrimSpace(p.Value); s != "" {
+				opts.context = s
+			}
+		case "buildArgs":
+			for _, s := range strings.Split(p.Value, "\n") {
+				if s = strings.TrimSpace(s); s == "" {
+					continue
+				}
+				if !strings.Contains(s, "=") {
+					return opts, fmt.Errorf("invalid build argument '%s', expected KEY=VALUE", s)
+				}
+				opts.buildArgs = append(opts.buildArgs, s)
+			}
+		case "tags":
+			for _, s := range strings.Split(p.Value, "\n") {
+				if s = dockerTag(s); s != "" {
+					opts.tags = appendUnique(opts.tags, s)
+				}
+			}
+		case "registry":
+			opts.registry = strings.TrimSpace(p.Value)
+		case "username":
+			opts.username = strings.TrimSpace(p.Value)
+		case "password":
+			opts.password = p.Value
+		case "push":
+			opts.push = p.Value == "true"
+		}
+	}
+
+	if opts.image == "" {
+		return opts, fmt.Errorf("image variable is empty. aborting")
+	}
+	if strings.Contains(opts.image[strings.LastIndex(opts.image, "/")+1:], ":") {
+		return opts, fmt.Errorf("image %s must not contain a tag, use tags variable", opts.image)
+	}
+	if len(opts.tags) == 0 {
+		opts.tags = []string{"latest"}
+	}
+	if opts.registry == "" {
+		opts.registry = dockerRegistry(opts.image)
+	}
+	return opts, nil
+}
+
+// dockerTag makes s a valid Docker tag, unknown variables are dropped
+func dockerTag(s string) string {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "{{.") {
+		return ""
+	}
+	s = strings.TrimLeft(dockerTagForbiddenChars.ReplaceAllString(s, "-"), ".-")
+	if len(s) > 128 {
+		s = s[:128]
+	}
+	return s
+}
+
+// dockerRegistry returns the registry host of image, empty for Docker Hub
+func dockerRegistry(image string) string {
+	i := strings.Index(image, "/")
+	if i < 0 {
+		return ""
+	}
+	host := image[:i]
+	if strings.ContainsAny(host, ".:") || host == "localhost" {
+		return host
+	}
+	return ""
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, e := range list {
+		if e == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+// dockerBuildArgs returns arguments of docker build
+func dockerBuildArgs(opts dockerBuildOptions) []string {
+	args := []string{"build", "--pull", "-f", opts.dockerfile}
+	for _, a := range opts.buildArgs {
+		args = append(args, "--build-arg", a)
+	}
+	for _, t := range opts.tags {
+		args = append(args, "-t", opts.image+":"+t)
+	}
+	return append(args, opts.context)
+}
+
+func runDockerBuild(a *sdk.Action, ab sdk.ActionBuild) sdk.Result {
+	res := sdk.Result{Status: sdk.StatusFail}
+
+	opts, err := getDockerBuildOptions(a)
+	if err != nil {
+		sendLog(ab.ID, sdk.DockerBuildAction, fmt.Sprintf("%s\n", err))
+		return res
+	}
+
+	// Registry credentials must not outlive the build on the worker host
+	config, err := ioutil.TempDir("", "cds-docker")
+	if err != nil {
+		sendLog(ab.ID, sdk.DockerBuildAction, fmt.Sprintf("Cannot create docker configuration: %s\n", err))
+		return res
+	}
+	defer os.RemoveAll(config)
+	env := append(os.Environ(), "DOCKER_CONFIG="+config)
+
+	// Login first, base images may be private
+	if opts.username != "" {
+		login := []string{"login", "--username", opts.username, "--password-stdin"}
+		if opts.registry != "" {
+			login = append(login, opts.registry)
+		}
+		if _, err := runDocker(ab, env, strings.NewReader(opts.password), login...); err != nil {
+			sendLog(ab.ID, sdk.DockerBuildAction, fmt.Sprintf("Cannot login to registry: %s\n", err))
+			return res
+		}
+	}
+
+	if _, err := runDocker(ab, env, nil, dockerBuildArgs(opts)...); err != nil {
+		sendLog(ab.ID, sdk.DockerBuildAction, fmt.Sprintf("Build failed: %s\n", err))
+		return res
+	}
+
+	if !opts.push {
+		res.Status = sdk.StatusSuccess
+		return res
+	}
+
+	var digest string
+	for _, t := range opts.tags {
+		out, err := runDocker(ab, env, nil, "push", opts.image+":"+t)
+		if err != nil {
+			sendLog(ab.ID, sdk.DockerBuildAction, fmt.Sprintf("Push failed: %s\n", err))
+			return res
+		}
+		if m := dockerDigestPattern.FindStringSubmatch(out); m != nil {
+			digest = m[1]
+		}
+	}
+
+	if digest == "" {
+		sendLog(ab.ID, sdk.DockerBuildAction, "Cannot find digest of pushed image\n")
+		return res
+	}
+
+	sendLog(ab.ID, sdk.DockerBuildAction, fmt.Sprintf("Pushed %s@%s\n", opts.image, digest))
+	vars := []sdk.Variable{
+		{Name: "docker.digest", Value: digest, Type: sdk.StringVariable},
+		{Name: "docker.image", Value: opts.image + "@" + digest, Type: sdk.StringVariable},
+	}
+	for _, v := range vars {
+		if err := addBuildVariable(v); err != nil {
+			sendLog(ab.ID, sdk.DockerBuildAction, fmt.Sprintf("Cannot add build variable %s: %s\n", v.Name, err))
+			return res
+		}
+	}
+
+	res.Status = sdk.StatusSuccess
+	return res
+}
+
+// runDocker runs docker with given arguments, sending its output as build logs.
+// The output is returned as well.
+func runDocker(ab sdk.ActionBuild, env []string, stdin io.Reader, args ...string) (string, error) {
+	cmd := exec.Command("docker", args...)
+	cmd.Env = env
+	cmd.Stdin = stdin
+
+	sendLog(ab.ID, sdk.DockerBuildAction, fmt.Sprintf("docker %s\n", strings.Join(args, " ")))
+	var out bytes.Buffer
+	logs := &logWriter{buildID: ab.ID, step: sdk.DockerBuildAction}
+	w := io.MultiWriter(logs, &out)
+	cmd.Stdout = w
+	cmd.Stderr = w
+	err := cmd.Run()
+	logs.flush()
+	return out.String(), err
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func TestGetDockerBuildOptions(t *testing.T) {
+	a := sdk.NewAction(sdk.DockerBuildAction)
+	a.Parameter(sdk.Parameter{Name: "image", Value: "registry.example.com/team/app"})
+	a.Parameter(sdk.Parameter{Name: "tags", Value: "1.2.3\nfeature/Foo Bar\n{{.git.branch}}\n1.2.3"})
+	a.Parameter(sdk.Parameter{Name: "buildArgs", Value: "VERSION=1.2.3\n\nGOPROXY=off"})
+	a.Parameter(sdk.Parameter{Name: "password", Value: "{{.cds.proj.unknown}}"})
+	a.Parameter(sdk.Parameter{Name: "push", Value: "true"})
+
+	opts, err := getDockerBuildOptions(a)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1.2.3", "feature-Foo-Bar"}, opts.tags)
+	assert.Equal(t, "registry.example.com", opts.registry)
+	assert.Equal(t, "", opts.password)
+	assert.True(t, opts.push)
+
+	assert.Equal(t, []string{"build", "--pull", "-f", "Dockerfile",
+		"--build-arg", "VERSION=1.2.3", "--build-arg", "GOPROXY=off",
+		"-t", "registry.example.com/team/app:1.2.3", "-t", "registry.example.com/team/app:feature-Foo-Bar", "."},
+		dockerBuildArgs(opts))
+
+	a = sdk.NewAction(sdk.DockerBuildAction)
+	a.Parameter(sdk.Parameter{Name: "image", Value: "localhost:5000/app:1.0"})
+	_, err = getDockerBuildOptions(a)
+	assert.Error(t, err)
+}
+
+func TestDockerRegistry(t *testing.T) {
+	assert.Equal(t, "", dockerRegistry("ubuntu"))
+	assert.Equal(t, "", dockerRegistry("team/app"))
+	assert.Equal(t, "localhost:5000", dockerRegistry("localhost:5000/app"))
+	assert.Equal(t, "registry.example.com", dockerRegistry("registry.example.com/team/app"))
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ovh/cds/sdk"
+)
+
+// gitAskPassScript gives https credentials to git without writing them in urls
+const gitAskPassScript = `#!/bin/sh
+case "$1" in
+	Username*) echo "$CDS_GIT_USER" ;;
+	*) echo "$CDS_GIT_PASSWORD" ;;
+esac
+`
+
+type gitCloneOptions struct {
+	url            string
+	branch         string
+	commit         string
+	directory      string
+	depth          int
+	submodules     bool
+	lfs            bool
+	sparseCheckout []string
+	user           string
+	password       string
+}
+
+// loadVCSClone asks API the clone urls and credentials of the application repository
+func loadVCSClone(abID int64) (sdk.VCSClone, error) {
+	var clone sdk.VCSClone
+	data, code, err := sdk.Request("GET", fmt.Sprintf("/queue/%d/clone", abID), nil)
+	if err != nil {
+		return clone, err
+	}
+	if code >= 300 {
+		return clone, fmt.Errorf("HTTP %d", code)
+	}
+	err = json.Unmarshal(data, &clone)
+	return clone, err
+}
+
+// getGitCloneOptions reads GitClone parameters. Urls given by trigger, like hooks, take precedence over the ones of clone
+func getGitCloneOptions(a *sdk.Action, ab sdk.ActionBuild, clone sdk.VCSClone) (gitCloneOptions, error) {
+	opts := gitCloneOptions{user: clone.User, password: clone.Password}
+	sshURL, httpURL := clone.SSHCloneURL, clone.HTTPCloneURL
+
+	for _, p := range ab.Args {
+		if p.Value == "" {
+			continue
+		}
+		switch p.Name {
+		case "git.url":
+			sshURL = p.Value
+		case "git.http_url":
+			httpURL = p.Value
+		}
+	}
+
+	for _, p := range a.Parameters {
+		// Unknown variables are considered empty
+		if strings.Contains(p.Value, "{{.") {
+			continue
+		}
+		switch p.Name {
+		case "url":
+			opts.url = strings.TrimSpace(p.Value)
+		case "branch":
+			opts.branch = strings.TrimSpace(p.Value)
+		case "commit":
+			opts.commit = strings.TrimSpace(p.Value)
+		case "directory":
+			opts.directory = strings.TrimSpace(p.Value)
+		case "depth":
+			if p.Value == "" {
+				continue
+			}
+			depth, err := strconv.Atoi(p.Value)
+			if err != nil || depth < 0 {
+				return opts, fmt.Errorf("depth must be a positive number")
+			}
+			opts.depth = depth
+		case "submodules":
+			opts.submodules = p.Value == "true"
+		case "lfs":
+			opts.lfs = p.Value == "true"
+		case "sparseCheckout":
+			for _, s := range strings.Split(p.Value, "\n") {
+				if s = strings.TrimSpace(s); s != "" {
+					opts.sparseCheckout = append(opts.sparseCheckout, s)
+				}
+			}
+		}
+	}
+
+	if opts.url == "" {
+		if sshURL != "" && (pkey != "" || httpURL == "") {
+			opts.url = sshURL
+		} else {
+			opts.url = httpURL
+		}
+	}
+	if opts.url == "" {
+		return opts, fmt.Errorf("no repository url, application is not linked to a repository")
+	}
+
+	if opts.directory == "" {
+		opts.directory = strings.TrimSuffix(path.Base(strings.TrimRight(opts.url, "/")), ".git")
+	}
+	return opts, nil
+}
+
+func runGitClone(a *sdk.Action, ab sdk.ActionBuild) sdk.Result {
+	res := sdk.Result{Status: sdk.StatusFail}
+
+	clone, err := loadVCSClone(ab.ID)
+	if err != nil {
+		sendLog(ab.ID, sdk.GitCloneAction, fmt.Sprintf("Cannot load application repository: %s\n", err))
+	}
+
+	opts, err := getGitCloneOptions(a, ab, clone)
+	if err != nil {
+		sendLog(ab.ID, sdk.GitCloneAction, fmt.Sprintf("%s\n", err))
+		return res
+	}
+
+	env := os.Environ()
+	// LFS files are fetched after checkout, only if asked
+	env = append(env, "GIT_LFS_SKIP_SMUDGE=1", "GIT_TERMINAL_PROMPT=0")
+	if pkey != "" && gitssh != "" {
+		env = append(env, fmt.Sprintf("%s=%s", pKEY, pkey), fmt.Sprintf("%s=%s", GitSSH, gitssh))
+	}
+	if opts.user != "" && (strings.HasPrefix(opts.url, "https://") || strings.HasPrefix(opts.url, "http://")) {
+		askpass, err := ioutil.TempFile("", "cds-askpass")
+		if err != nil {
+			sendLog(ab.ID, sdk.GitCloneAction, fmt.Sprintf("Cannot setup credentials: %s\n", err))
+			return res
+		}
+		defer os.Remove(askpass.Name())
+		askpass.WriteString(gitAskPassScript)
+		askpass.Close()
+		if err := os.Chmod(askpass.Name(), 0700); err != nil {
+			sendLog(ab.ID, sdk.GitCloneAction, fmt.Sprintf("Cannot setup credentials: %s\n", err))
+			return res
+		}
+		env = append(env, "GIT_ASKPASS="+askpass.Name(), "CDS_GIT_USER="+opts.user, "CDS_GIT_PASSWORD="+opts.password)
+	}
+
+	for i, c := range gitCloneCommands(opts) {
+		err := runGit(ab, env, c)
+		// A shallow clone may not contain the commit, fetch it then retry
+		if err != nil && c.args[0] == "checkout" && opts.depth > 0 && opts.commit != "" {
+			fetch := gitCommand{dir: c.dir, args: []string{"fetch", "--depth", strconv.Itoa(opts.depth), "origin", opts.commit}}
+			if err = runGit(ab, env, fetch); err == nil {
+				err = runGit(ab, env, c)
+			}
+		}
+		if err != nil {
+			sendLog(ab.ID, sdk.GitCloneAction, fmt.Sprintf("%s\n", err))
+			return res
+		}
+
+		// Sparse checkout paths must be written before checkout
+		if i == 0 && len(opts.sparseCheckout) > 0 {
+			content := strings.Join(opts.sparseCheckout, "\n") + "\n"
+			if err := ioutil.WriteFile(filepath.Join(opts.directory, ".git", "info", "sparse-checkout"), []byte(content), 0644); err != nil {
+				sendLog(ab.ID, sdk.GitCloneAction, fmt.Sprintf("Cannot setup sparse checkout: %s\n", err))
+				return res
+			}
+		}
+	}
+
+	res.Status = sdk.StatusSuccess
+	return res
+}
+
+// gitCommand is a git command run in dir
+type gitCommand struct {
+	dir  string
+	args []string
+}
+
+// gitCloneCommands returns git commands cloning the repository then checking out the commit
+func gitCloneCommands(opts gitCloneOptions) []gitCommand {
+	clone := []string{"clone", "--no-checkout"}
+	if opts.depth > 0 {
+		clone = append(clone, "--depth", strconv.Itoa(opts.depth))
+	}
+	if opts.branch != "" {
+		clone = append(clone, "--branch", opts.branch)
+	}
+	clone = append(clone, opts.url, opts.directory)
+	cmds := []gitCommand{{args: clone}}
+
+	if len(opts.sparseCheckout) > 0 {
+		cmds = append(cmds, gitCommand{dir: opts.directory, args: []string{"config", "core.sparseCheckout", "true"}})
+	}
+
+	checkout := []string{"checkout", "-f"}
+	switch {
+	case opts.commit != "" && opts.branch != "":
+		checkout = append(checkout, "-B", opts.branch, opts.commit)
+	case opts.commit != "":
+		checkout = append(checkout, opts.commit)
+	default:
+		checkout = append(checkout, "HEAD")
+	}
+	cmds = append(cmds, gitCommand{dir: opts.directory, args: checkout})
+
+	if opts.submodules {
+		submodules := []string{"submodule", "update", "--init", "--recursive"}
+		if opts.depth > 0 {
+			submodules = append(submodules, "--depth", strconv.Itoa(opts.depth))
+		}
+		cmds = append(cmds, gitCommand{dir: opts.directory, args: submodules})
+	}
+
+	if opts.lfs {
+		cmds = append(cmds, gitCommand{dir: opts.directory, args: []string{"lfs", "pull"}})
+	}
+	return cmds
+}
+
+func runGit(ab sdk.ActionBuild, env []string, c gitCommand) error {
+	cmd := exec.Command("git", c.args...)
+	cmd.Env = env
+	cmd.Dir = c.dir
+
+	sendLog(ab.ID, sdk.GitCloneAction, fmt.Sprintf("git %s\n", strings.Join(c.args, " ")))
+	out := &logWriter{buildID: ab.ID, step: sdk.GitCloneAction}
+	cmd.Stdout = out
+	cmd.Stderr = out
+	err := cmd.Run()
+	out.flush()
+	return err
+}
+
+// logWriter sends each written line as a build log
+type logWriter struct {
+	buildID int64
+	step    string
+	buf     []byte
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		sendLog(w.buildID, w.step, string(w.buf[:i+1]))
+		w.buf = w.buf[i+1:]
+	}
+}
+
+func (w *logWriter) flush() {
+	if len(w.buf) > 0 {
+		sendLog(w.buildID, w.step, string(w.buf)+"\n")
+		w.buf = nil
+	}
+}
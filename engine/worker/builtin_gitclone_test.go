@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func TestGetGitCloneOptions(t *testing.T) {
+	a := sdk.NewAction(sdk.GitCloneAction)
+	a.Parameter(sdk.Parameter{Name: "branch", Value: "master"})
+	a.Parameter(sdk.Parameter{Name: "commit", Value: "{{.git.hash}}"})
+	a.Parameter(sdk.Parameter{Name: "depth", Value: "10"})
+	ab := sdk.ActionBuild{Args: []sdk.Parameter{
+		{Name: "git.url", Value: "ssh://git@example.com/PRJ/my-repo.git"},
+		{Name: "git.http_url", Value: "https://example.com/scm/PRJ/my-repo.git"},
+	}}
+
+	// Without ssh key, https url is used
+	pkey = ""
+	opts, err := getGitCloneOptions(a, ab, sdk.VCSClone{})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/scm/PRJ/my-repo.git", opts.url)
+	assert.Equal(t, "my-repo", opts.directory)
+	assert.Equal(t, "", opts.commit)
+	assert.Equal(t, 10, opts.depth)
+
+	pkey = "/tmp/id_rsa"
+	defer func() { pkey = "" }()
+	opts, err = getGitCloneOptions(a, ab, sdk.VCSClone{})
+	assert.NoError(t, err)
+	assert.Equal(t, "ssh://git@example.com/PRJ/my-repo.git", opts.url)
+
+	// Without url given by trigger, urls of repositories manager are used
+	opts, err = getGitCloneOptions(a, sdk.ActionBuild{}, sdk.VCSClone{SSHCloneURL: "ssh://git@example.com/PRJ/other.git", User: "token"})
+	assert.NoError(t, err)
+	assert.Equal(t, "ssh://git@example.com/PRJ/other.git", opts.url)
+	assert.Equal(t, "token", opts.user)
+
+	_, err = getGitCloneOptions(a, sdk.ActionBuild{}, sdk.VCSClone{})
+	assert.Error(t, err)
+}
+
+func TestGitCloneCommands(t *testing.T) {
+	opts := gitCloneOptions{
+		url:            "https://example.com/my-repo.git",
+		directory:      "my-repo",
+		branch:         "master",
+		commit:         "abcdef",
+		depth:          10,
+		submodules:     true,
+		sparseCheckout: []string{"src/"},
+	}
+
+	cmds := gitCloneCommands(opts)
+	assert.Equal(t, 4, len(cmds))
+	assert.Equal(t, []string{"clone", "--no-checkout", "--depth", "10", "--branch", "master", "https://example.com/my-repo.git", "my-repo"}, cmds[0].args)
+	assert.Equal(t, "", cmds[0].dir)
+	assert.Equal(t, []string{"config", "core.sparseCheckout", "true"}, cmds[1].args)
+	assert.Equal(t, []string{"checkout", "-f", "-B", "master", "abcdef"}, cmds[2].args)
+	assert.Equal(t, "my-repo", cmds[2].dir)
+	assert.Equal(t, []string{"submodule", "update", "--init", "--recursive", "--depth", "10"}, cmds[3].args)
+}
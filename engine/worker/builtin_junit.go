@@ -57,26 +57,23 @@ func runParseJunitTestResultAction(a *sdk.Action, ab sdk.ActionBuild) sdk.Result
 
 	var v sdk.Tests
 	for _, f := range files {
-		var ftests sdk.Tests
-
 		data, err := ioutil.ReadFile(f)
 		if err != nil {
 			sendLog(ab.ID, sdk.JUnitAction, fmt.Sprintf("UnitTest parser: cannot read file %s (%s)", f, err))
 			return res
 		}
 
-		err = xml.Unmarshal([]byte(data), &v)
+		suites, coverage, err := parseTestReport(f, data)
 		if err != nil {
 			sendLog(ab.ID, sdk.JUnitAction, fmt.Sprintf("UnitTest parser: cannot interpret file %s (%s)", f, err))
 			return res
 		}
 
-		// Is it nosetests format ?
-		if s, ok := parseNoseTests(data); ok {
-			ftests.TestSuites = append(ftests.TestSuites, s)
+		v.TestSuites = append(v.TestSuites, suites...)
+		if coverage != nil {
+			sendLog(ab.ID, sdk.JUnitAction, fmt.Sprintf("Coverage parser: %s covers %d/%d lines\n", f, coverage.LinesCovered, coverage.LinesValid))
+			v.AddCoverageReports(*coverage)
 		}
-
-		v.TestSuites = append(v.TestSuites, ftests.TestSuites...)
 	}
 	// update global stats
 	for _, s := range v.TestSuites {
@@ -94,11 +91,17 @@ func runParseJunitTestResultAction(a *sdk.Action, ab sdk.ActionBuild) sdk.Result
 		}
 	}
 
-	if v.Total == 0 {
+	if v.Total == 0 && v.Coverage == nil {
 		sendLog(ab.ID, sdk.JUnitAction, "JUnit parser: No tests")
 		res.Status = sdk.StatusFail
 	}
 
+	// Without API, tests are only reported in logs
+	if localDir != "" {
+		sendLog(ab.ID, sdk.JUnitAction, fmt.Sprintf("JUnit parser: %d tests, %d ok, %d failed, %d skipped", v.Total, v.TotalOK, v.TotalKO, v.TotalSkipped))
+		return res
+	}
+
 	data, err := json.Marshal(v)
 	if err != nil {
 		res.Status = sdk.StatusFail
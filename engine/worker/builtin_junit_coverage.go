@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ovh/cds/sdk"
+)
+
+// parseCobertura reads lines counters of a Cobertura report. Old reports without
+// counters on the root element are computed from hits of each line.
+func parseCobertura(file string, data []byte) (*sdk.CoverageReport, error) {
+	report := &sdk.CoverageReport{Name: file}
+	var counted sdk.CoverageReport
+
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		t, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		e, ok := t.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch e.Name.Local {
+		case "coverage":
+			for _, a := range e.Attr {
+				switch a.Name.Local {
+				case "lines-valid":
+					report.LinesValid, _ = strconv.Atoi(a.Value)
+				case "lines-covered":
+					report.LinesCovered, _ = strconv.Atoi(a.Value)
+				}
+			}
+		case "line":
+			counted.LinesValid++
+			for _, a := range e.Attr {
+				if a.Name.Local == "hits" {
+					if hits, _ := strconv.ParseInt(a.Value, 10, 64); hits > 0 {
+						counted.LinesCovered++
+					}
+				}
+			}
+		}
+	}
+
+	if report.LinesValid == 0 {
+		report.LinesValid = counted.LinesValid
+		report.LinesCovered = counted.LinesCovered
+	}
+	return report, nil
+}
+
+func isLCOV(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("TN:")) || bytes.HasPrefix(data, []byte("SF:"))
+}
+
+// parseLCOV sums lines found and hit of all records of a LCOV tracefile
+func parseLCOV(file string, data []byte) (*sdk.CoverageReport, error) {
+	report := &sdk.CoverageReport{Name: file}
+
+	// Records without LF/LH summary are computed from DA lines
+	var found, hit, daFound, daHit int
+	var summary bool
+	endRecord := func() {
+		if summary {
+			report.LinesValid += found
+			report.LinesCovered += hit
+		} else {
+			report.LinesValid += daFound
+			report.LinesCovered += daHit
+		}
+		found, hit, daFound, daHit = 0, 0, 0, 0
+		summary = false
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "LF:"):
+			found, _ = strconv.Atoi(line[3:])
+			summary = true
+		case strings.HasPrefix(line, "LH:"):
+			hit, _ = strconv.Atoi(line[3:])
+			summary = true
+		case strings.HasPrefix(line, "DA:"):
+			fields := strings.Split(line[3:], ",")
+			if len(fields) < 2 {
+				continue
+			}
+			daFound++
+			if hits, _ := strconv.ParseInt(fields[1], 10, 64); hits > 0 {
+				daHit++
+			}
+		case line == "end_of_record":
+			endRecord()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// Last record may not be terminated
+	if summary || daFound > 0 {
+		endRecord()
+	}
+	return report, nil
+}
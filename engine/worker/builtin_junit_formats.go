@@ -0,0 +1,426 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ovh/cds/sdk"
+)
+
+// parseTestReport detects the format of a test or coverage report then parses it.
+// Supported formats are JUnit, nosetests, xUnit.net, NUnit, TAP, go test -json, Cobertura and LCOV.
+func parseTestReport(file string, data []byte) ([]sdk.TestSuite, *sdk.CoverageReport, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	trimmed := bytes.TrimSpace(data)
+
+	if bytes.HasPrefix(trimmed, []byte("<")) {
+		root, err := xmlRootName(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		switch root {
+		case "assemblies", "assembly":
+			suites, err := parseXUnit(data)
+			return suites, nil, err
+		case "test-run", "test-results":
+			suites, err := parseNUnit(data)
+			return suites, nil, err
+		case "coverage":
+			report, err := parseCobertura(file, data)
+			return nil, report, err
+		default:
+			suites, err := parseJUnit(data)
+			return suites, nil, err
+		}
+	}
+
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		suites, err := parseGoTestJSON(data)
+		return suites, nil, err
+	case isLCOV(trimmed):
+		report, err := parseLCOV(file, data)
+		return nil, report, err
+	case isTAP(trimmed):
+		suites, err := parseTAP(file, data)
+		return suites, nil, err
+	}
+	return nil, nil, fmt.Errorf("unknown report format")
+}
+
+func xmlRootName(data []byte) (string, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return "", err
+		}
+		if e, ok := t.(xml.StartElement); ok {
+			return e.Name.Local, nil
+		}
+	}
+}
+
+func parseJUnit(data []byte) ([]sdk.TestSuite, error) {
+	var v sdk.Tests
+	if err := xml.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+
+	// Is it nosetests format ?
+	if s, ok := parseNoseTests(data); ok {
+		v.TestSuites = append(v.TestSuites, s)
+	}
+	return v.TestSuites, nil
+}
+
+// formatSeconds formats a duration in seconds like JUnit reports
+func formatSeconds(s float64) string {
+	return strconv.FormatFloat(s, 'f', 3, 64)
+}
+
+// addTest adds t to suite and updates its counters
+func addTest(suite *sdk.TestSuite, t sdk.Test) {
+	suite.Total++
+	switch {
+	case t.Failure != "":
+		suite.Failures++
+	case t.Error != "":
+		suite.Errors++
+	case t.Skip != nil:
+		suite.Skip++
+	}
+	suite.Tests = append(suite.Tests, t)
+}
+
+// ----------------------------------- TAP ---------------------------
+
+var (
+	tapTestLine = regexp.MustCompile(`^(not ok|ok)\b\s*(\d+)?\s*-?\s*([^#]*)(?:#\s*(\w+)\s*(.*))?$`)
+	tapPlanLine = regexp.MustCompile(`^1\.\.\d+`)
+)
+
+func isTAP(data []byte) bool {
+	line := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		line = data[:i]
+	}
+	line = bytes.TrimSpace(line)
+	return bytes.HasPrefix(line, []byte("TAP version")) || tapPlanLine.Match(line) || tapTestLine.Match(line)
+}
+
+// parseTAP reads a Test Anything Protocol stream as a single suite named after the file
+func parseTAP(file string, data []byte) ([]sdk.TestSuite, error) {
+	suite := sdk.TestSuite{Name: strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))}
+
+	var current *sdk.Test
+	var diag []string
+	var inYAML bool
+	flush := func() {
+		if current == nil {
+			return
+		}
+		if current.Failure != "" && len(diag) > 0 {
+			current.Failure = strings.Join(diag, "\n")
+		}
+		addTest(&suite, *current)
+		current = nil
+		diag = nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		raw := scanner.Text()
+		line := strings.TrimSpace(raw)
+
+		// YAML diagnostic block of the previous test
+		if inYAML {
+			if line == "..." {
+				inYAML = false
+			} else {
+				diag = append(diag, line)
+			}
+			continue
+		}
+		if line == "---" && current != nil {
+			inYAML = true
+			continue
+		}
+		if strings.HasPrefix(line, "#") && current != nil {
+			diag = append(diag, strings.TrimSpace(strings.TrimPrefix(line, "#")))
+			continue
+		}
+
+		if strings.HasPrefix(line, "Bail out!") {
+			flush()
+			addTest(&suite, sdk.Test{Name: "Bail out", Failure: line})
+			break
+		}
+
+		// Subtests are indented, only top level results are kept
+		if strings.HasPrefix(raw, " ") || strings.HasPrefix(raw, "\t") {
+			continue
+		}
+
+		m := tapTestLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		flush()
+
+		t := sdk.Test{Name: strings.TrimSpace(m[3])}
+		if t.Name == "" {
+			t.Name = "test " + m[2]
+		}
+		directive := strings.ToUpper(m[4])
+		switch {
+		case directive == "SKIP" || directive == "TODO":
+			reason := strings.TrimSpace(m[5])
+			t.Skip = &reason
+		case m[1] == "not ok":
+			t.Failure = "not ok"
+		}
+		current = &t
+	}
+	flush()
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return []sdk.TestSuite{suite}, nil
+}
+
+// ----------------------------------- go test -json ---------------------------
+
+type goTestEvent struct {
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64
+	Output  string
+}
+
+// parseGoTestJSON reads output of go test -json, one suite per package
+func parseGoTestJSON(data []byte) ([]sdk.TestSuite, error) {
+	var suites []sdk.TestSuite
+	index := map[string]int{}
+	outputs := map[string][]string{}
+	failedTests := map[string]bool{}
+
+	suite := func(pkg string) *sdk.TestSuite {
+		i, ok := index[pkg]
+		if !ok {
+			i = len(suites)
+			index[pkg] = i
+			suites = append(suites, sdk.TestSuite{Name: pkg})
+		}
+		return &suites[i]
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		// go test -json may be mixed with build output
+		if line[0] != '{' {
+			continue
+		}
+
+		var e goTestEvent
+		if err := json.Unmarshal(line, &e); err != nil {
+			return nil, err
+		}
+		key := e.Package + " " + e.Test
+
+		switch e.Action {
+		case "output":
+			outputs[key] = append(outputs[key], e.Output)
+		case "pass", "fail", "skip":
+			s := suite(e.Package)
+			output := strings.Join(outputs[key], "")
+			delete(outputs, key)
+
+			if e.Test == "" {
+				// A package failing without failed test did not build or panicked
+				if e.Action == "fail" && !failedTests[e.Package] {
+					addTest(s, sdk.Test{Name: e.Package, Time: formatSeconds(e.Elapsed), Failure: output})
+				}
+				continue
+			}
+
+			t := sdk.Test{Name: e.Test, Time: formatSeconds(e.Elapsed)}
+			switch e.Action {
+			case "fail":
+				failedTests[e.Package] = true
+				t.Failure = output
+			case "skip":
+				t.Skip = &output
+			}
+			addTest(s, t)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return suites, nil
+}
+
+// ----------------------------------- xUnit.net ---------------------------
+
+type xunitAssemblies struct {
+	Assemblies []xunitAssembly `xml:"assembly"`
+}
+
+type xunitAssembly struct {
+	Name        string            `xml:"name,attr"`
+	Collections []xunitCollection `xml:"collection"`
+}
+
+type xunitCollection struct {
+	Tests []xunitTest `xml:"test"`
+}
+
+type xunitTest struct {
+	Name    string        `xml:"name,attr"`
+	Time    string        `xml:"time,attr"`
+	Result  string        `xml:"result,attr"`
+	Failure *xunitFailure `xml:"failure"`
+	Reason  string        `xml:"reason"`
+}
+
+type xunitFailure struct {
+	Message    string `xml:"message"`
+	StackTrace string `xml:"stack-trace"`
+}
+
+func (f *xunitFailure) String() string {
+	if f == nil {
+		return ""
+	}
+	return strings.TrimSpace(f.Message + "\n" + f.StackTrace)
+}
+
+// parseXUnit reads a xUnit.net v2 report, one suite per assembly
+func parseXUnit(data []byte) ([]sdk.TestSuite, error) {
+	var assemblies xunitAssemblies
+	root, err := xmlRootName(data)
+	if err != nil {
+		return nil, err
+	}
+	if root == "assembly" {
+		var a xunitAssembly
+		err = xml.Unmarshal(data, &a)
+		assemblies.Assemblies = append(assemblies.Assemblies, a)
+	} else {
+		err = xml.Unmarshal(data, &assemblies)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var suites []sdk.TestSuite
+	for _, a := range assemblies.Assemblies {
+		s := sdk.TestSuite{Name: filepath.Base(strings.Replace(a.Name, "\\", "/", -1))}
+		for _, c := range a.Collections {
+			for _, xt := range c.Tests {
+				t := sdk.Test{Name: xt.Name, Time: xt.Time}
+				switch xt.Result {
+				case "Fail":
+					t.Failure = xt.Failure.String()
+					if t.Failure == "" {
+						t.Failure = "Fail"
+					}
+				case "Skip", "NotRun":
+					reason := xt.Reason
+					t.Skip = &reason
+				}
+				addTest(&s, t)
+			}
+		}
+		suites = append(suites, s)
+	}
+	return suites, nil
+}
+
+// ----------------------------------- NUnit ---------------------------
+
+// nunitSuite matches test-suite elements of NUnit 3, and NUnit 2 where children are in results
+type nunitSuite struct {
+	Name     string       `xml:"name,attr"`
+	FullName string       `xml:"fullname,attr"`
+	Suites   []nunitSuite `xml:"test-suite"`
+	Cases    []nunitCase  `xml:"test-case"`
+	Results  *nunitSuite  `xml:"results"`
+}
+
+type nunitCase struct {
+	Name     string        `xml:"name,attr"`
+	FullName string        `xml:"fullname,attr"`
+	Result   string        `xml:"result,attr"`
+	Duration string        `xml:"duration,attr"`
+	Time     string        `xml:"time,attr"`
+	Failure  *xunitFailure `xml:"failure"`
+	Reason   struct {
+		Message string `xml:"message"`
+	} `xml:"reason"`
+}
+
+// parseNUnit reads a NUnit 2 or 3 report, one suite per fixture
+func parseNUnit(data []byte) ([]sdk.TestSuite, error) {
+	var root nunitSuite
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+
+	var suites []sdk.TestSuite
+	var walk func(n nunitSuite)
+	walk = func(n nunitSuite) {
+		if n.Results != nil {
+			n.Suites = append(n.Suites, n.Results.Suites...)
+			n.Cases = append(n.Cases, n.Results.Cases...)
+		}
+
+		if len(n.Cases) > 0 {
+			s := sdk.TestSuite{Name: n.FullName}
+			if s.Name == "" {
+				s.Name = n.Name
+			}
+			for _, c := range n.Cases {
+				t := sdk.Test{Name: c.Name, Time: c.Duration}
+				if t.Time == "" {
+					t.Time = c.Time
+				}
+				switch c.Result {
+				case "Failed", "Failure", "Error":
+					t.Failure = c.Failure.String()
+					if t.Failure == "" {
+						t.Failure = c.Result
+					}
+				case "Skipped", "Ignored", "NotRunnable", "Inconclusive":
+					reason := c.Reason.Message
+					t.Skip = &reason
+				}
+				addTest(&s, t)
+			}
+			suites = append(suites, s)
+		}
+
+		for _, child := range n.Suites {
+			walk(child)
+		}
+	}
+	walk(root)
+	return suites, nil
+}
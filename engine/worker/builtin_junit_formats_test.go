@@ -0,0 +1,147 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTAP(t *testing.T) {
+	data := `TAP version 13
+1..4
+ok 1 - first
+not ok 2 - second
+  ---
+  message: expected 1
+  ...
+ok 3 - third # SKIP not on linux
+    ok 1 - subtest ignored
+ok 4
+`
+	suites, coverage, err := parseTestReport("results/unit.tap", []byte(data))
+	assert.NoError(t, err)
+	assert.Nil(t, coverage)
+	assert.Len(t, suites, 1)
+	assert.Equal(t, "unit", suites[0].Name)
+	assert.Equal(t, 4, suites[0].Total)
+	assert.Equal(t, 1, suites[0].Failures)
+	assert.Equal(t, 1, suites[0].Skip)
+	assert.Equal(t, "message: expected 1", suites[0].Tests[1].Failure)
+	assert.Equal(t, "test 4", suites[0].Tests[3].Name)
+}
+
+func TestParseGoTestJSON(t *testing.T) {
+	data := `{"Action":"run","Package":"example.com/a","Test":"TestOK"}
+{"Action":"pass","Package":"example.com/a","Test":"TestOK","Elapsed":0.01}
+{"Action":"run","Package":"example.com/a","Test":"TestKO"}
+{"Action":"output","Package":"example.com/a","Test":"TestKO","Output":"a_test.go:12: boom\n"}
+{"Action":"fail","Package":"example.com/a","Test":"TestKO","Elapsed":0.02}
+{"Action":"fail","Package":"example.com/a","Elapsed":0.1}
+{"Action":"output","Package":"example.com/b","Output":"b.go:3: undefined: x\n"}
+{"Action":"fail","Package":"example.com/b","Elapsed":0}
+`
+	suites, _, err := parseTestReport("go.json", []byte(data))
+	assert.NoError(t, err)
+	assert.Len(t, suites, 2)
+	assert.Equal(t, 2, suites[0].Total)
+	assert.Equal(t, 1, suites[0].Failures)
+	assert.Equal(t, "a_test.go:12: boom\n", suites[0].Tests[1].Failure)
+	assert.Equal(t, "0.020", suites[0].Tests[1].Time)
+	// Build failure of package b
+	assert.Equal(t, 1, suites[1].Failures)
+}
+
+func TestParseXUnitAndNUnit(t *testing.T) {
+	xunit := `<?xml version="1.0" encoding="utf-8"?>
+<assemblies>
+  <assembly name="C:\build\Tests.dll" total="3">
+    <collection>
+      <test name="A.Pass" time="0.1" result="Pass" />
+      <test name="A.Fail" time="0.2" result="Fail"><failure><message>expected</message><stack-trace>at A</stack-trace></failure></test>
+      <test name="A.Skip" time="0" result="Skip"><reason>later</reason></test>
+    </collection>
+  </assembly>
+</assemblies>`
+	suites, _, err := parseTestReport("xunit.xml", []byte(xunit))
+	assert.NoError(t, err)
+	assert.Len(t, suites, 1)
+	assert.Equal(t, "Tests.dll", suites[0].Name)
+	assert.Equal(t, 3, suites[0].Total)
+	assert.Equal(t, 1, suites[0].Failures)
+	assert.Equal(t, 1, suites[0].Skip)
+	assert.Equal(t, "expected\nat A", suites[0].Tests[1].Failure)
+
+	nunit3 := `<test-run>
+  <test-suite type="Assembly" name="Tests.dll">
+    <test-suite type="TestFixture" name="Fixture" fullname="NS.Fixture">
+      <test-case name="Pass" result="Passed" duration="0.1" />
+      <test-case name="Fail" result="Failed" duration="0.1"><failure><message>no</message></failure></test-case>
+      <test-case name="Ignored" result="Skipped"><reason><message>later</message></reason></test-case>
+    </test-suite>
+  </test-suite>
+</test-run>`
+	suites, _, err = parseTestReport("nunit.xml", []byte(nunit3))
+	assert.NoError(t, err)
+	assert.Len(t, suites, 1)
+	assert.Equal(t, "NS.Fixture", suites[0].Name)
+	assert.Equal(t, 1, suites[0].Failures)
+	assert.Equal(t, "later", *suites[0].Tests[2].Skip)
+
+	nunit2 := `<test-results name="Tests.dll">
+  <test-suite name="Tests.dll"><results>
+    <test-suite name="Fixture"><results>
+      <test-case name="NS.Fixture.Pass" executed="True" result="Success" time="0.1" />
+      <test-case name="NS.Fixture.Fail" executed="True" result="Failure" time="0.1"><failure><message>no</message></failure></test-case>
+    </results></test-suite>
+  </results></test-suite>
+</test-results>`
+	suites, _, err = parseTestReport("nunit2.xml", []byte(nunit2))
+	assert.NoError(t, err)
+	assert.Len(t, suites, 1)
+	assert.Equal(t, "Fixture", suites[0].Name)
+	assert.Equal(t, 2, suites[0].Total)
+	assert.Equal(t, 1, suites[0].Failures)
+}
+
+func TestParseCoverage(t *testing.T) {
+	cobertura := `<?xml version="1.0" ?>
+<coverage line-rate="0.5" lines-covered="5" lines-valid="10" version="1.9"></coverage>`
+	_, report, err := parseTestReport("coverage.xml", []byte(cobertura))
+	assert.NoError(t, err)
+	assert.Equal(t, 10, report.LinesValid)
+	assert.Equal(t, 5, report.LinesCovered)
+
+	// Old Cobertura reports only have line rates
+	old := `<coverage line-rate="0.5"><packages><package><classes><class filename="a.py"><lines>
+<line number="1" hits="1"/><line number="2" hits="0"/><line number="3" hits="4"/>
+</lines></class></classes></package></packages></coverage>`
+	_, report, err = parseTestReport("coverage.xml", []byte(old))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, report.LinesValid)
+	assert.Equal(t, 2, report.LinesCovered)
+
+	lcov := `TN:
+SF:src/a.js
+DA:1,1
+DA:2,0
+LF:2
+LH:1
+end_of_record
+SF:src/b.js
+DA:1,3
+DA:2,1
+DA:3,0
+end_of_record
+`
+	suites, report, err := parseTestReport("lcov.info", []byte(lcov))
+	assert.NoError(t, err)
+	assert.Nil(t, suites)
+	assert.Equal(t, "lcov.info", report.Name)
+	assert.Equal(t, 5, report.LinesValid)
+	assert.Equal(t, 3, report.LinesCovered)
+}
+
+func TestParseUnknownReport(t *testing.T) {
+	_, _, err := parseTestReport("README", []byte("hello"))
+	assert.Error(t, err)
+}
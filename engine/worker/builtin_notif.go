@@ -62,6 +62,12 @@ func runNotifAction(a *sdk.Action, actionBuild sdk.ActionBuild) sdk.Result {
 		return res
 	}
 
+	if localDir != "" {
+		sendLog(actionBuild.ID, sdk.ScriptAction, fmt.Sprintf("Notif to %s not sent without API: %s\n%s\n", destination, title, message))
+		res.Status = sdk.StatusSuccess
+		return res
+	}
+
 	sendLog(actionBuild.ID, sdk.ScriptAction, "Send notif message to API\n")
 	path := fmt.Sprintf("/notif/%d", actionBuild.ID)
 	_, _, err = sdk.Request("POST", path, body)
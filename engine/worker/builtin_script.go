@@ -111,28 +111,13 @@ func runScriptAction(a *sdk.Action, actionBuild sdk.ActionBuild) sdk.Result {
 	cmd := exec.Command(shell, opts...)
 	res.Status = sdk.StatusUnknown
 
-	// worker export http port
-	cmd.Env = os.Environ()
-	cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%d", WorkerServerPort, exportport))
-	if pkey != "" && gitssh != "" {
-		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", pKEY, pkey))
-		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", GitSSH, gitssh))
-	}
-	workerpath, err := osext.Executable()
+	cmd.Env, err = scriptEnv()
 	if err != nil {
 		log.Warning("runScriptAction: Cannot get worker path: %s\n", err)
 		sendLog(actionBuild.ID, sdk.ScriptAction, "Failure due to internal error (Worker Path)")
 		res.Status = sdk.StatusFail
 		return res
 	}
-	log.Notice("Worker binary path: %s\n", path.Dir(workerpath))
-
-	for i := range cmd.Env {
-		if strings.HasPrefix(cmd.Env[i], "PATH") {
-			cmd.Env[i] = fmt.Sprintf("%s:%s", cmd.Env[i], path.Dir(workerpath))
-			break
-		}
-	}
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
@@ -198,3 +183,27 @@ func runScriptAction(a *sdk.Action, actionBuild sdk.ActionBuild) sdk.Result {
 	res.Status = sdk.StatusSuccess
 	return res
 }
+
+// scriptEnv returns environment of commands run by worker, giving access to worker export http port,
+// git ssh configuration and worker binary
+func scriptEnv() ([]string, error) {
+	env := os.Environ()
+	env = append(env, fmt.Sprintf("%s=%d", WorkerServerPort, exportport))
+	if pkey != "" && gitssh != "" {
+		env = append(env, fmt.Sprintf("%s=%s", pKEY, pkey))
+		env = append(env, fmt.Sprintf("%s=%s", GitSSH, gitssh))
+	}
+	workerpath, err := osext.Executable()
+	if err != nil {
+		return nil, err
+	}
+	log.Notice("Worker binary path: %s\n", path.Dir(workerpath))
+
+	for i := range env {
+		if strings.HasPrefix(env[i], "PATH") {
+			env[i] = fmt.Sprintf("%s:%s", env[i], path.Dir(workerpath))
+			break
+		}
+	}
+	return env, nil
+}
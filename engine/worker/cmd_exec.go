@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ovh/cds/sdk"
+)
+
+var cmdExecParams struct {
+	file        string
+	project     string
+	application string
+	pipeline    string
+	environment string
+	job         string
+	variables   string
+	artifacts   string
+	basedir     string
+}
+
+func init() {
+	flags := cmdExec.Flags()
+	flags.StringVar(&cmdExecParams.file, "file", "", "HCL file of the action to run")
+	flags.StringVar(&cmdExecParams.project, "project", "", "Key of the project whose pipeline is loaded from API")
+	flags.StringVar(&cmdExecParams.application, "application", "", "Application name, set as cds.application")
+	flags.StringVar(&cmdExecParams.pipeline, "pipeline", "", "Pipeline loaded from API")
+	flags.StringVar(&cmdExecParams.environment, "environment", sdk.DefaultEnv.Name, "Environment name, set as cds.environment")
+	flags.StringVar(&cmdExecParams.job, "job", "", "Job of the pipeline to run, mandatory if pipeline has several jobs")
+	flags.StringVar(&cmdExecParams.variables, "variables", "", "File of variables, one name=value per line")
+	flags.StringVar(&cmdExecParams.artifacts, "artifacts", "artifacts", "Directory where artifacts and caches are stored")
+	flags.StringVar(&cmdExecParams.basedir, "basedir", "", "Directory where the working directory of the job is created")
+}
+
+var cmdExec = &cobra.Command{
+	Use:   "exec",
+	Short: "worker exec --file <action.hcl> | --project <key> --pipeline <pipeline> [--job <job>]",
+	Long: `Run an action locally, without registering on API
+
+The action is read from an HCL file, or is a job of a pipeline loaded from API with the configuration of cds CLI.
+Steps run as in a worker, but logs are printed, artifacts and caches are stored in --artifacts directory
+and nothing is sent to API.
+
+Variables are read from --variables file, with names as used in placeholders.
+Parameters of the action are set by variables with the same name:
+
+	# comments and empty lines are ignored
+	cds.pip.version=1.0
+	git.branch=master
+	cds.app.message="quoted values\nare unquoted"
+`,
+	Run: execCmd,
+}
+
+func execCmd(cmd *cobra.Command, args []string) {
+	sdk.SetAgent(sdk.SDKAgent)
+
+	a, params, err := loadExecAction()
+	if err != nil {
+		sdk.Exit("Cannot load action: %s\n", err)
+	}
+
+	if cmdExecParams.variables != "" {
+		vars, err := readVariablesFile(cmdExecParams.variables)
+		if err != nil {
+			sdk.Exit("Cannot read variables: %s\n", err)
+		}
+		params = mergeParameters(params, vars)
+	}
+
+	// Parameters are set before their values replace placeholders of steps
+	for i := range a.Parameters {
+		for _, p := range params {
+			if p.Name == a.Parameters[i].Name {
+				a.Parameters[i].Value = p.Value
+			}
+		}
+	}
+
+	localDir, err = filepath.Abs(cmdExecParams.artifacts)
+	if err != nil {
+		sdk.Exit("Invalid artifacts directory: %s\n", err)
+	}
+
+	basedir = cmdExecParams.basedir
+	if basedir == "" {
+		basedir = os.TempDir()
+	}
+	name = "local"
+
+	port, err := server()
+	if err != nil {
+		sdk.Exit("cannot bind port for worker export: %s\n", err)
+	}
+	exportport = port
+
+	logChan = make(chan sdk.Log)
+	logged := make(chan bool)
+	go func() {
+		localLogger(logChan)
+		close(logged)
+	}()
+
+	for _, r := range a.Requirements {
+		ok, err := checkRequirement(r)
+		if err != nil {
+			fmt.Printf("Warning: cannot check requirement %s (%s: %s): %s\n", r.Name, r.Type, r.Value, err)
+		} else if !ok {
+			fmt.Printf("Warning: requirement %s (%s: %s) is not met\n", r.Name, r.Type, r.Value)
+		}
+	}
+
+	ab = sdk.ActionBuild{
+		ActionName: a.Name,
+		Args:       params,
+		Status:     sdk.StatusBuilding,
+	}
+	buildVariables = nil
+	res := run(*a, ab, nil)
+
+	close(logChan)
+	<-logged
+	fmt.Printf("%s: %s\n", a.Name, res.Status)
+	if res.Status != sdk.StatusSuccess {
+		os.Exit(1)
+	}
+}
+
+// loadExecAction loads action to run and builtin variables of its build
+func loadExecAction() (*sdk.Action, []sdk.Parameter, error) {
+	p := cmdExecParams
+	params := []sdk.Parameter{
+		{Name: "cds.project", Type: sdk.StringParameter, Value: p.project},
+		{Name: "cds.application", Type: sdk.StringParameter, Value: p.application},
+		{Name: "cds.pipeline", Type: sdk.StringParameter, Value: p.pipeline},
+		{Name: "cds.environment", Type: sdk.StringParameter, Value: p.environment},
+		{Name: "cds.buildNumber", Type: sdk.StringParameter, Value: "0"},
+		{Name: "cds.version", Type: sdk.StringParameter, Value: "0"},
+	}
+
+	if p.file != "" {
+		btes, err := ioutil.ReadFile(p.file)
+		if err != nil {
+			return nil, nil, err
+		}
+		a, err := sdk.NewActionFromScript(btes)
+		if err != nil {
+			return nil, nil, err
+		}
+		return a, params, nil
+	}
+
+	if p.project == "" || p.pipeline == "" {
+		return nil, nil, fmt.Errorf("--file or --project and --pipeline are mandatory")
+	}
+
+	pip, err := sdk.GetPipeline(p.project, p.pipeline)
+	if err != nil {
+		return nil, nil, err
+	}
+	a, err := findJob(pip, p.job)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, pp := range pip.Parameter {
+		pp.Name = "cds.pip." + pp.Name
+		params = append(params, pp)
+	}
+	return a, params, nil
+}
+
+// findJob returns the job of pipeline with given name, or its only job if name is empty
+func findJob(pip *sdk.Pipeline, name string) (*sdk.Action, error) {
+	var jobs []sdk.Action
+	var names []string
+	for _, s := range pip.Stages {
+		for _, j := range s.Jobs {
+			if j.Action.Name == name {
+				return &j.Action, nil
+			}
+			jobs = append(jobs, j.Action)
+			names = append(names, fmt.Sprintf("%s (stage %s)", j.Action.Name, s.Name))
+		}
+	}
+
+	if name == "" && len(jobs) == 1 {
+		return &jobs[0], nil
+	}
+	if name == "" {
+		return nil, fmt.Errorf("pipeline %s has %d jobs, select one with --job: %s", pip.Name, len(jobs), strings.Join(names, ", "))
+	}
+	return nil, fmt.Errorf("job %s not found in pipeline %s: %s", name, pip.Name, strings.Join(names, ", "))
+}
+
+// readVariablesFile reads name=value lines, values starting with a quote are unquoted
+func readVariablesFile(file string) ([]sdk.Parameter, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var params []sdk.Parameter
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		t := strings.SplitN(line, "=", 2)
+		if len(t) != 2 || strings.TrimSpace(t[0]) == "" {
+			return nil, fmt.Errorf("%s:%d: expected name=value", file, n)
+		}
+		value := strings.TrimSpace(t[1])
+		if strings.HasPrefix(value, `"`) {
+			if value, err = strconv.Unquote(value); err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid quoted value: %s", file, n, err)
+			}
+		}
+		params = append(params, sdk.Parameter{
+			Name:  strings.TrimSpace(t[0]),
+			Type:  sdk.StringParameter,
+			Value: value,
+		})
+	}
+	return params, scanner.Err()
+}
+
+// mergeParameters returns params with values of vars, vars override params with the same name
+func mergeParameters(params, vars []sdk.Parameter) []sdk.Parameter {
+	for _, v := range vars {
+		found := false
+		for i := range params {
+			if params[i].Name == v.Name {
+				params[i].Value = v.Value
+				found = true
+			}
+		}
+		if !found {
+			params = append(params, v)
+		}
+	}
+	return params
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func TestReadVariablesFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "cds-variables")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString("# build\n\ncds.pip.version = 1.0\ngit.branch=feat=x\ncds.app.message=\"a\\nb\"\n")
+	f.Close()
+
+	vars, err := readVariablesFile(f.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, "1.0", sdk.ParameterValue(vars, "cds.pip.version"))
+	assert.Equal(t, "feat=x", sdk.ParameterValue(vars, "git.branch"))
+	assert.Equal(t, "a\nb", sdk.ParameterValue(vars, "cds.app.message"))
+
+	params := mergeParameters([]sdk.Parameter{{Name: "git.branch", Value: "master"}}, vars)
+	assert.Len(t, params, 3)
+	assert.Equal(t, "feat=x", sdk.ParameterValue(params, "git.branch"))
+
+	ioutil.WriteFile(f.Name(), []byte("novalue\n"), 0644)
+	_, err = readVariablesFile(f.Name())
+	assert.Error(t, err)
+}
+
+func TestLocalArtifacts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cds-local")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	localDir = filepath.Join(dir, "artifacts")
+	defer func() { localDir = "" }()
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "a.txt"), []byte("foo"), 0644))
+	assert.NoError(t, saveLocalArtifact("v1", filepath.Join(dir, "a.txt")))
+
+	names, err := restoreLocalArtifacts("v1", filepath.Join(dir, "out"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a.txt"}, names)
+	content, err := ioutil.ReadFile(filepath.Join(dir, "out", "a.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", string(content))
+
+	_, err = restoreLocalArtifacts("v2", filepath.Join(dir, "out"))
+	assert.Error(t, err)
+}
@@ -76,15 +76,25 @@ func addBuildVarHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// OK, so now we got our new variable. We need to:
-	// - add it as a build var in API
+	if err := addBuildVariable(v); err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+}
+
+// addBuildVariable adds v to variables of current build, in API and in current building Action,
+// or only in current building Action when worker runs without API
+func addBuildVariable(v sdk.Variable) error {
 	buildVariables = append(buildVariables, v)
-	// - add it in current building Action
-	data, err = json.Marshal(v)
+	if localDir != "" {
+		return nil
+	}
+
+	data, err := json.Marshal(v)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+		return err
 	}
+
 	// Retrieve build info
 	var proj, app, pip, bnS string
 	for _, p := range ab.Args {
@@ -109,8 +119,5 @@ func addBuildVarHandler(w http.ResponseWriter, r *http.Request) {
 	if err == nil && code > 300 {
 		err = fmt.Errorf("HTTP %d", code)
 	}
-	if err != nil {
-		w.WriteHeader(http.StatusServiceUnavailable)
-		return
-	}
+	return err
 }
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/sdk"
+)
+
+// keepOnFailure is how long worker waits for debug sessions after a failed step, disabled if 0
+var keepOnFailure time.Duration
+
+// debugged is set once worker waited for debug sessions during current action build,
+// worker then unregisters once action build is done
+var debugged bool
+
+// debugOnFailure keeps the working directory of a failed step and opens shells to users connected through API,
+// until a shell exits or keepOnFailure is over
+func debugOnFailure(actionBuild sdk.ActionBuild, step string) {
+	if keepOnFailure <= 0 || debugged || localDir != "" {
+		return
+	}
+	debugged = true
+
+	deadline := time.Now().Add(keepOnFailure)
+	command := fmt.Sprintf("cds pipeline debug %s %s %s",
+		sdk.ParameterValue(actionBuild.Args, "cds.project"),
+		sdk.ParameterValue(actionBuild.Args, "cds.application"),
+		sdk.ParameterValue(actionBuild.Args, "cds.pipeline"))
+	if env := sdk.ParameterValue(actionBuild.Args, "cds.environment"); env != "" && env != sdk.DefaultEnv.Name {
+		command += " " + env
+	}
+	command += fmt.Sprintf(" %s %d", sdk.ParameterValue(actionBuild.Args, "cds.buildNumber"), actionBuild.ID)
+	sendLog(actionBuild.ID, "DEBUG", fmt.Sprintf("%s: Step %s failed, waiting for a debug session until %s: %s\n", name, step, deadline.Format(time.RFC3339), command))
+
+	for time.Now().Before(deadline) {
+		ended, err := debugSession(actionBuild, step, deadline)
+		if ended {
+			sendLog(actionBuild.ID, "DEBUG", fmt.Sprintf("%s: Debug session ended\n", name))
+			return
+		}
+		log.Notice("debugOnFailure> Debug session interrupted: %s\n", err)
+		time.Sleep(5 * time.Second)
+	}
+	sendLog(actionBuild.ID, "DEBUG", fmt.Sprintf("%s: No debug session before %s\n", name, deadline.Format(time.RFC3339)))
+}
+
+// debugWriter sends shell output through websocket
+type debugWriter struct {
+	sync.Mutex
+	conn *websocket.Conn
+}
+
+func (w *debugWriter) Write(b []byte) (int, error) {
+	w.Lock()
+	defer w.Unlock()
+	if err := w.conn.WriteMessage(websocket.BinaryMessage, b); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// debugSession runs a shell whose input and output are relayed by API to the user debugging action build.
+// It returns true once shell exited or deadline is over, false if connection to API was lost before.
+func debugSession(actionBuild sdk.ActionBuild, step string, deadline time.Time) (bool, error) {
+	conn, err := sdk.DialWebsocket(fmt.Sprintf("/queue/%d/debug", actionBuild.ID))
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	shell, opts := "/bin/sh", []string{"-i"}
+	if runtime.GOOS == "windows" {
+		shell, opts = "PowerShell", []string{"-NoLogo", "-Command", "-"}
+	}
+	cmd := exec.Command(shell, opts...)
+	if cmd.Env, err = scriptEnv(); err != nil {
+		return false, err
+	}
+	out := &debugWriter{conn: conn}
+	cmd.Stdout = out
+	cmd.Stderr = out
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return false, err
+	}
+
+	wd, _ := os.Getwd()
+	fmt.Fprintf(out, "Debug session of %s on worker %s in %s\nStep %s failed, exit the shell to end the session, it ends at %s anyway\n",
+		actionBuild.ActionName, name, wd, step, deadline.Format(time.RFC3339))
+	if err := cmd.Start(); err != nil {
+		return false, err
+	}
+
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	lost := make(chan error, 1)
+	go func() {
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				lost <- err
+				return
+			}
+			if _, err := stdin.Write(data); err != nil {
+				lost <- err
+				return
+			}
+		}
+	}()
+
+	timer := time.NewTimer(deadline.Sub(time.Now()))
+	defer timer.Stop()
+	select {
+	case <-exited:
+	case err := <-lost:
+		cmd.Process.Kill()
+		return false, err
+	case <-timer.C:
+		fmt.Fprintf(out, "\nDebug session timed out\n")
+		cmd.Process.Kill()
+	}
+
+	out.Lock()
+	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	out.Unlock()
+	return true, nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ovh/cds/sdk"
+)
+
+// localDir is set when worker runs an action without API (worker exec),
+// artifacts and caches are then stored in this directory
+var localDir string
+
+// localLogger prints logs of steps on stdout until inputChan is closed
+func localLogger(inputChan chan sdk.Log) {
+	for l := range inputChan {
+		value := l.Value
+		if !strings.HasSuffix(value, "\n") {
+			value += "\n"
+		}
+		fmt.Printf("[%s] %s", l.Step, value)
+	}
+}
+
+func localArtifactsDir(tag string) string {
+	return filepath.Join(localDir, tag)
+}
+
+// localCachePath returns the archive of cache key in local directory
+func localCachePath(key string) string {
+	return filepath.Join(localDir, ".cache", key+".tar.gz")
+}
+
+// saveLocalArtifact copies file into the directory of tag
+func saveLocalArtifact(tag, file string) error {
+	dir := localArtifactsDir(tag)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return copyFile(file, filepath.Join(dir, filepath.Base(file)))
+}
+
+// restoreLocalArtifacts copies all files of the directory of tag into dest and returns their names
+func restoreLocalArtifacts(tag, dest string) ([]string, error) {
+	files, err := ioutil.ReadDir(localArtifactsDir(tag))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no artifact with tag %s in %s", tag, localDir)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if dest == "" {
+		dest = "."
+	}
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		if err := copyFile(filepath.Join(localArtifactsDir(tag), f.Name()), filepath.Join(dest, f.Name())); err != nil {
+			return names, err
+		}
+		names = append(names, f.Name())
+	}
+	return names, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	fi, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, fi.Mode())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
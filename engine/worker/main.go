@@ -13,7 +13,9 @@ import (
 
 	"github.com/ovh/cds/engine/api/worker"
 	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/engine/metrics"
 	"github.com/ovh/cds/sdk"
+	"github.com/ovh/cds/sdk/tracing"
 )
 
 var (
@@ -37,8 +39,13 @@ var (
 	gitssh         string
 	startTimestamp *time.Time
 	nbActionsDone  int
+	// singleUse workers exit after one action, set by --single-use or by their model
+	singleUse bool
 )
 
+var actionDuration = metrics.NewHistogram("cds_worker_action_duration_seconds",
+	"Duration of actions run by the worker by status", metrics.DurationBuckets, "status")
+
 var mainCmd = &cobra.Command{
 	Use:   "worker",
 	Short: "CDS Worker",
@@ -86,6 +93,16 @@ var mainCmd = &cobra.Command{
 		}
 
 		model = int64(viper.GetInt("model"))
+		singleUse = viper.GetBool("single_use")
+		keepOnFailure = time.Duration(viper.GetInt("keep_on_failure")) * time.Minute
+
+		if addr := viper.GetString("metrics_listen"); addr != "" {
+			metrics.Serve(addr)
+		}
+
+		if err := tracing.Initialize("cds-worker", viper.GetString("tracing_exporter")); err != nil {
+			sdk.Exit("cannot initialize tracing: %s\n", err)
+		}
 
 		port, err := server()
 		if err != nil {
@@ -111,6 +128,9 @@ func init() {
 	flags.String("log-level", "notice", "Log Level : debug, info, notice, warning, critical")
 	viper.BindPFlag("log_level", flags.Lookup("log-level"))
 
+	flags.String("log-format", "text", "Log Format : text or json")
+	viper.BindPFlag("log_format", flags.Lookup("log-format"))
+
 	flags.String("api", "", "URL of CDS API")
 	viper.BindPFlag("api", flags.Lookup("api"))
 
@@ -120,6 +140,9 @@ func init() {
 	flags.Bool("single-use", false, "Exit after executing an action")
 	viper.BindPFlag("single_use", flags.Lookup("single-use"))
 
+	flags.Int("keep-on-failure", 0, "Minutes worker waits for a debug session after a failed step before unregistering, disabled if 0. Set by hatcheries from worker model")
+	viper.BindPFlag("keep_on_failure", flags.Lookup("keep-on-failure"))
+
 	flags.String("name", "", "Name of worker")
 	viper.BindPFlag("name", flags.Lookup("name"))
 
@@ -135,8 +158,15 @@ func init() {
 	flags.Int("ttl", 12, "Worker time to live (hours)")
 	viper.BindPFlag("ttl", flags.Lookup("ttl"))
 
+	flags.String("metrics-listen", "", "Expose Prometheus metrics on /metrics of this address (ie: :8087), disabled if empty")
+	viper.BindPFlag("metrics_listen", flags.Lookup("metrics-listen"))
+
+	flags.String("tracing-exporter", "", "Export traces to an OpenTelemetry collector (http://collector:4318) or a file (file:///tmp/traces.json), disabled if empty")
+	viper.BindPFlag("tracing_exporter", flags.Lookup("tracing-exporter"))
+
 	mainCmd.AddCommand(cmdExport)
 	mainCmd.AddCommand(cmdUpload)
+	mainCmd.AddCommand(cmdExec)
 }
 
 func main() {
@@ -145,8 +175,19 @@ func main() {
 	mainCmd.Execute()
 }
 
-// Will be removed when websocket conn is implemented
-// for now, poll the /queue
+// matching is true while API matches action builds with capabilities declared by worker,
+// worker then takes them without checking the queue
+var matching = true
+
+// capabilitiesDeclared is the last time worker declared its capabilities
+var capabilitiesDeclared time.Time
+
+const capabilitiesInterval = 5 * time.Minute
+
+// capabilitiesMinInterval limits how often worker declares capabilities when API asks for them
+const capabilitiesMinInterval = 10 * time.Second
+
+// Falls back to polling the /queue when API does not match action builds
 func queuePolling() {
 	for {
 		if WorkerID == "" {
@@ -163,6 +204,11 @@ func queuePolling() {
 			os.Exit(0)
 		}
 
+		if matching && WorkerID != "" {
+			takeMatching()
+			continue
+		}
+
 		checkQueue()
 		time.Sleep(5 * time.Second)
 	}
@@ -176,9 +222,86 @@ func checkQueue() {
 		WorkerID = ""
 		return
 	}
+	runQueue(queue)
+}
+
+// declareCapabilities checks all requirements used by actions and declares the ones worker meets
+func declareCapabilities() error {
+	requirements, err := sdk.GetRequirements()
+	if err != nil {
+		return err
+	}
+
+	capas := sdk.WorkerCapabilities{Requirements: []sdk.Requirement{}, Unmet: []sdk.Requirement{}}
+	for _, r := range requirements {
+		if ok, _ := checkRequirement(r); ok {
+			capas.Requirements = append(capas.Requirements, r)
+		} else {
+			capas.Unmet = append(capas.Unmet, r)
+		}
+	}
+
+	if err := sdk.DeclareWorkerCapabilities(capas); err != nil {
+		return err
+	}
+	capabilitiesDeclared = time.Now()
+	log.Debug("declareCapabilities> %d capabilities declared\n", len(capas.Requirements))
+	return nil
+}
+
+// takeMatching waits for API to give an action build matching worker capabilities, then runs it
+func takeMatching() {
+	if time.Since(capabilitiesDeclared) > capabilitiesInterval {
+		err := declareCapabilities()
+		if err == sdk.ErrNotFound {
+			log.Notice("takeMatching> API does not match action builds, polling queue\n")
+			matching = false
+			return
+		}
+		if err != nil {
+			log.Notice("takeMatching> Cannot declare capabilities: %s\n", err)
+			time.Sleep(5 * time.Second)
+			return
+		}
+	}
+
+	data, code, err := sdk.Request("POST", "/queue/take?timeout=30", nil)
+	if e, ok := err.(sdk.Error); ok && e.ID == sdk.ErrWorkerCapabilitiesMissing.ID {
+		// Capabilities expired, or action builds have requirements declared after them
+		if time.Since(capabilitiesDeclared) < capabilitiesMinInterval {
+			time.Sleep(capabilitiesMinInterval - time.Since(capabilitiesDeclared))
+		}
+		capabilitiesDeclared = time.Time{}
+		return
+	}
+	if err != nil {
+		log.Notice("takeMatching> Cannot take action build: %s\n", err)
+		time.Sleep(5 * time.Second)
+		WorkerID = ""
+		return
+	}
+	if code == http.StatusNoContent {
+		return
+	}
+	if code != http.StatusOK {
+		log.Notice("takeMatching> Cannot take action build: HTTP %d\n", code)
+		time.Sleep(5 * time.Second)
+		return
+	}
+
+	abi := worker.ActionBuildInfo{}
+	if err := json.Unmarshal(data, &abi); err != nil {
+		log.Notice("takeMatching> Cannot unmarshal action: %s\n", err)
+		return
+	}
+	runActionBuild(abi)
+}
 
+// runQueue takes the first action build of queue whose requirements are met
+func runQueue(queue []sdk.ActionBuild) {
 	for i := range queue {
 		requirementsOK := true
+		checkStart := time.Now()
 		// Check requirement
 		for _, r := range queue[i].Requirements {
 			log.Notice("Checking requirements %s(%v)=%s for %s", r.Name, r.Type, r.Value, queue[i].ID)
@@ -195,6 +318,10 @@ func checkQueue() {
 		}
 
 		if requirementsOK {
+			// Only checks of the action taken are recorded, checks are done on each poll
+			span := tracing.ContinueAt("worker.checkRequirements", sdk.ParameterValue(queue[i].Args, tracing.Parameter), checkStart)
+			span.SetAttribute("cds.worker", name)
+			span.Finish()
 			takeAction(queue[i])
 		}
 	}
@@ -207,10 +334,6 @@ func postCheckRequirementError(r *sdk.Requirement, err error) {
 }
 
 func takeAction(b sdk.ActionBuild) {
-	nbActionsDone++
-
-	gitssh = ""
-	pkey = ""
 	path := fmt.Sprintf("/queue/%d/take", b.ID)
 	data, code, err := sdk.Request("POST", path, nil)
 	if err != nil {
@@ -227,22 +350,44 @@ func takeAction(b sdk.ActionBuild) {
 		log.Notice("takeAction> Cannot unmarshal action: %s\n", err)
 		return
 	}
+	runActionBuild(abi)
+}
+
+// runActionBuild runs an action build taken by worker and sends its result
+func runActionBuild(abi worker.ActionBuildInfo) {
+	b := abi.ActionBuild
+	gitssh = ""
+	pkey = ""
+
+	st := startStep("worker.takeAction", sdk.ParameterValue(b.Args, tracing.Parameter))
+	st.span.SetAttribute("cds.worker", name)
+	st.span.SetAttribute("cds.action_build", b.ID)
+	var res sdk.Result
+	defer func() {
+		st.end(res.Status)
+		tracing.Flush()
+	}()
+	defer correlate(b)()
+	nbActionsDone++
 
 	// Reset build variables
 	ab = abi.ActionBuild
 	buildVariables = nil
-	res := run(abi.Action, abi.ActionBuild, abi.Secrets)
+	debugged = false
+	start := time.Now()
+	res = run(abi.Action, abi.ActionBuild, abi.Secrets)
+	actionDuration.Since(start, res.Status.String())
 	// Give time to buffered logs to be sent
 	time.Sleep(3 * time.Second)
 
-	path = fmt.Sprintf("/queue/%d/result", b.ID)
+	path := fmt.Sprintf("/queue/%d/result", b.ID)
 	body, err := json.MarshalIndent(res, " ", " ")
 	if err != nil {
 		log.Notice("takeAction>Cannot marshal result: %s\n", err)
 		return
 	}
 
-	code = 300
+	code := 300
 	var isThereAnyHopeLeft = 50
 	for code >= 300 {
 		_, code, err = sdk.Request("POST", path, body)
@@ -271,21 +416,51 @@ func takeAction(b sdk.ActionBuild) {
 		}
 	}
 
-	if viper.GetBool("single_use") {
+	// Worker environment may have been changed by debug sessions
+	if singleUse || debugged {
 		// Give time to logs to be flushed
 		time.Sleep(2 * time.Second)
-		// Unregister from engine
+		// Unregister from engine, which asks the hatchery to destroy this worker
 		err := unregister()
 		if err != nil {
 			log.Warning("takeAction> could not unregister: %s\n", err)
 		}
 		// then exit
-		log.Notice("takeAction> --single_use is on, exiting\n")
+		log.Notice("takeAction> worker is single use or was debugged, exiting\n")
+		st.end(res.Status)
+		tracing.Flush()
 		os.Exit(0)
 	}
 
 }
 
+// correlate adds fields of action build b to log lines and sends its request ID with API calls, until returned func is called
+func correlate(b sdk.ActionBuild) func() {
+	fields := log.Fields{
+		log.FieldActionBuildID:   b.ID,
+		log.FieldPipelineBuildID: b.PipelineBuildID,
+		log.FieldProject:         sdk.ParameterValue(b.Args, "cds.project"),
+		log.FieldApplication:     sdk.ParameterValue(b.Args, "cds.application"),
+		log.FieldPipeline:        sdk.ParameterValue(b.Args, "cds.pipeline"),
+		log.FieldRequestID:       sdk.ParameterValue(b.Args, sdk.RequestIDParameter),
+	}
+	for k, v := range fields {
+		if v == "" {
+			delete(fields, k)
+			continue
+		}
+		log.SetField(k, v)
+	}
+	sdk.SetRequestID(sdk.ParameterValue(b.Args, sdk.RequestIDParameter))
+
+	return func() {
+		for k := range fields {
+			log.DeleteField(k)
+		}
+		sdk.SetRequestID("")
+	}
+}
+
 func heartbeat() {
 	for {
 		time.Sleep(10 * time.Second)
@@ -58,6 +58,12 @@ func register(cdsURI string, name string, uk string) error {
 	var w sdk.Worker
 	json.Unmarshal(data, &w)
 	WorkerID = w.ID
+	// Capabilities are declared for a worker ID
+	capabilitiesDeclared = time.Time{}
+	log.SetField(log.FieldWorkerID, w.ID)
+	if w.SingleUse {
+		singleUse = true
+	}
 	sdk.Authorization(w.ID)
 	log.Notice("Registered: %s\n", data)
 	return nil
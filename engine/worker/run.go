@@ -89,8 +89,13 @@ func replaceBuildVariablesPlaceholder(a *sdk.Action) {
 	}
 }
 
-func runAction(a *sdk.Action, actionBuild sdk.ActionBuild) sdk.Result {
-	r := sdk.Result{
+func runAction(a *sdk.Action, actionBuild sdk.ActionBuild) (r sdk.Result) {
+	st := startStep("worker.runAction", "")
+	st.span.SetAttribute("cds.action", a.Name)
+	st.span.SetAttribute("cds.action_type", a.Type)
+	defer func() { st.end(r.Status) }()
+
+	r = sdk.Result{
 		Status:  sdk.StatusFail,
 		BuildID: actionBuild.ID,
 	}
@@ -134,6 +139,7 @@ func runAction(a *sdk.Action, actionBuild sdk.ActionBuild) sdk.Result {
 				sendLog(actionBuild.ID, childName, fmt.Sprintf("%s: Step %s finished (status: %s)\n", name, childName, r.Status))
 				if r.Status != sdk.StatusSuccess {
 					log.Printf("Stopping %s at step %s", a.Name, childName)
+					debugOnFailure(actionBuild, childName)
 					doNotRunChildrenAnymore = true
 				}
 			}
@@ -156,6 +162,7 @@ func runAction(a *sdk.Action, actionBuild sdk.ActionBuild) sdk.Result {
 		}
 		if finalActionResult.Status != sdk.StatusSuccess {
 			log.Printf("Stoping %s at final step %s", a.Name, childName)
+			debugOnFailure(actionBuild, childName)
 			return r
 		}
 		sendLog(actionBuild.ID, childName, fmt.Sprintf("%s: Final step %s finished (status: %s)\n", name, childName, finalActionResult.Status))
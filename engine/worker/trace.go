@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/ovh/cds/sdk"
+	"github.com/ovh/cds/sdk/tracing"
+)
+
+// step is an operation of the worker recorded in the trace of the pipeline build
+type step struct {
+	span     *tracing.Span
+	previous *tracing.Span
+}
+
+// startStep starts a span child of the current one, or of parent traceparent if given,
+// and makes it current so requests to the API are recorded in it
+func startStep(name, parent string) step {
+	if parent == "" {
+		parent = tracing.Current().TraceParent()
+	}
+	s := tracing.Continue(name, parent)
+	if s == nil {
+		return step{}
+	}
+	return step{span: s, previous: tracing.SetCurrent(s)}
+}
+
+// end ends the step with its status and restores the previous current span
+func (s step) end(status sdk.Status) {
+	if s.span == nil {
+		return
+	}
+	s.span.SetAttribute("cds.status", status)
+	if status == sdk.StatusFail {
+		s.span.SetError(fmt.Errorf("%s", status))
+	}
+	s.span.Finish()
+	tracing.SetCurrent(s.previous)
+}
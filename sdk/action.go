@@ -67,9 +67,11 @@ const (
 
 // Builtin Action
 const (
-	ScriptAction = "Script"
-	NotifAction  = "Notif"
-	JUnitAction  = "JUnit"
+	ScriptAction      = "Script"
+	NotifAction       = "Notif"
+	JUnitAction       = "JUnit"
+	GitCloneAction    = "GitClone"
+	DockerBuildAction = "DockerBuild"
 )
 
 const (
@@ -23,6 +23,8 @@ type ActionScript struct {
 		Final            bool                         `json:"final"`
 		ArtifactUpload   map[string]string            `json:"artifactUpload,omitempty"`
 		ArtifactDownload map[string]string            `json:"artifactDownload,omitempty"`
+		CacheSave        map[string]string            `json:"cacheSave,omitempty"`
+		CacheRestore     map[string]string            `json:"cacheRestore,omitempty"`
 		Script           string                       `json:"script,omitempty"`
 		JUnitReport      string                       `json:"jUnitReport,omitempty"`
 		Plugin           map[string]map[string]string `json:"plugin,omitempty"`
@@ -138,6 +140,18 @@ func NewActionFromScript(btes []byte) (*Action, error) {
 			goto next
 		}
 
+		//Action builtin = CacheSave
+		if v.CacheSave != nil {
+			newAction = NewActionCacheSave(v.CacheSave["key"], v.CacheSave["path"])
+			goto next
+		}
+
+		//Action builtin = CacheRestore
+		if v.CacheRestore != nil {
+			newAction = NewActionCacheRestore(v.CacheRestore["key"])
+			goto next
+		}
+
 		//Action builtin = Plugin
 		if v.Plugin != nil {
 			for k, v := range v.Plugin {
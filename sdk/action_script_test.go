@@ -149,6 +149,35 @@ steps  = [{
 	assert.Equal(t, "{{.cds.version}}", a.Actions[0].Parameters[1].Value)
 }
 
+func TestTestLoadFromActionScriptWithCache(t *testing.T) {
+	b := []byte(`
+steps  = [{
+	cacheRestore = {
+        key = "gomod-{{checksum \"go.sum\"}}"
+    }
+}, {
+	final = true
+	cacheSave = {
+        key = "gomod-{{checksum \"go.sum\"}}"
+        path = "go/pkg/mod"
+    }
+}]`)
+
+	a, err := NewActionFromScript(b)
+	assert.NotNil(t, a)
+	assert.NoError(t, err)
+	t.Logf("Action : %v", a)
+
+	assert.Equal(t, CacheRestore, a.Actions[0].Name)
+	assert.Equal(t, BuiltinAction, a.Actions[0].Type)
+	assert.Equal(t, "key", a.Actions[0].Parameters[0].Name)
+	assert.Equal(t, `gomod-{{checksum "go.sum"}}`, a.Actions[0].Parameters[0].Value)
+	assert.Equal(t, CacheSave, a.Actions[1].Name)
+	assert.Equal(t, true, a.Actions[1].Final)
+	assert.Equal(t, "path", a.Actions[1].Parameters[1].Name)
+	assert.Equal(t, "go/pkg/mod", a.Actions[1].Parameters[1].Value)
+}
+
 func TestTestLoadFromActionScriptWithPlugin(t *testing.T) {
 	b := []byte(`
 steps  = [{
@@ -1,13 +1,12 @@
 package sdk
 
 import (
-	"bytes"
 	"crypto/md5"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
-	"mime/multipart"
 	"net/http"
 	"net/url"
 	"os"
@@ -33,7 +32,44 @@ type Artifact struct {
 	Size         int64  `json:"size,omitempty"`
 	Perm         uint32 `json:"perm,omitempty"`
 	MD5sum       string `json:"md5sum,omitempty"`
+	SHA256       string `json:"sha256,omitempty"`
 	ObjectPath   string `json:"object_path,omitempty"`
+
+	// Promoted artifacts are released, they are kept by retention cleanup
+	Promoted   bool       `json:"promoted,omitempty"`
+	PromotedAt *time.Time `json:"promoted_at,omitempty"`
+	PromotedBy string     `json:"promoted_by,omitempty"`
+}
+
+// ArtifactSelector selects the build of a pipeline whose artifacts are fetched.
+// The last build with artifacts matching all set criteria is selected.
+type ArtifactSelector struct {
+	// Version of the build
+	Version int64
+	// Branch of the build
+	Branch string
+	// LastSuccess selects successful builds only
+	LastSuccess bool
+	// Promoted selects builds with promoted artifacts only
+	Promoted bool
+}
+
+// Values returns selector as query parameters
+func (s ArtifactSelector) Values() url.Values {
+	v := url.Values{}
+	if s.Version > 0 {
+		v.Set("version", strconv.FormatInt(s.Version, 10))
+	}
+	if s.Branch != "" {
+		v.Set("branch", s.Branch)
+	}
+	if s.LastSuccess {
+		v.Set("status", string(StatusSuccess))
+	}
+	if s.Promoted {
+		v.Set("promoted", "true")
+	}
+	return v
 }
 
 //GetName returns the name the artifact
@@ -55,8 +91,83 @@ const (
 // Header name for artifact upload
 const (
 	ArtifactFileName = "ARTIFACT-FILENAME"
+	// ArtifactSHA256Header holds the SHA-256 checksum of an uploaded chunk or a downloaded artifact
+	ArtifactSHA256Header = "ARTIFACT-SHA256"
+)
+
+const (
+	// ArtifactChunkSize is the size of chunks sent by UploadArtifact
+	ArtifactChunkSize int64 = 8 * 1024 * 1024
+	// MaxArtifactChunkSize is the maximum size of chunks accepted by API
+	MaxArtifactChunkSize int64 = 64 * 1024 * 1024
+	artifactRetries            = 5
 )
 
+// ArtifactUploadSession is a chunked upload of an artifact in progress
+type ArtifactUploadSession struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Tag         string    `json:"tag"`
+	Environment string    `json:"environment"`
+	BuildNumber int       `json:"build_number"`
+	Size        int64     `json:"size"`
+	Perm        uint32    `json:"perm,omitempty"`
+	SHA256      string    `json:"sha256"`
+	ChunkSize   int64     `json:"chunk_size"`
+	Received    []int     `json:"received"`
+	Created     time.Time `json:"created"`
+}
+
+// Chunks returns the number of chunks of the artifact
+func (u *ArtifactUploadSession) Chunks() int {
+	if u.ChunkSize <= 0 {
+		return 0
+	}
+	return int((u.Size + u.ChunkSize - 1) / u.ChunkSize)
+}
+
+// Chunk returns offset and size of chunk i in the artifact
+func (u *ArtifactUploadSession) Chunk(i int) (int64, int64) {
+	offset := int64(i) * u.ChunkSize
+	size := u.ChunkSize
+	if offset+size > u.Size {
+		size = u.Size - offset
+	}
+	return offset, size
+}
+
+// Missing returns indexes of chunks not received yet
+func (u *ArtifactUploadSession) Missing() []int {
+	received := make(map[int]bool, len(u.Received))
+	for _, i := range u.Received {
+		received[i] = true
+	}
+
+	missing := []int{}
+	for i := 0; i < u.Chunks(); i++ {
+		if !received[i] {
+			missing = append(missing, i)
+		}
+	}
+	return missing
+}
+
+// ArtifactChunk is a chunk of an artifact upload, stored until the upload completes
+type ArtifactChunk struct {
+	UploadID string
+	Index    int
+}
+
+//GetName returns the name of the chunk
+func (c *ArtifactChunk) GetName() string {
+	return fmt.Sprintf("%s-%d", c.UploadID, c.Index)
+}
+
+//GetPath returns the path of the chunk
+func (c *ArtifactChunk) GetPath() string {
+	return "artifact-uploads"
+}
+
 // DownloadArtifacts retrieves and download artifacts related to given project-pipeline-tag
 // and download them into destdir
 func DownloadArtifacts(project string, application string, pipeline string, tag string, destdir string, env string) error {
@@ -77,44 +188,113 @@ func DownloadArtifacts(project string, application string, pipeline string, tag
 }
 
 func download(project, app, pip string, a Artifact, destdir string) error {
+	uri := fmt.Sprintf("/project/%s/application/%s/pipeline/%s/artifact/download/%d", project, app, pip, a.ID)
+	destPath := path.Join(destdir, a.Name)
+	// Artifact is downloaded next to its destination then renamed once verified,
+	// so an interrupted download resumes where it stopped
+	partPath := destPath + ".part"
+
+	mode := os.FileMode(0644)
+	if a.Perm != uint32(0) {
+		mode = os.FileMode(a.Perm)
+	}
+
 	var lasterr error
+	for retry := 0; retry < artifactRetries; retry++ {
+		if retry > 0 {
+			time.Sleep(artifactRetryDelay(retry))
+		}
 
-	for retry := 5; retry >= 0; retry-- {
-		uri := fmt.Sprintf("/project/%s/application/%s/pipeline/%s/artifact/download/%d", project, app, pip, a.ID)
-		reader, code, err := Stream("GET", uri, nil)
-		if err != nil {
-			lasterr = err
+		if lasterr = downloadPart(uri, partPath, mode); lasterr != nil {
 			continue
 		}
-		if code >= 300 {
-			lasterr = fmt.Errorf("HTTP %d", code)
+
+		if lasterr = checkArtifactFile(partPath, a); lasterr != nil {
+			// Start again from scratch
+			os.Remove(partPath)
 			continue
 		}
-		destPath := path.Join(destdir, a.Name)
 
-		mode := os.FileMode(0644)
-		if a.Perm != uint32(0) {
-			mode = os.FileMode(a.Perm)
+		if err := os.Chmod(partPath, mode); err != nil {
+			return err
 		}
+		return os.Rename(partPath, destPath)
+	}
 
-		f, err := os.OpenFile(destPath, os.O_CREATE|os.O_WRONLY, mode)
-		if err != nil {
-			lasterr = err
-			continue
-		}
+	return fmt.Errorf("x%d: %s", artifactRetries, lasterr)
+}
 
-		_, err = io.Copy(f, reader)
-		if err != nil {
-			lasterr = err
-		}
+// downloadPart appends to file the content of the artifact it lacks
+func downloadPart(uri, file string, mode os.FileMode) error {
+	var offset int64
+	if fi, err := os.Stat(file); err == nil {
+		offset = fi.Size()
+	}
+
+	var mods []RequestModifier
+	if offset > 0 {
+		mods = append(mods, SetHeader("Range", fmt.Sprintf("bytes=%d-", offset)))
+	}
+
+	reader, code, err := Stream("GET", uri, nil, mods...)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	flag := os.O_CREATE | os.O_WRONLY
+	switch code {
+	case http.StatusOK:
+		// Range is ignored, the whole file is sent
+		flag |= os.O_TRUNC
+	case http.StatusPartialContent:
+		flag |= os.O_APPEND
+	case http.StatusRequestedRangeNotSatisfiable:
+		// Nothing left to download
+		return nil
+	default:
+		return fmt.Errorf("HTTP %d", code)
+	}
+
+	f, err := os.OpenFile(file, flag, mode)
+	if err != nil {
+		return err
+	}
 
+	if _, err := io.Copy(f, reader); err != nil {
 		f.Close()
-		if err == nil {
-			return nil
-		}
+		return err
+	}
+	return f.Close()
+}
+
+// checkArtifactFile verifies size and checksum of a downloaded artifact.
+// Artifacts uploaded before SHA-256 checksums were computed are checked with MD5.
+func checkArtifactFile(file string, a Artifact) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sha := sha256.New()
+	md := md5.New()
+	n, err := io.Copy(io.MultiWriter(sha, md), f)
+	if err != nil {
+		return err
+	}
+	if a.Size > 0 && n != a.Size {
+		return fmt.Errorf("%s: size is %d, expected %d", a.Name, n, a.Size)
 	}
 
-	return fmt.Errorf("x5: %s", lasterr)
+	sum, expected := hex.EncodeToString(sha.Sum(nil)), a.SHA256
+	if expected == "" {
+		sum, expected = hex.EncodeToString(md.Sum(nil)), a.MD5sum
+	}
+	if expected != "" && sum != expected {
+		return fmt.Errorf("%s: checksum is %s, expected %s", a.Name, sum, expected)
+	}
+	return nil
 }
 
 // DownloadArtifact downloads a single artifact from API
@@ -136,6 +316,64 @@ func DownloadArtifact(project, app, pip, tag, destdir, env, filename string) err
 	return fmt.Errorf("artifact not found")
 }
 
+// DownloadArtifactsFrom downloads into destdir artifacts of the build of a pipeline
+// selected by version, branch, status or promotion
+func DownloadArtifactsFrom(project, application, pipeline, env string, s ArtifactSelector, destdir string) ([]Artifact, error) {
+	arts, err := ListArtifactsFrom(project, application, pipeline, env, s)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range arts {
+		if err := download(project, application, pipeline, a, destdir); err != nil {
+			return nil, err
+		}
+	}
+	return arts, nil
+}
+
+// ListArtifactsFrom retrieves artifacts of the build of a pipeline selected by version, branch, status or promotion
+func ListArtifactsFrom(project, application, pipeline, env string, s ArtifactSelector) ([]Artifact, error) {
+	v := s.Values()
+	v.Set("envName", env)
+	uri := fmt.Sprintf("/project/%s/application/%s/pipeline/%s/artifact?%s", project, application, pipeline, v.Encode())
+	data, code, err := Request("GET", uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	if code >= 300 {
+		return nil, fmt.Errorf("cds: cannot list artifacts in %s-%s-%s-%s: HTTP %d", project, application, env, pipeline, code)
+	}
+
+	var arts []Artifact
+	if err := json.Unmarshal(data, &arts); err != nil {
+		return nil, err
+	}
+	return arts, nil
+}
+
+// PromoteArtifacts marks artifacts of a build as released, protecting them from retention cleanup
+func PromoteArtifacts(project, application, pipeline, env string, buildNumber int) error {
+	return promoteArtifacts("POST", project, application, pipeline, env, buildNumber)
+}
+
+// UnpromoteArtifacts removes release mark of artifacts of a build
+func UnpromoteArtifacts(project, application, pipeline, env string, buildNumber int) error {
+	return promoteArtifacts("DELETE", project, application, pipeline, env, buildNumber)
+}
+
+func promoteArtifacts(method, project, application, pipeline, env string, buildNumber int) error {
+	uri := fmt.Sprintf("/project/%s/application/%s/pipeline/%s/build/%d/promote?envName=%s", project, application, pipeline, buildNumber, url.QueryEscape(env))
+	_, code, err := Request(method, uri, nil)
+	if err != nil {
+		return err
+	}
+	if code >= 300 {
+		return fmt.Errorf("HTTP %d", code)
+	}
+	return nil
+}
+
 // ListArtifacts retrieves the list of file stored as artifacts for given project-pipeline-tag
 func ListArtifacts(project string, application string, pipeline string, tag string, env string) ([]Artifact, error) {
 	tag = strings.Replace(tag, "/", "-", -1)
@@ -163,80 +401,124 @@ func ListArtifacts(project string, application string, pipeline string, tag stri
 	return arts, nil
 }
 
-// UploadArtifact read file at filePath and upload it in projet-pipeline-tag starage directory
+// UploadArtifact read file at filePath and upload it in projet-pipeline-tag starage directory.
+// The file is sent in chunks, an interrupted upload resumes with the chunks not received yet.
 func UploadArtifact(project string, pipeline string, application string, tag string, filePath string, buildNumber int, env string) error {
 
 	tag = url.QueryEscape(tag)
 	tag = strings.Replace(tag, "/", "-", -1)
 
-	var err error
-	for i := 0; i < 5; i++ {
-		err = uploadArtifact(project, pipeline, application, tag, filePath, buildNumber, env)
-		if err == nil {
-			return nil
-		}
-		time.Sleep(1 * time.Second)
-	}
-
-	return fmt.Errorf("x5: %s", err)
-}
-
-func uploadArtifact(project string, pipeline string, application string, tag string, filePath string, buildNumber int, env string) error {
-	uri := fmt.Sprintf("/project/%s/application/%s/pipeline/%s/%d/artifact/%s", project, application, pipeline, buildNumber, tag)
-
 	file, err := os.Open(filePath)
 	if err != nil {
 		return err
 	}
+	defer file.Close()
 
-	//File stat
 	stat, err := file.Stat()
 	if err != nil {
 		return err
 	}
 
-	//Compute md5sum
-	hash := md5.New()
-	if _, errcopy := io.Copy(hash, file); errcopy != nil {
-		return errcopy
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return err
 	}
-	hashInBytes := hash.Sum(nil)[:16]
-	md5sumStr := hex.EncodeToString(hashInBytes)
-	file.Close()
 
-	//Reopen the file because we already read it for md5
-	file, err = os.Open(filePath)
+	u := ArtifactUploadSession{
+		Name:        filepath.Base(filePath),
+		Tag:         tag,
+		Environment: env,
+		BuildNumber: buildNumber,
+		Size:        stat.Size(),
+		Perm:        uint32(stat.Mode().Perm()),
+		SHA256:      hex.EncodeToString(h.Sum(nil)),
+		ChunkSize:   ArtifactChunkSize,
+	}
+	uri := fmt.Sprintf("/project/%s/application/%s/pipeline/%s", project, application, pipeline)
+
+	for i := 0; i < artifactRetries; i++ {
+		if i > 0 {
+			time.Sleep(artifactRetryDelay(i))
+		}
+		err = uploadArtifact(uri, file, u)
+		if err == nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("x%d: %s", artifactRetries, err)
+}
+
+func uploadArtifact(uri string, file *os.File, u ArtifactUploadSession) error {
+	// Start the upload, or resume it if API already received some chunks
+	data, err := json.Marshal(u)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
-	_, name := filepath.Split(filePath)
-
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-	part, err := writer.CreateFormFile(name, filepath.Base(filePath))
+	data, code, err := Request("POST", fmt.Sprintf("%s/%d/artifact/%s/upload", uri, u.BuildNumber, u.Tag), data)
 	if err != nil {
 		return err
 	}
-	_, err = io.Copy(part, file)
-
-	writer.WriteField("env", env)
-	writer.WriteField("size", strconv.FormatInt(stat.Size(), 10))
-	writer.WriteField("perm", strconv.FormatUint(uint64(stat.Mode().Perm()), 10))
-	writer.WriteField("md5sum", md5sumStr)
+	if code >= 300 {
+		return fmt.Errorf("HTTP %d", code)
+	}
+	if err := json.Unmarshal(data, &u); err != nil {
+		return err
+	}
 
-	if errclose := writer.Close(); errclose != nil {
-		return errclose
+	for _, i := range u.Missing() {
+		if err := uploadArtifactChunk(uri, file, u, i); err != nil {
+			return err
+		}
 	}
 
-	_, code, err := UploadMultiPart("POST", uri, body, SetHeader(ArtifactFileName, name), SetHeader("Content-Type", writer.FormDataContentType()))
+	_, code, err = Request("POST", fmt.Sprintf("%s/artifact/upload/%s", uri, u.ID), nil)
 	if err != nil {
 		return err
 	}
-
 	if code >= 300 {
-		return fmt.Errorf("HTTP Error %d\n", code)
+		return fmt.Errorf("HTTP %d", code)
 	}
-
 	return nil
 }
+
+// uploadArtifactChunk sends the chunk i of file, retrying on failure
+func uploadArtifactChunk(uri string, file *os.File, u ArtifactUploadSession, i int) error {
+	offset, size := u.Chunk(i)
+	chunk := make([]byte, size)
+	if _, err := file.ReadAt(chunk, offset); err != nil {
+		return err
+	}
+	sum := sha256.Sum256(chunk)
+
+	var err error
+	for retry := 0; retry < artifactRetries; retry++ {
+		if retry > 0 {
+			time.Sleep(artifactRetryDelay(retry))
+		}
+
+		var code int
+		_, code, err = Request("POST", fmt.Sprintf("%s/artifact/upload/%s/%d", uri, u.ID, i), chunk,
+			SetHeader(ArtifactSHA256Header, hex.EncodeToString(sum[:])), SetHeader("Content-Type", "application/octet-stream"))
+		if e, ok := err.(Error); ok && e.ID == ErrArtifactUploadNotFound.ID {
+			// Upload expired, it has to start again
+			return err
+		}
+		if err == nil && code >= 300 {
+			err = fmt.Errorf("HTTP %d", code)
+		}
+		if err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("chunk %d: %s", i, err)
+}
+
+// artifactRetryDelay returns how long to wait before the given retry of an artifact transfer
+func artifactRetryDelay(retry int) time.Duration {
+	d := time.Duration(1<<uint(retry-1)) * time.Second
+	if d > 30*time.Second {
+		d = 30 * time.Second
+	}
+	return d
+}
@@ -0,0 +1,55 @@
+package sdk
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArtifactUploadSessionChunks(t *testing.T) {
+	u := ArtifactUploadSession{Size: 25, ChunkSize: 10}
+	assert.Equal(t, 3, u.Chunks())
+
+	offset, size := u.Chunk(2)
+	assert.Equal(t, int64(20), offset)
+	assert.Equal(t, int64(5), size)
+
+	u.Received = []int{0, 2}
+	assert.Equal(t, []int{1}, u.Missing())
+
+	empty := ArtifactUploadSession{Size: 0, ChunkSize: 10}
+	assert.Equal(t, 0, empty.Chunks())
+	assert.Empty(t, empty.Missing())
+}
+
+func TestCheckArtifactFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "artifact")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("hello")
+	f.Close()
+
+	a := Artifact{Name: "hello", Size: 5, SHA256: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"}
+	assert.NoError(t, checkArtifactFile(f.Name(), a))
+
+	// Artifacts uploaded without SHA-256 are checked with MD5
+	a = Artifact{Name: "hello", MD5sum: "5d41402abc4b2a76b9719d911017c592"}
+	assert.NoError(t, checkArtifactFile(f.Name(), a))
+
+	a = Artifact{Name: "hello", Size: 6, SHA256: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"}
+	assert.Error(t, checkArtifactFile(f.Name(), a))
+
+	a = Artifact{Name: "hello", SHA256: "0000000000000000000000000000000000000000000000000000000000000000"}
+	assert.Error(t, checkArtifactFile(f.Name(), a))
+}
+
+func TestArtifactSelectorValues(t *testing.T) {
+	assert.Equal(t, "", ArtifactSelector{}.Values().Encode())
+
+	s := ArtifactSelector{Version: 12, Branch: "feat/a", LastSuccess: true, Promoted: true}
+	assert.Equal(t, "branch=feat%2Fa&promoted=true&status=Success&version=12", s.Values().Encode())
+}
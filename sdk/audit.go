@@ -0,0 +1,87 @@
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// AuditEvent records a mutating call handled by CDS API
+type AuditEvent struct {
+	ID         int64       `json:"id"`
+	Date       time.Time   `json:"date"`
+	Method     string      `json:"method"`
+	Path       string      `json:"path"`
+	Route      string      `json:"route"`
+	ProjectKey string      `json:"project_key,omitempty"`
+	Username   string      `json:"username,omitempty"`
+	Worker     string      `json:"worker,omitempty"`
+	IP         string      `json:"ip"`
+	Status     int         `json:"status"`
+	Before     string      `json:"before,omitempty"`
+	After      string      `json:"after,omitempty"`
+	Diff       []AuditDiff `json:"diff,omitempty"`
+}
+
+// AuditDiff is a field which changed between before and after states of a resource
+type AuditDiff struct {
+	Field  string `json:"field"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// AuditFilter restricts audit events returned by API
+type AuditFilter struct {
+	ProjectKey string
+	Username   string
+	Since      time.Time
+	Until      time.Time
+	Limit      int
+}
+
+// Values encodes filter as query parameters
+func (f AuditFilter) Values() url.Values {
+	v := url.Values{}
+	if f.ProjectKey != "" {
+		v.Set("project", f.ProjectKey)
+	}
+	if f.Username != "" {
+		v.Set("user", f.Username)
+	}
+	if !f.Since.IsZero() {
+		v.Set("since", f.Since.Format(time.RFC3339))
+	}
+	if !f.Until.IsZero() {
+		v.Set("until", f.Until.Format(time.RFC3339))
+	}
+	if f.Limit > 0 {
+		v.Set("limit", fmt.Sprintf("%d", f.Limit))
+	}
+	return v
+}
+
+// ListAuditEvents returns audit events matching filter
+func ListAuditEvents(f AuditFilter) ([]AuditEvent, error) {
+	uri := "/audit"
+	if f.ProjectKey != "" {
+		uri = fmt.Sprintf("/project/%s/audit", f.ProjectKey)
+	}
+
+	data, code, err := Request("GET", uri+"?"+f.Values().Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if code != http.StatusOK {
+		return nil, fmt.Errorf("Error [%d]: %s", code, data)
+	}
+
+	var events []AuditEvent
+	if err := json.Unmarshal(data, &events); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
@@ -23,6 +23,8 @@ type ActionBuild struct {
 	Done             time.Time     `json:"done,omitempty"`
 	Logs             string        `json:"logs,omitempty"`
 	Model            string        `json:"model,omitempty"`
+	GroupID          int64         `json:"group_id,omitempty"`
+	Priority         int           `json:"priority"`
 }
 
 // BuildState define struct returned when looking for build state informations
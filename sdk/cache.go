@@ -0,0 +1,234 @@
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Builtin cache manipulation actions
+const (
+	CacheSave    = "Cache Save"
+	CacheRestore = "Cache Restore"
+)
+
+// Header name for cache upload
+const (
+	CacheMD5Header = "CACHE-MD5SUM"
+)
+
+// Cache is an archive of build dependencies shared between builds of a project
+type Cache struct {
+	ID         int64     `json:"id"`
+	Project    string    `json:"project"`
+	Key        string    `json:"key"`
+	Size       int64     `json:"size"`
+	MD5sum     string    `json:"md5sum,omitempty"`
+	ObjectPath string    `json:"object_path,omitempty"`
+	Created    time.Time `json:"created"`
+	LastUsed   time.Time `json:"last_used"`
+	// Upload distinguishes a new upload of the cache, so it never overwrites the stored archive
+	Upload string `json:"-"`
+}
+
+//GetName returns the name of the cache archive: a new one for an upload, the stored one otherwise
+func (c *Cache) GetName() string {
+	if c.Upload != "" {
+		return c.Key + "." + c.Upload + ".tar.gz"
+	}
+	if c.ObjectPath != "" {
+		return path.Base(c.ObjectPath)
+	}
+	return c.Key + ".tar.gz"
+}
+
+//GetPath returns the storage path of the cache archive
+func (c *Cache) GetPath() string {
+	return fmt.Sprintf("cache/%s", c.Project)
+}
+
+// ProjectCaches lists caches of a project with their total size and the limit
+// above which least recently used caches are evicted
+type ProjectCaches struct {
+	Size    int64   `json:"size"`
+	MaxSize int64   `json:"max_size"`
+	Caches  []Cache `json:"caches"`
+}
+
+// CacheLimit is the maximum size of caches of a project, in bytes
+type CacheLimit struct {
+	MaxSize int64 `json:"max_size"`
+}
+
+var cacheKeyForbiddenChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// CacheKey makes given key usable in urls and object store paths
+func CacheKey(key string) string {
+	return cacheKeyForbiddenChars.ReplaceAllString(strings.TrimSpace(key), "-")
+}
+
+// GetCache returns cache of a project stored with given key
+func GetCache(project, key string) (*Cache, error) {
+	uri := fmt.Sprintf("/project/%s/cache/%s", project, CacheKey(key))
+	data, code, err := Request("GET", uri, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if code == http.StatusNotFound {
+		return nil, ErrCacheNotFound
+	}
+	if code != http.StatusOK {
+		return nil, fmt.Errorf("Error [%d]: %s", code, data)
+	}
+
+	var c Cache
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// DownloadCache streams archive of given cache, caller must close it
+func DownloadCache(project, key string) (io.ReadCloser, error) {
+	uri := fmt.Sprintf("/project/%s/cache/%s/download", project, CacheKey(key))
+	reader, code, err := Stream("GET", uri, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if code == http.StatusNotFound {
+		reader.Close()
+		return nil, ErrCacheNotFound
+	}
+	if code >= 300 {
+		reader.Close()
+		return nil, fmt.Errorf("HTTP %d", code)
+	}
+	return reader, nil
+}
+
+// UploadCache stores archive at filePath as cache of a project with given key
+func UploadCache(project, key, filePath, md5sum string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	setLength := func(req *http.Request) {
+		req.ContentLength = stat.Size()
+	}
+
+	uri := fmt.Sprintf("/project/%s/cache/%s", project, CacheKey(key))
+	data, code, err := Upload("POST", uri, file, setLength, SetHeader("Content-Type", "application/octet-stream"), SetHeader(CacheMD5Header, md5sum))
+	if err != nil {
+		return err
+	}
+
+	if code >= 300 {
+		if e := DecodeError(data); e != nil {
+			return e
+		}
+		return fmt.Errorf("HTTP Error %d", code)
+	}
+	return nil
+}
+
+// ListCaches returns caches of a project
+func ListCaches(project string) (*ProjectCaches, error) {
+	uri := fmt.Sprintf("/project/%s/cache", project)
+	data, code, err := Request("GET", uri, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if code != http.StatusOK {
+		return nil, fmt.Errorf("Error [%d]: %s", code, data)
+	}
+
+	var pc ProjectCaches
+	if err := json.Unmarshal(data, &pc); err != nil {
+		return nil, err
+	}
+	return &pc, nil
+}
+
+// DeleteCache removes cache of a project stored with given key
+func DeleteCache(project, key string) error {
+	uri := fmt.Sprintf("/project/%s/cache/%s", project, CacheKey(key))
+	data, code, err := Request("DELETE", uri, nil)
+	if err != nil {
+		return err
+	}
+
+	if code != http.StatusOK {
+		return fmt.Errorf("Error [%d]: %s", code, data)
+	}
+	return nil
+}
+
+// UpdateCacheLimit sets the maximum size of caches of a project, 0 resets it to the default
+func UpdateCacheLimit(project string, maxSize int64) error {
+	data, err := json.Marshal(CacheLimit{MaxSize: maxSize})
+	if err != nil {
+		return err
+	}
+
+	uri := fmt.Sprintf("/project/%s/cache", project)
+	data, code, err := Request("PUT", uri, data)
+	if err != nil {
+		return err
+	}
+
+	if code != http.StatusOK {
+		return fmt.Errorf("Error [%d]: %s", code, data)
+	}
+	return nil
+}
+
+//NewActionCacheSave creates a builtin action cache save
+func NewActionCacheSave(key, path string) Action {
+	return Action{
+		Name: CacheSave,
+		Type: BuiltinAction,
+		Parameters: []Parameter{
+			{
+				Name:  "key",
+				Value: key,
+				Type:  StringParameter,
+			},
+			{
+				Name:  "path",
+				Value: path,
+				Type:  StringParameter,
+			},
+		},
+	}
+}
+
+//NewActionCacheRestore creates a builtin action cache restore
+func NewActionCacheRestore(key string) Action {
+	return Action{
+		Name: CacheRestore,
+		Type: BuiltinAction,
+		Parameters: []Parameter{
+			{
+				Name:  "key",
+				Value: key,
+				Type:  StringParameter,
+			},
+		},
+	}
+}
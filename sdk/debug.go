@@ -0,0 +1,65 @@
+package sdk
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// DialWebsocket opens an authenticated websocket on given path of API
+func DialWebsocket(path string) (*websocket.Conn, error) {
+	if err := readConfig(); err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(Host + path)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "https" {
+		u.Scheme = "wss"
+	} else {
+		u.Scheme = "ws"
+	}
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	initRequest(req)
+	authenticate(req)
+	// Handshake headers are set by dialer
+	req.Header.Del("Connection")
+
+	dialer := websocket.Dialer{HandshakeTimeout: 30 * time.Second}
+	if os.Getenv("CDS_SKIP_VERIFY") != "" {
+		dialer.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	conn, resp, err := dialer.Dial(u.String(), req.Header)
+	if err == websocket.ErrBadHandshake && resp != nil {
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		if e := DecodeError(body); e != nil {
+			return nil, e
+		}
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return conn, err
+}
+
+// DebugActionBuild opens a shell on the worker waiting for a debug session after a failed step of action build
+func DebugActionBuild(key, app, pip, env string, bn int64, actionBuildID int64) (*websocket.Conn, error) {
+	if env == "" {
+		env = DefaultEnv.Name
+	}
+	path := fmt.Sprintf("/project/%s/application/%s/pipeline/%s/build/%d/action/%d/debug?envName=%s",
+		key, app, pip, bn, actionBuildID, url.QueryEscape(env))
+	return DialWebsocket(path)
+}
@@ -92,6 +92,23 @@ var (
 	ErrNoParentBuildFound                    = &Error{ID: 78, Status: http.StatusNotFound}
 	ErrParameterExists                       = &Error{ID: 79, Status: http.StatusConflict}
 	ErrNoHatchery                            = &Error{ID: 80, Status: http.StatusNotFound}
+	ErrLDAPGroupSyncDisabled                 = &Error{ID: 81, Status: http.StatusBadRequest}
+	ErrRoleNotFound                          = &Error{ID: 82, Status: http.StatusNotFound}
+	ErrInvalidRole                           = &Error{ID: 83, Status: http.StatusBadRequest}
+	ErrRoleBuiltin                           = &Error{ID: 84, Status: http.StatusForbidden}
+	ErrRoleUsed                              = &Error{ID: 85, Status: http.StatusConflict}
+	ErrInvalidAutoscaling                    = &Error{ID: 86, Status: http.StatusBadRequest}
+	ErrGroupQuotaReached                     = &Error{ID: 87, Status: http.StatusTooManyRequests}
+	ErrInvalidGroupQuota                     = &Error{ID: 88, Status: http.StatusBadRequest}
+	ErrCacheNotFound                         = &Error{ID: 89, Status: http.StatusNotFound}
+	ErrCacheTooLarge                         = &Error{ID: 90, Status: http.StatusRequestEntityTooLarge}
+	ErrInvalidCacheLimit                     = &Error{ID: 91, Status: http.StatusBadRequest}
+	ErrArtifactChecksum                      = &Error{ID: 92, Status: http.StatusBadRequest}
+	ErrArtifactUploadNotFound                = &Error{ID: 93, Status: http.StatusNotFound}
+	ErrArtifactUploadIncomplete              = &Error{ID: 94, Status: http.StatusBadRequest}
+	ErrWorkerCapabilitiesMissing             = &Error{ID: 95, Status: http.StatusPreconditionFailed}
+	ErrNoDebugSession                        = &Error{ID: 96, Status: http.StatusNotFound}
+	ErrArtifactPromoted                      = &Error{ID: 97, Status: http.StatusConflict}
 )
 
 // SupportedLanguages on API errors
@@ -183,6 +200,23 @@ You can safely use them in a String or Text parameter`,
 	ErrNoParentBuildFound.ID:                    "no parent build found",
 	ErrParameterExists.ID:                       "parameter already exists",
 	ErrNoHatchery.ID:                            "No hatchery found",
+	ErrLDAPGroupSyncDisabled.ID:                 "LDAP group synchronization is not enabled",
+	ErrRoleNotFound.ID:                          "role does not exist",
+	ErrInvalidRole.ID:                           "role must have a name and known capabilities",
+	ErrRoleBuiltin.ID:                           "builtin roles cannot be modified",
+	ErrRoleUsed.ID:                              "role is still granted to groups",
+	ErrInvalidAutoscaling.ID:                    "invalid worker model autoscaling settings",
+	ErrGroupQuotaReached.ID:                     "group has reached its maximum number of concurrent builds",
+	ErrInvalidGroupQuota.ID:                     "invalid group quota",
+	ErrCacheNotFound.ID:                         "cache not found",
+	ErrCacheTooLarge.ID:                         "cache is larger than the cache size limit of the project",
+	ErrInvalidCacheLimit.ID:                     "invalid cache size limit",
+	ErrArtifactChecksum.ID:                      "artifact checksum mismatch",
+	ErrArtifactUploadNotFound.ID:                "artifact upload not found",
+	ErrArtifactUploadIncomplete.ID:              "artifact upload is incomplete",
+	ErrWorkerCapabilitiesMissing.ID:             "worker capabilities must be declared",
+	ErrNoDebugSession.ID:                        "no worker waits for a debug session on this action build",
+	ErrArtifactPromoted.ID:                      "a promoted artifact with the same name and tag cannot be replaced",
 }
 
 var errorsFrench = map[int]string{
@@ -268,6 +302,23 @@ Vous pouvez les utiliser sans problème dans un paramêtre de type String ou Tex
 	ErrNoParentBuildFound.ID:                    "aucun build parent n'a pu être trouvé",
 	ErrParameterExists.ID:                       "le paramètre existe déjà",
 	ErrNoHatchery.ID:                            "La hatchery n'existe pas",
+	ErrLDAPGroupSyncDisabled.ID:                 "la synchronisation des groupes LDAP n'est pas activée",
+	ErrRoleNotFound.ID:                          "le rôle n'existe pas",
+	ErrInvalidRole.ID:                           "le rôle doit avoir un nom et des capacités connues",
+	ErrRoleBuiltin.ID:                           "les rôles prédéfinis ne peuvent pas être modifiés",
+	ErrRoleUsed.ID:                              "le rôle est encore attribué à des groupes",
+	ErrInvalidAutoscaling.ID:                    "paramètres de mise à l'échelle du modèle de worker invalides",
+	ErrGroupQuotaReached.ID:                     "le groupe a atteint son nombre maximum de builds simultanés",
+	ErrInvalidGroupQuota.ID:                     "quota de groupe invalide",
+	ErrCacheNotFound.ID:                         "cache introuvable",
+	ErrCacheTooLarge.ID:                         "le cache dépasse la taille maximale des caches du projet",
+	ErrInvalidCacheLimit.ID:                     "taille maximale des caches invalide",
+	ErrArtifactChecksum.ID:                      "la somme de contrôle de l'artefact ne correspond pas",
+	ErrArtifactUploadNotFound.ID:                "envoi d'artefact introuvable",
+	ErrArtifactUploadIncomplete.ID:              "l'envoi de l'artefact est incomplet",
+	ErrWorkerCapabilitiesMissing.ID:             "les capacités du worker doivent être déclarées",
+	ErrNoDebugSession.ID:                        "aucun worker n'attend de session de debug sur ce build",
+	ErrArtifactPromoted.ID:                      "un artefact promu avec le même nom et le même tag ne peut pas être remplacé",
 }
 
 var matcher = language.NewMatcher(SupportedLanguages)
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 )
 
 // Group represent a group of user.
@@ -22,6 +23,7 @@ type Group struct {
 type GroupPermission struct {
 	Group      Group `json:"group"`
 	Permission int   `json:"permission"`
+	Role       *Role `json:"role,omitempty"`
 	Recursive  bool  `json:"recursive,omitempty"`
 }
 
@@ -29,24 +31,42 @@ type GroupPermission struct {
 type EnvironmentGroup struct {
 	Environment Environment `json:"environment"`
 	Permission  int         `json:"permission"`
+	Role        *Role       `json:"role,omitempty"`
 }
 
 // ApplicationGroup represent a link with a pipeline
 type ApplicationGroup struct {
 	Application Application `json:"application"`
 	Permission  int         `json:"permission"`
+	Role        *Role       `json:"role,omitempty"`
 }
 
 // PipelineGroup represent a link with a pipeline
 type PipelineGroup struct {
 	Pipeline   Pipeline `json:"pipeline"`
 	Permission int      `json:"permission"`
+	Role       *Role    `json:"role,omitempty"`
 }
 
 // ProjectGroup represent a link with a project
 type ProjectGroup struct {
 	Project    Project `json:"project"`
 	Permission int     `json:"permission"`
+	Role       *Role   `json:"role,omitempty"`
+}
+
+// LDAPGroupSyncReport lists changes applied (or to apply in dry-run mode) by a LDAP group synchronization
+type LDAPGroupSyncReport struct {
+	DryRun  bool                  `json:"dry_run"`
+	Date    time.Time             `json:"date"`
+	Changes []LDAPGroupSyncChange `json:"changes"`
+}
+
+// LDAPGroupSyncChange represents a membership change on a CDS group
+type LDAPGroupSyncChange struct {
+	Group    string `json:"group"`
+	Username string `json:"username"`
+	Change   string `json:"change"`
 }
 
 // NewGroup instanciate a new Group
@@ -266,3 +286,22 @@ func UnsetUserGroupAdmin(groupName string, userName string) error {
 
 	return nil
 }
+
+// GetLDAPGroupSyncReport returns changes LDAP group synchronization would apply
+func GetLDAPGroupSyncReport() (LDAPGroupSyncReport, error) {
+	var report LDAPGroupSyncReport
+	data, code, err := Request("GET", "/group/ldap/sync", nil)
+	if err != nil {
+		return report, err
+	}
+
+	if code != http.StatusOK {
+		return report, fmt.Errorf("Error [%d]: %s", code, data)
+	}
+
+	if err := json.Unmarshal(data, &report); err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
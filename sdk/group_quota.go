@@ -0,0 +1,71 @@
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// GroupQuota limits concurrent builds of a group and sets its share of workers
+// when several groups have builds waiting in queue
+type GroupQuota struct {
+	GroupID int64 `json:"group_id"`
+	// MaxBuilding is the maximum number of concurrent action builds, 0 means unlimited
+	MaxBuilding int64 `json:"max_building"`
+	// Weight is the share of the group in queue, relative to other groups weights
+	Weight int64 `json:"weight"`
+	// Building is the current number of action builds of the group
+	Building int64 `json:"building"`
+}
+
+// DefaultGroupQuota is the quota of groups without one
+var DefaultGroupQuota = GroupQuota{Weight: 1}
+
+// IsValid checks quota values
+func (q GroupQuota) IsValid() error {
+	if q.MaxBuilding < 0 {
+		return NewError(ErrInvalidGroupQuota, fmt.Errorf("max building must be positive"))
+	}
+	if q.Weight < 1 {
+		return NewError(ErrInvalidGroupQuota, fmt.Errorf("weight must be greater than 0"))
+	}
+	return nil
+}
+
+// GetGroupQuota returns quota of a group
+func GetGroupQuota(groupName string) (GroupQuota, error) {
+	var q GroupQuota
+	path := fmt.Sprintf("/group/%s/quota", groupName)
+	data, code, err := Request("GET", path, nil)
+	if err != nil {
+		return q, err
+	}
+
+	if code != http.StatusOK {
+		return q, fmt.Errorf("Error [%d]: %s", code, data)
+	}
+
+	if err := json.Unmarshal(data, &q); err != nil {
+		return q, err
+	}
+	return q, nil
+}
+
+// UpdateGroupQuota sets quota of a group
+func UpdateGroupQuota(groupName string, q GroupQuota) error {
+	data, err := json.Marshal(q)
+	if err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf("/group/%s/quota", groupName)
+	data, code, err := Request("PUT", path, data)
+	if err != nil {
+		return err
+	}
+
+	if code != http.StatusOK {
+		return fmt.Errorf("Error [%d]: %s", code, data)
+	}
+	return nil
+}
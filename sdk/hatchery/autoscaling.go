@@ -0,0 +1,124 @@
+package hatchery
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ovh/cds/sdk"
+)
+
+// demandSmoothing is the weight of the last demand in the predictive demand average
+const demandSmoothing = 0.3
+
+// scaler computes how many workers of each model hatchery has to spawn or kill.
+// It keeps the last scaling dates and the smoothed demand of each model between two hatchery routines
+type scaler struct {
+	mutex  sync.Mutex
+	models map[int64]*modelScaling
+}
+
+type modelScaling struct {
+	lastScaleUp   time.Time
+	lastScaleDown time.Time
+	demand        float64
+}
+
+func newScaler() *scaler {
+	return &scaler{models: map[int64]*modelScaling{}}
+}
+
+func (s *scaler) model(id int64) *modelScaling {
+	m, ok := s.models[id]
+	if !ok {
+		m = &modelScaling{}
+		s.models[id] = m
+	}
+	return m
+}
+
+// decide returns the wanted number of idle workers of a model and the number of workers
+// to spawn (positive) or to kill (negative) to reach it.
+// Without autoscaling settings, it spawns up to WantedCount+provision and kills only
+// when the surplus exceeds provision
+func (s *scaler) decide(ms sdk.ModelStatus, provision int, now time.Time) (int64, int64) {
+	if ms.Autoscaling == nil {
+		wanted := ms.WantedCount + int64(provision)
+		diff := wanted - ms.CurrentCount
+		if diff < 0 && -diff < int64(provision) { // Chill...
+			return wanted, 0
+		}
+		return wanted, diff
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	a := ms.Autoscaling
+	m := s.model(ms.ModelID)
+
+	demand := ms.WantedCount
+	if a.Predictive {
+		m.demand = demandSmoothing*float64(ms.WantedCount) + (1-demandSmoothing)*m.demand
+		if predicted := int64(math.Ceil(m.demand)); predicted > demand {
+			demand = predicted
+		}
+	}
+	wanted := demand + int64(a.WarmPoolAt(now)) + int64(provision)
+
+	// Actions waiting for too long: spawn more workers right now
+	urgent := a.QueueWaitThreshold > 0 && ms.QueueWait >= int64(a.QueueWaitThreshold)
+	if urgent {
+		step := a.ScaleUpStep
+		if step == 0 {
+			step = 1
+		}
+		wanted += int64(step)
+	}
+
+	min := int64(a.Min)
+	if !ms.SingleUse {
+		min -= ms.BuildingCount
+	}
+	if wanted < min {
+		wanted = min
+	}
+	if a.Max > 0 {
+		if max := int64(a.Max) - ms.BuildingCount; wanted > max {
+			wanted = max
+		}
+	}
+	if wanted < 0 {
+		wanted = 0
+	}
+
+	diff := wanted - ms.CurrentCount
+	switch {
+	case diff > 0:
+		if !urgent && now.Sub(m.lastScaleUp) < time.Duration(a.ScaleUpCooldown)*time.Second {
+			return wanted, 0
+		}
+	case diff < 0:
+		last := m.lastScaleUp
+		if m.lastScaleDown.After(last) {
+			last = m.lastScaleDown
+		}
+		if now.Sub(last) < time.Duration(a.ScaleDownCooldown)*time.Second {
+			return wanted, 0
+		}
+	}
+	return wanted, diff
+}
+
+// scaled records that workers of a model have been spawned (up) or killed
+func (s *scaler) scaled(modelID int64, up bool, now time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	m := s.model(modelID)
+	if up {
+		m.lastScaleUp = now
+		return
+	}
+	m.lastScaleDown = now
+}
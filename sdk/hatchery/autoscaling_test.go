@@ -0,0 +1,121 @@
+package hatchery
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func TestDecideWithoutAutoscaling(t *testing.T) {
+	s := newScaler()
+	now := time.Now()
+
+	wanted, diff := s.decide(sdk.ModelStatus{ModelID: 1, WantedCount: 3, CurrentCount: 1}, 1, now)
+	assert.Equal(t, int64(4), wanted)
+	assert.Equal(t, int64(3), diff)
+
+	// Surplus lower than provision is kept
+	_, diff = s.decide(sdk.ModelStatus{ModelID: 1, WantedCount: 0, CurrentCount: 2}, 2, now)
+	assert.Equal(t, int64(0), diff)
+
+	_, diff = s.decide(sdk.ModelStatus{ModelID: 1, WantedCount: 0, CurrentCount: 4}, 2, now)
+	assert.Equal(t, int64(-2), diff)
+}
+
+func TestDecideWithAutoscaling(t *testing.T) {
+	s := newScaler()
+	now := time.Date(2017, 1, 2, 10, 0, 0, 0, time.UTC) // monday
+	a := &sdk.ModelAutoscaling{
+		Min:               2,
+		Max:               10,
+		WarmPool:          1,
+		WarmPoolSchedule:  []sdk.WarmPoolSchedule{{Days: []time.Weekday{time.Monday}, From: "08:00", To: "19:00", WarmPool: 3}},
+		ScaleUpCooldown:   10,
+		ScaleDownCooldown: 60,
+	}
+
+	// Scheduled warm pool
+	wanted, diff := s.decide(sdk.ModelStatus{ModelID: 1, WantedCount: 2, Autoscaling: a}, 0, now)
+	assert.Equal(t, int64(5), wanted)
+	assert.Equal(t, int64(5), diff)
+	s.scaled(1, true, now)
+
+	// Scale up cooldown
+	_, diff = s.decide(sdk.ModelStatus{ModelID: 1, WantedCount: 4, CurrentCount: 5, Autoscaling: a}, 0, now.Add(5*time.Second))
+	assert.Equal(t, int64(0), diff)
+	_, diff = s.decide(sdk.ModelStatus{ModelID: 1, WantedCount: 4, CurrentCount: 5, Autoscaling: a}, 0, now.Add(11*time.Second))
+	assert.Equal(t, int64(2), diff)
+
+	// Max counts building workers
+	wanted, _ = s.decide(sdk.ModelStatus{ModelID: 1, WantedCount: 20, BuildingCount: 4, Autoscaling: a}, 0, now.Add(20*time.Second))
+	assert.Equal(t, int64(6), wanted)
+
+	// Scale down cooldown
+	_, diff = s.decide(sdk.ModelStatus{ModelID: 1, CurrentCount: 5, Autoscaling: a}, 0, now.Add(30*time.Second))
+	assert.Equal(t, int64(0), diff)
+	wanted, diff = s.decide(sdk.ModelStatus{ModelID: 1, CurrentCount: 5, Autoscaling: a}, 0, now.Add(61*time.Second))
+	assert.Equal(t, int64(3), wanted)
+	assert.Equal(t, int64(-2), diff)
+
+	// Out of schedule, min is kept
+	night := now.Add(12 * time.Hour)
+	wanted, _ = s.decide(sdk.ModelStatus{ModelID: 1, Autoscaling: a}, 0, night)
+	assert.Equal(t, int64(2), wanted)
+}
+
+func TestDecideQueueWait(t *testing.T) {
+	s := newScaler()
+	now := time.Now()
+	a := &sdk.ModelAutoscaling{ScaleUpCooldown: 600, QueueWaitThreshold: 30, ScaleUpStep: 2}
+	s.scaled(1, true, now)
+
+	_, diff := s.decide(sdk.ModelStatus{ModelID: 1, WantedCount: 1, QueueWait: 10, Autoscaling: a}, 0, now)
+	assert.Equal(t, int64(0), diff)
+
+	// Threshold exceeded bypasses cooldown
+	_, diff = s.decide(sdk.ModelStatus{ModelID: 1, WantedCount: 1, QueueWait: 45, Autoscaling: a}, 0, now)
+	assert.Equal(t, int64(3), diff)
+}
+
+func TestDecidePredictive(t *testing.T) {
+	s := newScaler()
+	now := time.Now()
+	a := &sdk.ModelAutoscaling{Predictive: true}
+
+	s.decide(sdk.ModelStatus{ModelID: 1, WantedCount: 10, Autoscaling: a}, 0, now)
+	// Demand dropped, smoothed demand keeps workers
+	wanted, _ := s.decide(sdk.ModelStatus{ModelID: 1, WantedCount: 0, CurrentCount: 3, Autoscaling: a}, 0, now)
+	assert.Equal(t, int64(3), wanted)
+}
+
+func TestParseWarmPoolSchedule(t *testing.T) {
+	s, err := sdk.ParseWarmPoolSchedule("1-5 08:00-19:00 3")
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}, s.Days)
+	assert.Equal(t, 3, s.WarmPool)
+
+	s, err = sdk.ParseWarmPoolSchedule("0,6 00:00-24:00 0")
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Weekday{time.Sunday, time.Saturday}, s.Days)
+
+	for _, invalid := range []string{"", "1-5 08:00 3", "1-7 08:00-19:00 3", "1-5 25:00-26:00 1", "1-5 08:00-19:00 -1"} {
+		_, err := sdk.ParseWarmPoolSchedule(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestDecideSingleUse(t *testing.T) {
+	s := newScaler()
+	a := &sdk.ModelAutoscaling{Min: 3}
+
+	// Building workers come back idle
+	wanted, _ := s.decide(sdk.ModelStatus{ModelID: 1, BuildingCount: 2, Autoscaling: a}, 0, time.Now())
+	assert.Equal(t, int64(1), wanted)
+
+	// Building single use workers are destroyed after their action
+	wanted, _ = s.decide(sdk.ModelStatus{ModelID: 2, BuildingCount: 2, SingleUse: true, Autoscaling: a}, 0, time.Now())
+	assert.Equal(t, int64(3), wanted)
+}
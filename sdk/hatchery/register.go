@@ -10,7 +10,9 @@ import (
 
 	"github.com/facebookgo/httpcontrol"
 	"github.com/ovh/cds/engine/log"
+	"github.com/ovh/cds/engine/metrics"
 	"github.com/ovh/cds/sdk"
+	"github.com/ovh/cds/sdk/tracing"
 )
 
 // Interface describe an interface for each hatchery mode (mesos, local)
@@ -27,6 +29,12 @@ type Interface interface {
 var (
 	// Client is a CDS Client
 	Client sdk.HTTPClient
+
+	// defaultScaler keeps autoscaling state of models between two hatchery routines
+	defaultScaler = newScaler()
+
+	spawns        = metrics.NewCounter("cds_hatchery_spawns_total", "Workers spawned by model and result (success or failure)", "model", "result")
+	spawnDuration = metrics.NewHistogram("cds_hatchery_spawn_duration_seconds", "Time to spawn a worker by model", metrics.DefaultBuckets, "model")
 )
 
 // Born creates hatchery
@@ -71,12 +79,11 @@ func hatcheryRoutine(h Interface, provision int) error {
 	log.Debug("hatcheryRoutine> len(wms)=%d\n", len(wms))
 
 	for _, ms := range wms {
-		// Provisionning
-		ms.WantedCount += int64(provision)
+		wanted, diff := defaultScaler.decide(ms, provision, time.Now())
 
-		log.Debug("hatcheryRoutine> ms.CurrentCount=%d ms.WantedCount=%d\n", ms.CurrentCount, ms.WantedCount)
+		log.Debug("hatcheryRoutine> ms.CurrentCount=%d ms.WantedCount=%d wanted=%d\n", ms.CurrentCount, ms.WantedCount, wanted)
 
-		if ms.CurrentCount == ms.WantedCount {
+		if diff == 0 {
 			// ok, do nothing
 			continue
 		}
@@ -89,37 +96,46 @@ func hatcheryRoutine(h Interface, provision int) error {
 			continue
 		}
 
-		if ms.CurrentCount < ms.WantedCount {
-			diff := ms.WantedCount - ms.CurrentCount
+		if diff > 0 {
 			// Check the number of worker started by hatchery
-			if ms.WantedCount < int64(h.WorkerStarted(m))-ms.BuildingCount {
+			if wanted < int64(h.WorkerStarted(m))-ms.BuildingCount {
 				// Ok so they are starting...
-				log.Notice("%d wanted, but %d (%d building) %s workers started already...\n", ms.WantedCount, h.WorkerStarted(m), ms.BuildingCount, ms.ModelName)
+				log.Notice("%d wanted, but %d (%d building) %s workers started already...\n", wanted, h.WorkerStarted(m), ms.BuildingCount, ms.ModelName)
 				continue
 			}
-			log.Notice("I got to spawn %d %s worker ! (%d/%d)\n", diff, ms.ModelName, ms.CurrentCount, ms.WantedCount)
+			log.Notice("I got to spawn %d %s worker ! (%d/%d)\n", diff, ms.ModelName, ms.CurrentCount, wanted)
 
 			for i := 0; i < int(diff); i++ {
-				if errSpawn := h.SpawnWorker(m, ms.Requirements); errSpawn != nil {
-					log.Warning("Cannot spawn %s: %s\n", ms.ModelName, errSpawn)
+				start := time.Now()
+				// Spawn continues the trace of a queued action build the model can run, if any
+				var parent string
+				if i < len(ms.TraceParents) {
+					parent = ms.TraceParents[i]
+				}
+				span := tracing.Start("hatchery.SpawnWorker", parent)
+				span.SetAttribute("cds.hatchery", h.Hatchery().Name)
+				span.SetAttribute("cds.model", ms.ModelName)
+				errSpawn := h.SpawnWorker(m, ms.Requirements)
+				span.SetError(errSpawn)
+				span.Finish()
+				if errSpawn != nil {
+					log.WithField(log.FieldModel, ms.ModelName).Warning("Cannot spawn %s: %s\n", ms.ModelName, errSpawn)
+					spawns.Inc(ms.ModelName, "failure")
 					continue
 				}
+				spawnDuration.Since(start, ms.ModelName)
+				spawns.Inc(ms.ModelName, "success")
 			}
+			defaultScaler.scaled(ms.ModelID, true, time.Now())
 			continue
 		}
 
-		if ms.CurrentCount > ms.WantedCount {
-			diff := ms.CurrentCount - ms.WantedCount
-			if int(diff) < provision { // Chill...
-				continue
-			}
-			log.Notice("I got to kill %d %s worker !\n", diff, ms.ModelName)
+		log.Notice("I got to kill %d %s worker !\n", -diff, ms.ModelName)
 
-			if err := killWorker(h, m); err != nil {
-				return err
-			}
-			continue
+		if err := killWorker(h, m); err != nil {
+			return err
 		}
+		defaultScaler.scaled(ms.ModelID, false, time.Now())
 	}
 
 	return nil
@@ -128,6 +144,7 @@ func hatcheryRoutine(h Interface, provision int) error {
 // Register calls CDS API to register current hatchery
 func Register(h *sdk.Hatchery, token string) error {
 
+	log.SetField(log.FieldHatchery, h.Name)
 	log.Notice("Register Hatchery %s\n", h.Name)
 
 	h.UID = token
@@ -37,6 +37,19 @@ const (
 	PasswordPlaceholder string = "**********"
 )
 
+// RequestIDParameter is the name of the build parameter carrying the ID of the API request which started a pipeline build
+const RequestIDParameter = "cds.request_id"
+
+// ParameterValue returns value of the parameter with given name, or an empty string
+func ParameterValue(params []Parameter, name string) string {
+	for _, p := range params {
+		if p.Name == name {
+			return p.Value
+		}
+	}
+	return ""
+}
+
 // ParameterTypeFromString returns a parameter Type from a given string
 func ParameterTypeFromString(in string) ParameterType {
 	switch in {
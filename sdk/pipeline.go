@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"sync"
 	"time"
 )
@@ -22,6 +23,7 @@ type Pipeline struct {
 	AttachedApplication []Application     `json:"attached_application,omitempty"`
 	Permission          int               `json:"permission"`
 	LastModified        int64             `json:"last_modified"`
+	Priority            *int              `json:"priority,omitempty"`
 }
 
 // PipelineBuild Struct for history table
@@ -83,6 +85,22 @@ func PipelineTypeFromString(in string) PipelineType {
 	}
 }
 
+// Default priorities of pipeline types in queue, deployments first
+var defaultPipelinePriorities = map[PipelineType]int{
+	DeploymentPipeline: 20,
+	TestingPipeline:    10,
+	BuildPipeline:      0,
+}
+
+// PipelinePriority returns priority in queue of a pipeline: its own priority if set,
+// else the default priority of its type
+func PipelinePriority(t PipelineType, priority *int) int {
+	if priority != nil {
+		return *priority
+	}
+	return defaultPipelinePriorities[t]
+}
+
 // PipelineAction represents an action in a pipeline
 type PipelineAction struct {
 	ActionName      string      `json:"actionName"`
@@ -174,6 +192,31 @@ func AddPipeline(name string, projectKey string, pipelineType PipelineType, para
 	return nil
 }
 
+// UpdatePipelinePriority sets priority in queue of a pipeline
+func UpdatePipelinePriority(key, name string, priority int) error {
+	p, err := GetPipeline(key, name)
+	if err != nil {
+		return err
+	}
+	p.Priority = &priority
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf("/project/%s/pipeline/%s", key, name)
+	data, code, err := Request("PUT", path, data)
+	if err != nil {
+		return err
+	}
+	if code >= 300 {
+		return fmt.Errorf("Error [%d]: %s", code, data)
+	}
+
+	return nil
+}
+
 // DeletePipelineAction delete the given action from the given pipeline
 func DeletePipelineAction(projectKey string, pipelineName string, actionPipelineID int64) error {
 	path := fmt.Sprintf("/project/%s/pipeline/%s/action/%d", projectKey, pipelineName, actionPipelineID)
@@ -235,17 +278,39 @@ func MoveActionInPipeline(projectKey, pipelineName string, actionPipelineID int6
 // - If the pipeline build result is failed, it will only restart failed actions
 // - If the pipeline build result is success, it will restart all actions
 func RestartPipeline(key, app, pip, env string, bn int) (chan Log, error) {
-	uri := fmt.Sprintf("/project/%s/application/%s/pipeline/%s/build/%d/restart", key, app, pip, bn)
+	if err := RestartPipelineBuild(key, app, pip, env, int64(bn)); err != nil {
+		return nil, err
+	}
+
+	return StreamPipelineBuild(key, app, pip, env, bn, false)
+}
+
+// RestartPipelineBuild restarts a pipeline build as RestartPipeline, without streaming its logs
+func RestartPipelineBuild(key, app, pip, env string, bn int64) error {
+	uri := fmt.Sprintf("/project/%s/application/%s/pipeline/%s/build/%d/restart?envName=%s", key, app, pip, bn, url.QueryEscape(env))
 
 	_, code, err := Request("POST", uri, nil)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	if code > 300 {
-		return nil, fmt.Errorf("HTTP %d", code)
+		return fmt.Errorf("HTTP %d", code)
 	}
+	return nil
+}
 
-	return StreamPipelineBuild(key, app, pip, env, bn, false)
+// StopPipelineBuild stops a building pipeline build
+func StopPipelineBuild(key, app, pip, env string, bn int64) error {
+	uri := fmt.Sprintf("/project/%s/application/%s/pipeline/%s/build/%d/stop?envName=%s", key, app, pip, bn, url.QueryEscape(env))
+
+	_, code, err := Request("POST", uri, nil)
+	if err != nil {
+		return err
+	}
+	if code > 300 {
+		return fmt.Errorf("HTTP %d", code)
+	}
+	return nil
 }
 
 //GetPipelineCommits returns list of commit between this build and the previous
@@ -317,6 +317,15 @@ type VCSRepo struct {
 	SSHCloneURL  string `json:"ssh_url"`  //Git clone URL  "ssh://git@<baseURL>/PRJ/my-repo.git"
 }
 
+//VCSClone gives GitClone builtin action the clone urls of the application repository,
+//and the credentials of its repositories manager to clone it over https
+type VCSClone struct {
+	HTTPCloneURL string `json:"http_url,omitempty"`
+	SSHCloneURL  string `json:"ssh_url,omitempty"`
+	User         string `json:"user,omitempty"`
+	Password     string `json:"password,omitempty"`
+}
+
 //VCSAuthor represents the auhor for every commit
 type VCSAuthor struct {
 	Name        string `json:"name"`
@@ -12,8 +12,11 @@ import (
 	"os"
 	"path"
 	"strings"
+	"sync"
 
 	"github.com/spf13/viper"
+
+	"github.com/ovh/cds/sdk/tracing"
 )
 
 var (
@@ -33,6 +36,11 @@ var (
 	RequestedWithValue = "X-CDS-SDK"
 	//SessionTokenHeader is user as HTTP header
 	SessionTokenHeader = "Session-Token"
+	// RequestIDHeader is used as HTTP header to correlate logs of a request
+	RequestIDHeader = "X-Request-Id"
+	// correlation ID sent with all requests, guarded by requestIDMutex
+	requestID      string
+	requestIDMutex sync.RWMutex
 	// HTTP client
 	client HTTPClient
 	// current agent calling
@@ -51,6 +59,13 @@ func Authorization(h string) {
 	hash = h
 }
 
+// SetRequestID sets the correlation ID sent with all next calls, like the request ID of the pipeline build run by a worker
+func SetRequestID(id string) {
+	requestIDMutex.Lock()
+	requestID = id
+	requestIDMutex.Unlock()
+}
+
 // Agent describe the type of authentication method to use
 type Agent string
 
@@ -88,6 +103,27 @@ func initRequest(req *http.Request) {
 	req.Header.Set("User-Agent", string(agent))
 	req.Header.Set("Connection", "close")
 	req.Header.Add(RequestedWithHeader, RequestedWithValue)
+	requestIDMutex.RLock()
+	id := requestID
+	requestIDMutex.RUnlock()
+	if id != "" {
+		req.Header.Set(RequestIDHeader, id)
+	}
+}
+
+// authenticate sets authentication headers of worker, hatchery or user
+func authenticate(req *http.Request) {
+	if hash != "" {
+		basedHash := base64.StdEncoding.EncodeToString([]byte(hash))
+		req.Header.Set(AuthHeader, basedHash)
+	}
+	if user != "" && password != "" {
+		req.SetBasicAuth(user, password)
+	}
+	if user != "" && token != "" {
+		req.Header.Add(SessionTokenHeader, token)
+		req.SetBasicAuth(user, token)
+	}
 }
 
 func readConfig() error {
@@ -197,15 +233,19 @@ func Request(method string, path string, args []byte, mods ...RequestModifier) (
 }
 
 // Stream makes an authenticated http request and return io.ReadCloser
-func Stream(method string, path string, args []byte, mods ...RequestModifier) (io.ReadCloser, int, error) {
+// Results are named so that the request span ends with the returned status and error
+func Stream(method string, path string, args []byte, mods ...RequestModifier) (_ io.ReadCloser, code int, err error) {
 	var savederror error
 
-	err := readConfig()
+	err = readConfig()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error reading configuration: %s\n", err)
 		os.Exit(1)
 	}
 
+	span := startRequestSpan(method, path)
+	defer func() { endRequestSpan(span, code, err) }()
+
 	for i := 0; i < 10; i++ {
 		var req *http.Request
 		if args != nil {
@@ -218,6 +258,9 @@ func Stream(method string, path string, args []byte, mods ...RequestModifier) (i
 			continue
 		}
 		initRequest(req)
+		if span != nil {
+			req.Header.Set(tracing.Header, span.TraceParent())
+		}
 
 		for i := range mods {
 			mods[i](req)
@@ -225,20 +268,11 @@ func Stream(method string, path string, args []byte, mods ...RequestModifier) (i
 
 		//No auth on /login route
 		if !strings.HasPrefix(path, "/login") {
-			if hash != "" {
-				basedHash := base64.StdEncoding.EncodeToString([]byte(hash))
-				req.Header.Set(AuthHeader, basedHash)
-			}
-			if user != "" && password != "" {
-				req.SetBasicAuth(user, password)
-			}
-			if user != "" && token != "" {
-				req.Header.Add(SessionTokenHeader, token)
-				req.SetBasicAuth(user, token)
-			}
+			authenticate(req)
 		}
 
-		resp, err := client.Do(req)
+		var resp *http.Response
+		resp, err = client.Do(req)
 
 		// if everything is fine, return body
 		if err == nil && resp.StatusCode < 500 {
@@ -283,7 +317,34 @@ func Stream(method string, path string, args []byte, mods ...RequestModifier) (i
 		}
 	}
 
-	return nil, 0, fmt.Errorf("x10: %s", savederror)
+	err = fmt.Errorf("x10: %s", savederror)
+	return nil, 0, err
+}
+
+// startRequestSpan starts the span of a request to the API, child of the current operation if any
+func startRequestSpan(method, path string) *tracing.Span {
+	parent := tracing.Current()
+	if parent == nil {
+		return nil
+	}
+	if i := strings.Index(path, "?"); i >= 0 {
+		path = path[:i]
+	}
+	span := tracing.Continue(method+" "+path, parent.TraceParent())
+	span.SetKind(tracing.KindClient)
+	return span
+}
+
+func endRequestSpan(span *tracing.Span, code int, err error) {
+	if span == nil {
+		return
+	}
+	span.SetAttribute("http.status_code", code)
+	if err == nil && code >= 500 {
+		err = fmt.Errorf("HTTP %d", code)
+	}
+	span.SetError(err)
+	span.Finish()
 }
 
 // UploadMultiPart upload multipart
@@ -358,6 +419,13 @@ func Upload(method string, path string, body io.ReadCloser, mods ...RequestModif
 	}
 	initRequest(req)
 
+	span := startRequestSpan(method, path)
+	code := 0
+	defer func() { endRequestSpan(span, code, err) }()
+	if span != nil {
+		req.Header.Set(tracing.Header, span.TraceParent())
+	}
+
 	for i := range mods {
 		mods[i](req)
 	}
@@ -374,6 +442,7 @@ func Upload(method string, path string, body io.ReadCloser, mods ...RequestModif
 		return nil, 0, err
 	}
 	defer resp.Body.Close()
+	code = resp.StatusCode
 
 	if verbose {
 		fmt.Fprintf(os.Stderr, "Response Status: %s\n", resp.Status)
@@ -0,0 +1,170 @@
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// Capabilities a role can grant on a project, an application, a pipeline or an environment
+const (
+	CapabilityRead          = "read"
+	CapabilityReadSecrets   = "read_secrets"
+	CapabilityExecute       = "execute"
+	CapabilityEditVariables = "edit_variables"
+	CapabilityWrite         = "write"
+)
+
+// Capabilities lists all known capabilities
+var Capabilities = []string{
+	CapabilityRead,
+	CapabilityReadSecrets,
+	CapabilityExecute,
+	CapabilityEditVariables,
+	CapabilityWrite,
+}
+
+// Builtin roles matching legacy permission levels
+var (
+	RoleRead             = Role{Name: "read", Builtin: true, Capabilities: []string{CapabilityRead}}
+	RoleReadExecute      = Role{Name: "read-execute", Builtin: true, Capabilities: []string{CapabilityRead, CapabilityExecute}}
+	RoleReadWriteExecute = Role{Name: "read-write-execute", Builtin: true, Capabilities: []string{CapabilityRead, CapabilityExecute, CapabilityEditVariables, CapabilityWrite}}
+)
+
+// Role is a named set of capabilities granted to a group
+type Role struct {
+	ID           int64    `json:"id"`
+	Name         string   `json:"name"`
+	Description  string   `json:"description"`
+	Builtin      bool     `json:"builtin"`
+	Capabilities []string `json:"capabilities"`
+}
+
+// AccessExplanation details why a user has or lacks a capability on a resource
+type AccessExplanation struct {
+	Username   string        `json:"username"`
+	Capability string        `json:"capability"`
+	Granted    bool          `json:"granted"`
+	Admin      bool          `json:"admin"`
+	Resources  []AccessCheck `json:"resources"`
+}
+
+// AccessCheck details grants of a user on a single resource
+type AccessCheck struct {
+	Type    string        `json:"type"`
+	Name    string        `json:"name"`
+	Granted bool          `json:"granted"`
+	Grants  []AccessGrant `json:"grants"`
+}
+
+// AccessGrant is a role given to one of the user groups on a resource
+type AccessGrant struct {
+	Group string `json:"group"`
+	Role  Role   `json:"role"`
+}
+
+// RoleFromPermission returns the builtin role matching a legacy permission level
+func RoleFromPermission(perm int) *Role {
+	var r Role
+	switch {
+	case perm >= 7:
+		r = RoleReadWriteExecute
+	case perm >= 5:
+		r = RoleReadExecute
+	case perm >= 4:
+		r = RoleRead
+	default:
+		r = Role{Capabilities: []string{}}
+	}
+	return &r
+}
+
+// Has returns true if the role grants given capability
+func (r *Role) Has(capability string) bool {
+	for _, c := range r.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// Permission returns the legacy permission level granted by the role
+func (r *Role) Permission() int {
+	switch {
+	case r.Has(CapabilityWrite):
+		return 7
+	case r.Has(CapabilityExecute):
+		return 5
+	case r.Has(CapabilityRead):
+		return 4
+	}
+	return 0
+}
+
+// IsValid checks role name and capabilities
+func (r *Role) IsValid() bool {
+	if r.Name == "" || len(r.Capabilities) == 0 {
+		return false
+	}
+	for _, c := range r.Capabilities {
+		known := false
+		for _, k := range Capabilities {
+			if c == k {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return false
+		}
+	}
+	return true
+}
+
+// ListRoles returns all roles
+func ListRoles() ([]Role, error) {
+	data, code, err := Request("GET", "/role", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if code != http.StatusOK {
+		return nil, fmt.Errorf("Error [%d]: %s", code, data)
+	}
+
+	var roles []Role
+	if err := json.Unmarshal(data, &roles); err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+// ExplainAccess asks API why a user has or lacks a capability on a project resources
+func ExplainAccess(projectKey, username, capability, application, pipeline, environment string) (*AccessExplanation, error) {
+	params := url.Values{}
+	params.Set("user", username)
+	params.Set("capability", capability)
+	params.Set("application", application)
+	params.Set("pipeline", pipeline)
+	params.Set("environment", environment)
+
+	uri := fmt.Sprintf("/project/%s/access?%s", projectKey, params.Encode())
+	data, code, err := Request("GET", uri, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if code != http.StatusOK {
+		return nil, fmt.Errorf("Error [%d]: %s", code, data)
+	}
+
+	var e AccessExplanation
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+
+	return &e, nil
+}
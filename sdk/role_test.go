@@ -0,0 +1,32 @@
+package sdk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoleFromPermission(t *testing.T) {
+	assert.Equal(t, "read", RoleFromPermission(4).Name)
+	assert.Equal(t, "read-execute", RoleFromPermission(5).Name)
+	assert.Equal(t, "read-write-execute", RoleFromPermission(7).Name)
+	assert.False(t, RoleFromPermission(0).Has(CapabilityRead))
+}
+
+func TestRolePermission(t *testing.T) {
+	assert.Equal(t, 7, RoleReadWriteExecute.Permission())
+	assert.Equal(t, 5, RoleReadExecute.Permission())
+	assert.Equal(t, 4, RoleRead.Permission())
+
+	editor := Role{Name: "editor", Capabilities: []string{CapabilityRead, CapabilityEditVariables}}
+	assert.Equal(t, 4, editor.Permission())
+	assert.True(t, editor.Has(CapabilityEditVariables))
+	assert.False(t, editor.Has(CapabilityWrite))
+}
+
+func TestRoleIsValid(t *testing.T) {
+	assert.True(t, RoleRead.IsValid())
+	assert.False(t, (&Role{Name: "empty"}).IsValid())
+	assert.False(t, (&Role{Capabilities: []string{CapabilityRead}}).IsValid())
+	assert.False(t, (&Role{Name: "unknown", Capabilities: []string{"delete"}}).IsValid())
+}
@@ -3,6 +3,8 @@ package sdk
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"time"
 )
 
 // Tests contains all informations about tests in a pipeline build
@@ -13,6 +15,7 @@ type Tests struct {
 	TotalKO         int         `json:"ko"`
 	TotalSkipped    int         `json:"skipped"`
 	TestSuites      []TestSuite `xml:"testsuite" json:"test_suites"`
+	Coverage        *Coverage   `xml:"-" json:"coverage,omitempty"`
 }
 
 // TestSuite defines the result of a group of tests
@@ -34,6 +37,97 @@ type Test struct {
 	Skip    *string `xml:"skipped" json:"skipped"`
 }
 
+// Coverage contains code coverage of a pipeline build, merged from all its reports
+type Coverage struct {
+	LinesValid   int              `json:"lines_valid"`
+	LinesCovered int              `json:"lines_covered"`
+	Percent      float64          `json:"percent"`
+	Reports      []CoverageReport `json:"reports"`
+}
+
+// CoverageReport is the coverage read from one Cobertura or LCOV file
+type CoverageReport struct {
+	Name         string `json:"name"`
+	LinesValid   int    `json:"lines_valid"`
+	LinesCovered int    `json:"lines_covered"`
+}
+
+// CoverageTrend is the coverage of one build of a pipeline
+type CoverageTrend struct {
+	BuildNumber  int64     `json:"build_number"`
+	Version      int64     `json:"version"`
+	Branch       string    `json:"branch"`
+	Done         time.Time `json:"done"`
+	Percent      float64   `json:"percent"`
+	LinesValid   int       `json:"lines_valid"`
+	LinesCovered int       `json:"lines_covered"`
+}
+
+// TestCaseResult is the result of a test case in one build
+type TestCaseResult struct {
+	BuildNumber int64     `json:"build_number"`
+	Branch      string    `json:"branch"`
+	Hash        string    `json:"hash"`
+	Suite       string    `json:"suite"`
+	Name        string    `json:"name"`
+	Status      Status    `json:"status"`
+	Duration    float64   `json:"duration"`
+	Created     time.Time `json:"created"`
+	// Attempt is 0 for the last run of the build, and counts runs before restarts of the build
+	Attempt int `json:"attempt"`
+}
+
+// TestCaseStats aggregates results of a test case over last builds.
+// Flips counts status changes between runs on the same commit,
+// FlakyScore is the ratio of flips to reruns of a commit.
+type TestCaseStats struct {
+	Suite       string  `json:"suite"`
+	Name        string  `json:"name"`
+	Runs        int     `json:"runs"`
+	Failures    int     `json:"failures"`
+	Skipped     int     `json:"skipped"`
+	Flips       int     `json:"flips"`
+	FlakyScore  float64 `json:"flaky_score"`
+	AvgDuration float64 `json:"avg_duration"`
+	MaxDuration float64 `json:"max_duration"`
+	LastStatus  Status  `json:"last_status"`
+}
+
+// AddCoverageReports adds reports to coverage of tests, replacing reports with the same name, then updates totals
+func (t *Tests) AddCoverageReports(reports ...CoverageReport) {
+	if len(reports) == 0 {
+		return
+	}
+	if t.Coverage == nil {
+		t.Coverage = &Coverage{}
+	}
+
+	for _, r := range reports {
+		var found bool
+		for i := range t.Coverage.Reports {
+			if t.Coverage.Reports[i].Name == r.Name {
+				found = true
+				t.Coverage.Reports[i] = r
+				break
+			}
+		}
+		if !found {
+			t.Coverage.Reports = append(t.Coverage.Reports, r)
+		}
+	}
+
+	t.Coverage.LinesValid = 0
+	t.Coverage.LinesCovered = 0
+	for _, r := range t.Coverage.Reports {
+		t.Coverage.LinesValid += r.LinesValid
+		t.Coverage.LinesCovered += r.LinesCovered
+	}
+	t.Coverage.Percent = 0
+	if t.Coverage.LinesValid > 0 {
+		t.Coverage.Percent = float64(t.Coverage.LinesCovered) * 100 / float64(t.Coverage.LinesValid)
+	}
+}
+
 // GetTestResults retrieves tests results for a specific build
 func GetTestResults(proj, app, pip, env string, bn int) (Tests, error) {
 	if env == "" {
@@ -57,3 +151,112 @@ func GetTestResults(proj, app, pip, env string, bn int) (Tests, error) {
 
 	return t, nil
 }
+
+// GetCoverageTrend retrieves coverage of last builds of a pipeline, most recent first.
+// limit 0 uses the API default.
+func GetCoverageTrend(proj, app, pip, env string, limit int) ([]CoverageTrend, error) {
+	if env == "" {
+		env = DefaultEnv.Name
+	}
+	uri := fmt.Sprintf("/project/%s/application/%s/pipeline/%s/test/coverage?envName=%s", proj, app, pip, url.QueryEscape(env))
+	if limit > 0 {
+		uri += fmt.Sprintf("&limit=%d", limit)
+	}
+
+	data, code, err := Request("GET", uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	if code > 300 {
+		return nil, fmt.Errorf("HTTP %d", code)
+	}
+
+	var trend []CoverageTrend
+	if err := json.Unmarshal(data, &trend); err != nil {
+		return nil, err
+	}
+	return trend, nil
+}
+
+// TestHistoryQuery selects builds of a pipeline whose test results are analysed
+type TestHistoryQuery struct {
+	Env    string
+	Branch string
+	// Builds is the number of last builds to analyse, 0 for the API default
+	Builds int
+}
+
+func (q TestHistoryQuery) values() url.Values {
+	v := url.Values{}
+	env := q.Env
+	if env == "" {
+		env = DefaultEnv.Name
+	}
+	v.Set("envName", env)
+	if q.Branch != "" {
+		v.Set("branch", q.Branch)
+	}
+	if q.Builds > 0 {
+		v.Set("builds", fmt.Sprintf("%d", q.Builds))
+	}
+	return v
+}
+
+// GetTestCaseHistory retrieves results of test cases in last builds of a pipeline.
+// Empty suite or name match all test cases.
+func GetTestCaseHistory(proj, app, pip string, q TestHistoryQuery, suite, name string) ([]TestCaseResult, error) {
+	v := q.values()
+	if suite != "" {
+		v.Set("suite", suite)
+	}
+	if name != "" {
+		v.Set("name", name)
+	}
+	uri := fmt.Sprintf("/project/%s/application/%s/pipeline/%s/test/history?%s", proj, app, pip, v.Encode())
+
+	var results []TestCaseResult
+	if err := getTestAnalytics(uri, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// GetFlakyTests retrieves test cases changing status on the same commit in last builds of a pipeline, flakiest first
+func GetFlakyTests(proj, app, pip string, q TestHistoryQuery) ([]TestCaseStats, error) {
+	uri := fmt.Sprintf("/project/%s/application/%s/pipeline/%s/test/flaky?%s", proj, app, pip, q.values().Encode())
+
+	var stats []TestCaseStats
+	if err := getTestAnalytics(uri, &stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// GetSlowestTests retrieves the limit test cases with the highest average duration in last builds of a pipeline
+func GetSlowestTests(proj, app, pip string, q TestHistoryQuery, limit int) ([]TestCaseStats, error) {
+	v := q.values()
+	if limit > 0 {
+		v.Set("limit", fmt.Sprintf("%d", limit))
+	}
+	uri := fmt.Sprintf("/project/%s/application/%s/pipeline/%s/test/slowest?%s", proj, app, pip, v.Encode())
+
+	var stats []TestCaseStats
+	if err := getTestAnalytics(uri, &stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func getTestAnalytics(uri string, v interface{}) error {
+	data, code, err := Request("GET", uri, nil)
+	if err != nil {
+		return err
+	}
+	if code > 300 {
+		if e := DecodeError(data); e != nil {
+			return e
+		}
+		return fmt.Errorf("HTTP %d", code)
+	}
+	return json.Unmarshal(data, v)
+}
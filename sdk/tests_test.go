@@ -0,0 +1,22 @@
+package sdk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddCoverageReports(t *testing.T) {
+	var tests Tests
+	tests.AddCoverageReports()
+	assert.Nil(t, tests.Coverage)
+
+	tests.AddCoverageReports(CoverageReport{Name: "a", LinesValid: 10, LinesCovered: 5}, CoverageReport{Name: "b", LinesValid: 30, LinesCovered: 10})
+	assert.Equal(t, 40, tests.Coverage.LinesValid)
+	assert.Equal(t, 37.5, tests.Coverage.Percent)
+
+	// Report a of a later upload replaces the first one
+	tests.AddCoverageReports(CoverageReport{Name: "a", LinesValid: 10, LinesCovered: 10})
+	assert.Len(t, tests.Coverage.Reports, 2)
+	assert.Equal(t, 50.0, tests.Coverage.Percent)
+}
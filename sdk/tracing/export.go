@@ -0,0 +1,287 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	batchSize     = 256
+	queueSize     = 4096
+	flushInterval = 5 * time.Second
+)
+
+// Exporter sends spans to a tracing backend
+type Exporter interface {
+	Export(service string, spans []*Span) error
+}
+
+var exporter = struct {
+	sync.RWMutex
+	Exporter
+	service string
+	queue   chan *Span
+	flush   chan chan bool
+}{}
+
+// Initialize enables tracing of service, exporting spans to target:
+// - an OpenTelemetry collector URL accepting OTLP/HTTP JSON, ie: http://localhost:4318
+// - a file where spans are appended as OTLP JSON lines, ie: file:///var/log/cds/traces.json
+// Tracing stays disabled if target is empty.
+func Initialize(service, target string) error {
+	if target == "" {
+		return nil
+	}
+
+	var e Exporter
+	switch {
+	case strings.HasPrefix(target, "http://"), strings.HasPrefix(target, "https://"):
+		e = NewCollectorExporter(target)
+	case strings.HasPrefix(target, "file://"):
+		e = NewFileExporter(strings.TrimPrefix(target, "file://"))
+	default:
+		return fmt.Errorf("invalid tracing exporter %s: expected http(s)://collector or file:///path", target)
+	}
+
+	exporter.Lock()
+	defer exporter.Unlock()
+	if exporter.queue == nil {
+		exporter.queue = make(chan *Span, queueSize)
+		exporter.flush = make(chan chan bool)
+		go exportRoutine(exporter.queue, exporter.flush)
+	}
+	exporter.Exporter = e
+	exporter.service = service
+	return nil
+}
+
+// Enabled returns true if spans are exported
+func Enabled() bool {
+	exporter.RLock()
+	defer exporter.RUnlock()
+	return exporter.Exporter != nil
+}
+
+// Flush exports pending spans, to be called before the process exits
+func Flush() {
+	exporter.RLock()
+	flush := exporter.flush
+	exporter.RUnlock()
+	if flush == nil {
+		return
+	}
+
+	done := make(chan bool)
+	select {
+	case flush <- done:
+		<-done
+	case <-time.After(flushInterval):
+	}
+}
+
+// export queues span, it is dropped if the exporter cannot keep up
+func export(s *Span) {
+	exporter.RLock()
+	queue := exporter.queue
+	exporter.RUnlock()
+	if queue == nil {
+		return
+	}
+
+	select {
+	case queue <- s:
+	default:
+	}
+}
+
+func exportRoutine(queue chan *Span, flush chan chan bool) {
+	var batch []*Span
+	send := func() {
+		if len(batch) == 0 {
+			return
+		}
+		exporter.RLock()
+		e, service := exporter.Exporter, exporter.service
+		exporter.RUnlock()
+		if err := e.Export(service, batch); err != nil {
+			fmt.Fprintf(os.Stderr, "tracing> cannot export %d spans: %s\n", len(batch), err)
+		}
+		batch = nil
+	}
+
+	ticker := time.NewTicker(flushInterval)
+	for {
+		select {
+		case s := <-queue:
+			batch = append(batch, s)
+			if len(batch) >= batchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case done := <-flush:
+			for len(queue) > 0 {
+				batch = append(batch, <-queue)
+			}
+			send()
+			done <- true
+		}
+	}
+}
+
+// CollectorExporter sends spans to an OpenTelemetry collector with OTLP/HTTP JSON
+type CollectorExporter struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewCollectorExporter returns an exporter to the collector at address
+func NewCollectorExporter(address string) *CollectorExporter {
+	url := strings.TrimSuffix(address, "/")
+	if !strings.HasSuffix(url, "/v1/traces") {
+		url += "/v1/traces"
+	}
+	return &CollectorExporter{URL: url, Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Export posts spans to the collector
+func (c *CollectorExporter) Export(service string, spans []*Span) error {
+	b, err := json.Marshal(otlpRequest(service, spans))
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.Client.Post(c.URL, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("collector returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// FileExporter appends spans to a file, one OTLP JSON request per line
+type FileExporter struct {
+	Path string
+}
+
+// NewFileExporter returns an exporter to file at path
+func NewFileExporter(path string) *FileExporter {
+	return &FileExporter{Path: path}
+}
+
+// Export appends spans to the file
+func (f *FileExporter) Export(service string, spans []*Span) error {
+	b, err := json.Marshal(otlpRequest(service, spans))
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(f.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(b, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// OTLP JSON encoding, see opentelemetry-proto trace/v1/trace.proto
+
+type otlpTraces struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+func otlpRequest(service string, spans []*Span) otlpTraces {
+	res := otlpResourceSpans{
+		Resource:   otlpResource{Attributes: []otlpAttribute{{Key: "service.name", Value: otlpValue{StringValue: service}}}},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "github.com/ovh/cds"}}},
+	}
+
+	for _, s := range spans {
+		s.mutex.Lock()
+		span := otlpSpan{
+			TraceID:           s.TraceID,
+			SpanID:            s.SpanID,
+			ParentSpanID:      s.ParentID,
+			Name:              s.Name,
+			Kind:              s.Kind,
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+			Status:            otlpStatus{Code: 1},
+		}
+		if s.Error != "" {
+			span.Status = otlpStatus{Code: 2, Message: s.Error}
+		}
+
+		keys := make([]string, 0, len(s.Attributes))
+		for k := range s.Attributes {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			span.Attributes = append(span.Attributes, otlpAttribute{Key: k, Value: otlpValue{StringValue: s.Attributes[k]}})
+		}
+		s.mutex.Unlock()
+
+		res.ScopeSpans[0].Spans = append(res.ScopeSpans[0].Spans, span)
+	}
+
+	return otlpTraces{ResourceSpans: []otlpResourceSpans{res}}
+}
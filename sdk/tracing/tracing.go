@@ -0,0 +1,236 @@
+// Package tracing records spans of a pipeline build across API, scheduler, hatcheries and workers.
+//
+// Trace context is propagated with the W3C traceparent format used by OpenTelemetry:
+// in the traceparent HTTP header between processes, and in the cds.traceparent build parameter
+// from a pipeline build to its actions. Spans are exported to a file or to an OpenTelemetry collector.
+//
+// When tracing is not initialized, Start returns a nil *Span whose methods do nothing.
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Header is the HTTP header carrying trace context
+const Header = "traceparent"
+
+// Parameter is the name of the build parameter carrying trace context of a pipeline build
+const Parameter = "cds.traceparent"
+
+// ParentParameter is the name of the build parameter carrying trace context of the parent pipeline build
+const ParentParameter = "cds.parent.traceparent"
+
+// SpanContext identifies a span in a trace
+type SpanContext struct {
+	TraceID string
+	SpanID  string
+}
+
+// IsValid returns true if both IDs are set
+func (sc SpanContext) IsValid() bool {
+	return validID(sc.TraceID, 16) && validID(sc.SpanID, 8)
+}
+
+// TraceParent formats span context as a W3C traceparent value
+func (sc SpanContext) TraceParent() string {
+	if !sc.IsValid() {
+		return ""
+	}
+	return "00-" + sc.TraceID + "-" + sc.SpanID + "-01"
+}
+
+// ParseTraceParent parses a W3C traceparent value
+func ParseTraceParent(s string) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return SpanContext{}, false
+	}
+	sc := SpanContext{TraceID: strings.ToLower(parts[1]), SpanID: strings.ToLower(parts[2])}
+	if !sc.IsValid() {
+		return SpanContext{}, false
+	}
+	return sc, true
+}
+
+func validID(id string, size int) bool {
+	b, err := hex.DecodeString(id)
+	if err != nil || len(b) != size {
+		return false
+	}
+	for _, c := range b {
+		if c != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func newID(size int) string {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		// Not unique, but tracing must never break a build
+		copy(b, fmt.Sprintf("%x", time.Now().UnixNano()))
+	}
+	return hex.EncodeToString(b)
+}
+
+// NewContext returns the context of a new span, child of parent traceparent if valid
+func NewContext(parent string) SpanContext {
+	if p, ok := ParseTraceParent(parent); ok {
+		return SpanContext{TraceID: p.TraceID, SpanID: newID(8)}
+	}
+	return SpanContext{TraceID: newID(16), SpanID: newID(8)}
+}
+
+// Kinds of span, as defined by OpenTelemetry
+const (
+	KindInternal = 1
+	KindServer   = 2
+	KindClient   = 3
+)
+
+// Span is a timed operation of a trace
+type Span struct {
+	SpanContext
+	ParentID   string
+	Name       string
+	Kind       int
+	Start      time.Time
+	End        time.Time
+	Attributes map[string]string
+	Error      string
+
+	mutex sync.Mutex
+	ended bool
+}
+
+// Start starts a span, child of parent traceparent if valid, in a new trace otherwise.
+// It returns nil if tracing is disabled.
+func Start(name, parent string) *Span {
+	return StartAt(name, parent, time.Now())
+}
+
+// StartAt starts a span at given time, to record operations already done
+func StartAt(name, parent string, start time.Time) *Span {
+	if !Enabled() {
+		return nil
+	}
+	p, _ := ParseTraceParent(parent)
+	return Restore(name, NewContext(parent), p.SpanID, start)
+}
+
+// Continue starts a span in the trace of parent traceparent.
+// It returns nil if parent is not valid, operations outside of a trace are not recorded.
+func Continue(name, parent string) *Span {
+	return ContinueAt(name, parent, time.Now())
+}
+
+// ContinueAt starts a span at given time in the trace of parent traceparent, nil if parent is not valid
+func ContinueAt(name, parent string, start time.Time) *Span {
+	if _, ok := ParseTraceParent(parent); !ok {
+		return nil
+	}
+	return StartAt(name, parent, start)
+}
+
+// Restore recreates a span whose context was propagated before the span ended,
+// like the span of a pipeline build whose context is stored in build parameters
+func Restore(name string, sc SpanContext, parentID string, start time.Time) *Span {
+	if !Enabled() || !sc.IsValid() {
+		return nil
+	}
+	return &Span{
+		SpanContext: sc,
+		ParentID:    parentID,
+		Name:        name,
+		Kind:        KindInternal,
+		Start:       start,
+		Attributes:  map[string]string{},
+	}
+}
+
+// TraceParent returns the traceparent value propagating span
+func (s *Span) TraceParent() string {
+	if s == nil {
+		return ""
+	}
+	return s.SpanContext.TraceParent()
+}
+
+// SetAttribute sets an attribute of span
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	s.Attributes[key] = fmt.Sprintf("%v", value)
+	s.mutex.Unlock()
+}
+
+// SetKind sets kind of span, KindInternal by default
+func (s *Span) SetKind(kind int) {
+	if s == nil {
+		return
+	}
+	s.Kind = kind
+}
+
+// SetError marks span as failed if err is not nil
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mutex.Lock()
+	s.Error = err.Error()
+	s.mutex.Unlock()
+}
+
+// Finish ends span now and exports it
+func (s *Span) Finish() {
+	s.FinishAt(time.Now())
+}
+
+// FinishAt ends span at given time and exports it. Only the first call exports the span.
+func (s *Span) FinishAt(end time.Time) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	if s.ended {
+		s.mutex.Unlock()
+		return
+	}
+	s.ended = true
+	s.End = end
+	s.mutex.Unlock()
+
+	export(s)
+}
+
+// current is the span of the operation in progress in a process running one operation at a time, like a worker
+var current = struct {
+	sync.Mutex
+	span *Span
+}{}
+
+// SetCurrent sets the span of the operation in progress, used as parent of outgoing requests.
+// It returns the previous current span, to be restored when the operation ends.
+func SetCurrent(s *Span) *Span {
+	current.Lock()
+	defer current.Unlock()
+	previous := current.span
+	current.span = s
+	return previous
+}
+
+// Current returns the span of the operation in progress
+func Current() *Span {
+	current.Lock()
+	defer current.Unlock()
+	return current.span
+}
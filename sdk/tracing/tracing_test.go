@@ -0,0 +1,66 @@
+package tracing
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	sc, ok := ParseTraceParent("00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01")
+	assert.True(t, ok)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", sc.TraceParent())
+
+	for _, s := range []string{"", "00-4bf92f3577b34da6a3ce929d0e0e4736-01", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01", "00-4bf92f3577b34da6a3ce929d0e0e4736-xyz-01"} {
+		_, ok := ParseTraceParent(s)
+		assert.False(t, ok, s)
+	}
+
+	child := NewContext(sc.TraceParent())
+	assert.Equal(t, sc.TraceID, child.TraceID)
+	assert.NotEqual(t, sc.SpanID, child.SpanID)
+	assert.True(t, NewContext("").IsValid())
+}
+
+func TestFileExporter(t *testing.T) {
+	assert.Nil(t, Start("disabled", ""))
+
+	f, err := ioutil.TempFile("", "traces")
+	assert.NoError(t, err)
+	f.Close()
+	defer os.Remove(f.Name())
+
+	assert.NoError(t, Initialize("cds-test", "file://"+f.Name()))
+	assert.Error(t, Initialize("cds-test", "udp://localhost"))
+
+	root := Start("root", "")
+	assert.Nil(t, Continue("orphan", ""))
+	child := ContinueAt("child", root.TraceParent(), time.Now().Add(-time.Second))
+	child.SetAttribute("cds.status", "Fail")
+	child.SetError(os.ErrNotExist)
+	child.Finish()
+	root.Finish()
+	root.Finish()
+	Flush()
+
+	b, err := ioutil.ReadFile(f.Name())
+	assert.NoError(t, err)
+
+	var req otlpTraces
+	assert.NoError(t, json.Unmarshal(b, &req))
+	if assert.Len(t, req.ResourceSpans, 1) && assert.Len(t, req.ResourceSpans[0].ScopeSpans[0].Spans, 2) {
+		spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+		assert.Equal(t, "cds-test", req.ResourceSpans[0].Resource.Attributes[0].Value.StringValue)
+		assert.Equal(t, "child", spans[0].Name)
+		assert.Equal(t, root.TraceID, spans[0].TraceID)
+		assert.Equal(t, root.SpanID, spans[0].ParentSpanID)
+		assert.Equal(t, 2, spans[0].Status.Code)
+		assert.Equal(t, "root", spans[1].Name)
+		assert.Equal(t, "", spans[1].ParentSpanID)
+	}
+}
@@ -3,6 +3,7 @@ package sdk
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"time"
 )
 
@@ -15,6 +16,7 @@ type Worker struct {
 	Model      int64     `json:"model"`
 	HatcheryID int64     `json:"hatchery_id"`
 	Status     Status    `json:"status"` // Waiting, Building, Disabled, Unknown
+	SingleUse  bool      `json:"single_use"`
 }
 
 // Existing worker type
@@ -36,24 +38,31 @@ var (
 // Model represents a worker model (ex: Go 1.5.1 Docker Images)
 // with specified capabilities (ex: go, golint and go2xunit binaries)
 type Model struct {
-	ID           int64         `json:"id" db:"id"`
-	Name         string        `json:"name"  db:"name"`
-	Type         string        `json:"type"  db:"type"`
-	Image        string        `json:"image" db:"image"`
-	Capabilities []Requirement `json:"capabilities" db:"-"`
-	CreatedBy    User          `json:"created_by" db:"-"`
-	GroupID      int64         `json:"group_id" db:"group_id"`
+	ID            int64             `json:"id" db:"id"`
+	Name          string            `json:"name"  db:"name"`
+	Type          string            `json:"type"  db:"type"`
+	Image         string            `json:"image" db:"image"`
+	Capabilities  []Requirement     `json:"capabilities" db:"-"`
+	CreatedBy     User              `json:"created_by" db:"-"`
+	GroupID       int64             `json:"group_id" db:"group_id"`
+	Autoscaling   *ModelAutoscaling `json:"autoscaling,omitempty" db:"-"`
+	SingleUse     bool              `json:"single_use,omitempty" db:"single_use"`
+	KeepOnFailure int64             `json:"keep_on_failure,omitempty" db:"keep_on_failure"` // minutes workers wait for a debug session after a failed step
 }
 
 // ModelStatus sums up the number of worker deployed and wanted for a given model
 type ModelStatus struct {
-	ModelID       int64         `json:"model_id" yaml:"-"`
-	ModelName     string        `json:"model_name" yaml:"name"`
-	ModelGroupID  int64         `json:"model_group_id" yaml:"model_group_id"`
-	CurrentCount  int64         `json:"current_count" yaml:"current"`
-	WantedCount   int64         `json:"wanted_count" yaml:"wanted"`
-	BuildingCount int64         `json:"building_count" yaml:"building"`
-	Requirements  []Requirement `json:"requirements"`
+	ModelID       int64             `json:"model_id" yaml:"-"`
+	ModelName     string            `json:"model_name" yaml:"name"`
+	ModelGroupID  int64             `json:"model_group_id" yaml:"model_group_id"`
+	CurrentCount  int64             `json:"current_count" yaml:"current"`
+	WantedCount   int64             `json:"wanted_count" yaml:"wanted"`
+	BuildingCount int64             `json:"building_count" yaml:"building"`
+	Requirements  []Requirement     `json:"requirements"`
+	QueueWait     int64             `json:"queue_wait" yaml:"queue_wait"`
+	Autoscaling   *ModelAutoscaling `json:"autoscaling,omitempty" yaml:"-"`
+	SingleUse     bool              `json:"single_use" yaml:"single_use"`
+	TraceParents  []string          `json:"trace_parents,omitempty" yaml:"-"`
 }
 
 // OpenstackModelData type details the "Image" field of Openstack type model
@@ -63,6 +72,68 @@ type OpenstackModelData struct {
 	UserData string `json:"user_data"`
 }
 
+// WorkerCapabilities are the requirements met by a worker, declared once so that engine hands it matching action builds
+type WorkerCapabilities struct {
+	Requirements []Requirement `json:"requirements"`
+	// Unmet are the requirements checked by worker but not met, nil if worker does not declare them
+	Unmet []Requirement `json:"unmet"`
+}
+
+func containsRequirement(reqs []Requirement, r Requirement) bool {
+	for _, capa := range reqs {
+		if capa.Type == r.Type && capa.Value == r.Value {
+			return true
+		}
+	}
+	return false
+}
+
+// Meet returns true if all given requirements are in worker capabilities
+func (c WorkerCapabilities) Meet(reqs []Requirement) bool {
+	for _, r := range reqs {
+		if !containsRequirement(c.Requirements, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// Knows returns true if all given requirements were checked by worker when declaring its capabilities.
+// Requirements added since then have to be checked by declaring capabilities again.
+func (c WorkerCapabilities) Knows(reqs []Requirement) bool {
+	if c.Unmet == nil {
+		return true
+	}
+	for _, r := range reqs {
+		if !containsRequirement(c.Requirements, r) && !containsRequirement(c.Unmet, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// DeclareWorkerCapabilities declares to engine the capabilities of calling worker
+func DeclareWorkerCapabilities(c WorkerCapabilities) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	_, code, err := Request("PUT", "/worker/capabilities", data)
+	if e, ok := err.(Error); (ok && e.ID == ErrNotFound.ID) || code == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if code >= 300 {
+		return fmt.Errorf("API error (%d)", code)
+	}
+
+	return nil
+}
+
 // GetWorkers retrieves from engine all worker the user has access to
 func GetWorkers(models ...string) ([]Worker, error) {
 
@@ -176,6 +247,47 @@ func UpdateWorkerModel(id int64, name string, t string, value string) error {
 	return nil
 }
 
+// SetWorkerModelSingleUse sets whether workers of a model run only one action before being destroyed
+func SetWorkerModelSingleUse(id int64, singleUse bool) error {
+	uri := fmt.Sprintf("/worker/model/%d", id)
+
+	data, err := json.Marshal(map[string]interface{}{"id": id, "single_use": singleUse})
+	if err != nil {
+		return err
+	}
+
+	data, code, err := Request("PUT", uri, data)
+	if err != nil {
+		return err
+	}
+	if code >= 300 {
+		return fmt.Errorf("Error [%d]: %s", code, data)
+	}
+
+	return nil
+}
+
+// SetWorkerModelKeepOnFailure sets how many minutes workers of a model wait for a debug session
+// after a failed step, disabled if 0
+func SetWorkerModelKeepOnFailure(id int64, minutes int64) error {
+	uri := fmt.Sprintf("/worker/model/%d", id)
+
+	data, err := json.Marshal(map[string]interface{}{"id": id, "keep_on_failure": minutes})
+	if err != nil {
+		return err
+	}
+
+	data, code, err := Request("PUT", uri, data)
+	if err != nil {
+		return err
+	}
+	if code >= 300 {
+		return fmt.Errorf("Error [%d]: %s", code, data)
+	}
+
+	return nil
+}
+
 // GetWorkerModels retrieves all worker models avaialbe to user
 func GetWorkerModels() ([]Model, error) {
 	uri := fmt.Sprintf("/worker/model")
@@ -0,0 +1,180 @@
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ModelAutoscaling configures how hatcheries scale workers of a model
+type ModelAutoscaling struct {
+	// Min is the minimum number of workers, idle or building. Building workers of single use models
+	// are not counted since they are destroyed once their action is done
+	Min int `json:"min"`
+	// Max is the maximum number of workers, idle or building. 0 means unlimited
+	Max int `json:"max"`
+	// WarmPool is the number of idle workers kept ready, out of WarmPoolSchedule
+	WarmPool int `json:"warm_pool"`
+	// WarmPoolSchedule overrides WarmPool at given days and hours
+	WarmPoolSchedule []WarmPoolSchedule `json:"warm_pool_schedule,omitempty"`
+	// ScaleUpCooldown is the minimum delay between two spawns, in seconds
+	ScaleUpCooldown int `json:"scale_up_cooldown"`
+	// ScaleDownCooldown is the minimum delay after a spawn or a kill before killing workers, in seconds
+	ScaleDownCooldown int `json:"scale_down_cooldown"`
+	// QueueWaitThreshold is the queue wait time, in seconds, after which ScaleUpStep more workers are spawned
+	// regardless of ScaleUpCooldown. 0 disables queue wait based scale up
+	QueueWaitThreshold int `json:"queue_wait_threshold"`
+	// ScaleUpStep is the number of extra workers spawned when QueueWaitThreshold is exceeded
+	ScaleUpStep int `json:"scale_up_step"`
+	// Predictive smoothes demand over time so that workers are kept when demand oscillates
+	Predictive bool `json:"predictive"`
+}
+
+// WarmPoolSchedule is a warm pool size applied on some days between From and To (hatchery local time)
+type WarmPoolSchedule struct {
+	Days     []time.Weekday `json:"days"`
+	From     string         `json:"from"`
+	To       string         `json:"to"`
+	WarmPool int            `json:"warm_pool"`
+}
+
+// IsValid checks autoscaling settings consistency
+func (a *ModelAutoscaling) IsValid() error {
+	if a.Min < 0 || a.Max < 0 || a.WarmPool < 0 || a.ScaleUpCooldown < 0 || a.ScaleDownCooldown < 0 ||
+		a.QueueWaitThreshold < 0 || a.ScaleUpStep < 0 {
+		return NewError(ErrInvalidAutoscaling, fmt.Errorf("values must be positive"))
+	}
+	if a.Max > 0 && a.Min > a.Max {
+		return NewError(ErrInvalidAutoscaling, fmt.Errorf("min %d is greater than max %d", a.Min, a.Max))
+	}
+	for _, s := range a.WarmPoolSchedule {
+		if _, err := parseHour(s.From); err != nil {
+			return NewError(ErrInvalidAutoscaling, err)
+		}
+		if _, err := parseHour(s.To); err != nil {
+			return NewError(ErrInvalidAutoscaling, err)
+		}
+		if s.WarmPool < 0 {
+			return NewError(ErrInvalidAutoscaling, fmt.Errorf("warm pool must be positive"))
+		}
+	}
+	return nil
+}
+
+// WarmPoolAt returns the warm pool size at given time: the first matching schedule, or WarmPool
+func (a *ModelAutoscaling) WarmPoolAt(t time.Time) int {
+	minutes := t.Hour()*60 + t.Minute()
+	for _, s := range a.WarmPoolSchedule {
+		if !s.matchDay(t.Weekday()) {
+			continue
+		}
+		from, err := parseHour(s.From)
+		if err != nil {
+			continue
+		}
+		to, err := parseHour(s.To)
+		if err != nil {
+			continue
+		}
+		if minutes >= from && minutes < to {
+			return s.WarmPool
+		}
+	}
+	return a.WarmPool
+}
+
+func (s WarmPoolSchedule) matchDay(d time.Weekday) bool {
+	if len(s.Days) == 0 {
+		return true
+	}
+	for _, day := range s.Days {
+		if day == d {
+			return true
+		}
+	}
+	return false
+}
+
+// parseHour returns minutes since midnight of a HH:MM hour
+func parseHour(s string) (int, error) {
+	t := strings.Split(s, ":")
+	if len(t) != 2 {
+		return 0, fmt.Errorf("invalid hour %s, expected HH:MM", s)
+	}
+	h, errH := strconv.Atoi(t[0])
+	m, errM := strconv.Atoi(t[1])
+	if errH != nil || errM != nil || h < 0 || h > 24 || m < 0 || m > 59 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid hour %s, expected HH:MM", s)
+	}
+	return h*60 + m, nil
+}
+
+// ParseWarmPoolSchedule parses a schedule formatted as "<days> <from>-<to> <size>",
+// days being a range (1-5) or a list (0,6) of weekdays, 0 is sunday. i.e. "1-5 08:00-19:00 3"
+func ParseWarmPoolSchedule(s string) (WarmPoolSchedule, error) {
+	var res WarmPoolSchedule
+	t := strings.Fields(s)
+	if len(t) != 3 {
+		return res, fmt.Errorf("invalid schedule %s, expected \"<days> <from>-<to> <size>\"", s)
+	}
+
+	if i := strings.Index(t[0], "-"); i > 0 {
+		from, errF := strconv.Atoi(t[0][:i])
+		to, errT := strconv.Atoi(t[0][i+1:])
+		if errF != nil || errT != nil || from < 0 || to > 6 || from > to {
+			return res, fmt.Errorf("invalid days %s", t[0])
+		}
+		for d := from; d <= to; d++ {
+			res.Days = append(res.Days, time.Weekday(d))
+		}
+	} else {
+		for _, day := range strings.Split(t[0], ",") {
+			d, err := strconv.Atoi(day)
+			if err != nil || d < 0 || d > 6 {
+				return res, fmt.Errorf("invalid days %s", t[0])
+			}
+			res.Days = append(res.Days, time.Weekday(d))
+		}
+	}
+
+	hours := strings.Split(t[1], "-")
+	if len(hours) != 2 {
+		return res, fmt.Errorf("invalid hours %s, expected HH:MM-HH:MM", t[1])
+	}
+	res.From, res.To = hours[0], hours[1]
+	if _, err := parseHour(res.From); err != nil {
+		return res, err
+	}
+	if _, err := parseHour(res.To); err != nil {
+		return res, err
+	}
+
+	size, err := strconv.Atoi(t[2])
+	if err != nil || size < 0 {
+		return res, fmt.Errorf("invalid warm pool size %s", t[2])
+	}
+	res.WarmPool = size
+	return res, nil
+}
+
+// UpdateWorkerModelAutoscaling sets autoscaling settings of a worker model
+func UpdateWorkerModelAutoscaling(id int64, a ModelAutoscaling) error {
+	uri := fmt.Sprintf("/worker/model/%d", id)
+
+	data, err := json.Marshal(Model{ID: id, Autoscaling: &a})
+	if err != nil {
+		return err
+	}
+
+	data, code, err := Request("PUT", uri, data)
+	if err != nil {
+		return err
+	}
+	if code >= 300 {
+		return fmt.Errorf("Error [%d]: %s", code, data)
+	}
+
+	return nil
+}
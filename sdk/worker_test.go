@@ -0,0 +1,35 @@
+package sdk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkerCapabilitiesMeet(t *testing.T) {
+	c := WorkerCapabilities{Requirements: []Requirement{
+		{Name: "git", Type: BinaryRequirement, Value: "git"},
+		{Name: "gitlab", Type: NetworkAccessRequirement, Value: "gitlab.local:443"},
+	}}
+
+	assert.True(t, c.Meet(nil))
+	assert.True(t, c.Meet([]Requirement{{Name: "Git", Type: BinaryRequirement, Value: "git"}}))
+	assert.False(t, c.Meet([]Requirement{{Name: "git", Type: HostnameRequirement, Value: "git"}}))
+	assert.False(t, c.Meet([]Requirement{
+		{Name: "git", Type: BinaryRequirement, Value: "git"},
+		{Name: "docker", Type: BinaryRequirement, Value: "docker"},
+	}))
+}
+
+func TestWorkerCapabilitiesKnows(t *testing.T) {
+	docker := Requirement{Name: "docker", Type: BinaryRequirement, Value: "docker"}
+	c := WorkerCapabilities{Requirements: []Requirement{{Name: "git", Type: BinaryRequirement, Value: "git"}}}
+
+	// Workers not declaring unmet requirements know all of them
+	assert.True(t, c.Knows([]Requirement{docker}))
+
+	c.Unmet = []Requirement{}
+	assert.False(t, c.Knows([]Requirement{docker}))
+	c.Unmet = append(c.Unmet, docker)
+	assert.True(t, c.Knows([]Requirement{docker, {Name: "Git", Type: BinaryRequirement, Value: "git"}}))
+}